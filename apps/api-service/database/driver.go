@@ -9,7 +9,10 @@ import (
 var DB *gorm.DB
 
 func Default(dsn string) *gorm.DB {
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+		// Unique index violations come back as gorm.ErrDuplicatedKey.
+		TranslateError: true,
+	})
 	if err != nil {
 		log.Fatalf("failed opening connection to mysql: %v", err)
 	}
@@ -0,0 +1,109 @@
+package account
+
+import (
+	"errors"
+	"flash/internal/auth"
+	objectStorage "flash/sdk/object_storage"
+	"flash/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service *Service
+}
+
+func NewController(db *gorm.DB, objectStorage objectStorage.Provider) *Controller {
+	return &Controller{Service: NewService(db, objectStorage)}
+}
+
+func (controller Controller) RequestExport(context *gin.Context) {
+	queued, err := controller.Service.RequestExport(context.GetUint64("user_id"))
+	if err != nil {
+		respondAccountError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusAccepted, gin.H{"job": queued})
+}
+
+func (controller Controller) ListExports(context *gin.Context) {
+	exports, err := controller.Service.ListExports(context.GetUint64("user_id"))
+	if err != nil {
+		respondAccountError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, exports)
+}
+
+func (controller Controller) DownloadExport(context *gin.Context) {
+	jobID, err := strconv.ParseUint(context.Param("id"), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "Invalid export ID")
+		context.Abort()
+		return
+	}
+
+	url, result, err := controller.Service.ExportDownloadURL(context.GetUint64("user_id"), jobID)
+	if err != nil {
+		respondAccountError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{
+		"url":    url,
+		"export": result,
+	})
+}
+
+func (controller Controller) RequestDeletion(context *gin.Context) {
+	var request RequestDeletionRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	user, err := controller.Service.RequestDeletion(context.GetUint64("user_id"), context.GetUint64("session_id"), request.Password, request.Confirmation)
+	if err != nil {
+		respondAccountError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{
+		"deletion_requested_at": user.DeletionRequestedAt,
+		"deletion_scheduled_at": user.DeletionScheduledAt,
+	})
+}
+
+func (controller Controller) CancelDeletion(context *gin.Context) {
+	user, err := controller.Service.CancelDeletion(context.GetUint64("user_id"))
+	if err != nil {
+		respondAccountError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, user)
+}
+
+func respondAccountError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrExportNotFound):
+		utils.APIRespondError(context, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrExportNotReady), errors.Is(err, ErrDeletionAlreadyRequested), errors.Is(err, ErrDeletionNotRequested):
+		utils.APIRespondError(context, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrExportExpired):
+		utils.APIRespondError(context, http.StatusGone, err.Error())
+	case errors.Is(err, auth.ErrInvalidPassword), errors.Is(err, ErrDeletionConfirmation):
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrDeletionReauthRequired):
+		utils.APIRespondError(context, http.StatusForbidden, err.Error())
+	default:
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+	}
+	context.Abort()
+}
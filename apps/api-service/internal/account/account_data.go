@@ -0,0 +1,247 @@
+package account
+
+import (
+	"encoding/json"
+	"flash/models"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// accountData is everything a user owns, as it is written to an export and as it is removed on deletion.
+type accountData struct {
+	User                 models.User              `json:"user"`
+	Sessions             []models.UserSession     `json:"sessions"`
+	APIKeys              []models.APIKey          `json:"api_keys"`
+	Workspaces           []models.Workspace       `json:"workspaces"`
+	WorkspaceMemberships []models.WorkspaceMember `json:"workspace_memberships"`
+	ParsedFiles          []models.ParsedFile      `json:"parsed_files"`
+	Appointments         []models.Appointment     `json:"appointments"`
+	Projects             []projectData            `json:"-"`
+}
+
+// projectData is one project with its builder content and the rows hanging off it.
+type projectData struct {
+	Project          models.Project                   `json:"project"`
+	LinktreeSections []models.LinktreeSection         `json:"linktree_sections"`
+	DisplayPosters   []models.MenuDisplayPoster       `json:"display_posters"`
+	WaitlistSignups  []models.WaitlistSignup          `json:"waitlist_signups"`
+	Revisions        []models.ProjectRevision         `json:"revisions"`
+	Domains          []models.ProjectDomain           `json:"domains"`
+	SubDomainHistory []models.ProjectSubDomainHistory `json:"sub_domain_history"`
+}
+
+// loadAccountData reads every record the user owns. Soft-deleted rows are included: they are still the user's data
+// and are still in the database. Each query starts from db.Unscoped() so conditions don't carry over.
+func loadAccountData(db *gorm.DB, userID uint64) (*accountData, error) {
+	data := &accountData{}
+
+	if err := db.Unscoped().Preload("Identities").First(&data.User, userID).Error; err != nil {
+		return nil, err
+	}
+
+	queries := []struct {
+		target any
+		query  *gorm.DB
+	}{
+		{&data.Sessions, db.Unscoped().Where("user_id = ?", userID)},
+		{&data.APIKeys, db.Unscoped().Where("user_id = ?", userID)},
+		{&data.Workspaces, db.Unscoped().Where("owner_id = ?", userID)},
+		{&data.WorkspaceMemberships, db.Unscoped().Where("user_id = ?", userID)},
+		{&data.ParsedFiles, db.Unscoped().Where("user_id = ?", userID)},
+	}
+	for _, item := range queries {
+		if err := item.query.Order("id ASC").Find(item.target).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var projectIDs []uint64
+	if err := db.Unscoped().Model(&models.Project{}).Where("user_id = ?", userID).Order("id ASC").Pluck("id", &projectIDs).Error; err != nil {
+		return nil, err
+	}
+
+	appointments := db.Unscoped().Where("user_id = ?", userID)
+	if len(projectIDs) > 0 {
+		appointments = db.Unscoped().Where("user_id = ? OR project_id IN ?", userID, projectIDs)
+	}
+	if err := appointments.Order("id ASC").Find(&data.Appointments).Error; err != nil {
+		return nil, err
+	}
+
+	for _, projectID := range projectIDs {
+		project, err := loadProjectData(db, projectID)
+		if err != nil {
+			return nil, fmt.Errorf("project %d: %w", projectID, err)
+		}
+		data.Projects = append(data.Projects, *project)
+	}
+
+	return data, nil
+}
+
+func loadProjectData(db *gorm.DB, projectID uint64) (*projectData, error) {
+	data := &projectData{}
+
+	// Preload every content type: a project only has rows for its own, and older projects may have changed type.
+	if err := db.Unscoped().
+		Preload("Portfolio").
+		Preload("Portfolio.WorkExperiences").
+		Preload("Portfolio.Education").
+		Preload("Portfolio.Showcases").
+		Preload("Portfolio.Showcases.ShowcaseTechnologies").
+		Preload("Portfolio.Skills").
+		Preload("Biz").
+		Preload("Biz.Services").
+		Preload("Biz.Products").
+		Preload("Biz.Testimonials").
+		Preload("Biz.SocialLinks").
+		Preload("Biz.FAQs").
+		Preload("Biz.Gallery").
+		Preload("Linktree").
+		Preload("Linktree.Links").
+		Preload("Menu").
+		Preload("Menu.Categories").
+		Preload("Menu.Items").
+		Preload("Waitlist").
+		First(&data.Project, projectID).Error; err != nil {
+		return nil, err
+	}
+
+	if linktree := data.Project.Linktree; linktree != nil {
+		if err := db.Unscoped().Where("linktree_id = ?", linktree.ID).Order("id ASC").Find(&data.LinktreeSections).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	queries := []struct {
+		target any
+		query  *gorm.DB
+	}{
+		{&data.DisplayPosters, db.Unscoped().Where("project_id = ?", projectID)},
+		{&data.WaitlistSignups, db.Unscoped().Where("project_id = ?", projectID)},
+		{&data.Revisions, db.Unscoped().Where("project_id = ?", projectID)},
+		{&data.Domains, db.Unscoped().Where("project_id = ?", projectID)},
+		{&data.SubDomainHistory, db.Unscoped().Where("project_id = ?", projectID)},
+	}
+	for _, item := range queries {
+		if err := item.query.Order("id ASC").Find(item.target).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+func (data *accountData) projectIDs() []uint64 {
+	ids := make([]uint64, 0, len(data.Projects))
+	for _, project := range data.Projects {
+		ids = append(ids, project.Project.ID)
+	}
+	return ids
+}
+
+// assetPaths lists the storage objects the user's records point at. Only objects stored under the user's own
+// projects and avatar folder count; a URL copied from somewhere else is neither exported nor deleted.
+func (data *accountData) assetPaths(storageBase string) ([]string, error) {
+	seen := map[string]bool{}
+	var paths []string
+
+	if data.User.ImageURL != nil {
+		avatar := storagePath(*data.User.ImageURL, storageBase)
+		if strings.HasPrefix(avatar, fmt.Sprintf("users/%d/", data.User.ID)) {
+			seen[avatar] = true
+			paths = append(paths, avatar)
+		}
+	}
+
+	for _, project := range data.Projects {
+		projectPaths, err := project.assetPaths(storageBase)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range projectPaths {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// assetPaths lists the objects stored under the project's folder that its records point at, plus its OG image.
+func (project *projectData) assetPaths(storageBase string) ([]string, error) {
+	encoded, err := json.Marshal(project)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, err
+	}
+
+	projectPrefix := fmt.Sprintf("projects/%d/", project.Project.ID)
+	ogImage := fmt.Sprintf("og_images/%d.png", project.Project.ID)
+
+	seen := map[string]bool{}
+	var paths []string
+	walkStrings(decoded, func(value string) {
+		path := storagePath(value, storageBase)
+		if path == "" || seen[path] {
+			return
+		}
+		if strings.HasPrefix(path, projectPrefix) || path == ogImage {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	})
+
+	return paths, nil
+}
+
+func walkStrings(value any, visit func(string)) {
+	switch typed := value.(type) {
+	case string:
+		visit(typed)
+	case []any:
+		for _, item := range typed {
+			walkStrings(item, visit)
+		}
+	case map[string]any:
+		for _, item := range typed {
+			walkStrings(item, visit)
+		}
+	}
+}
+
+// storagePath turns a stored URL back into its object path. storageBase is the provider's URL for an empty path,
+// i.e. the prefix every public URL it hands out starts with.
+func storagePath(value string, storageBase string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+
+	if storageBase != "" && strings.HasPrefix(value, storageBase) {
+		value = strings.TrimPrefix(value, storageBase)
+	} else if strings.Contains(value, "://") {
+		return ""
+	}
+
+	// Drop any query string, e.g. from a signed URL.
+	if index := strings.IndexAny(value, "?#"); index >= 0 {
+		value = value[:index]
+	}
+
+	path := strings.TrimPrefix(value, "/")
+	if strings.Contains(path, "..") {
+		return ""
+	}
+
+	return path
+}
@@ -0,0 +1,372 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flash/internal/auth"
+	"flash/internal/job"
+	"flash/models"
+	"flash/sdk/mailer"
+	objectStorage "flash/sdk/object_storage"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultDeletionGracePeriod is how long a deletion request can be cancelled. ACCOUNT_DELETION_GRACE_DAYS
+// overrides it.
+const defaultDeletionGracePeriod = 14 * 24 * time.Hour
+
+// deletionReauthWindow is how recently an account without a password must have signed in to request deletion.
+const deletionReauthWindow = 10 * time.Minute
+
+// deletionBatchSize bounds how many accounts one scheduler run purges.
+const deletionBatchSize = 10
+
+var (
+	ErrDeletionAlreadyRequested = errors.New("account deletion is already scheduled")
+	ErrDeletionNotRequested     = errors.New("account deletion is not scheduled")
+	ErrDeletionConfirmation     = errors.New("type your email address to confirm")
+	ErrDeletionReauthRequired   = errors.New("sign in again to confirm deleting your account")
+)
+
+// RequestDeletion schedules the account for deletion after the grace period. Accounts with a password must enter
+// it. Accounts that only sign in through a provider have an empty password: they type their email and must be on a
+// session they signed in to within deletionReauthWindow, so a stolen long-lived session can't delete them. Every
+// other device is signed out, the current one stays signed in so the request can still be cancelled.
+func (service Service) RequestDeletion(userID uint64, currentSessionID uint64, password string, confirmation string) (*models.User, error) {
+	var user models.User
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		if user.DeletionScheduledAt != nil {
+			return ErrDeletionAlreadyRequested
+		}
+
+		if user.Password != nil && *user.Password != "" {
+			if bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(password)) != nil {
+				return auth.ErrInvalidPassword
+			}
+		} else {
+			if !strings.EqualFold(strings.TrimSpace(confirmation), user.Email) {
+				return ErrDeletionConfirmation
+			}
+			if err := requireRecentSignIn(tx, user.ID, currentSessionID); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		scheduledAt := now.Add(deletionGracePeriod())
+		user.DeletionRequestedAt = &now
+		user.DeletionScheduledAt = &scheduledAt
+
+		return tx.Model(&user).Updates(map[string]any{
+			"deletion_requested_at": now,
+			"deletion_scheduled_at": scheduledAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	authService := auth.Service{DB: service.DB}
+	if _, err := authService.RevokeAllSessions(userID, currentSessionID); err != nil {
+		log.Printf("[WARN] Failed to sign out other sessions of user %d: %v", userID, err)
+	}
+
+	if err := sendDeletionNotice(&user); err != nil {
+		log.Printf("[WARN] Failed to send deletion notice to user %d: %v", userID, err)
+	}
+
+	return &user, nil
+}
+
+// requireRecentSignIn checks that the current session was signed in to within deletionReauthWindow.
+func requireRecentSignIn(tx *gorm.DB, userID uint64, sessionID uint64) error {
+	err := auth.Service{DB: tx}.RequireRecentSignIn(userID, sessionID, deletionReauthWindow)
+	if errors.Is(err, auth.ErrRecentSignInRequired) {
+		return ErrDeletionReauthRequired
+	}
+	return err
+}
+
+func (service Service) CancelDeletion(userID uint64) (*models.User, error) {
+	var user models.User
+	if err := service.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	if user.DeletionScheduledAt == nil {
+		return nil, ErrDeletionNotRequested
+	}
+
+	if err := service.DB.Model(&user).Updates(map[string]any{
+		"deletion_requested_at": nil,
+		"deletion_scheduled_at": nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+	user.DeletionRequestedAt = nil
+	user.DeletionScheduledAt = nil
+
+	return &user, nil
+}
+
+// RunScheduledDeletions purges accounts whose grace period has passed. A purge that fails is retried on the next
+// run; the account stays scheduled until it succeeds.
+func (service Service) RunScheduledDeletions(ctx context.Context) error {
+	var dueIDs []uint64
+	if err := service.DB.WithContext(ctx).Model(&models.User{}).
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", time.Now()).
+		Order("deletion_scheduled_at ASC").
+		Limit(deletionBatchSize).
+		Pluck("id", &dueIDs).Error; err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, userID := range dueIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := service.purgeUser(ctx, userID); err != nil {
+			errs = append(errs, fmt.Errorf("delete user %d: %w", userID, err))
+			continue
+		}
+		log.Printf("[INFO] Deleted account %d", userID)
+	}
+
+	return errors.Join(errs...)
+}
+
+// purgeUser removes the storage objects first and the rows second. If storage fails nothing is lost yet and the
+// whole purge is retried; deleting an object that is already gone is not an error.
+func (service Service) purgeUser(ctx context.Context, userID uint64) error {
+	db := service.DB.WithContext(ctx)
+
+	data, err := loadAccountData(db, userID)
+	if err != nil {
+		return err
+	}
+
+	storageBase, _ := service.ObjectStorage.GetURL("")
+	paths, err := data.assetPaths(storageBase)
+	if err != nil {
+		return err
+	}
+
+	exportPaths, err := exportArchivePaths(db, userID)
+	if err != nil {
+		return err
+	}
+	paths = append(paths, exportPaths...)
+
+	for _, path := range paths {
+		if _, err := service.ObjectStorage.Delete(path); err != nil {
+			return fmt.Errorf("delete object %s: %w", path, err)
+		}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return deleteAccountRows(tx, data)
+	})
+}
+
+// PurgeProject permanently removes one project the same way an account purge does: storage objects first, rows
+// second. The project trash calls it once a trashed project's retention has passed.
+func PurgeProject(ctx context.Context, db *gorm.DB, storage objectStorage.Provider, projectID uint64) error {
+	db = db.WithContext(ctx)
+
+	data, err := loadProjectData(db, projectID)
+	if err != nil {
+		return err
+	}
+
+	storageBase, _ := storage.GetURL("")
+	paths, err := data.assetPaths(storageBase)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if _, err := storage.Delete(path); err != nil {
+			return fmt.Errorf("delete object %s: %w", path, err)
+		}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return deleteProjectRows(tx, []uint64{projectID})
+	})
+}
+
+func deleteAccountRows(tx *gorm.DB, data *accountData) error {
+	userID := data.User.ID
+	projectIDs := data.projectIDs()
+
+	if len(projectIDs) > 0 {
+		if err := deleteProjectRows(tx, projectIDs); err != nil {
+			return err
+		}
+	}
+
+	// Revisions the user made on other people's projects stay, without the author.
+	if err := tx.Unscoped().Model(&models.ProjectRevision{}).Where("user_id = ?", userID).Update("user_id", nil).Error; err != nil {
+		return err
+	}
+
+	var workspaceIDs []uint64
+	if err := tx.Unscoped().Model(&models.Workspace{}).Where("owner_id = ?", userID).Pluck("id", &workspaceIDs).Error; err != nil {
+		return err
+	}
+	if len(workspaceIDs) > 0 {
+		// Members' own projects in the user's workspaces go back to being personal projects.
+		if err := tx.Unscoped().Model(&models.Project{}).Where("workspace_id IN ?", workspaceIDs).Update("workspace_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("workspace_id IN ?", workspaceIDs).Delete(&models.WorkspaceMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("workspace_id IN ?", workspaceIDs).Delete(&models.WorkspaceInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("id IN ?", workspaceIDs).Delete(&models.Workspace{}).Error; err != nil {
+			return err
+		}
+	}
+
+	byUser := []any{
+		&models.WorkspaceMember{},
+		&models.Appointment{},
+		&models.ParsedFile{},
+		&models.Job{},
+		&models.APIKey{},
+		&models.UserSession{},
+		&models.UserToken{},
+		&models.UserIdentity{},
+		&models.UserRecoveryCode{},
+		&models.TwoFactorChallenge{},
+		&models.OIDCState{},
+	}
+	for _, model := range byUser {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Unscoped().Where("invited_by = ? AND accepted_at IS NULL", userID).Delete(&models.WorkspaceInvitation{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Delete(&models.User{}, userID).Error
+}
+
+// deleteProjectRows hard-deletes projects and everything under them, soft-deleted rows included. Page activity is
+// kept for platform totals but stripped of the visitor's IP address.
+func deleteProjectRows(tx *gorm.DB, projectIDs []uint64) error {
+	if err := tx.Unscoped().Model(&models.PageActivity{}).Where("project_id IN ?", projectIDs).Update("ip_address", "").Error; err != nil {
+		return err
+	}
+
+	// Technologies may hang off a showcase without a portfolio_id of their own.
+	portfolios := tx.Unscoped().Model(&models.Portfolio{}).Select("id").Where("project_id IN ?", projectIDs)
+	showcases := tx.Unscoped().Model(&models.Showcase{}).Select("id").Where("portfolio_id IN (?)", portfolios)
+	if err := tx.Unscoped().Where("showcase_id IN (?)", showcases).Delete(&models.ShowcaseTechnology{}).Error; err != nil {
+		return err
+	}
+
+	children := []struct {
+		parent     any
+		foreignKey string
+		models     []any
+	}{
+		{&models.Portfolio{}, "portfolio_id", []any{&models.ShowcaseTechnology{}, &models.Showcase{}, &models.WorkExperience{}, &models.Education{}, &models.Skill{}}},
+		{&models.Biz{}, "biz_id", []any{&models.Service{}, &models.Product{}, &models.Testimonial{}, &models.BizSocialLink{}, &models.BizFAQ{}, &models.BizGallery{}}},
+		{&models.Linktree{}, "linktree_id", []any{&models.LinktreeLink{}, &models.LinktreeSection{}}},
+		{&models.Menu{}, "menu_id", []any{&models.MenuItem{}, &models.MenuCategory{}, &models.MenuDisplayPoster{}}},
+		{&models.Waitlist{}, "waitlist_id", []any{&models.WaitlistSignup{}}},
+	}
+	for _, child := range children {
+		var parentIDs []uint64
+		if err := tx.Unscoped().Model(child.parent).Where("project_id IN ?", projectIDs).Pluck("id", &parentIDs).Error; err != nil {
+			return err
+		}
+
+		if len(parentIDs) > 0 {
+			for _, model := range child.models {
+				if err := tx.Unscoped().Where(child.foreignKey+" IN ?", parentIDs).Delete(model).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := tx.Unscoped().Where("project_id IN ?", projectIDs).Delete(child.parent).Error; err != nil {
+			return err
+		}
+	}
+
+	byProject := []any{
+		&models.MenuDisplayPoster{},
+		&models.WaitlistSignup{},
+		&models.Appointment{},
+		&models.ProjectRevision{},
+		&models.ProjectDomain{},
+		&models.ProjectSubDomainHistory{},
+	}
+	for _, model := range byProject {
+		if err := tx.Unscoped().Where("project_id IN ?", projectIDs).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Unscoped().Where("id IN ?", projectIDs).Delete(&models.Project{}).Error
+}
+
+// exportArchivePaths lists archives from earlier exports that are still in storage.
+func exportArchivePaths(db *gorm.DB, userID uint64) ([]string, error) {
+	var exports []models.Job
+	if err := db.Where("type = ? AND user_id = ? AND result IS NOT NULL", job.TypeAccountExport, userID).Find(&exports).Error; err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, queued := range exports {
+		var result ExportResult
+		if json.Unmarshal(*queued.Result, &result) == nil && result.Path != "" {
+			paths = append(paths, result.Path)
+		}
+	}
+
+	return paths, nil
+}
+
+func deletionGracePeriod() time.Duration {
+	if days, err := strconv.Atoi(os.Getenv("ACCOUNT_DELETION_GRACE_DAYS")); err == nil && days >= 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return defaultDeletionGracePeriod
+}
+
+func sendDeletionNotice(user *models.User) error {
+	when := user.DeletionScheduledAt.Format("January 2, 2006")
+	intro := fmt.Sprintf("Your Kislap account and everything in it will be permanently deleted on %s.", when)
+	cancel := "If you didn't ask for this, or changed your mind, sign in and cancel the deletion from your account settings before then."
+
+	return mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your Kislap account is scheduled for deletion",
+		Text:    fmt.Sprintf("%s\n\n%s\n", intro, cancel),
+		HTML:    fmt.Sprintf(`<p>%s</p><p>%s</p>`, html.EscapeString(intro), html.EscapeString(cancel)),
+	})
+}
@@ -0,0 +1,130 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"flash/internal/auth"
+	"flash/models"
+	"flash/sdk/mailer"
+	"flash/shared/testdb"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRequestDeletion(t *testing.T) {
+	mailer.Default(mailer.NewInMemoryMailer())
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		password     string
+		sessionAge   time.Duration
+		enterPass    string
+		confirmation string
+		wantErr      error
+	}{
+		{name: "password account", password: string(hash), sessionAge: 24 * time.Hour, enterPass: "correct horse"},
+		{name: "password account with the wrong password", password: string(hash), sessionAge: time.Minute, enterPass: "battery staple", wantErr: auth.ErrInvalidPassword},
+		{name: "oauth account right after signing in", confirmation: " OAuth@Example.com ", sessionAge: time.Minute},
+		{name: "oauth account without the email", sessionAge: time.Minute, confirmation: "someone@example.com", wantErr: ErrDeletionConfirmation},
+		{name: "oauth account on an old session", confirmation: "oauth@example.com", sessionAge: time.Hour, wantErr: ErrDeletionReauthRequired},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := testdb.Open(t, &models.User{}, &models.UserSession{})
+			service := Service{DB: db}
+
+			password := test.password
+			testdb.Create(t, db,
+				&models.User{ID: 1, FirstName: "Ada", Email: "oauth@example.com", Password: &password},
+				&models.UserSession{ID: 5, UserID: 1, TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour), CreatedAt: time.Now().Add(-test.sessionAge)},
+			)
+
+			user, err := service.RequestDeletion(1, 5, test.enterPass, test.confirmation)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("RequestDeletion() error = %v, want %v", err, test.wantErr)
+			}
+			if test.wantErr == nil && user.DeletionScheduledAt == nil {
+				t.Error("RequestDeletion() did not schedule the deletion")
+			}
+		})
+	}
+}
+
+func TestRunScheduledDeletions(t *testing.T) {
+	service, storage := seedAccount(t)
+
+	if err := service.DB.Model(&models.User{}).Where("id = ?", 1).Update("deletion_scheduled_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.RunScheduledDeletions(context.Background()); err != nil {
+		t.Fatalf("RunScheduledDeletions() error = %v", err)
+	}
+
+	// Ada's rows are gone, soft-deleted or not; Bob's stay.
+	remaining := []struct {
+		model any
+		want  int64
+	}{
+		{&models.User{}, 1},
+		{&models.UserSession{}, 1},
+		{&models.APIKey{}, 0},
+		{&models.Workspace{}, 0},
+		{&models.WorkspaceMember{}, 0},
+		{&models.Project{}, 1},
+		{&models.Linktree{}, 1},
+		{&models.LinktreeLink{}, 0},
+		{&models.Appointment{}, 0},
+		{&models.ProjectRevision{}, 1},
+		{&models.Job{}, 0},
+		{&models.PageActivity{}, 2},
+	}
+	for _, table := range remaining {
+		var count int64
+		if err := service.DB.Unscoped().Model(table.model).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != table.want {
+			t.Errorf("%T: %d rows left, want %d", table.model, count, table.want)
+		}
+	}
+
+	var bobsProject models.Project
+	if err := service.DB.First(&bobsProject, 11).Error; err != nil {
+		t.Fatal(err)
+	}
+	if bobsProject.WorkspaceID != nil {
+		t.Error("Bob's project is still in the deleted workspace")
+	}
+
+	var revision models.ProjectRevision
+	if err := service.DB.Where("project_id = ?", 11).First(&revision).Error; err != nil {
+		t.Fatal(err)
+	}
+	if revision.UserID != nil {
+		t.Error("the revision Ada made on Bob's project still names her")
+	}
+
+	// Visits stay in the platform totals without the visitor's address.
+	var activities []models.PageActivity
+	service.DB.Order("project_id ASC").Find(&activities)
+	if activities[0].IPAddress != "" || activities[1].IPAddress != "203.0.113.8" {
+		t.Errorf("page activity IPs = %q, %q, want Ada's cleared only", activities[0].IPAddress, activities[1].IPAddress)
+	}
+
+	// Only objects under Ada's own folders go, not the other project's file her link borrowed.
+	paths := storage.Paths()
+	slices.Sort(paths)
+	if want := []string{"projects/11/logo.png", "projects/99/theirs.png"}; !slices.Equal(paths, want) {
+		t.Errorf("storage holds %v, want %v", paths, want)
+	}
+}
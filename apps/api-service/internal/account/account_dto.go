@@ -0,0 +1,7 @@
+package account
+
+type RequestDeletionRequest struct {
+	// Password is required for accounts that have one; accounts without confirm by typing their email instead.
+	Password     string `json:"password"`
+	Confirmation string `json:"confirmation"`
+}
@@ -0,0 +1,294 @@
+package account
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flash/internal/job"
+	"flash/models"
+	objectStorage "flash/sdk/object_storage"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	// exportFormatVersion is written to manifest.json so the layout can change without breaking readers.
+	exportFormatVersion = 1
+	// exportLifeSpan is how long a finished export can be downloaded before it is removed from storage.
+	exportLifeSpan = 7 * 24 * time.Hour
+	// exportLinkLifeSpan is how long a download link stays valid once handed out.
+	exportLinkLifeSpan = 15 * time.Minute
+)
+
+var (
+	ErrExportNotFound = errors.New("export not found")
+	ErrExportNotReady = errors.New("export is not ready yet")
+	ErrExportExpired  = errors.New("export has expired, please request a new one")
+)
+
+type exportPayload struct {
+	UserID uint64 `json:"user_id"`
+}
+
+// ExportResult is stored on the job. Path is cleared once the archive expires and is removed from storage.
+type ExportResult struct {
+	Path          string    `json:"path,omitempty"`
+	Size          int64     `json:"size"`
+	Projects      int       `json:"projects"`
+	Assets        int       `json:"assets"`
+	MissingAssets []string  `json:"missing_assets,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	Expired       bool      `json:"expired,omitempty"`
+}
+
+type exportManifest struct {
+	FormatVersion int       `json:"format_version"`
+	GeneratedAt   time.Time `json:"generated_at"`
+	UserID        uint64    `json:"user_id"`
+	Projects      []uint64  `json:"projects"`
+	Assets        []string  `json:"assets"`
+	MissingAssets []string  `json:"missing_assets,omitempty"`
+}
+
+// RequestExport queues an export of everything the user owns. An export that is still queued or running is
+// returned instead of starting another.
+func (service Service) RequestExport(userID uint64) (*models.Job, error) {
+	var running models.Job
+	err := service.DB.
+		Where("type = ? AND user_id = ? AND status IN ?", job.TypeAccountExport, userID, []string{job.StatusPending, job.StatusProcessing}).
+		Order("id DESC").
+		Take(&running).Error
+	if err == nil {
+		return &running, nil
+	}
+
+	return job.NewService(service.DB).Enqueue(job.EnqueuePayload{
+		UserID:      &userID,
+		Type:        job.TypeAccountExport,
+		Reference:   fmt.Sprintf("user:%d", userID),
+		Payload:     exportPayload{UserID: userID},
+		MaxAttempts: 2,
+	})
+}
+
+// ListExports returns the user's export jobs, newest first.
+func (service Service) ListExports(userID uint64) ([]models.Job, error) {
+	var exports []models.Job
+	if err := service.DB.
+		Where("type = ? AND user_id = ?", job.TypeAccountExport, userID).
+		Order("id DESC").
+		Limit(20).
+		Find(&exports).Error; err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+// ExportDownloadURL hands out a short-lived link to a finished export.
+func (service Service) ExportDownloadURL(userID uint64, jobID uint64) (string, *ExportResult, error) {
+	queued, err := job.NewService(service.DB).Show(jobID, userID)
+	if err != nil || queued.Type != job.TypeAccountExport {
+		return "", nil, ErrExportNotFound
+	}
+
+	if queued.Status != job.StatusCompleted || queued.Result == nil {
+		return "", nil, ErrExportNotReady
+	}
+
+	var result ExportResult
+	if err := json.Unmarshal(*queued.Result, &result); err != nil {
+		return "", nil, err
+	}
+	if result.Expired || result.Path == "" || time.Now().After(result.ExpiresAt) {
+		return "", nil, ErrExportExpired
+	}
+
+	url, err := service.ObjectStorage.GetSignedURL(result.Path, exportLinkLifeSpan)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return url, &result, nil
+}
+
+// HandleExportJob builds the archive: manifest.json, account.json, one JSON file per project and a copy of every
+// stored asset the records point at. It is assembled in a temporary file so large accounts don't sit in memory.
+func (service Service) HandleExportJob(ctx context.Context, queued *models.Job) (any, error) {
+	var payload exportPayload
+	if err := json.Unmarshal(queued.Payload, &payload); err != nil {
+		return nil, err
+	}
+
+	data, err := loadAccountData(service.DB.WithContext(ctx), payload.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	storageBase, _ := service.ObjectStorage.GetURL("")
+	assets, err := data.assetPaths(storageBase)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := os.CreateTemp("", "kislap-export-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	manifest := exportManifest{
+		FormatVersion: exportFormatVersion,
+		GeneratedAt:   time.Now(),
+		UserID:        payload.UserID,
+		Projects:      data.projectIDs(),
+		Assets:        []string{},
+	}
+
+	writer := zip.NewWriter(archive)
+
+	if err := writeJSON(writer, "account.json", data); err != nil {
+		return nil, err
+	}
+	for _, project := range data.Projects {
+		if err := writeJSON(writer, fmt.Sprintf("projects/%d.json", project.Project.ID), project); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range assets {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		copied, err := service.copyAsset(writer, path)
+		if err != nil {
+			return nil, fmt.Errorf("asset %s: %w", path, err)
+		}
+		if copied {
+			manifest.Assets = append(manifest.Assets, path)
+		} else {
+			manifest.MissingAssets = append(manifest.MissingAssets, path)
+		}
+	}
+
+	if err := writeJSON(writer, "manifest.json", manifest); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	size, err := archive.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("exports/users/%d/%s.zip", payload.UserID, token)
+	if _, err := service.ObjectStorage.Upload(path, archive, "application/zip"); err != nil {
+		return nil, err
+	}
+
+	return ExportResult{
+		Path:          path,
+		Size:          size,
+		Projects:      len(data.Projects),
+		Assets:        len(manifest.Assets),
+		MissingAssets: manifest.MissingAssets,
+		ExpiresAt:     time.Now().Add(exportLifeSpan),
+	}, nil
+}
+
+// copyAsset streams one stored object into the archive under assets/. Objects that no longer exist are reported
+// in the manifest rather than failing the export.
+func (service Service) copyAsset(writer *zip.Writer, path string) (bool, error) {
+	content, err := service.ObjectStorage.Download(path)
+	if errors.Is(err, objectStorage.ErrObjectNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer content.Close()
+
+	entry, err := writer.Create("assets/" + path)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := io.Copy(entry, content); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// PruneExports removes archives past their expiry from storage and marks their jobs expired.
+func (service Service) PruneExports(ctx context.Context) error {
+	var finished []models.Job
+	if err := service.DB.WithContext(ctx).
+		Where("type = ? AND status = ? AND finished_at < ?", job.TypeAccountExport, job.StatusCompleted, time.Now().Add(-exportLifeSpan)).
+		Where("JSON_EXTRACT(result, '$.path') IS NOT NULL").
+		Find(&finished).Error; err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, queued := range finished {
+		if err := service.expireExport(&queued); err != nil {
+			errs = append(errs, fmt.Errorf("export %d: %w", queued.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (service Service) expireExport(queued *models.Job) error {
+	if queued.Result == nil {
+		return nil
+	}
+
+	var result ExportResult
+	if err := json.Unmarshal(*queued.Result, &result); err != nil {
+		return err
+	}
+
+	if result.Path != "" {
+		if _, err := service.ObjectStorage.Delete(result.Path); err != nil {
+			return err
+		}
+		log.Printf("[INFO] Removed expired account export %s", result.Path)
+	}
+
+	result.Path = ""
+	result.Expired = true
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	return service.DB.Model(queued).Update("result", json.RawMessage(encoded)).Error
+}
+
+func writeJSON(writer *zip.Writer, name string, value any) error {
+	entry, err := writer.Create(name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -0,0 +1,176 @@
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flash/internal/job"
+	"flash/models"
+	objectStorage "flash/sdk/object_storage"
+	"flash/shared/testdb"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const testStorageBase = "https://cdn.example.com"
+
+// openAccountDB opens a database with every table an export reads or a purge deletes from.
+func openAccountDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	return testdb.Open(t,
+		&models.User{}, &models.UserIdentity{}, &models.UserSession{}, &models.UserToken{}, &models.UserRecoveryCode{},
+		&models.TwoFactorChallenge{}, &models.OIDCState{}, &models.APIKey{}, &models.Job{}, &models.ParsedFile{},
+		&models.Workspace{}, &models.WorkspaceMember{}, &models.WorkspaceInvitation{}, &models.Appointment{},
+		&models.Project{}, &models.ProjectRevision{}, &models.ProjectDomain{}, &models.ProjectSubDomainHistory{},
+		&models.PageActivity{},
+		&models.Portfolio{}, &models.WorkExperience{}, &models.Education{}, &models.Showcase{}, &models.ShowcaseTechnology{}, &models.Skill{},
+		&models.Biz{}, &models.Service{}, &models.Product{}, &models.Testimonial{}, &models.BizSocialLink{}, &models.BizFAQ{}, &models.BizGallery{},
+		&models.Linktree{}, &models.LinktreeLink{}, &models.LinktreeSection{},
+		&models.Menu{}, &models.MenuCategory{}, &models.MenuItem{}, &models.MenuDisplayPoster{},
+		&models.Waitlist{}, &models.WaitlistSignup{},
+	)
+}
+
+// seedAccount gives Ada (user 1) a linktree project with stored assets, a workspace Bob (user 2) keeps a project
+// in, and an earlier export. Bob's project has a revision Ada made. Ada's links also point at an object of
+// another project and at one that is no longer stored.
+func seedAccount(t *testing.T) (Service, *objectStorage.InMemoryProvider) {
+	t.Helper()
+
+	db := openAccountDB(t)
+	storage := objectStorage.NewInMemoryProvider(testStorageBase)
+	for _, path := range []string{
+		"users/1/avatar.png", "og_images/10.png", "projects/10/logo.png", "projects/10/link.png",
+		"projects/11/logo.png", "projects/99/theirs.png", "exports/users/1/old.zip",
+	} {
+		if _, err := storage.Upload(path, strings.NewReader("content of "+path), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	url := func(path string) *string {
+		value := testStorageBase + "/" + path
+		return &value
+	}
+	empty, subDomain := "", "ada"
+	workspaceID, adaID := uint64(30), uint64(1)
+	oldExport := json.RawMessage(`{"path":"exports/users/1/old.zip"}`)
+
+	testdb.Create(t, db,
+		&models.User{ID: 1, FirstName: "Ada", Email: "ada@example.com", Password: &empty, ImageURL: url("users/1/avatar.png")},
+		&models.User{ID: 2, FirstName: "Bob", Email: "bob@example.com", Password: &empty},
+		&models.UserSession{UserID: 1, TokenHash: "ada-session", ExpiresAt: time.Now().Add(time.Hour)},
+		&models.UserSession{UserID: 2, TokenHash: "bob-session", ExpiresAt: time.Now().Add(time.Hour)},
+		&models.APIKey{UserID: 1, Name: "CI", Prefix: "kslp_ada", KeyHash: "ada-key"},
+		&models.Workspace{ID: workspaceID, OwnerID: 1, Name: "Studio"},
+		&models.WorkspaceMember{WorkspaceID: workspaceID, UserID: 2, Role: "editor"},
+		&models.Project{ID: 10, UserID: 1, Name: "Ada", Slug: "ada", SubDomain: &subDomain, Type: "linktree", OGImageURL: url("og_images/10.png")},
+		&models.Project{ID: 11, UserID: 2, WorkspaceID: &workspaceID, Name: "Bob", Slug: "bob", Type: "linktree"},
+		&models.Linktree{ID: 20, ProjectID: 10, UserID: 1, Name: "Ada", LogoURL: url("projects/10/logo.png")},
+		&models.LinktreeLink{LinktreeID: 20, Title: "Portfolio", URL: "https://ada.dev", ImageURL: url("projects/10/link.png")},
+		&models.LinktreeLink{LinktreeID: 20, Title: "Borrowed", URL: "https://bob.dev", ImageURL: url("projects/99/theirs.png")},
+		&models.LinktreeLink{LinktreeID: 20, Title: "Gone", URL: "https://ada.dev/gone", ImageURL: url("projects/10/missing.png")},
+		&models.Linktree{ID: 21, ProjectID: 11, UserID: 2, Name: "Bob", LogoURL: url("projects/11/logo.png")},
+		&models.Appointment{UserID: 1, ProjectID: 10, Name: "Visitor", Email: "visitor@example.com"},
+		&models.ProjectRevision{ProjectID: 10, UserID: &adaID, Revision: 1, Type: "linktree"},
+		&models.ProjectRevision{ProjectID: 11, UserID: &adaID, Revision: 1, Type: "linktree"},
+		&models.PageActivity{ProjectID: 10, Type: "view", PageURL: "/", IPAddress: "203.0.113.7"},
+		&models.PageActivity{ProjectID: 11, Type: "view", PageURL: "/", IPAddress: "203.0.113.8"},
+		&models.Job{UserID: &adaID, Type: job.TypeAccountExport, Status: job.StatusCompleted, Result: &oldExport},
+	)
+
+	return Service{DB: db, ObjectStorage: storage}, storage
+}
+
+func TestHandleExportJob(t *testing.T) {
+	service, storage := seedAccount(t)
+
+	value, err := service.HandleExportJob(context.Background(), &models.Job{Payload: json.RawMessage(`{"user_id":1}`)})
+	if err != nil {
+		t.Fatalf("HandleExportJob() error = %v", err)
+	}
+	result := value.(ExportResult)
+	if !strings.HasPrefix(result.Path, "exports/users/1/") || result.Projects != 1 || result.Assets != 4 {
+		t.Fatalf("result = %+v, want one project and four assets under exports/users/1/", result)
+	}
+	if !slices.Equal(result.MissingAssets, []string{"projects/10/missing.png"}) {
+		t.Fatalf("missing assets = %v", result.MissingAssets)
+	}
+
+	stored, ok := storage.Get(result.Path)
+	if !ok || stored.ContentType != "application/zip" || int64(len(stored.Content)) != result.Size {
+		t.Fatalf("archive not uploaded as a %d byte zip", result.Size)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(stored.Content), int64(len(stored.Content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+		files[file.Name] = string(content)
+	}
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	want := []string{
+		"account.json", "assets/og_images/10.png", "assets/projects/10/link.png", "assets/projects/10/logo.png",
+		"assets/users/1/avatar.png", "manifest.json", "projects/10.json",
+	}
+	if !slices.Equal(names, want) {
+		t.Fatalf("archive holds %v, want %v", names, want)
+	}
+	if files["assets/projects/10/logo.png"] != "content of projects/10/logo.png" {
+		t.Fatalf("logo = %q, want the stored object", files["assets/projects/10/logo.png"])
+	}
+
+	var manifest exportManifest
+	if err := json.Unmarshal([]byte(files["manifest.json"]), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.FormatVersion != exportFormatVersion || manifest.UserID != 1 || !slices.Equal(manifest.Projects, []uint64{10}) ||
+		len(manifest.Assets) != 4 || !slices.Equal(manifest.MissingAssets, result.MissingAssets) {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+
+	var account struct {
+		User     models.User          `json:"user"`
+		Sessions []models.UserSession `json:"sessions"`
+		APIKeys  []models.APIKey      `json:"api_keys"`
+	}
+	if err := json.Unmarshal([]byte(files["account.json"]), &account); err != nil {
+		t.Fatal(err)
+	}
+	if account.User.Email != "ada@example.com" || len(account.Sessions) != 1 || len(account.APIKeys) != 1 {
+		t.Fatalf("account.json = %+v, want Ada's session and API key", account)
+	}
+	for _, secret := range []string{"ada-session", "ada-key", "bob@example.com"} {
+		if strings.Contains(files["account.json"], secret) {
+			t.Fatalf("account.json contains %q", secret)
+		}
+	}
+
+	var project projectData
+	if err := json.Unmarshal([]byte(files["projects/10.json"]), &project); err != nil {
+		t.Fatal(err)
+	}
+	if project.Project.Linktree == nil || len(project.Project.Linktree.Links) != 3 || len(project.Revisions) != 1 {
+		t.Fatalf("projects/10.json = %+v, want the linktree with its links and revision", project)
+	}
+}
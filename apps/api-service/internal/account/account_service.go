@@ -0,0 +1,27 @@
+package account
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	objectStorage "flash/sdk/object_storage"
+
+	"gorm.io/gorm"
+)
+
+// Service covers the account as a whole: exporting everything a user owns and deleting it.
+type Service struct {
+	DB            *gorm.DB
+	ObjectStorage objectStorage.Provider
+}
+
+func NewService(db *gorm.DB, objectStorage objectStorage.Provider) *Service {
+	return &Service{DB: db, ObjectStorage: objectStorage}
+}
+
+func randomToken() (string, error) {
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buffer), nil
+}
@@ -0,0 +1,83 @@
+package api_key
+
+import (
+	"errors"
+	"flash/shared/apikey"
+	"flash/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service *Service
+}
+
+func NewController(db *gorm.DB) *Controller {
+	return &Controller{Service: NewService(db)}
+}
+
+func (controller Controller) List(context *gin.Context) {
+	keys, err := controller.Service.List(context.GetUint64("user_id"))
+	if err != nil {
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, keys)
+}
+
+func (controller Controller) Scopes(context *gin.Context) {
+	utils.APIRespondSuccess(context, http.StatusOK, apikey.Scopes)
+}
+
+func (controller Controller) Create(context *gin.Context) {
+	var request CreateAPIKeyRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	key, err := controller.Service.Create(context.GetUint64("user_id"), request)
+	if err != nil {
+		respondAPIKeyError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusCreated, key)
+}
+
+func (controller Controller) Revoke(context *gin.Context) {
+	keyID, err := strconv.ParseUint(context.Param("id"), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "Invalid API key ID")
+		context.Abort()
+		return
+	}
+
+	key, err := controller.Service.Revoke(context.GetUint64("user_id"), keyID)
+	if err != nil {
+		respondAPIKeyError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, key)
+}
+
+func respondAPIKeyError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrAPIKeyNotFound):
+		utils.APIRespondError(context, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrTooManyAPIKeys):
+		utils.APIRespondError(context, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrInvalidScope), errors.Is(err, ErrInvalidExpiry):
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+	default:
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+	}
+	context.Abort()
+}
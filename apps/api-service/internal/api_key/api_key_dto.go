@@ -0,0 +1,18 @@
+package api_key
+
+import (
+	"flash/models"
+	"time"
+)
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse is the only time the full key is returned; only its hash is stored.
+type CreateAPIKeyResponse struct {
+	models.APIKey
+	Key string `json:"key"`
+}
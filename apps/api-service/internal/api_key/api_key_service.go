@@ -0,0 +1,107 @@
+package api_key
+
+import (
+	"errors"
+	"flash/models"
+	"flash/shared/apikey"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxActiveKeysPerUser keeps a leaked session from minting an unbounded number of keys.
+const maxActiveKeysPerUser = 20
+
+var (
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrInvalidScope   = errors.New("unknown API key scope")
+	ErrInvalidExpiry  = errors.New("expires_at must be in the future")
+	ErrTooManyAPIKeys = fmt.Errorf("you can have at most %d active API keys", maxActiveKeysPerUser)
+)
+
+type Service struct {
+	DB *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+func (service Service) Create(userID uint64, request CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	scopes := make([]string, 0, len(request.Scopes))
+	seen := map[string]bool{}
+	for _, scope := range request.Scopes {
+		scope = strings.TrimSpace(scope)
+		if !apikey.ValidScope(scope) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+
+	var active int64
+	if err := service.DB.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Count(&active).Error; err != nil {
+		return nil, err
+	}
+	if active >= maxActiveKeysPerUser {
+		return nil, ErrTooManyAPIKeys
+	}
+
+	key, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.APIKey{
+		UserID:    userID,
+		Name:      strings.TrimSpace(request.Name),
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    scopes,
+		ExpiresAt: request.ExpiresAt,
+	}
+	if err := service.DB.Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &CreateAPIKeyResponse{APIKey: record, Key: key}, nil
+}
+
+func (service Service) List(userID uint64) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := service.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (service Service) Revoke(userID uint64, keyID uint64) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := service.DB.Where("id = ? AND user_id = ?", keyID, userID).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	if key.RevokedAt == nil {
+		now := time.Now()
+		if err := service.DB.Model(&key).Update("revoked_at", now).Error; err != nil {
+			return nil, err
+		}
+		key.RevokedAt = &now
+	}
+
+	return &key, nil
+}
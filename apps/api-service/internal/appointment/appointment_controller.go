@@ -1,6 +1,8 @@
 package appointment
 
 import (
+	"errors"
+	"flash/shared/access"
 	"flash/utils"
 	"math"
 	"net/http"
@@ -31,7 +33,33 @@ func (controller Controller) List(c *gin.Context) {
 		limit = 10
 	}
 
-	appointments, total, err := controller.Service.List(page, limit, projectID)
+	userID := c.GetUint64("user_id")
+	role := c.GetString("user_role")
+	var ownerID *uint64
+
+	if projectID != "" {
+		parsedProjectID, err := strconv.ParseUint(projectID, 10, 64)
+		if err != nil {
+			utils.APIRespondError(c, http.StatusBadRequest, "Invalid Project ID")
+			return
+		}
+
+		if _, _, err := access.AuthorizeProject(controller.Service.DB, userID, role, parsedProjectID, access.PermissionRead); err != nil {
+			switch {
+			case errors.Is(err, access.ErrProjectForbidden):
+				utils.APIRespondError(c, http.StatusForbidden, err.Error())
+			case errors.Is(err, access.ErrProjectNotFound):
+				utils.APIRespondError(c, http.StatusNotFound, err.Error())
+			default:
+				utils.APIRespondError(c, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+	} else if !access.IsAdmin(role) && role != access.RoleSupport {
+		ownerID = &userID
+	}
+
+	appointments, total, err := controller.Service.List(page, limit, projectID, ownerID)
 	if err != nil {
 		utils.APIRespondError(c, http.StatusBadRequest, err.Error())
 		return
@@ -2,6 +2,7 @@ package appointment
 
 import (
 	"flash/models"
+	"flash/shared/access"
 
 	"gorm.io/gorm"
 )
@@ -10,7 +11,7 @@ type Service struct {
 	DB *gorm.DB
 }
 
-func (service *Service) List(page int, limit int, projectID string) ([]models.Appointment, int64, error) {
+func (service *Service) List(page int, limit int, projectID string, ownerID *uint64) ([]models.Appointment, int64, error) {
 	var appointments []models.Appointment
 	var total int64
 
@@ -20,6 +21,10 @@ func (service *Service) List(page int, limit int, projectID string) ([]models.Ap
 		db = db.Where("project_id = ?", projectID)
 	}
 
+	if ownerID != nil {
+		db = db.Where("project_id IN (?)", access.AccessibleProjectIDs(service.DB, *ownerID))
+	}
+
 	if err := db.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
@@ -1,9 +1,13 @@
 package auth
 
 import (
+	"errors"
+	"flash/sdk/oidc"
 	"flash/shared/cookie"
+	"flash/shared/jwt"
 	"flash/utils"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -32,7 +36,7 @@ func (controller Controller) Login(context *gin.Context) {
 		return
 	}
 
-	result, err := controller.Service.Login(input.Email, input.Password)
+	result, err := controller.Service.Login(input.Email, input.Password, sessionClient(context))
 
 	if err != nil {
 		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
@@ -40,6 +44,15 @@ func (controller Controller) Login(context *gin.Context) {
 		return
 	}
 
+	if result.TwoFactor != nil {
+		utils.APIRespondSuccess(context, http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     result.TwoFactor.Token,
+			"expires_at":          result.TwoFactor.ExpiresAt,
+		})
+		return
+	}
+
 	cookie.SetCookie(context, "refresh_token", result.RefreshToken)
 
 	utils.APIRespondSuccess(context, http.StatusOK, gin.H{
@@ -67,9 +80,21 @@ func (controller Controller) Refresh(context *gin.Context) {
 	uid := userID.(uint64)
 	strRefreshToken := refreshToken.(string)
 
-	result, err := controller.Service.Refresh(uid, strRefreshToken)
+	result, err := controller.Service.Refresh(uid, context.GetUint64("session_id"), strRefreshToken, sessionClient(context))
 
 	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) || errors.Is(err, ErrSessionRevoked) ||
+			errors.Is(err, ErrRefreshTokenReused) || errors.Is(err, ErrInvalidRefreshToken) {
+			cookie.ClearCookie(context, "refresh_token")
+			utils.APIRespondError(context, http.StatusUnauthorized, err.Error())
+			context.Abort()
+			return
+		}
+		if errors.Is(err, ErrRefreshTokenRotated) {
+			utils.APIRespondError(context, http.StatusConflict, err.Error())
+			context.Abort()
+			return
+		}
 		context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		context.Abort()
 		return
@@ -94,9 +119,14 @@ func (controller *Controller) GithubLogin(context *gin.Context) {
 		return
 	}
 
-	result, err := controller.Service.GithubLogin(input.Code)
+	result, err := controller.Service.GithubLogin(input.Code, sessionClient(context))
 
 	if err != nil {
+		if errors.Is(err, ErrIdentityNotLinked) {
+			utils.APIRespondError(context, http.StatusConflict, err.Error())
+			context.Abort()
+			return
+		}
 		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
 		context.Abort()
 		return
@@ -121,9 +151,14 @@ func (controller *Controller) GoogleLogin(context *gin.Context) {
 		return
 	}
 
-	result, err := controller.Service.GoogleLogin(input.Code)
+	result, err := controller.Service.GoogleLogin(input.Code, sessionClient(context))
 
 	if err != nil {
+		if errors.Is(err, ErrIdentityNotLinked) {
+			utils.APIRespondError(context, http.StatusConflict, err.Error())
+			context.Abort()
+			return
+		}
 		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
 		context.Abort()
 		return
@@ -148,7 +183,7 @@ func (controller Controller) Logout(context *gin.Context) {
 
 	uid := userID.(uint64)
 
-	err := controller.Service.Logout(uid)
+	err := controller.Service.Logout(uid, context.GetUint64("session_id"))
 
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -160,3 +195,454 @@ func (controller Controller) Logout(context *gin.Context) {
 
 	utils.APIRespondSuccess(context, http.StatusOK, gin.H{})
 }
+
+func (controller Controller) ListSessions(context *gin.Context) {
+	sessions, err := controller.Service.ListSessions(context.GetUint64("user_id"), context.GetUint64("session_id"))
+	if err != nil {
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, sessions)
+}
+
+func (controller Controller) RevokeSession(context *gin.Context) {
+	sessionID, err := strconv.ParseUint(context.Param("id"), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "Invalid session ID")
+		context.Abort()
+		return
+	}
+
+	if err := controller.Service.RevokeSession(context.GetUint64("user_id"), sessionID); err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			utils.APIRespondError(context, http.StatusNotFound, err.Error())
+			context.Abort()
+			return
+		}
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+
+	if sessionID == context.GetUint64("session_id") {
+		cookie.ClearCookie(context, "refresh_token")
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"revoked": true})
+}
+
+// RevokeAllSessions signs out every device. Pass except_current=true to stay signed in on this one.
+func (controller Controller) RevokeAllSessions(context *gin.Context) {
+	exceptSessionID := uint64(0)
+	if context.Query("except_current") == "true" {
+		exceptSessionID = context.GetUint64("session_id")
+	}
+
+	revoked, err := controller.Service.RevokeAllSessions(context.GetUint64("user_id"), exceptSessionID)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+
+	if exceptSessionID == 0 {
+		cookie.ClearCookie(context, "refresh_token")
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"revoked": revoked})
+}
+
+func (controller Controller) VerifyEmail(context *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := context.ShouldBindJSON(&input); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	user, err := controller.Service.VerifyEmail(input.Token)
+	if err != nil {
+		respondEmailError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, user)
+}
+
+func (controller Controller) ResendVerificationEmail(context *gin.Context) {
+	if err := controller.Service.ResendVerificationEmail(context.GetUint64("user_id")); err != nil {
+		respondEmailError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"sent": true})
+}
+
+func (controller Controller) ForgotPassword(context *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := context.ShouldBindJSON(&input); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	if err := controller.Service.RequestPasswordReset(input.Email); err != nil {
+		respondEmailError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"sent": true})
+}
+
+func (controller Controller) ResetPassword(context *gin.Context) {
+	var input struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=8"`
+	}
+
+	if err := context.ShouldBindJSON(&input); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	if err := controller.Service.ResetPassword(input.Token, input.Password); err != nil {
+		respondEmailError(context, err)
+		return
+	}
+
+	cookie.ClearCookie(context, "refresh_token")
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"reset": true})
+}
+
+func (controller Controller) RequestEmailChange(context *gin.Context) {
+	var input struct {
+		Email    string `json:"email" binding:"required,email,max=255"`
+		Password string `json:"password"`
+	}
+
+	if err := context.ShouldBindJSON(&input); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	if err := controller.Service.RequestEmailChange(context.GetUint64("user_id"), input.Email, input.Password); err != nil {
+		respondEmailError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"sent": true})
+}
+
+func (controller Controller) ConfirmEmailChange(context *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := context.ShouldBindJSON(&input); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	user, err := controller.Service.ConfirmEmailChange(input.Token)
+	if user == nil && err != nil {
+		respondEmailError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, user)
+}
+
+func (controller Controller) ListIdentities(context *gin.Context) {
+	identities, err := controller.Service.ListIdentities(context.GetUint64("user_id"))
+	if err != nil {
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, identities)
+}
+
+func (controller Controller) LinkIdentity(context *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := context.ShouldBindJSON(&input); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	identity, err := controller.Service.LinkIdentity(context.GetUint64("user_id"), context.Param("provider"), input.Code)
+	if err != nil {
+		respondIdentityError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, identity)
+}
+
+func (controller Controller) UnlinkIdentity(context *gin.Context) {
+	if err := controller.Service.UnlinkIdentity(context.GetUint64("user_id"), context.Param("provider")); err != nil {
+		respondIdentityError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"unlinked": true})
+}
+
+func (controller Controller) ListOIDCProviders(context *gin.Context) {
+	utils.APIRespondSuccess(context, http.StatusOK, oidc.List())
+}
+
+// AuthorizeOIDC returns the provider URL for the frontend to redirect to.
+func (controller Controller) AuthorizeOIDC(context *gin.Context) {
+	authURL, binding, err := controller.Service.BeginOIDC(context.Param("provider"), OIDCIntentLogin, nil)
+	if err != nil {
+		respondIdentityError(context, err)
+		return
+	}
+
+	cookie.SetCookieFor(context, OIDCBrowserCookie, binding, oidcStateLifeSpan)
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"url": authURL})
+}
+
+func (controller Controller) AuthorizeOIDCLink(context *gin.Context) {
+	userID := context.GetUint64("user_id")
+
+	authURL, binding, err := controller.Service.BeginOIDC(context.Param("provider"), OIDCIntentLink, &userID)
+	if err != nil {
+		respondIdentityError(context, err)
+		return
+	}
+
+	cookie.SetCookieFor(context, OIDCBrowserCookie, binding, oidcStateLifeSpan)
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"url": authURL})
+}
+
+func (controller Controller) OIDCCallback(context *gin.Context) {
+	var input struct {
+		Code  string `json:"code" binding:"required"`
+		State string `json:"state" binding:"required"`
+	}
+
+	if err := context.ShouldBindJSON(&input); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	binding, _ := context.Cookie(OIDCBrowserCookie)
+	// The binding is single use like the state, so drop it whatever the outcome.
+	cookie.ClearCookie(context, OIDCBrowserCookie)
+
+	result, err := controller.Service.CompleteOIDC(context.Param("provider"), input.Code, input.State, binding, sessionClient(context))
+	if err != nil {
+		respondIdentityError(context, err)
+		return
+	}
+
+	if result.Intent == OIDCIntentLink {
+		utils.APIRespondSuccess(context, http.StatusOK, gin.H{"identity": result.Identity})
+		return
+	}
+
+	cookie.SetCookie(context, "refresh_token", result.Login.RefreshToken)
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{
+		"access_token": result.Login.AccessToken,
+		"user":         result.Login.User,
+	})
+}
+
+func (controller Controller) TwoFactorStatus(context *gin.Context) {
+	status, err := controller.Service.TwoFactorStatus(context.GetUint64("user_id"))
+	if err != nil {
+		respondTwoFactorError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, status)
+}
+
+func (controller Controller) EnableTwoFactor(context *gin.Context) {
+	var input struct {
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := context.ShouldBindJSON(&input); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	setup, err := controller.Service.BeginTwoFactorSetup(context.GetUint64("user_id"), input.Password)
+	if err != nil {
+		respondTwoFactorError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, setup)
+}
+
+func (controller Controller) ConfirmTwoFactor(context *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := context.ShouldBindJSON(&input); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	codes, err := controller.Service.ConfirmTwoFactor(context.GetUint64("user_id"), input.Code)
+	if err != nil {
+		respondTwoFactorError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func (controller Controller) RegenerateRecoveryCodes(context *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := context.ShouldBindJSON(&input); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	codes, err := controller.Service.RegenerateRecoveryCodes(context.GetUint64("user_id"), input.Code)
+	if err != nil {
+		respondTwoFactorError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func (controller Controller) DisableTwoFactor(context *gin.Context) {
+	var input struct {
+		Password     string `json:"password" binding:"required"`
+		Code         string `json:"code" binding:"required_without=RecoveryCode"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	if err := context.ShouldBindJSON(&input); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	if err := controller.Service.DisableTwoFactor(context.GetUint64("user_id"), input.Password, input.Code, input.RecoveryCode); err != nil {
+		respondTwoFactorError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"disabled": true})
+}
+
+// TwoFactorChallenge is the second step of a password login for accounts with 2FA on.
+func (controller Controller) TwoFactorChallenge(context *gin.Context) {
+	var input struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code" binding:"required_without=RecoveryCode"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+
+	if err := context.ShouldBindJSON(&input); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	result, err := controller.Service.CompleteTwoFactorLogin(input.ChallengeToken, input.Code, input.RecoveryCode, sessionClient(context))
+	if err != nil {
+		respondTwoFactorError(context, err)
+		return
+	}
+
+	cookie.SetCookie(context, "refresh_token", result.RefreshToken)
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{
+		"access_token": result.AccessToken,
+		"user":         result.User,
+	})
+}
+
+// JWKS publishes the public signing keys so other apps can verify access tokens without calling the API.
+// It is served raw, without the API envelope, because JWKS clients expect the standard shape.
+func (controller Controller) JWKS(context *gin.Context) {
+	context.Header("Cache-Control", "public, max-age=300")
+	context.JSON(http.StatusOK, jwt.JWKS())
+}
+
+func sessionClient(context *gin.Context) SessionClient {
+	return SessionClient{
+		UserAgent: context.Request.UserAgent(),
+		IPAddress: context.ClientIP(),
+	}
+}
+
+func respondEmailError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrUserTokenInvalid):
+		utils.APIRespondError(context, http.StatusGone, err.Error())
+	case errors.Is(err, ErrEmailTaken), errors.Is(err, ErrEmailAlreadyVerified):
+		utils.APIRespondError(context, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrUserTokenThrottled):
+		utils.APIRespondError(context, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, ErrInvalidPassword), errors.Is(err, ErrEmailUnchanged):
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+	default:
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+	}
+	context.Abort()
+}
+
+func respondIdentityError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrIdentityNotFound), errors.Is(err, ErrUnknownProvider):
+		utils.APIRespondError(context, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrIdentityInUse), errors.Is(err, ErrProviderAlreadyLinked), errors.Is(err, ErrLastLoginMethod),
+		errors.Is(err, ErrIdentityNotLinked):
+		utils.APIRespondError(context, http.StatusConflict, err.Error())
+	default:
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+	}
+	context.Abort()
+}
+
+func respondTwoFactorError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrTwoFactorChallengeInvalid), errors.Is(err, ErrTooManyTwoFactorAttempts):
+		utils.APIRespondError(context, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, ErrTwoFactorAlreadyEnabled):
+		utils.APIRespondError(context, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrInvalidTwoFactorCode), errors.Is(err, ErrInvalidPassword),
+		errors.Is(err, ErrTwoFactorNotEnabled), errors.Is(err, ErrTwoFactorSetupNotStarted),
+		errors.Is(err, ErrTwoFactorPasswordRequired):
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+	default:
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+	}
+	context.Abort()
+}
@@ -0,0 +1,354 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flash/models"
+	"flash/sdk/mailer"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+	TokenPurposeChangeEmail   = "change_email"
+
+	verifyEmailLifeSpan   = 48 * time.Hour
+	resetPasswordLifeSpan = time.Hour
+	changeEmailLifeSpan   = 24 * time.Hour
+
+	// userTokenResendInterval keeps a single address from being flooded with emails.
+	userTokenResendInterval = time.Minute
+)
+
+var (
+	ErrUserTokenInvalid     = errors.New("link is invalid or has expired")
+	ErrUserTokenThrottled   = errors.New("an email was sent recently, please wait a minute before requesting another")
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	ErrEmailTaken           = errors.New("email already registered")
+	ErrEmailUnchanged       = errors.New("new email is the same as the current one")
+	ErrInvalidPassword      = errors.New("invalid password")
+)
+
+// SendVerificationEmail mails a link that confirms the user owns their address.
+func (service Service) SendVerificationEmail(user *models.User) error {
+	token, err := issueUserToken(service.DB, user.ID, TokenPurposeVerifyEmail, user.Email, verifyEmailLifeSpan)
+	if err != nil {
+		return err
+	}
+
+	return sendActionEmail(user.Email, "Verify your email address",
+		"Confirm your email address to start publishing sites on Kislap.",
+		"Verify email", webURL("/verify-email", token), verifyEmailLifeSpan)
+}
+
+func (service Service) ResendVerificationEmail(userID uint64) error {
+	var user models.User
+	if err := service.DB.First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+	if recentlyIssued(service.DB, user.ID, TokenPurposeVerifyEmail) {
+		return ErrUserTokenThrottled
+	}
+
+	return service.SendVerificationEmail(&user)
+}
+
+func (service Service) VerifyEmail(token string) (*models.User, error) {
+	var user models.User
+
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		userToken, err := consumeUserToken(tx, TokenPurposeVerifyEmail, token)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.First(&user, userToken.UserID).Error; err != nil {
+			return err
+		}
+
+		// The address changed after the link was sent, so it no longer proves anything.
+		if !strings.EqualFold(user.Email, userToken.Email) {
+			return ErrUserTokenInvalid
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		return tx.Model(&user).Update("email_verified_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// RequestPasswordReset mails a reset link. It reports success for unknown addresses too, so the endpoint can't be
+// used to find out who has an account.
+func (service Service) RequestPasswordReset(email string) error {
+	var user models.User
+	err := service.DB.Where("email = ?", strings.TrimSpace(email)).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if recentlyIssued(service.DB, user.ID, TokenPurposeResetPassword) {
+		return nil
+	}
+
+	token, err := issueUserToken(service.DB, user.ID, TokenPurposeResetPassword, user.Email, resetPasswordLifeSpan)
+	if err != nil {
+		return err
+	}
+
+	return sendActionEmail(user.Email, "Reset your password",
+		"We received a request to reset your Kislap password. If it wasn't you, you can ignore this email.",
+		"Reset password", webURL("/reset-password", token), resetPasswordLifeSpan)
+}
+
+// ResetPassword sets a new password and signs the user out everywhere, since whoever knew the old one may still
+// hold a session.
+func (service Service) ResetPassword(token string, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	var userID uint64
+	err = service.DB.Transaction(func(tx *gorm.DB) error {
+		userToken, err := consumeUserToken(tx, TokenPurposeResetPassword, token)
+		if err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.First(&user, userToken.UserID).Error; err != nil {
+			return err
+		}
+		if !strings.EqualFold(user.Email, userToken.Email) {
+			return ErrUserTokenInvalid
+		}
+		userID = user.ID
+
+		updates := map[string]any{
+			"password":      string(hashedPassword),
+			"refresh_token": nil,
+		}
+		// Following the link proves the user reads this mailbox.
+		if user.EmailVerifiedAt == nil {
+			updates["email_verified_at"] = time.Now()
+		}
+
+		return tx.Model(&user).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = service.RevokeAllSessions(userID, 0)
+	return err
+}
+
+// RequestEmailChange mails a confirmation link to the new address. The account keeps its current email until the
+// link is followed.
+func (service Service) RequestEmailChange(userID uint64, newEmail string, password string) error {
+	var user models.User
+	if err := service.DB.First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	// Accounts created through GitHub or Google have no password to confirm with.
+	if user.Password != nil && *user.Password != "" {
+		if bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(password)) != nil {
+			return ErrInvalidPassword
+		}
+	}
+
+	newEmail = strings.TrimSpace(newEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		return ErrEmailUnchanged
+	}
+	if taken, err := emailTaken(service.DB, newEmail, user.ID); err != nil {
+		return err
+	} else if taken {
+		return ErrEmailTaken
+	}
+	if recentlyIssued(service.DB, user.ID, TokenPurposeChangeEmail) {
+		return ErrUserTokenThrottled
+	}
+
+	token, err := issueUserToken(service.DB, user.ID, TokenPurposeChangeEmail, newEmail, changeEmailLifeSpan)
+	if err != nil {
+		return err
+	}
+
+	return sendActionEmail(newEmail, "Confirm your new email address",
+		fmt.Sprintf("Confirm this address to use it for your Kislap account instead of %s.", user.Email),
+		"Confirm email", webURL("/confirm-email", token), changeEmailLifeSpan)
+}
+
+// ConfirmEmailChange moves the account onto the new address and lets the old one know.
+func (service Service) ConfirmEmailChange(token string) (*models.User, error) {
+	var user models.User
+	var previousEmail string
+
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		userToken, err := consumeUserToken(tx, TokenPurposeChangeEmail, token)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.First(&user, userToken.UserID).Error; err != nil {
+			return err
+		}
+		if taken, err := emailTaken(tx, userToken.Email, user.ID); err != nil {
+			return err
+		} else if taken {
+			return ErrEmailTaken
+		}
+
+		now := time.Now()
+		previousEmail = user.Email
+		user.Email = userToken.Email
+		user.EmailVerifiedAt = &now
+
+		return tx.Model(&user).Updates(map[string]any{
+			"email":             user.Email,
+			"email_verified_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notice := mailer.Message{
+		To:      previousEmail,
+		Subject: "Your Kislap email address was changed",
+		Text:    fmt.Sprintf("The email address on your Kislap account was changed to %s. If you didn't do this, contact support right away.", user.Email),
+	}
+	if err := mailer.Send(notice); err != nil {
+		return &user, fmt.Errorf("email changed but the notice to the previous address failed: %w", err)
+	}
+
+	return &user, nil
+}
+
+// issueUserToken creates a single-use token and drops any unused one issued earlier for the same purpose, so only
+// the newest link works.
+func issueUserToken(db *gorm.DB, userID uint64, purpose string, email string, lifeSpan time.Duration) (string, error) {
+	buffer := make([]byte, 32)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buffer)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Delete(&models.UserToken{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.UserToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			Email:     email,
+			TokenHash: hashUserToken(token),
+			ExpiresAt: time.Now().Add(lifeSpan),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeUserToken marks a token used. The row lock makes sure two requests racing with the same link can't both
+// succeed.
+func consumeUserToken(tx *gorm.DB, purpose string, token string) (*models.UserToken, error) {
+	var userToken models.UserToken
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hashUserToken(token), purpose, time.Now()).
+		First(&userToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserTokenInvalid
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := tx.Model(&userToken).Update("used_at", now).Error; err != nil {
+		return nil, err
+	}
+
+	return &userToken, nil
+}
+
+func recentlyIssued(db *gorm.DB, userID uint64, purpose string) bool {
+	var count int64
+	db.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at > ?", userID, purpose, time.Now().Add(-userTokenResendInterval)).
+		Count(&count)
+	return count > 0
+}
+
+func emailTaken(db *gorm.DB, email string, exceptUserID uint64) (bool, error) {
+	var count int64
+	if err := db.Model(&models.User{}).Where("email = ? AND id <> ?", email, exceptUserID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func hashUserToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
+
+func webURL(path string, token string) string {
+	base := os.Getenv("APP_WEB_URL")
+	if base == "" {
+		base = "https://builder.kislap.app"
+	}
+
+	return fmt.Sprintf("%s%s?token=%s", strings.TrimRight(base, "/"), path, url.QueryEscape(token))
+}
+
+func sendActionEmail(to string, subject string, intro string, action string, link string, lifeSpan time.Duration) error {
+	expiry := fmt.Sprintf("This link expires in %s and can only be used once.", humanizeDuration(lifeSpan))
+
+	return mailer.Send(mailer.Message{
+		To:      to,
+		Subject: subject,
+		Text:    fmt.Sprintf("%s\n\n%s: %s\n\n%s\n", intro, action, link, expiry),
+		HTML: fmt.Sprintf(`<p>%s</p><p><a href="%s">%s</a></p><p>%s</p>`,
+			html.EscapeString(intro), html.EscapeString(link), html.EscapeString(action), html.EscapeString(expiry)),
+	})
+}
+
+func humanizeDuration(duration time.Duration) string {
+	if hours := int(duration.Hours()); hours > 1 {
+		return fmt.Sprintf("%d hours", hours)
+	}
+	return "1 hour"
+}
@@ -0,0 +1,289 @@
+package auth
+
+import (
+	"errors"
+	"flash/models"
+	"flash/sdk/mailer"
+	"flash/shared/testdb"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var linkPattern = regexp.MustCompile(`https?://\S+`)
+
+// newEmailService returns a service whose mail lands in the returned inbox. User 1 has not verified
+// ada@example.com and signs in with "correct horse"; user 2 owns bob@example.com.
+func newEmailService(t *testing.T) (Service, *mailer.InMemoryMailer) {
+	t.Helper()
+
+	inbox := mailer.NewInMemoryMailer()
+	mailer.Default(inbox)
+	t.Cleanup(func() { mailer.Default(nil) })
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed, empty := string(hash), ""
+
+	db := testdb.Open(t, &models.User{}, &models.UserToken{}, &models.UserSession{})
+	testdb.Create(t, db,
+		&models.User{ID: 1, FirstName: "Ada", Email: "ada@example.com", Password: &hashed},
+		&models.User{ID: 2, FirstName: "Bob", Email: "bob@example.com", Password: &empty},
+	)
+
+	return Service{DB: db}, inbox
+}
+
+// tokenSentTo pulls the token out of the link in the latest email to an address.
+func tokenSentTo(t *testing.T, inbox *mailer.InMemoryMailer, to string) string {
+	t.Helper()
+
+	message, ok := inbox.Last(to)
+	if !ok {
+		t.Fatalf("no email sent to %s", to)
+	}
+	link, err := url.Parse(linkPattern.FindString(message.Text))
+	if err != nil || link.Query().Get("token") == "" {
+		t.Fatalf("email to %s has no token link: %q", to, message.Text)
+	}
+	return link.Query().Get("token")
+}
+
+func loadUser(t *testing.T, service Service, id uint64) models.User {
+	t.Helper()
+
+	var user models.User
+	if err := service.DB.First(&user, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// ageTokens moves every token of a purpose into the past, out of the resend throttle.
+func ageTokens(t *testing.T, service Service, purpose string) {
+	t.Helper()
+
+	if err := service.DB.Model(&models.UserToken{}).Where("purpose = ?", purpose).
+		Update("created_at", time.Now().Add(-userTokenResendInterval-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	service, inbox := newEmailService(t)
+
+	if err := service.ResendVerificationEmail(1); err != nil {
+		t.Fatalf("ResendVerificationEmail() error = %v", err)
+	}
+	token := tokenSentTo(t, inbox, "ada@example.com")
+
+	user, err := service.VerifyEmail(token)
+	if err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+	if user.EmailVerifiedAt == nil || loadUser(t, service, 1).EmailVerifiedAt == nil {
+		t.Fatal("VerifyEmail() did not mark the address verified")
+	}
+
+	if _, err := service.VerifyEmail(token); !errors.Is(err, ErrUserTokenInvalid) {
+		t.Fatalf("VerifyEmail() with a used link: error = %v, want %v", err, ErrUserTokenInvalid)
+	}
+	if err := service.ResendVerificationEmail(1); !errors.Is(err, ErrEmailAlreadyVerified) {
+		t.Fatalf("ResendVerificationEmail() once verified: error = %v, want %v", err, ErrEmailAlreadyVerified)
+	}
+}
+
+func TestVerifyEmailRejectsExpiredLinks(t *testing.T) {
+	service, inbox := newEmailService(t)
+
+	if err := service.ResendVerificationEmail(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DB.Model(&models.UserToken{}).Where("user_id = ?", 1).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.VerifyEmail(tokenSentTo(t, inbox, "ada@example.com")); !errors.Is(err, ErrUserTokenInvalid) {
+		t.Fatalf("VerifyEmail() with an expired link: error = %v, want %v", err, ErrUserTokenInvalid)
+	}
+	if loadUser(t, service, 1).EmailVerifiedAt != nil {
+		t.Fatal("an expired link verified the address")
+	}
+}
+
+func TestVerifyEmailAfterTheAddressChanged(t *testing.T) {
+	service, inbox := newEmailService(t)
+
+	if err := service.ResendVerificationEmail(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DB.Model(&models.User{}).Where("id = ?", 1).Update("email", "ada@work.example").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// The link proves ownership of the old address only.
+	if _, err := service.VerifyEmail(tokenSentTo(t, inbox, "ada@example.com")); !errors.Is(err, ErrUserTokenInvalid) {
+		t.Fatalf("VerifyEmail() after the address changed: error = %v, want %v", err, ErrUserTokenInvalid)
+	}
+	if loadUser(t, service, 1).EmailVerifiedAt != nil {
+		t.Fatal("the new address was verified by a link sent to the old one")
+	}
+}
+
+func TestResendVerificationEmailIsThrottled(t *testing.T) {
+	service, inbox := newEmailService(t)
+
+	if err := service.ResendVerificationEmail(1); err != nil {
+		t.Fatal(err)
+	}
+	first := tokenSentTo(t, inbox, "ada@example.com")
+
+	if err := service.ResendVerificationEmail(1); !errors.Is(err, ErrUserTokenThrottled) {
+		t.Fatalf("ResendVerificationEmail() right away: error = %v, want %v", err, ErrUserTokenThrottled)
+	}
+	if sent := len(inbox.Sent()); sent != 1 {
+		t.Fatalf("sent %d emails, want 1", sent)
+	}
+
+	ageTokens(t, service, TokenPurposeVerifyEmail)
+	if err := service.ResendVerificationEmail(1); err != nil {
+		t.Fatalf("ResendVerificationEmail() after a minute: %v", err)
+	}
+
+	// Only the newest link works.
+	if _, err := service.VerifyEmail(first); !errors.Is(err, ErrUserTokenInvalid) {
+		t.Fatalf("VerifyEmail() with the superseded link: error = %v, want %v", err, ErrUserTokenInvalid)
+	}
+	if _, err := service.VerifyEmail(tokenSentTo(t, inbox, "ada@example.com")); err != nil {
+		t.Fatalf("VerifyEmail() with the newest link: %v", err)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	service, inbox := newEmailService(t)
+	testdb.Create(t, service.DB, &models.UserSession{UserID: 1, TokenHash: "other-device", ExpiresAt: time.Now().Add(time.Hour)})
+
+	if err := service.RequestPasswordReset("nobody@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset() for an unknown address: %v", err)
+	}
+	if err := service.RequestPasswordReset(" ada@example.com "); err != nil {
+		t.Fatal(err)
+	}
+	// A second request within the minute is swallowed, without telling the caller.
+	if err := service.RequestPasswordReset("ada@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if sent := len(inbox.Sent()); sent != 1 {
+		t.Fatalf("sent %d emails, want only the first reset link", sent)
+	}
+
+	token := tokenSentTo(t, inbox, "ada@example.com")
+	if err := service.ResetPassword(token, "battery staple"); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+
+	user := loadUser(t, service, 1)
+	if bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte("battery staple")) != nil {
+		t.Fatal("the password was not changed")
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Fatal("following the reset link did not verify the address")
+	}
+	var active int64
+	service.DB.Model(&models.UserSession{}).Where("user_id = ? AND revoked_at IS NULL", 1).Count(&active)
+	if active != 0 {
+		t.Fatalf("%d sessions still active after the reset", active)
+	}
+
+	if err := service.ResetPassword(token, "another one"); !errors.Is(err, ErrUserTokenInvalid) {
+		t.Fatalf("ResetPassword() with a used link: error = %v, want %v", err, ErrUserTokenInvalid)
+	}
+}
+
+func TestResetPasswordAfterTheAddressChanged(t *testing.T) {
+	service, inbox := newEmailService(t)
+
+	if err := service.RequestPasswordReset("ada@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DB.Model(&models.User{}).Where("id = ?", 1).Update("email", "ada@work.example").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.ResetPassword(tokenSentTo(t, inbox, "ada@example.com"), "battery staple"); !errors.Is(err, ErrUserTokenInvalid) {
+		t.Fatalf("ResetPassword() after the address changed: error = %v, want %v", err, ErrUserTokenInvalid)
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+	service, inbox := newEmailService(t)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{name: "wrong password", email: "ada@work.example", password: "battery staple", wantErr: ErrInvalidPassword},
+		{name: "same address", email: " ADA@example.com ", password: "correct horse", wantErr: ErrEmailUnchanged},
+		{name: "taken address", email: "bob@example.com", password: "correct horse", wantErr: ErrEmailTaken},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := service.RequestEmailChange(1, test.email, test.password); !errors.Is(err, test.wantErr) {
+				t.Fatalf("RequestEmailChange() error = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+
+	if err := service.RequestEmailChange(1, "ada@work.example", "correct horse"); err != nil {
+		t.Fatalf("RequestEmailChange() error = %v", err)
+	}
+	if err := service.RequestEmailChange(1, "ada@work.example", "correct horse"); !errors.Is(err, ErrUserTokenThrottled) {
+		t.Fatalf("RequestEmailChange() right away: error = %v, want %v", err, ErrUserTokenThrottled)
+	}
+	if user := loadUser(t, service, 1); user.Email != "ada@example.com" {
+		t.Fatalf("email = %s before the change was confirmed", user.Email)
+	}
+
+	token := tokenSentTo(t, inbox, "ada@work.example")
+	user, err := service.ConfirmEmailChange(token)
+	if err != nil {
+		t.Fatalf("ConfirmEmailChange() error = %v", err)
+	}
+	if user.Email != "ada@work.example" || user.EmailVerifiedAt == nil {
+		t.Fatalf("ConfirmEmailChange() = %s verified at %v", user.Email, user.EmailVerifiedAt)
+	}
+	if _, ok := inbox.Last("ada@example.com"); !ok {
+		t.Fatal("the previous address was not told about the change")
+	}
+
+	if _, err := service.ConfirmEmailChange(token); !errors.Is(err, ErrUserTokenInvalid) {
+		t.Fatalf("ConfirmEmailChange() with a used link: error = %v, want %v", err, ErrUserTokenInvalid)
+	}
+}
+
+func TestConfirmEmailChangeWhenTheAddressWasTakenMeanwhile(t *testing.T) {
+	service, inbox := newEmailService(t)
+
+	// Bob's account signed in through GitHub, so it has no password to confirm with.
+	if err := service.RequestEmailChange(2, "shared@example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DB.Model(&models.User{}).Where("id = ?", 1).Update("email", "shared@example.com").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.ConfirmEmailChange(tokenSentTo(t, inbox, "shared@example.com")); !errors.Is(err, ErrEmailTaken) {
+		t.Fatalf("ConfirmEmailChange() error = %v, want %v", err, ErrEmailTaken)
+	}
+	if user := loadUser(t, service, 2); user.Email != "bob@example.com" {
+		t.Fatalf("email = %s, want it unchanged", user.Email)
+	}
+}
@@ -0,0 +1,392 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flash/models"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+	"golang.org/x/oauth2/google"
+	"gorm.io/gorm"
+)
+
+const (
+	ProviderGithub = "github"
+	ProviderGoogle = "google"
+)
+
+var (
+	ErrUnknownProvider       = errors.New("unknown sign-in provider")
+	ErrIdentityNotLinked     = errors.New("an account with this email already exists, sign in to it and link this provider from your settings")
+	ErrIdentityInUse         = errors.New("this provider account is already linked to another user")
+	ErrProviderAlreadyLinked = errors.New("a different account from this provider is already linked")
+	ErrIdentityNotFound      = errors.New("provider is not linked to this account")
+	ErrLastLoginMethod       = errors.New("set a password or link another provider before unlinking your only sign-in method")
+)
+
+// providerProfile is what a provider tells us about the person behind an OAuth code.
+type providerProfile struct {
+	ID        string
+	Email     string
+	FirstName string
+	LastName  string
+	AvatarURL string
+}
+
+// loginWithIdentity signs in through a provider. Accounts are matched on the provider's user ID, never on email
+// alone: an existing account with the same email has to link the provider from a signed-in session first.
+func (service Service) loginWithIdentity(provider string, profile *providerProfile, device SessionClient) (*LoginResponse, error) {
+	var user models.User
+
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var identity models.UserIdentity
+		err := tx.Where("provider = ? AND provider_user_id = ?", provider, profile.ID).First(&identity).Error
+		if err == nil {
+			if err := tx.First(&user, identity.UserID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&identity).Updates(map[string]any{"email": profile.Email, "last_login_at": now}).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// Links carried over from the old github/google flags have no provider ID yet; claim them by email.
+		err = tx.Select("user_identities.*").
+			Joins("JOIN users ON users.id = user_identities.user_id").
+			Where("user_identities.provider = ? AND user_identities.provider_user_id IS NULL AND users.email = ?", provider, profile.Email).
+			First(&identity).Error
+		if err == nil {
+			if err := tx.First(&user, identity.UserID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&identity).Updates(map[string]any{
+				"provider_user_id": profile.ID,
+				"email":            profile.Email,
+				"last_login_at":    now,
+			}).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		err = tx.Where("email = ?", profile.Email).First(&user).Error
+		if err == nil {
+			return ErrIdentityNotLinked
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		emptyPassword := ""
+		user = models.User{
+			FirstName:  profile.FirstName,
+			LastName:   profile.LastName,
+			Email:      profile.Email,
+			Password:   &emptyPassword,
+			Role:       "default",
+			Newsletter: true,
+			// Providers only hand out addresses they have verified.
+			EmailVerifiedAt: &now,
+		}
+		if profile.AvatarURL != "" {
+			user.ImageURL = &profile.AvatarURL
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:         user.ID,
+			Provider:       provider,
+			ProviderUserID: &profile.ID,
+			Email:          &profile.Email,
+			LastLoginAt:    &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.DB.Where("user_id = ?", user.ID).Find(&user.Identities).Error; err != nil {
+		return nil, err
+	}
+
+	return service.startSession(&user, device)
+}
+
+func (service Service) ListIdentities(userID uint64) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	if err := service.DB.Where("user_id = ?", userID).Order("provider").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+
+	return identities, nil
+}
+
+// LinkIdentity attaches a provider account to the signed-in user, so either can be used to sign in afterwards.
+func (service Service) LinkIdentity(userID uint64, provider string, code string) (*models.UserIdentity, error) {
+	profile, err := fetchProviderProfile(context.Background(), provider, code)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.linkProfile(userID, provider, profile)
+}
+
+func (service Service) linkProfile(userID uint64, provider string, profile *providerProfile) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.UserIdentity
+		err := tx.Where("provider = ? AND provider_user_id = ?", provider, profile.ID).First(&existing).Error
+		if err == nil {
+			if existing.UserID != userID {
+				return ErrIdentityInUse
+			}
+			identity = existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		err = tx.Where("user_id = ? AND provider = ?", userID, provider).First(&existing).Error
+		if err == nil {
+			// A carried-over link is the same account, just not confirmed yet.
+			if existing.ProviderUserID != nil {
+				return ErrProviderAlreadyLinked
+			}
+			identity = existing
+			return tx.Model(&identity).Updates(map[string]any{"provider_user_id": profile.ID, "email": profile.Email}).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		identity = models.UserIdentity{
+			UserID:         userID,
+			Provider:       provider,
+			ProviderUserID: &profile.ID,
+			Email:          &profile.Email,
+		}
+		return tx.Create(&identity).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+// UnlinkIdentity removes a provider, unless it is the only way left to sign in to the account.
+func (service Service) UnlinkIdentity(userID uint64, provider string) error {
+	return service.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		var identity models.UserIdentity
+		if err := tx.Where("user_id = ? AND provider = ?", userID, provider).First(&identity).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrIdentityNotFound
+			}
+			return err
+		}
+
+		hasPassword := user.Password != nil && *user.Password != ""
+		if !hasPassword {
+			var others int64
+			if err := tx.Model(&models.UserIdentity{}).
+				Where("user_id = ? AND id <> ?", userID, identity.ID).
+				Count(&others).Error; err != nil {
+				return err
+			}
+			if others == 0 {
+				return ErrLastLoginMethod
+			}
+		}
+
+		return tx.Delete(&identity).Error
+	})
+}
+
+var (
+	oauthConfigsOnce sync.Once
+	oauthConfigs     map[string]*oauth2.Config
+)
+
+// oauthConfig returns the settings of the providers that sign in with plain OAuth rather than OIDC. They are read
+// from the environment once, on first use.
+func oauthConfig(provider string) *oauth2.Config {
+	oauthConfigsOnce.Do(func() {
+		oauthConfigs = map[string]*oauth2.Config{
+			ProviderGithub: {
+				RedirectURL:  os.Getenv("GITHUB_REDIRECT_URL"),
+				ClientID:     os.Getenv("GITHUB_CLIENT_ID"),
+				ClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
+				Scopes:       []string{"user:email"},
+				Endpoint:     github.Endpoint,
+			},
+			ProviderGoogle: {
+				RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
+				ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
+				ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+				Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
+				Endpoint:     google.Endpoint,
+			},
+		}
+	})
+
+	return oauthConfigs[provider]
+}
+
+// fetchProviderProfile exchanges the code and reads the account. An *http.Client stored in ctx under
+// oauth2.HTTPClient carries every request, which is how tests stub the providers.
+func fetchProviderProfile(ctx context.Context, provider string, code string) (*providerProfile, error) {
+	switch provider {
+	case ProviderGithub:
+		return fetchGithubProfile(ctx, code)
+	case ProviderGoogle:
+		return fetchGoogleProfile(ctx, code)
+	default:
+		return nil, ErrUnknownProvider
+	}
+}
+
+func fetchGithubProfile(ctx context.Context, code string) (*providerProfile, error) {
+	githubOAuthConfig := oauthConfig(ProviderGithub)
+
+	token, err := githubOAuthConfig.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	client := githubOAuthConfig.Client(ctx, token)
+	resp, err := client.Get("https://api.github.com/user")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var githubUser struct {
+		ID        int64  `json:"id"`
+		Name      string `json:"name"`
+		Email     string `json:"email"`
+		Login     string `json:"login"`
+		AvatarURL string `json:"avatar_url"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&githubUser); err != nil {
+		return nil, err
+	}
+
+	if githubUser.ID == 0 {
+		return nil, fmt.Errorf("could not retrieve the GitHub account")
+	}
+
+	if githubUser.Email == "" {
+		emailResp, err := client.Get("https://api.github.com/user/emails")
+		if err != nil {
+			return nil, err
+		}
+		defer emailResp.Body.Close()
+
+		if emailResp.StatusCode != 200 {
+			bodyBytes, _ := io.ReadAll(emailResp.Body)
+			return nil, fmt.Errorf("github API error: %s - %s", emailResp.Status, string(bodyBytes))
+		}
+
+		var emails []struct {
+			Email    string `json:"email"`
+			Primary  bool   `json:"primary"`
+			Verified bool   `json:"verified"`
+		}
+
+		if err := json.NewDecoder(emailResp.Body).Decode(&emails); err != nil {
+			return nil, fmt.Errorf("error decoding emails: %v", err)
+		}
+
+		for _, e := range emails {
+			if e.Primary && e.Verified {
+				githubUser.Email = e.Email
+				break
+			}
+		}
+	}
+
+	if githubUser.Email == "" {
+		return nil, fmt.Errorf("could not retrieve a primary, verified email from GitHub")
+	}
+
+	name := githubUser.Name
+	if name == "" {
+		name = githubUser.Login
+	}
+	firstName, lastName := parseGitHubName(name)
+
+	return &providerProfile{
+		ID:        strconv.FormatInt(githubUser.ID, 10),
+		Email:     githubUser.Email,
+		FirstName: firstName,
+		LastName:  lastName,
+		AvatarURL: githubUser.AvatarURL,
+	}, nil
+}
+
+func fetchGoogleProfile(ctx context.Context, code string) (*providerProfile, error) {
+	googleOAuthConfig := oauthConfig(ProviderGoogle)
+
+	token, err := googleOAuthConfig.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	client := googleOAuthConfig.Client(ctx, token)
+	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var googleUser struct {
+		ID            string `json:"id"`
+		Email         string `json:"email"`
+		VerifiedEmail bool   `json:"verified_email"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&googleUser); err != nil {
+		return nil, err
+	}
+
+	if googleUser.ID == "" {
+		return nil, fmt.Errorf("could not retrieve the Google account")
+	}
+
+	if !googleUser.VerifiedEmail {
+		return nil, fmt.Errorf("google email not verified")
+	}
+
+	firstName, lastName := parseGitHubName(googleUser.Name)
+
+	return &providerProfile{
+		ID:        googleUser.ID,
+		Email:     strings.TrimSpace(googleUser.Email),
+		FirstName: firstName,
+		LastName:  lastName,
+		AvatarURL: googleUser.Picture,
+	}, nil
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// stubTransport answers provider requests by host and path, and fails the test on anything else.
+type stubTransport struct {
+	t         *testing.T
+	responses map[string]stubResponse
+}
+
+type stubResponse struct {
+	status int
+	body   string
+}
+
+func (stub stubTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	key := request.URL.Host + request.URL.Path
+	response, ok := stub.responses[key]
+	if !ok {
+		stub.t.Errorf("unexpected request to %s", key)
+		response = stubResponse{status: http.StatusNotFound, body: `{}`}
+	}
+
+	// Token endpoints get the client credentials; everything after them the access token.
+	if !strings.HasSuffix(key, "token") && request.Header.Get("Authorization") != "Bearer provider-token" {
+		stub.t.Errorf("request to %s sent Authorization %q", key, request.Header.Get("Authorization"))
+	}
+
+	return &http.Response{
+		StatusCode: response.status,
+		Status:     http.StatusText(response.status),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(response.body)),
+		Request:    request,
+	}, nil
+}
+
+func stubbedContext(t *testing.T, responses map[string]stubResponse) context.Context {
+	client := &http.Client{Transport: stubTransport{t: t, responses: responses}}
+	return context.WithValue(context.Background(), oauth2.HTTPClient, client)
+}
+
+const stubToken = `{"access_token":"provider-token","token_type":"bearer"}`
+
+func TestFetchGithubProfile(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string]stubResponse
+		want      providerProfile
+		wantErr   string
+	}{
+		{
+			name: "public email",
+			responses: map[string]stubResponse{
+				"github.com/login/oauth/access_token": {http.StatusOK, stubToken},
+				"api.github.com/user":                 {http.StatusOK, `{"id":42,"name":"Ada Lovelace","email":"ada@example.com","login":"ada","avatar_url":"https://avatars.test/ada"}`},
+			},
+			want: providerProfile{ID: "42", Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", AvatarURL: "https://avatars.test/ada"},
+		},
+		{
+			name: "private email falls back to the primary verified address and the login",
+			responses: map[string]stubResponse{
+				"github.com/login/oauth/access_token": {http.StatusOK, stubToken},
+				"api.github.com/user":                 {http.StatusOK, `{"id":7,"login":"octocat"}`},
+				"api.github.com/user/emails":          {http.StatusOK, `[{"email":"old@example.com","primary":false,"verified":true},{"email":"cat@example.com","primary":true,"verified":true}]`},
+			},
+			want: providerProfile{ID: "7", Email: "cat@example.com", FirstName: "octocat"},
+		},
+		{
+			name: "no primary verified email",
+			responses: map[string]stubResponse{
+				"github.com/login/oauth/access_token": {http.StatusOK, stubToken},
+				"api.github.com/user":                 {http.StatusOK, `{"id":7,"login":"octocat"}`},
+				"api.github.com/user/emails":          {http.StatusOK, `[{"email":"cat@example.com","primary":true,"verified":false}]`},
+			},
+			wantErr: "primary, verified email",
+		},
+		{
+			name: "emails endpoint error",
+			responses: map[string]stubResponse{
+				"github.com/login/oauth/access_token": {http.StatusOK, stubToken},
+				"api.github.com/user":                 {http.StatusOK, `{"id":7,"login":"octocat"}`},
+				"api.github.com/user/emails":          {http.StatusForbidden, `{"message":"Resource not accessible"}`},
+			},
+			wantErr: "github API error",
+		},
+		{
+			name: "account missing from the response",
+			responses: map[string]stubResponse{
+				"github.com/login/oauth/access_token": {http.StatusOK, stubToken},
+				"api.github.com/user":                 {http.StatusUnauthorized, `{"message":"Bad credentials"}`},
+			},
+			wantErr: "could not retrieve the GitHub account",
+		},
+		{
+			name: "rejected code",
+			responses: map[string]stubResponse{
+				"github.com/login/oauth/access_token": {http.StatusBadRequest, `{"error":"bad_verification_code"}`},
+			},
+			wantErr: "bad_verification_code",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profile, err := fetchProviderProfile(stubbedContext(t, test.responses), ProviderGithub, "code")
+			assertProfile(t, profile, err, test.want, test.wantErr)
+		})
+	}
+}
+
+func TestFetchGoogleProfile(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string]stubResponse
+		want      providerProfile
+		wantErr   string
+	}{
+		{
+			name: "verified account",
+			responses: map[string]stubResponse{
+				"oauth2.googleapis.com/token":           {http.StatusOK, stubToken},
+				"www.googleapis.com/oauth2/v2/userinfo": {http.StatusOK, `{"id":"1089","email":" grace@example.com ","verified_email":true,"name":"Grace Hopper","picture":"https://photos.test/grace"}`},
+			},
+			want: providerProfile{ID: "1089", Email: "grace@example.com", FirstName: "Grace", LastName: "Hopper", AvatarURL: "https://photos.test/grace"},
+		},
+		{
+			name: "unverified email",
+			responses: map[string]stubResponse{
+				"oauth2.googleapis.com/token":           {http.StatusOK, stubToken},
+				"www.googleapis.com/oauth2/v2/userinfo": {http.StatusOK, `{"id":"1089","email":"grace@example.com","verified_email":false}`},
+			},
+			wantErr: "google email not verified",
+		},
+		{
+			name: "account missing from the response",
+			responses: map[string]stubResponse{
+				"oauth2.googleapis.com/token":           {http.StatusOK, stubToken},
+				"www.googleapis.com/oauth2/v2/userinfo": {http.StatusUnauthorized, `{"error":{"code":401}}`},
+			},
+			wantErr: "could not retrieve the Google account",
+		},
+		{
+			name: "rejected code",
+			responses: map[string]stubResponse{
+				"oauth2.googleapis.com/token": {http.StatusBadRequest, `{"error":"invalid_grant"}`},
+			},
+			wantErr: "invalid_grant",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profile, err := fetchProviderProfile(stubbedContext(t, test.responses), ProviderGoogle, "code")
+			assertProfile(t, profile, err, test.want, test.wantErr)
+		})
+	}
+}
+
+func TestFetchProviderProfileUnknownProvider(t *testing.T) {
+	if _, err := fetchProviderProfile(context.Background(), "gitlab", "code"); err != ErrUnknownProvider {
+		t.Fatalf("error = %v, want ErrUnknownProvider", err)
+	}
+}
+
+func assertProfile(t *testing.T, profile *providerProfile, err error, want providerProfile, wantErr string) {
+	t.Helper()
+
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("error = %v, want one containing %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if *profile != want {
+		t.Errorf("profile = %+v, want %+v", *profile, want)
+	}
+}
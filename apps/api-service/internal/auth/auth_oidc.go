@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flash/models"
+	"flash/sdk/oidc"
+	"time"
+
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	OIDCIntentLogin = "login"
+	OIDCIntentLink  = "link"
+
+	// oidcStateLifeSpan is how long the user has to get through the provider's sign-in page.
+	oidcStateLifeSpan = 10 * time.Minute
+
+	// OIDCBrowserCookie holds the value that ties a sign-in attempt to the browser that started it.
+	OIDCBrowserCookie = "oidc_browser"
+)
+
+var (
+	ErrOIDCStateInvalid  = errors.New("sign-in request is invalid or has expired, please try again")
+	ErrOIDCEmailRequired = errors.New("the provider did not share a verified email address")
+)
+
+// OIDCResult is the outcome of a callback: a new session for login requests, the linked identity for link
+// requests.
+type OIDCResult struct {
+	Intent   string
+	Login    *LoginResponse
+	Identity *models.UserIdentity
+}
+
+// BeginOIDC records the state, nonce and PKCE verifier of a sign-in attempt and returns the provider URL to send
+// the browser to, along with the browser binding the caller must store in OIDCBrowserCookie. Without the binding a
+// callback carrying someone else's state is rejected, which stops an attacker from finishing their own sign-in in
+// the victim's browser. Link requests remember the signed-in user, so the callback itself needs no session.
+func (service Service) BeginOIDC(providerName string, intent string, userID *uint64) (string, string, error) {
+	provider, ok := oidc.Get(providerName)
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+	binding, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, err := provider.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	// Abandoned attempts are cleared here rather than by a scheduled task; there are never many of them.
+	if err := service.DB.Where("expires_at < ?", now).Delete(&models.OIDCState{}).Error; err != nil {
+		return "", "", err
+	}
+
+	if err := service.DB.Create(&models.OIDCState{
+		Provider:     providerName,
+		StateHash:    hashUserToken(state),
+		BrowserHash:  hashUserToken(binding),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		Intent:       intent,
+		UserID:       userID,
+		ExpiresAt:    now.Add(oidcStateLifeSpan),
+	}).Error; err != nil {
+		return "", "", err
+	}
+
+	return authURL, binding, nil
+}
+
+// CompleteOIDC handles the redirect back from the provider. The state is single use: it is deleted before the
+// code is exchanged, so a replayed callback fails even if the exchange errors. binding is the OIDCBrowserCookie
+// value; it must belong to the same attempt as the state.
+func (service Service) CompleteOIDC(providerName string, code string, state string, binding string, device SessionClient) (*OIDCResult, error) {
+	provider, ok := oidc.Get(providerName)
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	if binding == "" {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	var attempt models.OIDCState
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("state_hash = ? AND browser_hash = ? AND provider = ? AND expires_at > ?",
+				hashUserToken(state), hashUserToken(binding), providerName, time.Now()).
+			First(&attempt).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOIDCStateInvalid
+			}
+			return err
+		}
+
+		return tx.Delete(&attempt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := provider.Exchange(context.Background(), code, attempt.CodeVerifier, attempt.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailRequired
+	}
+
+	profile := &providerProfile{
+		ID:        claims.Subject,
+		Email:     claims.Email,
+		FirstName: claims.GivenName,
+		LastName:  claims.FamilyName,
+		AvatarURL: claims.Picture,
+	}
+	if profile.FirstName == "" {
+		profile.FirstName, profile.LastName = parseGitHubName(claims.Name)
+	}
+
+	if attempt.Intent == OIDCIntentLink && attempt.UserID != nil {
+		identity, err := service.linkProfile(*attempt.UserID, providerName, profile)
+		if err != nil {
+			return nil, err
+		}
+		return &OIDCResult{Intent: OIDCIntentLink, Identity: identity}, nil
+	}
+
+	login, err := service.loginWithIdentity(providerName, profile, device)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCResult{Intent: OIDCIntentLogin, Login: login}, nil
+}
+
+func randomHex(size int) (string, error) {
+	buffer := make([]byte, size)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buffer), nil
+}
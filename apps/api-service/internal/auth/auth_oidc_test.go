@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"errors"
+	"flash/models"
+	"flash/sdk/oidc"
+	"flash/shared/jwt"
+	"flash/shared/testdb"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// newOIDCService registers a mock issuer as the "mock" provider and returns a service on an empty database.
+func newOIDCService(t *testing.T) (Service, *oidc.MockServer) {
+	t.Helper()
+
+	if err := jwt.Configure(&jwt.KeySet{
+		ActiveID: "test",
+		Keys: map[string]*jwt.SigningKey{
+			"test": {ID: "test", Algorithm: jwt.AlgorithmHS256, Secret: []byte("oidc-test-secret")},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	mock, err := oidc.NewMockServer("kislap-web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mock.Close)
+
+	provider := oidc.NewProvider(mock.Config("mock", "https://kislap.test/auth/oidc/mock/callback"))
+	provider.HTTPClient = mock.Client()
+	oidc.Default(oidc.NewRegistry(provider))
+
+	db := testdb.Open(t, &models.OIDCState{}, &models.User{}, &models.UserIdentity{}, &models.UserSession{})
+	return Service{DB: db}, mock
+}
+
+// beginOIDC starts a sign-in and walks the browser through the mock's authorize page.
+func beginOIDC(t *testing.T, service Service, mock *oidc.MockServer, intent string, userID *uint64) (string, string, string) {
+	t.Helper()
+
+	authURL, binding, err := service.BeginOIDC("mock", intent, userID)
+	if err != nil {
+		t.Fatalf("BeginOIDC() error = %v", err)
+	}
+	if binding == "" {
+		t.Fatal("BeginOIDC() returned no browser binding")
+	}
+
+	code, state, err := mock.SignIn(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, state, binding
+}
+
+func TestCompleteOIDCSignsIn(t *testing.T) {
+	service, mock := newOIDCService(t)
+	mock.SetUser(oidc.MockUser{Subject: "ada", Email: "ada@example.com", EmailVerified: true, Name: "Ada Lovelace"})
+
+	code, state, binding := beginOIDC(t, service, mock, OIDCIntentLogin, nil)
+
+	result, err := service.CompleteOIDC("mock", code, state, binding, SessionClient{UserAgent: "test"})
+	if err != nil {
+		t.Fatalf("CompleteOIDC() error = %v", err)
+	}
+	if result.Intent != OIDCIntentLogin || result.Login == nil || result.Login.RefreshToken == "" {
+		t.Fatalf("CompleteOIDC() = %+v", result)
+	}
+	if user := result.Login.User; user.Email != "ada@example.com" || user.FirstName != "Ada" || user.LastName != "Lovelace" {
+		t.Fatalf("signed in as %+v", user)
+	}
+
+	var identity models.UserIdentity
+	if err := service.DB.Where("provider = ? AND provider_user_id = ?", "mock", "ada").First(&identity).Error; err != nil {
+		t.Fatalf("identity was not recorded: %v", err)
+	}
+
+	// The state is single use.
+	if _, err := service.CompleteOIDC("mock", code, state, binding, SessionClient{}); !errors.Is(err, ErrOIDCStateInvalid) {
+		t.Fatalf("replayed CompleteOIDC() error = %v, want %v", err, ErrOIDCStateInvalid)
+	}
+}
+
+func TestCompleteOIDCRequiresTheBrowserThatStartedIt(t *testing.T) {
+	service, mock := newOIDCService(t)
+
+	// The attacker signs in with their own account but stops before the callback, then gets the victim's browser to
+	// finish it. The victim's browser only holds the binding of a sign-in it started itself, if any.
+	attackerCode, attackerState, attackerBinding := beginOIDC(t, service, mock, OIDCIntentLogin, nil)
+	_, victimBinding, err := service.BeginOIDC("mock", OIDCIntentLogin, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		binding string
+	}{
+		{name: "no cookie", binding: ""},
+		{name: "another attempt's cookie", binding: victimBinding},
+		{name: "made up cookie", binding: "0000"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := service.CompleteOIDC("mock", attackerCode, attackerState, test.binding, SessionClient{}); !errors.Is(err, ErrOIDCStateInvalid) {
+				t.Fatalf("CompleteOIDC() error = %v, want %v", err, ErrOIDCStateInvalid)
+			}
+		})
+	}
+
+	var sessions int64
+	if err := service.DB.Model(&models.UserSession{}).Count(&sessions).Error; err != nil {
+		t.Fatal(err)
+	}
+	if sessions != 0 {
+		t.Fatalf("%d sessions were started without the browser binding", sessions)
+	}
+
+	// A mismatched cookie doesn't burn the attempt for the browser that does hold it.
+	if _, err := service.CompleteOIDC("mock", attackerCode, attackerState, attackerBinding, SessionClient{}); err != nil {
+		t.Fatalf("CompleteOIDC() with the right cookie error = %v", err)
+	}
+}
+
+func TestCompleteOIDCLinksToTheUserThatStartedIt(t *testing.T) {
+	service, mock := newOIDCService(t)
+	mock.SetUser(oidc.MockUser{Subject: "grace", Email: "grace@work.example", EmailVerified: true, Name: "Grace Hopper"})
+
+	empty := ""
+	testdb.Create(t, service.DB, &models.User{ID: 7, FirstName: "Grace", Email: "grace@example.com", Password: &empty})
+
+	userID := uint64(7)
+	code, state, binding := beginOIDC(t, service, mock, OIDCIntentLink, &userID)
+
+	result, err := service.CompleteOIDC("mock", code, state, binding, SessionClient{})
+	if err != nil {
+		t.Fatalf("CompleteOIDC() error = %v", err)
+	}
+	if result.Intent != OIDCIntentLink || result.Identity == nil || result.Identity.UserID != 7 {
+		t.Fatalf("CompleteOIDC() = %+v", result)
+	}
+}
+
+func TestCompleteOIDCRequiresAVerifiedEmail(t *testing.T) {
+	service, mock := newOIDCService(t)
+	mock.SetUser(oidc.MockUser{Subject: "eve", Email: "eve@example.com", EmailVerified: false})
+
+	code, state, binding := beginOIDC(t, service, mock, OIDCIntentLogin, nil)
+
+	if _, err := service.CompleteOIDC("mock", code, state, binding, SessionClient{}); !errors.Is(err, ErrOIDCEmailRequired) {
+		t.Fatalf("CompleteOIDC() error = %v, want %v", err, ErrOIDCEmailRequired)
+	}
+
+	var user models.User
+	if err := service.DB.Where("email = ?", "eve@example.com").First(&user).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("an account was created for an unverified email: %v", err)
+	}
+}
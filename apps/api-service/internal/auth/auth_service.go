@@ -2,21 +2,12 @@ package auth
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/json"
 	"errors"
 	"flash/models"
-	"flash/shared/jwt"
-	"fmt"
-	"io"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/github"
-	"golang.org/x/oauth2/google"
 	"gorm.io/gorm"
 )
 
@@ -29,12 +20,15 @@ type LoginResponse struct {
 	AccessToken  string
 	RefreshToken string
 	User         models.User
+
+	// TwoFactor is set instead of the tokens when the password was right but the account has 2FA on.
+	TwoFactor *TwoFactorChallenge
 }
 
-func (service Service) Login(email string, password string) (*LoginResponse, error) {
+func (service Service) Login(email string, password string, device SessionClient) (*LoginResponse, error) {
 	var user models.User
 
-	dbUser := service.DB.Where("email = ?", email).First(&user)
+	dbUser := service.DB.Preload("Identities").Where("email = ?", email).First(&user)
 	if errors.Is(dbUser.Error, gorm.ErrRecordNotFound) {
 		return nil, errors.New("user not found")
 	} else if dbUser.Error != nil {
@@ -48,256 +42,62 @@ func (service Service) Login(email string, password string) (*LoginResponse, err
 	userPassword := []byte(*user.Password)
 
 	if err := bcrypt.CompareHashAndPassword(userPassword, []byte(password)); err != nil {
-		return nil, errors.New("invalid password")
+		return nil, ErrInvalidPassword
+	}
+
+	if user.TwoFactorConfirmedAt != nil {
+		return service.beginTwoFactorChallenge(&user)
 	}
 
-	return service.generateTokens(&user)
+	return service.startSession(&user, device)
 }
 
-func (service Service) Refresh(userID uint64, oldRefreshToken string) (*LoginResponse, error) {
+// Refresh rotates the refresh token of one device session. Tokens issued before sessions existed carry no
+// session ID and are migrated onto a new session.
+func (service Service) Refresh(userID uint64, sessionID uint64, oldRefreshToken string, device SessionClient) (*LoginResponse, error) {
 	var user models.User
 
-	if err := service.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := service.DB.Preload("Identities").Where("id = ?", userID).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
 		}
 		return nil, err
 	}
 
-	if user.RefreshToken == nil {
-		return nil, errors.New("no refresh token stored")
+	if sessionID == 0 {
+		return service.migrateLegacyRefresh(&user, oldRefreshToken, device)
 	}
 
-	digest := sha256.Sum256([]byte(oldRefreshToken))
-	if bcrypt.CompareHashAndPassword([]byte(*user.RefreshToken), digest[:]) != nil {
-		return nil, errors.New("invalid refresh token")
-	}
-
-	return service.generateTokens(&user)
+	return service.rotateSession(&user, sessionID, oldRefreshToken, device)
 }
 
-func (service Service) GithubLogin(code string) (*LoginResponse, error) {
-	var githubOAuthConfig = &oauth2.Config{
-		RedirectURL:  os.Getenv("GITHUB_REDIRECT_URL"),
-		ClientID:     os.Getenv("GITHUB_CLIENT_ID"),
-		ClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
-		Scopes:       []string{"user:email"},
-		Endpoint:     github.Endpoint,
-	}
-
-	token, err := githubOAuthConfig.Exchange(context.Background(), code)
-	if err != nil {
-		return nil, err
-	}
-
-	client := githubOAuthConfig.Client(context.Background(), token)
-	resp, err := client.Get("https://api.github.com/user")
+// GithubLogin signs in with the GitHub account behind an OAuth code. See loginWithIdentity for how the account is
+// matched.
+func (service Service) GithubLogin(code string, device SessionClient) (*LoginResponse, error) {
+	profile, err := fetchGithubProfile(context.Background(), code)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var githubUser struct {
-		ID        int64  `json:"id"`
-		Name      string `json:"name"`
-		Email     string `json:"email"`
-		Login     string `json:"login"`
-		AvatarURL string `json:"avatar_url"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&githubUser); err != nil {
-		return nil, err
-	}
-
-	if githubUser.Email == "" {
-		emailResp, err := client.Get("https://api.github.com/user/emails")
-		if err != nil {
-			return nil, err
-		}
-		defer emailResp.Body.Close()
-
-		if emailResp.StatusCode != 200 {
-			bodyBytes, _ := io.ReadAll(emailResp.Body)
-			return nil, fmt.Errorf("github API error: %s - %s", emailResp.Status, string(bodyBytes))
-		}
-
-		var emails []struct {
-			Email    string `json:"email"`
-			Primary  bool   `json:"primary"`
-			Verified bool   `json:"verified"`
-		}
-
-		if err := json.NewDecoder(emailResp.Body).Decode(&emails); err != nil {
-			return nil, fmt.Errorf("error decoding emails: %v", err)
-		}
-
-		for _, e := range emails {
-			if e.Primary && e.Verified {
-				githubUser.Email = e.Email
-				break
-			}
-		}
-	}
-
-	if githubUser.Email == "" {
-		return nil, fmt.Errorf("could not retrieve a primary, verified email from GitHub")
-	}
-
-	firstName, lastName := parseGitHubName(githubUser.Name)
-	var user models.User
-
-	err = service.DB.Where("email = ?", githubUser.Email).Where("github = ?", 1).First(&user).Error
-
-	fmt.Println("the email:", githubUser.Email)
-
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		registeredUser := &models.User{
-			FirstName:  firstName,
-			LastName:   lastName,
-			Email:      githubUser.Email,
-			Role:       "default",
-			ImageURL:   &githubUser.AvatarURL,
-			Newsletter: true,
-			Github:     true,
-		}
-		emptyPassword := ""
-		registeredUser.Password = &emptyPassword
-
-		if createErr := service.DB.Create(registeredUser).Error; createErr != nil {
-			return nil, createErr
-		}
-		user = *registeredUser
-
-	} else if err != nil {
-		return nil, err
-	} else {
-		if !user.Github {
-			service.DB.Model(&user).Update("github", true)
-		}
-	}
-
-	return service.generateTokens(&user)
+	return service.loginWithIdentity(ProviderGithub, profile, device)
 }
 
-func (service Service) GoogleLogin(code string) (*LoginResponse, error) {
-	var googleOAuthConfig = &oauth2.Config{
-		RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
-		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-		ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-		Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
-		Endpoint:     google.Endpoint,
-	}
-
-	token, err := googleOAuthConfig.Exchange(context.Background(), code)
+func (service Service) GoogleLogin(code string, device SessionClient) (*LoginResponse, error) {
+	profile, err := fetchGoogleProfile(context.Background(), code)
 	if err != nil {
 		return nil, err
 	}
 
-	client := googleOAuthConfig.Client(context.Background(), token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var googleUser struct {
-		ID            string `json:"id"`
-		Email         string `json:"email"`
-		VerifiedEmail bool   `json:"verified_email"`
-		Name          string `json:"name"`
-		Picture       string `json:"picture"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&googleUser); err != nil {
-		return nil, err
-	}
-
-	if !googleUser.VerifiedEmail {
-		return nil, fmt.Errorf("google email not verified")
-	}
-
-	firstName, lastName := parseGitHubName(googleUser.Name)
-	var user models.User
-
-	err = service.DB.Where("email = ?", googleUser.Email).First(&user).Error
-
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		registeredUser := &models.User{
-			FirstName:  firstName,
-			LastName:   lastName,
-			Email:      googleUser.Email,
-			Role:       "default",
-			ImageURL:   &googleUser.Picture,
-			Newsletter: true,
-			Google:     true,
-			Github:     false,
-		}
-		emptyPassword := ""
-		registeredUser.Password = &emptyPassword
-
-		if createErr := service.DB.Create(registeredUser).Error; createErr != nil {
-			return nil, createErr
-		}
-		user = *registeredUser
-
-	} else if err != nil {
-		return nil, err
-	} else {
-		if !user.Google {
-			service.DB.Model(&user).Update("google", true)
-		}
-	}
-
-	return service.generateTokens(&user)
-}
-
-func (service Service) generateTokens(user *models.User) (*LoginResponse, error) {
-	accessTokenLifeSpan := 300
-	accessToken, err := jwt.GenerateToken(*user, &accessTokenLifeSpan)
-	if err != nil {
-		return nil, err
-	}
-
-	refreshTokenLifeSpan := 604800
-	refreshToken, err := jwt.GenerateToken(*user, &refreshTokenLifeSpan)
-	if err != nil {
-		return nil, err
-	}
-
-	hashedToken, err := jwt.HashToken(refreshToken)
-	if err != nil {
-		return nil, err
-	}
-
-	user.RefreshToken = &hashedToken
-	if err := service.DB.Save(&user).Error; err != nil {
-		return nil, err
-	}
-
-	return &LoginResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		User:         *user,
-	}, nil
+	return service.loginWithIdentity(ProviderGoogle, profile, device)
 }
 
-func (service Service) Logout(userID uint64) error {
-	var user models.User
-
-	if err := service.DB.Where("id = ?", userID).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("user not found")
-		}
-		return err
-	}
-
-	user.RefreshToken = nil
-
-	if err := service.DB.Save(&user).Error; err != nil {
-		return err
+// Logout ends the session the request was made from. Other devices stay signed in.
+func (service Service) Logout(userID uint64, sessionID uint64) error {
+	if sessionID == 0 {
+		return service.DB.Model(&models.User{}).Where("id = ?", userID).Update("refresh_token", nil).Error
 	}
 
-	return nil
+	return revokeSessions(service.DB.Where("id = ? AND user_id = ?", sessionID, userID), RevokedReasonLogout)
 }
 
 func parseGitHubName(fullName string) (string, string) {
@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flash/models"
+	"flash/shared/jwt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	accessTokenLifeSpan  = 300
+	refreshTokenLifeSpan = 604800
+
+	// refreshRaceWindow tolerates two tabs refreshing with the same token at once; only the first one rotates.
+	refreshRaceWindow = 30 * time.Second
+
+	RevokedReasonLogout = "logout"
+	RevokedReasonUser   = "revoked"
+	RevokedReasonReuse  = "reuse_detected"
+)
+
+var (
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrSessionRevoked       = errors.New("session has been revoked or has expired")
+	ErrRefreshTokenReused   = errors.New("refresh token reuse detected, the session has been revoked")
+	ErrRefreshTokenRotated  = errors.New("refresh token was already rotated")
+	ErrInvalidRefreshToken  = errors.New("invalid refresh token")
+	ErrRecentSignInRequired = errors.New("sign in again to continue")
+)
+
+// SessionClient describes the device a session was started from.
+type SessionClient struct {
+	UserAgent string
+	IPAddress string
+}
+
+// startSession opens a new device session and issues the first token pair for it.
+func (service Service) startSession(user *models.User, device SessionClient) (*LoginResponse, error) {
+	var response *LoginResponse
+
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		placeholder := make([]byte, 32)
+		if _, err := rand.Read(placeholder); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		session := models.UserSession{
+			UserID:     user.ID,
+			TokenHash:  hex.EncodeToString(placeholder),
+			UserAgent:  optionalString(device.UserAgent, 512),
+			IPAddress:  optionalString(device.IPAddress, 45),
+			LastUsedAt: &now,
+			ExpiresAt:  now.Add(refreshTokenLifeSpan * time.Second),
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		tokens, err := issueTokens(user, session.ID)
+		if err != nil {
+			return err
+		}
+
+		response = tokens
+		return tx.Model(&session).Update("token_hash", jwt.HashSessionToken(tokens.RefreshToken)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// rotateSession swaps the presented refresh token for a new one. Presenting a token that was already rotated
+// means it leaked or was replayed, so the whole session is revoked.
+func (service Service) rotateSession(user *models.User, sessionID uint64, refreshToken string, device SessionClient) (*LoginResponse, error) {
+	var response *LoginResponse
+	reused := false
+
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		var session models.UserSession
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", sessionID, user.ID).
+			First(&session).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSessionNotFound
+			}
+			return err
+		}
+
+		now := time.Now()
+		if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+			return ErrSessionRevoked
+		}
+
+		presented := jwt.HashSessionToken(refreshToken)
+		if presented != session.TokenHash {
+			if session.PreviousTokenHash != nil && *session.PreviousTokenHash == presented &&
+				session.RotatedAt != nil && now.Sub(*session.RotatedAt) < refreshRaceWindow {
+				return ErrRefreshTokenRotated
+			}
+
+			reused = true
+			return revokeSessions(tx.Where("id = ?", session.ID), RevokedReasonReuse)
+		}
+
+		tokens, err := issueTokens(user, session.ID)
+		if err != nil {
+			return err
+		}
+		response = tokens
+
+		return tx.Model(&session).Updates(map[string]any{
+			"token_hash":          jwt.HashSessionToken(tokens.RefreshToken),
+			"previous_token_hash": presented,
+			"rotated_at":          now,
+			"last_used_at":        now,
+			"expires_at":          now.Add(refreshTokenLifeSpan * time.Second),
+			"user_agent":          optionalString(device.UserAgent, 512),
+			"ip_address":          optionalString(device.IPAddress, 45),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrRefreshTokenReused
+	}
+
+	return response, nil
+}
+
+// migrateLegacyRefresh accepts a refresh token issued before sessions existed, checked against the single hash
+// on users.refresh_token, and moves the user onto a session.
+func (service Service) migrateLegacyRefresh(user *models.User, refreshToken string, device SessionClient) (*LoginResponse, error) {
+	if user.RefreshToken == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	digest := sha256.Sum256([]byte(refreshToken))
+	if bcrypt.CompareHashAndPassword([]byte(*user.RefreshToken), digest[:]) != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err := service.DB.Model(user).Update("refresh_token", nil).Error; err != nil {
+		return nil, err
+	}
+
+	return service.startSession(user, device)
+}
+
+func (service Service) ListSessions(userID uint64, currentSessionID uint64) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	if err := service.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+func (service Service) RevokeSession(userID uint64, sessionID uint64) error {
+	result := service.DB.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Updates(map[string]any{"revoked_at": time.Now(), "revoked_reason": RevokedReasonUser})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeAllSessions signs the user out everywhere, optionally keeping the session making the request.
+func (service Service) RevokeAllSessions(userID uint64, exceptSessionID uint64) (int64, error) {
+	query := service.DB.Where("user_id = ?", userID)
+	if exceptSessionID != 0 {
+		query = query.Where("id <> ?", exceptSessionID)
+	}
+
+	result := query.Model(&models.UserSession{}).
+		Where("revoked_at IS NULL").
+		Updates(map[string]any{"revoked_at": time.Now(), "revoked_reason": RevokedReasonUser})
+
+	return result.RowsAffected, result.Error
+}
+
+// RequireRecentSignIn checks that the current session was created by a sign-in within window. Refreshing rotates
+// a session's token but keeps its creation time, so only a new sign-in passes. Accounts without a password use it in
+// place of entering one before sensitive changes.
+func (service Service) RequireRecentSignIn(userID uint64, sessionID uint64, window time.Duration) error {
+	var session models.UserSession
+	err := service.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRecentSignInRequired
+	}
+	if err != nil {
+		return err
+	}
+
+	if time.Since(session.CreatedAt) > window {
+		return ErrRecentSignInRequired
+	}
+
+	return nil
+}
+
+func revokeSessions(query *gorm.DB, reason string) error {
+	return query.Model(&models.UserSession{}).
+		Where("revoked_at IS NULL").
+		Updates(map[string]any{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+func issueTokens(user *models.User, sessionID uint64) (*LoginResponse, error) {
+	accessLifeSpan := accessTokenLifeSpan
+	accessToken, err := jwt.GenerateSessionToken(*user, &accessLifeSpan, jwt.TokenTypeAccess, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshLifeSpan := refreshTokenLifeSpan
+	refreshToken, err := jwt.GenerateSessionToken(*user, &refreshLifeSpan, jwt.TokenTypeRefresh, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		User:         *user,
+	}, nil
+}
+
+func optionalString(value string, limit int) *string {
+	if value == "" {
+		return nil
+	}
+	if len(value) > limit {
+		value = value[:limit]
+	}
+	return &value
+}
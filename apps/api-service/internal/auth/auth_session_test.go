@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"errors"
+	"flash/models"
+	"flash/shared/jwt"
+	"flash/shared/testdb"
+	"testing"
+	"time"
+)
+
+var testDevice = SessionClient{UserAgent: "Firefox", IPAddress: "10.0.0.1"}
+
+// newSessionService returns a service with user 1 on an empty session table.
+func newSessionService(t *testing.T) Service {
+	t.Helper()
+
+	if err := jwt.Configure(&jwt.KeySet{
+		ActiveID: "test",
+		Keys: map[string]*jwt.SigningKey{
+			"test": {ID: "test", Algorithm: jwt.AlgorithmHS256, Secret: []byte("session-test-secret")},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	db := testdb.Open(t, &models.User{}, &models.UserIdentity{}, &models.UserSession{})
+	empty := ""
+	testdb.Create(t, db,
+		&models.User{ID: 1, FirstName: "Ada", Email: "ada@example.com", Password: &empty},
+		&models.User{ID: 2, FirstName: "Bob", Email: "bob@example.com", Password: &empty},
+	)
+
+	return Service{DB: db}
+}
+
+func sessionOf(t *testing.T, refreshToken string) uint64 {
+	t.Helper()
+
+	token, err := jwt.ValidateToken(refreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jwt.SessionID(token)
+}
+
+func loadSession(t *testing.T, service Service, id uint64) models.UserSession {
+	t.Helper()
+
+	var session models.UserSession
+	if err := service.DB.First(&session, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+func startTestSession(t *testing.T, service Service) (*LoginResponse, uint64) {
+	t.Helper()
+
+	user := models.User{ID: 1, Email: "ada@example.com"}
+	tokens, err := service.startSession(&user, testDevice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens, sessionOf(t, tokens.RefreshToken)
+}
+
+func TestRefreshRotatesTheRefreshToken(t *testing.T) {
+	service := newSessionService(t)
+	first, sessionID := startTestSession(t, service)
+
+	second, err := service.Refresh(1, sessionID, first.RefreshToken, SessionClient{UserAgent: "Safari", IPAddress: "10.0.0.2"})
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || sessionOf(t, second.RefreshToken) != sessionID {
+		t.Fatal("Refresh() did not issue a new refresh token for the same session")
+	}
+
+	session := loadSession(t, service, sessionID)
+	if session.TokenHash != jwt.HashSessionToken(second.RefreshToken) ||
+		session.PreviousTokenHash == nil || *session.PreviousTokenHash != jwt.HashSessionToken(first.RefreshToken) {
+		t.Fatal("session does not hold the new token with the old one as previous")
+	}
+	if session.RotatedAt == nil || session.UserAgent == nil || *session.UserAgent != "Safari" {
+		t.Fatalf("session = %+v, want it rotated and moved to the new device", session)
+	}
+
+	// The rotated token keeps working.
+	if _, err := service.Refresh(1, sessionID, second.RefreshToken, testDevice); err != nil {
+		t.Fatalf("Refresh() with the rotated token: %v", err)
+	}
+}
+
+func TestRefreshWithinTheRaceWindow(t *testing.T) {
+	service := newSessionService(t)
+	first, sessionID := startTestSession(t, service)
+
+	second, err := service.Refresh(1, sessionID, first.RefreshToken, testDevice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second tab refreshing with the same token right away is turned down without ending the session.
+	if _, err := service.Refresh(1, sessionID, first.RefreshToken, testDevice); !errors.Is(err, ErrRefreshTokenRotated) {
+		t.Fatalf("Refresh() within the race window: error = %v, want %v", err, ErrRefreshTokenRotated)
+	}
+	if session := loadSession(t, service, sessionID); session.RevokedAt != nil {
+		t.Fatal("the race revoked the session")
+	}
+	if _, err := service.Refresh(1, sessionID, second.RefreshToken, testDevice); err != nil {
+		t.Fatalf("Refresh() with the current token after the race: %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesTheSession(t *testing.T) {
+	service := newSessionService(t)
+	first, sessionID := startTestSession(t, service)
+
+	second, err := service.Refresh(1, sessionID, first.RefreshToken, testDevice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DB.Model(&models.UserSession{}).Where("id = ?", sessionID).
+		Update("rotated_at", time.Now().Add(-refreshRaceWindow-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.Refresh(1, sessionID, first.RefreshToken, testDevice); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Refresh() with a replayed token: error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+
+	session := loadSession(t, service, sessionID)
+	if session.RevokedAt == nil || session.RevokedReason == nil || *session.RevokedReason != RevokedReasonReuse {
+		t.Fatalf("session = %+v, want it revoked for reuse", session)
+	}
+
+	// Whoever holds the current token is signed out too.
+	if _, err := service.Refresh(1, sessionID, second.RefreshToken, testDevice); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("Refresh() after reuse: error = %v, want %v", err, ErrSessionRevoked)
+	}
+}
+
+func TestRefreshWithAnUnknownTokenRevokesTheSession(t *testing.T) {
+	service := newSessionService(t)
+	_, sessionID := startTestSession(t, service)
+	forged, _ := startTestSession(t, service)
+
+	// Only the token the session last rotated away from gets the race allowance.
+	if _, err := service.Refresh(1, sessionID, forged.RefreshToken, testDevice); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Refresh() with another session's token: error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if session := loadSession(t, service, sessionID); session.RevokedAt == nil {
+		t.Fatal("session is still active")
+	}
+}
+
+func TestRefreshRejectsEndedSessions(t *testing.T) {
+	service := newSessionService(t)
+	tokens, sessionID := startTestSession(t, service)
+
+	if _, err := service.Refresh(2, sessionID, tokens.RefreshToken, testDevice); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("Refresh() of another user's session: error = %v, want %v", err, ErrSessionNotFound)
+	}
+
+	if err := service.DB.Model(&models.UserSession{}).Where("id = ?", sessionID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Refresh(1, sessionID, tokens.RefreshToken, testDevice); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("Refresh() of an expired session: error = %v, want %v", err, ErrSessionRevoked)
+	}
+}
+
+func TestRefreshMigratesALegacyToken(t *testing.T) {
+	service := newSessionService(t)
+
+	legacy, err := jwt.GenerateToken(models.User{ID: 1, Email: "ada@example.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed, err := jwt.HashToken(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DB.Model(&models.User{}).Where("id = ?", 1).Update("refresh_token", hashed).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.Refresh(1, 0, legacy+"x", testDevice); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() with the wrong legacy token: error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+
+	tokens, err := service.Refresh(1, 0, legacy, testDevice)
+	if err != nil {
+		t.Fatalf("Refresh() with the legacy token: %v", err)
+	}
+	if sessionOf(t, tokens.RefreshToken) == 0 {
+		t.Fatal("the legacy token was not moved onto a session")
+	}
+
+	var user models.User
+	if err := service.DB.First(&user, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.RefreshToken != nil {
+		t.Fatal("users.refresh_token was kept after the migration")
+	}
+
+	// The legacy token is single use.
+	if _, err := service.Refresh(1, 0, legacy, testDevice); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() with the migrated legacy token: error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
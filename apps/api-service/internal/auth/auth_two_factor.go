@@ -0,0 +1,406 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flash/models"
+	"flash/shared/totp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// twoFactorChallengeLifeSpan is how long the user has to enter a code after their password was accepted.
+	twoFactorChallengeLifeSpan = 5 * time.Minute
+	// maxTwoFactorAttempts caps guesses per challenge; a 6 digit code must not be brute forced in one login.
+	maxTwoFactorAttempts = 5
+
+	recoveryCodeCount    = 10
+	recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorSetupNotStarted  = errors.New("start two-factor setup before confirming it")
+	ErrTwoFactorPasswordRequired = errors.New("set a password before enabling two-factor authentication")
+	ErrInvalidTwoFactorCode      = errors.New("invalid authentication code")
+	ErrTwoFactorChallengeInvalid = errors.New("sign-in attempt is invalid or has expired, please log in again")
+	ErrTooManyTwoFactorAttempts  = errors.New("too many invalid codes, please log in again")
+)
+
+// TwoFactorChallenge is what a password login returns instead of tokens when the account has 2FA on.
+type TwoFactorChallenge struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+	QRCode string `json:"qr_code"`
+}
+
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Pending                bool       `json:"pending"`
+	ConfirmedAt            *time.Time `json:"confirmed_at"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+func (service Service) TwoFactorStatus(userID uint64) (*TwoFactorStatus, error) {
+	var user models.User
+	if err := service.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	status := &TwoFactorStatus{
+		Enabled:     user.TwoFactorConfirmedAt != nil,
+		Pending:     user.TwoFactorConfirmedAt == nil && user.TwoFactorSecret != nil,
+		ConfirmedAt: user.TwoFactorConfirmedAt,
+	}
+	if status.Enabled {
+		if err := service.DB.Model(&models.UserRecoveryCode{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Count(&status.RecoveryCodesRemaining).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+// BeginTwoFactorSetup stores a new, unconfirmed secret and returns it with its QR code. 2FA stays off until
+// ConfirmTwoFactor proves the authenticator app has it; starting again replaces the pending secret.
+func (service Service) BeginTwoFactorSetup(userID uint64, password string) (*TwoFactorSetup, error) {
+	var user models.User
+	if err := service.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	if user.TwoFactorConfirmedAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	// Accounts created through GitHub, Google or OIDC carry an empty password.
+	if user.Password == nil || *user.Password == "" {
+		return nil, ErrTwoFactorPasswordRequired
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(password)); err != nil {
+		return nil, ErrInvalidPassword
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	uri := totp.URI(twoFactorIssuer(), user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.DB.Model(&user).Updates(map[string]any{
+		"two_factor_secret":    secret,
+		"two_factor_last_step": nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{
+		Secret: secret,
+		URI:    uri,
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// ConfirmTwoFactor turns 2FA on once the user enters a code from the pending secret, and returns the recovery
+// codes. They are only shown this once.
+func (service Service) ConfirmTwoFactor(userID uint64, code string) ([]string, error) {
+	var codes []string
+
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		if user.TwoFactorConfirmedAt != nil {
+			return ErrTwoFactorAlreadyEnabled
+		}
+		if user.TwoFactorSecret == nil {
+			return ErrTwoFactorSetupNotStarted
+		}
+
+		step, ok := totp.Validate(*user.TwoFactorSecret, code, time.Now())
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+
+		if err := tx.Model(&user).Updates(map[string]any{
+			"two_factor_confirmed_at": time.Now(),
+			"two_factor_last_step":    step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes invalidates every existing recovery code and issues a fresh set.
+func (service Service) RegenerateRecoveryCodes(userID uint64, code string) ([]string, error) {
+	var codes []string
+
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := lockTwoFactorUser(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if ok, err := verifyTOTP(tx, user, code); err != nil {
+			return err
+		} else if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor needs both the password and a second factor, so a stolen session alone can't turn 2FA off.
+func (service Service) DisableTwoFactor(userID uint64, password string, code string, recoveryCode string) error {
+	return service.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := lockTwoFactorUser(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if user.Password == nil || *user.Password == "" || bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(password)) != nil {
+			return ErrInvalidPassword
+		}
+
+		if ok, err := verifySecondFactor(tx, user, code, recoveryCode); err != nil {
+			return err
+		} else if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.TwoFactorChallenge{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(user).Updates(map[string]any{
+			"two_factor_secret":       nil,
+			"two_factor_confirmed_at": nil,
+			"two_factor_last_step":    nil,
+		}).Error
+	})
+}
+
+// CompleteTwoFactorLogin finishes a password login with a TOTP or recovery code. Wrong codes count against the
+// challenge, which is dropped after maxTwoFactorAttempts; a correct one consumes it and opens the session.
+func (service Service) CompleteTwoFactorLogin(token string, code string, recoveryCode string, device SessionClient) (*LoginResponse, error) {
+	var user *models.User
+	var verified, exhausted bool
+
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		var challenge models.TwoFactorChallenge
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND expires_at > ?", hashUserToken(token), time.Now()).
+			First(&challenge).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTwoFactorChallengeInvalid
+			}
+			return err
+		}
+
+		var err error
+		user, err = lockTwoFactorUser(tx, challenge.UserID)
+		if errors.Is(err, ErrTwoFactorNotEnabled) || errors.Is(err, gorm.ErrRecordNotFound) {
+			// 2FA was turned off or the account removed since the password step.
+			return ErrTwoFactorChallengeInvalid
+		} else if err != nil {
+			return err
+		}
+
+		verified, err = verifySecondFactor(tx, user, code, recoveryCode)
+		if err != nil {
+			return err
+		}
+
+		exhausted = !verified && challenge.Attempts+1 >= maxTwoFactorAttempts
+		if verified || exhausted {
+			return tx.Delete(&challenge).Error
+		}
+
+		// A failed attempt is committed, not rolled back, so it still counts.
+		return tx.Model(&challenge).Update("attempts", gorm.Expr("attempts + 1")).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if exhausted {
+		return nil, ErrTooManyTwoFactorAttempts
+	}
+	if !verified {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	if err := service.DB.Preload("Identities").First(user, user.ID).Error; err != nil {
+		return nil, err
+	}
+
+	return service.startSession(user, device)
+}
+
+// beginTwoFactorChallenge is called by Login after the password checks out.
+func (service Service) beginTwoFactorChallenge(user *models.User) (*LoginResponse, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	// Abandoned challenges are cleared here rather than by a scheduled task, the same as OIDC states.
+	if err := service.DB.Where("expires_at < ?", now).Delete(&models.TwoFactorChallenge{}).Error; err != nil {
+		return nil, err
+	}
+
+	challenge := models.TwoFactorChallenge{
+		UserID:    user.ID,
+		TokenHash: hashUserToken(token),
+		ExpiresAt: now.Add(twoFactorChallengeLifeSpan),
+	}
+	if err := service.DB.Create(&challenge).Error; err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		TwoFactor: &TwoFactorChallenge{Token: token, ExpiresAt: challenge.ExpiresAt},
+	}, nil
+}
+
+func lockTwoFactorUser(tx *gorm.DB, userID uint64) (*models.User, error) {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	if user.TwoFactorConfirmedAt == nil || user.TwoFactorSecret == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	return &user, nil
+}
+
+func verifySecondFactor(tx *gorm.DB, user *models.User, code string, recoveryCode string) (bool, error) {
+	if strings.TrimSpace(recoveryCode) != "" {
+		return useRecoveryCode(tx, user.ID, recoveryCode)
+	}
+
+	return verifyTOTP(tx, user, code)
+}
+
+// verifyTOTP accepts a code only from a time step later than the last one used, so each code works once.
+func verifyTOTP(tx *gorm.DB, user *models.User, code string) (bool, error) {
+	step, ok := totp.Validate(*user.TwoFactorSecret, code, time.Now())
+	if !ok || (user.TwoFactorLastStep != nil && step <= *user.TwoFactorLastStep) {
+		return false, nil
+	}
+
+	if err := tx.Model(user).Update("two_factor_last_step", step).Error; err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func useRecoveryCode(tx *gorm.DB, userID uint64, recoveryCode string) (bool, error) {
+	result := tx.Model(&models.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(recoveryCode)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint64) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.UserRecoveryCode, 0, recoveryCodeCount)
+	for len(codes) < recoveryCodeCount {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		rows = append(rows, models.UserRecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	}
+
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// generateRecoveryCode returns a code like "K7QPM-3XWTA". Easily confused characters (0/O, 1/I) are left out.
+func generateRecoveryCode() (string, error) {
+	buffer := make([]byte, 10)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	for index, value := range buffer {
+		if index == 5 {
+			builder.WriteByte('-')
+		}
+		builder.WriteByte(recoveryCodeAlphabet[int(value)%len(recoveryCodeAlphabet)])
+	}
+
+	return builder.String(), nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, which people tend to get wrong when typing codes in.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	return hashUserToken(normalized)
+}
+
+func twoFactorIssuer() string {
+	if issuer := os.Getenv("TWO_FACTOR_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "Kislap"
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"errors"
+	"flash/models"
+	"flash/shared/testdb"
+	"flash/shared/totp"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBeginTwoFactorSetupRequiresAPassword(t *testing.T) {
+	db := testdb.Open(t, &models.User{})
+	service := Service{DB: db}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty, hashed := "", string(hash)
+	testdb.Create(t, db,
+		&models.User{ID: 1, FirstName: "OAuth", Email: "oauth@example.com", Password: &empty},
+		&models.User{ID: 2, FirstName: "Local", Email: "local@example.com", Password: &hashed},
+	)
+
+	tests := []struct {
+		name     string
+		userID   uint64
+		password string
+		wantErr  error
+	}{
+		{name: "oauth-only account", userID: 1, password: "", wantErr: ErrTwoFactorPasswordRequired},
+		{name: "wrong password", userID: 2, password: "battery staple", wantErr: ErrInvalidPassword},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := service.BeginTwoFactorSetup(test.userID, test.password); !errors.Is(err, test.wantErr) {
+				t.Fatalf("BeginTwoFactorSetup() error = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestDisableTwoFactorRejectsAnEmptyPassword(t *testing.T) {
+	db := testdb.Open(t, &models.User{}, &models.UserRecoveryCode{}, &models.TwoFactorChallenge{})
+	service := Service{DB: db}
+
+	empty, secret, confirmedAt := "", "JBSWY3DPEHPK3PXP", time.Now()
+	testdb.Create(t, db, &models.User{
+		ID:                   1,
+		FirstName:            "OAuth",
+		Email:                "oauth@example.com",
+		Password:             &empty,
+		TwoFactorSecret:      &secret,
+		TwoFactorConfirmedAt: &confirmedAt,
+	})
+
+	if err := service.DisableTwoFactor(1, "", "000000", ""); !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("DisableTwoFactor() error = %v, want ErrInvalidPassword", err)
+	}
+}
+
+// newTwoFactorService returns a service where user 1 has 2FA on with the returned secret and recovery codes.
+func newTwoFactorService(t *testing.T) (Service, string, []string) {
+	t.Helper()
+
+	service := newSessionService(t)
+	if err := service.DB.AutoMigrate(&models.UserRecoveryCode{}, &models.TwoFactorChallenge{}); err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DB.Model(&models.User{}).Where("id = ?", 1).Updates(map[string]any{
+		"two_factor_secret":       secret,
+		"two_factor_confirmed_at": time.Now(),
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	codes, err := replaceRecoveryCodes(service.DB, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return service, secret, codes
+}
+
+// passwordStep stands in for Login after the password was accepted.
+func passwordStep(t *testing.T, service Service) string {
+	t.Helper()
+
+	response, err := service.beginTwoFactorChallenge(&models.User{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.TwoFactor.Token
+}
+
+func codeAt(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+
+	code, err := totp.Code(secret, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestCompleteTwoFactorLoginCapsAttempts(t *testing.T) {
+	service, secret, _ := newTwoFactorService(t)
+	challenge := passwordStep(t, service)
+	wrong := "000000"
+	if wrong == codeAt(t, secret, time.Now()) {
+		wrong = "111111"
+	}
+
+	for attempt := 1; attempt < maxTwoFactorAttempts; attempt++ {
+		if _, err := service.CompleteTwoFactorLogin(challenge, wrong, "", testDevice); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("attempt %d: error = %v, want %v", attempt, err, ErrInvalidTwoFactorCode)
+		}
+	}
+	if _, err := service.CompleteTwoFactorLogin(challenge, wrong, "", testDevice); !errors.Is(err, ErrTooManyTwoFactorAttempts) {
+		t.Fatalf("attempt %d: error = %v, want %v", maxTwoFactorAttempts, err, ErrTooManyTwoFactorAttempts)
+	}
+
+	// The challenge is gone, so even the right code needs a fresh password login.
+	if _, err := service.CompleteTwoFactorLogin(challenge, codeAt(t, secret, time.Now()), "", testDevice); !errors.Is(err, ErrTwoFactorChallengeInvalid) {
+		t.Fatalf("right code after the cap: error = %v, want %v", err, ErrTwoFactorChallengeInvalid)
+	}
+}
+
+func TestCompleteTwoFactorLoginRejectsAReplayedCode(t *testing.T) {
+	service, secret, _ := newTwoFactorService(t)
+	now := time.Now()
+	code := codeAt(t, secret, now)
+
+	tokens, err := service.CompleteTwoFactorLogin(passwordStep(t, service), code, "", testDevice)
+	if err != nil {
+		t.Fatalf("CompleteTwoFactorLogin() error = %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("CompleteTwoFactorLogin() = %+v, want a session", tokens)
+	}
+
+	if _, err := service.CompleteTwoFactorLogin(passwordStep(t, service), code, "", testDevice); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("same code again: error = %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+	// A code from an earlier step is still inside the skew, but older than the one already used.
+	if _, err := service.CompleteTwoFactorLogin(passwordStep(t, service), codeAt(t, secret, now.Add(-totp.Period)), "", testDevice); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("earlier code: error = %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+}
+
+func TestCompleteTwoFactorLoginWithARecoveryCode(t *testing.T) {
+	service, _, codes := newTwoFactorService(t)
+
+	// Case, spaces and dashes don't matter.
+	typed := strings.ToLower(strings.ReplaceAll(codes[0], "-", " "))
+	if _, err := service.CompleteTwoFactorLogin(passwordStep(t, service), "", typed, testDevice); err != nil {
+		t.Fatalf("CompleteTwoFactorLogin() with a recovery code: %v", err)
+	}
+	if _, err := service.CompleteTwoFactorLogin(passwordStep(t, service), "", codes[0], testDevice); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("used recovery code: error = %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	status, err := service.TwoFactorStatus(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.RecoveryCodesRemaining != recoveryCodeCount-1 {
+		t.Fatalf("recovery codes remaining = %d, want %d", status.RecoveryCodesRemaining, recoveryCodeCount-1)
+	}
+}
+
+func TestCompleteTwoFactorLoginRejectsStaleChallenges(t *testing.T) {
+	service, secret, _ := newTwoFactorService(t)
+
+	expired := passwordStep(t, service)
+	if err := service.DB.Model(&models.TwoFactorChallenge{}).Where("token_hash = ?", hashUserToken(expired)).
+		Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.CompleteTwoFactorLogin(expired, codeAt(t, secret, time.Now()), "", testDevice); !errors.Is(err, ErrTwoFactorChallengeInvalid) {
+		t.Fatalf("expired challenge: error = %v, want %v", err, ErrTwoFactorChallengeInvalid)
+	}
+
+	// 2FA was turned off between the password and the code.
+	pending := passwordStep(t, service)
+	if err := service.DB.Model(&models.User{}).Where("id = ?", 1).Update("two_factor_confirmed_at", nil).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.CompleteTwoFactorLogin(pending, codeAt(t, secret, time.Now()), "", testDevice); !errors.Is(err, ErrTwoFactorChallengeInvalid) {
+		t.Fatalf("2FA disabled meanwhile: error = %v, want %v", err, ErrTwoFactorChallengeInvalid)
+	}
+}
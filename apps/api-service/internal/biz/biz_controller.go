@@ -5,6 +5,7 @@ import (
 	"flash/internal/project"
 	"flash/models"
 	objectStorage "flash/sdk/object_storage"
+	"flash/shared/access"
 	"flash/utils"
 	"fmt"
 	"net/http"
@@ -91,9 +92,11 @@ func (controller Controller) Save(context *gin.Context) {
 		}
 	}
 
-	biz, err := controller.Service.Save(request.ToServicePayload())
+	payload := request.ToServicePayload()
+
+	biz, err := controller.Service.Save(utils.RequestActor(context), payload)
 	if err != nil {
-		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		utils.APIRespondError(context, access.HTTPStatus(err, http.StatusBadRequest), err.Error())
 		context.Abort()
 		return
 	}
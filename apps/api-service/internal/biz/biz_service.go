@@ -5,6 +5,7 @@ import (
 	"flash/internal/project"
 	"flash/models"
 	objectStorage "flash/sdk/object_storage"
+	"flash/shared/access"
 	"fmt"
 	"mime/multipart"
 	"time"
@@ -18,7 +19,14 @@ type Service struct {
 	ObjectStorage  objectStorage.Provider
 }
 
-func (service *Service) Save(payload Payload) (*models.Biz, error) {
+func (service *Service) Save(actor access.Actor, payload Payload) (*models.Biz, error) {
+	proj, err := actor.Authorize(service.DB, uint64(payload.ProjectID), access.PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	// Rows belong to the project owner, whichever collaborator saves them.
+	payload.UserID = int64(proj.UserID)
+
 	var themeRaw *json.RawMessage
 	var themeName *string
 
@@ -32,6 +40,7 @@ func (service *Service) Save(payload Payload) (*models.Biz, error) {
 	}
 
 	var biz models.Biz
+	var released []string
 	isNew := payload.BizID == nil
 
 	if isNew {
@@ -47,7 +56,7 @@ func (service *Service) Save(payload Payload) (*models.Biz, error) {
 			LayoutName:      &payload.LayoutName,
 		}
 	} else {
-		if err := service.DB.First(&biz, payload.BizID).Error; err != nil {
+		if err := service.DB.Where("project_id = ?", payload.ProjectID).First(&biz, payload.BizID).Error; err != nil {
 			return nil, err
 		}
 	}
@@ -77,19 +86,19 @@ func (service *Service) Save(payload Payload) (*models.Biz, error) {
 	// Logo
 	if payload.Logo != nil {
 		if biz.LogoURL != nil {
-			service.deleteImageInBackground(*biz.LogoURL)
+			released = append(released, *biz.LogoURL)
 		}
 		url, _ := service.uploadImage(payload.Logo, int64(biz.ProjectID), "branding")
 		biz.LogoURL = &url
 	} else if payload.LogoURL == nil && biz.LogoURL != nil {
-		service.deleteImageInBackground(*biz.LogoURL)
+		released = append(released, *biz.LogoURL)
 		biz.LogoURL = nil
 	}
 
 	// Hero Image
 	if payload.HeroImage != nil {
 		if biz.HeroImageURL != nil {
-			service.deleteImageInBackground(*biz.HeroImageURL)
+			released = append(released, *biz.HeroImageURL)
 		}
 		url, _ := service.uploadImage(payload.HeroImage, int64(biz.ProjectID), "hero")
 		biz.HeroImageURL = &url
@@ -98,7 +107,7 @@ func (service *Service) Save(payload Payload) (*models.Biz, error) {
 	// About Image
 	if payload.AboutImage != nil {
 		if biz.AboutImageURL != nil {
-			service.deleteImageInBackground(*biz.AboutImageURL)
+			released = append(released, *biz.AboutImageURL)
 		}
 		url, _ := service.uploadImage(payload.AboutImage, int64(biz.ProjectID), "about")
 		biz.AboutImageURL = &url
@@ -109,17 +118,20 @@ func (service *Service) Save(payload Payload) (*models.Biz, error) {
 	biz.HeroDescription = payload.HeroDescription
 
 	// --- 3. Save Biz & Sync Collections ---
-	err := service.DB.Transaction(func(tx *gorm.DB) error {
+	err = project.SaveWithRevision(service.DB, biz.ProjectID, &actor.UserID, func(tx *gorm.DB) error {
 		if err := tx.Save(&biz).Error; err != nil {
 			return err
 		}
-		return service.runAllSyncs(tx, &biz, payload)
+		return service.runAllSyncs(tx, &biz, payload, &released)
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
+	// Replaced images can still back a revision, so they are released rather than deleted outright.
+	project.ReleaseAssets(service.DB, service.ObjectStorage, biz.ProjectID, released...)
+
 	service.DB.Preload("Services").
 		Preload("Products").
 		Preload("Testimonials").
@@ -131,14 +143,14 @@ func (service *Service) Save(payload Payload) (*models.Biz, error) {
 	return &biz, nil
 }
 
-func (service *Service) runAllSyncs(db *gorm.DB, biz *models.Biz, payload Payload) error {
-	if err := service.syncServices(db, biz.ID, int64(biz.ProjectID), payload.Services); err != nil {
+func (service *Service) runAllSyncs(db *gorm.DB, biz *models.Biz, payload Payload, released *[]string) error {
+	if err := service.syncServices(db, biz.ID, int64(biz.ProjectID), payload.Services, released); err != nil {
 		return err
 	}
-	if err := service.syncProducts(db, biz.ID, int64(biz.ProjectID), payload.Products); err != nil {
+	if err := service.syncProducts(db, biz.ID, int64(biz.ProjectID), payload.Products, released); err != nil {
 		return err
 	}
-	if err := service.syncTestimonials(db, biz.ID, int64(biz.ProjectID), payload.Testimonials); err != nil {
+	if err := service.syncTestimonials(db, biz.ID, int64(biz.ProjectID), payload.Testimonials, released); err != nil {
 		return err
 	}
 	if err := service.syncSocialLinks(db, biz.ID, payload.SocialLinks); err != nil {
@@ -147,14 +159,14 @@ func (service *Service) runAllSyncs(db *gorm.DB, biz *models.Biz, payload Payloa
 	if err := service.syncFAQs(db, biz.ID, payload.FAQs); err != nil {
 		return err
 	}
-	if err := service.syncGalleryImages(db, biz.ID, int64(biz.ProjectID), payload.GalleryImages); err != nil {
+	if err := service.syncGalleryImages(db, biz.ID, int64(biz.ProjectID), payload.GalleryImages, released); err != nil {
 		return err
 	}
 	return nil
 }
 
 // Updated Sync Products to include Category
-func (service *Service) syncProducts(db *gorm.DB, bizID uint64, projectID int64, requests []ProductRequest) error {
+func (service *Service) syncProducts(db *gorm.DB, bizID uint64, projectID int64, requests []ProductRequest, released *[]string) error {
 	var existing []models.Product
 	db.Where("biz_id = ?", bizID).Find(&existing)
 
@@ -183,7 +195,7 @@ func (service *Service) syncProducts(db *gorm.DB, bizID uint64, projectID int64,
 			url, _ := service.uploadImage(request.Image, projectID, "products")
 			newImgURL = &url
 			if oldImage != "" {
-				service.deleteImageInBackground(oldImage)
+				*released = append(*released, oldImage)
 			}
 		}
 
@@ -204,7 +216,7 @@ func (service *Service) syncProducts(db *gorm.DB, bizID uint64, projectID int64,
 		if !processedIDs[id] {
 			db.Delete(item)
 			if item.ImageURL != nil {
-				service.deleteImageInBackground(*item.ImageURL)
+				*released = append(*released, *item.ImageURL)
 			}
 		}
 	}
@@ -227,7 +239,7 @@ func (service *Service) syncFAQs(db *gorm.DB, bizID uint64, requests []FAQReques
 }
 
 // New Sync Gallery
-func (service *Service) syncGalleryImages(db *gorm.DB, bizID uint64, projectID int64, requests []GalleryImageRequest) error {
+func (service *Service) syncGalleryImages(db *gorm.DB, bizID uint64, projectID int64, requests []GalleryImageRequest, released *[]string) error {
 	var existing []models.BizGallery
 	db.Where("biz_id = ?", bizID).Find(&existing)
 
@@ -256,7 +268,7 @@ func (service *Service) syncGalleryImages(db *gorm.DB, bizID uint64, projectID i
 			url, _ := service.uploadImage(request.Image, projectID, "gallery")
 			newImgURL = &url
 			if oldImage != "" {
-				service.deleteImageInBackground(oldImage)
+				*released = append(*released, oldImage)
 			}
 		} else if request.ImageURL != nil {
 			newImgURL = request.ImageURL
@@ -272,7 +284,7 @@ func (service *Service) syncGalleryImages(db *gorm.DB, bizID uint64, projectID i
 		if !processedIDs[id] {
 			db.Delete(item)
 			if item.ImageURL != nil {
-				service.deleteImageInBackground(*item.ImageURL)
+				*released = append(*released, *item.ImageURL)
 			}
 		}
 	}
@@ -281,7 +293,7 @@ func (service *Service) syncGalleryImages(db *gorm.DB, bizID uint64, projectID i
 
 // ... syncServices, syncTestimonials, syncSocialLinks (Keep as is or adjust categories if needed)
 
-func (service *Service) syncServices(db *gorm.DB, bizID uint64, projectID int64, requests []ServiceRequest) error {
+func (service *Service) syncServices(db *gorm.DB, bizID uint64, projectID int64, requests []ServiceRequest, released *[]string) error {
 	var existing []models.Service
 	db.Where("biz_id = ?", bizID).Find(&existing)
 	existingMap := make(map[uint64]*models.Service)
@@ -306,7 +318,7 @@ func (service *Service) syncServices(db *gorm.DB, bizID uint64, projectID int64,
 			url, _ := service.uploadImage(request.Image, projectID, "services")
 			newImgURL = &url
 			if oldImage != "" {
-				service.deleteImageInBackground(oldImage)
+				*released = append(*released, oldImage)
 			}
 		}
 		model.BizID = bizID
@@ -323,14 +335,14 @@ func (service *Service) syncServices(db *gorm.DB, bizID uint64, projectID int64,
 		if !processedIDs[id] {
 			db.Delete(item)
 			if item.ImageURL != nil {
-				service.deleteImageInBackground(*item.ImageURL)
+				*released = append(*released, *item.ImageURL)
 			}
 		}
 	}
 	return nil
 }
 
-func (service *Service) syncTestimonials(db *gorm.DB, bizID uint64, projectID int64, requests []TestimonialRequest) error {
+func (service *Service) syncTestimonials(db *gorm.DB, bizID uint64, projectID int64, requests []TestimonialRequest, released *[]string) error {
 	var existing []models.Testimonial
 	db.Where("biz_id = ?", bizID).Find(&existing)
 	existingMap := make(map[uint64]*models.Testimonial)
@@ -355,7 +367,7 @@ func (service *Service) syncTestimonials(db *gorm.DB, bizID uint64, projectID in
 			url, _ := service.uploadImage(request.Avatar, projectID, "testimonials")
 			newAvatarURL = &url
 			if oldAvatar != "" {
-				service.deleteImageInBackground(oldAvatar)
+				*released = append(*released, oldAvatar)
 			}
 		}
 		model.BizID = bizID
@@ -370,7 +382,7 @@ func (service *Service) syncTestimonials(db *gorm.DB, bizID uint64, projectID in
 		if !processedIDs[id] {
 			db.Delete(item)
 			if item.AvatarURL != nil {
-				service.deleteImageInBackground(*item.AvatarURL)
+				*released = append(*released, *item.AvatarURL)
 			}
 		}
 	}
@@ -428,18 +440,6 @@ func (service *Service) uploadImage(file *multipart.FileHeader, projectID int64,
 	return url, nil
 }
 
-func (service *Service) deleteImageInBackground(path string) {
-	if path == "" {
-		return
-	}
-	go func(url string) {
-		_, err := service.ObjectStorage.Delete(url)
-		if err != nil {
-			fmt.Printf("Failed to delete background image %s: %v\n", url, err)
-		}
-	}(path)
-}
-
 func marshalTheme(theme ThemeRequest) (*json.RawMessage, error) {
 	themeJSON, err := json.Marshal(theme)
 	if err != nil {
@@ -1,6 +1,7 @@
 package document
 
 import (
+	"flash/internal/job"
 	"flash/sdk/llm"
 	objectStorage "flash/sdk/object_storage"
 	"flash/utils"
@@ -12,7 +13,8 @@ import (
 )
 
 type Controller struct {
-	Service *Service
+	Service    *Service
+	JobService *job.Service
 }
 
 func NewController(db *gorm.DB, llm llm.Provider, objectStorage objectStorage.Provider) *Controller {
@@ -21,7 +23,7 @@ func NewController(db *gorm.DB, llm llm.Provider, objectStorage objectStorage.Pr
 		LLM:           llm,
 		ObjectStorage: objectStorage,
 	}
-	return &Controller{Service: service}
+	return &Controller{Service: service, JobService: job.NewService(db)}
 }
 
 func (controller Controller) Parse(context *gin.Context) {
@@ -64,12 +66,28 @@ func (controller Controller) Parse(context *gin.Context) {
 		return
 	}
 
-	data, err := controller.Service.Parse(request.ToServicePayload(theFile, file.Filename))
+	userID := context.GetUint64("user_id")
+	staged, err := controller.Service.StageParseFiles(userID, []*multipart.FileHeader{file})
 	if err != nil {
-		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+
+	queued, err := controller.JobService.Enqueue(job.EnqueuePayload{
+		UserID: &userID,
+		Type:   job.TypeParseDocument,
+		Payload: ParseJobPayload{
+			Type:  request.Type,
+			Files: staged,
+		},
+	})
+	if err != nil {
+		controller.Service.ReleaseParseFiles(staged)
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
 		context.Abort()
 		return
 	}
 
-	utils.APIRespondSuccess(context, http.StatusOK, data)
+	utils.APIRespondSuccess(context, http.StatusAccepted, gin.H{"job": queued})
 }
@@ -16,6 +16,19 @@ type Payload struct {
 	Files []FilePayload
 }
 
+// ParseJobPayload is what a parse_document job carries. The uploaded files wait in object storage so a worker can
+// run it after the request that uploaded them is gone; the job only holds their keys.
+type ParseJobPayload struct {
+	ParsedFileID *uint64        `json:"parsed_file_id,omitempty"`
+	Type         string         `json:"type"`
+	Files        []ParseJobFile `json:"files"`
+}
+
+type ParseJobFile struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
 func (request ParseDocumentRequest) ToServicePayload(file io.ReadSeeker, fileName string) Payload {
 	return Payload{
 		Type: request.Type,
@@ -0,0 +1,81 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"path/filepath"
+	"time"
+)
+
+// StageParseFiles stores uploads for a parse job under the user's uploads/parse/ folder. If one upload fails, the
+// ones already stored are removed again.
+func (service Service) StageParseFiles(userID uint64, files []*multipart.FileHeader) ([]ParseJobFile, error) {
+	staged := make([]ParseJobFile, 0, len(files))
+	for _, file := range files {
+		key, err := service.stageParseFile(userID, file)
+		if err != nil {
+			service.ReleaseParseFiles(staged)
+			return nil, err
+		}
+		staged = append(staged, ParseJobFile{Name: file.Filename, Key: key})
+	}
+
+	return staged, nil
+}
+
+func (service Service) stageParseFile(userID uint64, file *multipart.FileHeader) (string, error) {
+	opened, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer opened.Close()
+
+	key := fmt.Sprintf("uploads/parse/%d/%d_%s", userID, time.Now().UnixNano(), filepath.Base(file.Filename))
+	contentType := file.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	if _, err := service.ObjectStorage.Upload(key, opened, contentType); err != nil {
+		return "", fmt.Errorf("storage upload failed: %w", err)
+	}
+	return key, nil
+}
+
+// OpenParseJob reads a job's staged files back for parsing.
+func (service Service) OpenParseJob(payload ParseJobPayload) (Payload, error) {
+	files := make([]FilePayload, 0, len(payload.Files))
+	for _, file := range payload.Files {
+		stored, err := service.ObjectStorage.Download(file.Key)
+		if err != nil {
+			return Payload{}, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		content, err := io.ReadAll(stored)
+		stored.Close()
+		if err != nil {
+			return Payload{}, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+
+		files = append(files, FilePayload{
+			Name: file.Name,
+			File: bytes.NewReader(content),
+		})
+	}
+
+	return Payload{
+		Type:  payload.Type,
+		Files: files,
+	}, nil
+}
+
+// ReleaseParseFiles removes staged files once their job is done with them, whether it succeeded or gave up.
+func (service Service) ReleaseParseFiles(files []ParseJobFile) {
+	for _, file := range files {
+		if _, err := service.ObjectStorage.Delete(file.Key); err != nil {
+			log.Printf("[WARN] Failed to remove staged upload %s: %v", file.Key, err)
+		}
+	}
+}
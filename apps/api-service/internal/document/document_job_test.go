@@ -0,0 +1,74 @@
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	objectStorage "flash/sdk/object_storage"
+)
+
+// uploadedFiles builds the file headers a multipart request with these files would produce.
+func uploadedFiles(t *testing.T, files map[string]string) []*multipart.FileHeader {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, content := range files {
+		part, err := writer.CreateFormFile("files", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(content))
+	}
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["files"]
+}
+
+func TestParseJobFilesRoundTrip(t *testing.T) {
+	storage := objectStorage.NewInMemoryProvider("https://cdn.test")
+	service := Service{ObjectStorage: storage}
+
+	staged, err := service.StageParseFiles(7, uploadedFiles(t, map[string]string{"menu.pdf": "%PDF-1.4 menu"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(staged) != 1 || !strings.HasPrefix(staged[0].Key, "uploads/parse/7/") {
+		t.Fatalf("StageParseFiles() = %+v", staged)
+	}
+
+	// The job row only ever holds the key.
+	encoded, err := json.Marshal(ParseJobPayload{Type: "menu", Files: staged})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(encoded), "PDF") {
+		t.Fatalf("job payload %s carries the file", encoded)
+	}
+
+	payload, err := service.OpenParseJob(ParseJobPayload{Type: "menu", Files: staged})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(payload.Files[0].File)
+	if payload.Type != "menu" || payload.Files[0].Name != "menu.pdf" || string(content) != "%PDF-1.4 menu" {
+		t.Fatalf("OpenParseJob() = %+v with %q", payload, content)
+	}
+
+	service.ReleaseParseFiles(staged)
+	if _, ok := storage.Get(staged[0].Key); ok {
+		t.Fatal("staged file is still stored after release")
+	}
+	if _, err := service.OpenParseJob(ParseJobPayload{Type: "menu", Files: staged}); err == nil {
+		t.Fatal("OpenParseJob() of released files succeeded")
+	}
+}
@@ -14,7 +14,6 @@ import (
 	"flash/sdk/llm/prompt"
 	objectStorage "flash/sdk/object_storage"
 	pdf "flash/shared/pdf"
-	"flash/utils"
 
 	"gorm.io/gorm"
 )
@@ -101,12 +100,7 @@ func (service Service) parseMenu(files []FilePayload) (*MenuResponse, error) {
 	}
 
 	generatedPrompt := prompt.MenuToJSON(content)
-	aiResp, err := service.LLM.Generate(generatedPrompt, media)
-	if err != nil || aiResp == "" || aiResp == "null" {
-		return nil, err
-	}
-
-	return utils.ParseLLMJSON[MenuResponse](aiResp)
+	return llm.GenerateStructured[MenuResponse](service.LLM, generatedPrompt, media, llm.StructuredOptions{})
 }
 
 func (service Service) prepareMenuInputs(files []FilePayload) (string, *llm.Media, error) {
@@ -156,15 +150,10 @@ func (service Service) parseResume(inputFile FilePayload) (*PortfolioResponse, e
 
 	generatedPrompt := prompt.ResumeToJSON(content)
 	if media != nil {
-		generatedPrompt = prompt.ObjectStorageFileToContent(media.URL)
-	}
-
-	aiResp, err := service.LLM.Generate(generatedPrompt, media)
-	if err != nil || aiResp == "" || aiResp == "null" {
-		return nil, err
+		generatedPrompt = prompt.ResumeImageToJSON()
 	}
 
-	return utils.ParseLLMJSON[PortfolioResponse](aiResp)
+	return llm.GenerateStructured[PortfolioResponse](service.LLM, generatedPrompt, media, llm.StructuredOptions{})
 }
 
 func (service Service) extractResumeContent(inputFile FilePayload) (string, *llm.Media, error) {
@@ -191,12 +180,12 @@ func (service Service) extractResumeContent(inputFile FilePayload) (string, *llm
 	}
 
 	filename := fmt.Sprintf("resumes/%d_fallback.png", time.Now().UnixNano())
-	uploadedURL, err := service.ObjectStorage.Upload(filename, imgReader, "image/png")
+	media, err := service.uploadMedia(filename, imgReader, "image/png")
 	if err != nil {
 		return "", nil, err
 	}
 
-	return "", &llm.Media{URL: uploadedURL}, nil
+	return "", media, nil
 }
 
 func (service Service) createMenuPDFPreviewMedia(inputFile FilePayload) (*llm.Media, error) {
@@ -210,12 +199,7 @@ func (service Service) createMenuPDFPreviewMedia(inputFile FilePayload) (*llm.Me
 	}
 
 	filename := fmt.Sprintf("menus/%d_preview.png", time.Now().UnixNano())
-	uploadedURL, err := service.ObjectStorage.Upload(filename, imgReader, "image/png")
-	if err != nil {
-		return nil, err
-	}
-
-	return &llm.Media{URL: uploadedURL, MimeType: "image/png"}, nil
+	return service.uploadMedia(filename, imgReader, "image/png")
 }
 
 func (service Service) uploadMenuImageMedia(inputFile FilePayload) (*llm.Media, error) {
@@ -239,12 +223,22 @@ func (service Service) uploadMenuImageMedia(inputFile FilePayload) (*llm.Media,
 		extension = mimeExtensionFromContentType(mimeType)
 	}
 	filename := fmt.Sprintf("menus/%d%s", time.Now().UnixNano(), extension)
-	uploadedURL, err := service.ObjectStorage.Upload(filename, inputFile.File, mimeType)
+	return service.uploadMedia(filename, inputFile.File, mimeType)
+}
+
+// uploadMedia stores an attachment for the LLM along with the content digest recorded fixtures are keyed by.
+func (service Service) uploadMedia(filename string, content io.Reader, mimeType string) (*llm.Media, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadedURL, err := service.ObjectStorage.Upload(filename, bytes.NewReader(data), mimeType)
 	if err != nil {
 		return nil, err
 	}
 
-	return &llm.Media{URL: uploadedURL, MimeType: mimeType}, nil
+	return &llm.Media{URL: uploadedURL, MimeType: mimeType, Digest: llm.MediaDigest(data)}, nil
 }
 
 func (service Service) extractPDFText(inputFile FilePayload) (string, error) {
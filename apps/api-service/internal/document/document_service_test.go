@@ -0,0 +1,56 @@
+package document
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"flash/sdk/llm"
+)
+
+// fixtureDir is where LLM_FIXTURE_DIR points in .env.example, relative to this package.
+const fixtureDir = "../../misc/llm-fixtures"
+
+func openSample(t *testing.T, name string) FilePayload {
+	t.Helper()
+
+	file, err := os.Open("../../misc/curl/files/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return FilePayload{Name: name, File: file}
+}
+
+func TestParseResumeFromFixtures(t *testing.T) {
+	service := Service{LLM: &llm.FixtureSDK{Dir: fixtureDir}}
+
+	data, err := service.ParseForType(Payload{Type: "resume", Files: []FilePayload{openSample(t, "sample.pdf")}})
+	if err != nil {
+		t.Fatalf("ParseForType() error = %v", err)
+	}
+
+	resume, ok := data.(*PortfolioResponse)
+	if !ok {
+		t.Fatalf("ParseForType() = %T, want *PortfolioResponse", data)
+	}
+	if resume.JobTitle == nil || *resume.JobTitle != "Full-stack Web Developer" {
+		t.Fatalf("job title = %v", resume.JobTitle)
+	}
+	if len(resume.WorkExperiences) != 3 || resume.WorkExperiences[1].Company != "Vaskeappen" || resume.WorkExperiences[0].EndDate != nil {
+		t.Fatalf("work experiences = %+v", resume.WorkExperiences)
+	}
+	// The recording is fenced and leaves out skill URLs; both are repaired before decoding.
+	if len(resume.Skills) != 5 || resume.Skills[0].Name != "PHP" || resume.Skills[0].URL != nil {
+		t.Fatalf("skills = %+v", resume.Skills)
+	}
+}
+
+func TestParseWithoutARecordingFails(t *testing.T) {
+	service := Service{LLM: &llm.FixtureSDK{Dir: fixtureDir}}
+
+	_, err := service.ParseForType(Payload{Type: "resume", Files: []FilePayload{openSample(t, "sample2.pdf")}})
+	if !errors.Is(err, llm.ErrFixtureNotFound) {
+		t.Fatalf("ParseForType() error = %v, want %v", err, llm.ErrFixtureNotFound)
+	}
+}
@@ -0,0 +1,47 @@
+package file
+
+import (
+	"errors"
+	objectStorage "flash/sdk/object_storage"
+	"flash/utils"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Controller serves objects written by the local filesystem storage provider.
+type Controller struct {
+	Storage *objectStorage.LocalFSProvider
+}
+
+func NewController(storage *objectStorage.LocalFSProvider) *Controller {
+	return &Controller{Storage: storage}
+}
+
+func (controller Controller) Serve(context *gin.Context) {
+	fullPath, err := controller.Storage.Open(
+		context.Param("filepath"),
+		context.Query("expires"),
+		context.Query("signature"),
+	)
+	if err != nil {
+		if errors.Is(err, objectStorage.ErrInvalidSignature) {
+			utils.APIRespondError(context, http.StatusForbidden, err.Error())
+			context.Abort()
+			return
+		}
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil || info.IsDir() {
+		utils.APIRespondError(context, http.StatusNotFound, "File not found")
+		context.Abort()
+		return
+	}
+
+	context.File(fullPath)
+}
@@ -0,0 +1,53 @@
+package file
+
+import (
+	objectStorage "flash/sdk/object_storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestServe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storage := &objectStorage.LocalFSProvider{RootDir: t.TempDir(), BaseURL: "/files", SigningKey: "secret"}
+	if _, err := storage.Upload("projects/1/logo.png", strings.NewReader("image"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	signed, _ := storage.GetSignedURL("projects/1/logo.png", time.Minute)
+	expired, _ := storage.GetSignedURL("projects/1/logo.png", -time.Minute)
+
+	router := gin.New()
+	router.GET("/files/*filepath", NewController(storage).Serve)
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{name: "stored file", url: "/files/projects/1/logo.png", status: http.StatusOK},
+		{name: "signed url", url: signed, status: http.StatusOK},
+		{name: "expired signature", url: expired, status: http.StatusForbidden},
+		{name: "tampered signature", url: "/files/projects/1/logo.png?expires=9999999999&signature=forged", status: http.StatusForbidden},
+		{name: "missing file", url: "/files/projects/1/missing.png", status: http.StatusNotFound},
+		{name: "directory", url: "/files/projects/1", status: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.url, nil))
+
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body.String())
+			}
+			if test.status == http.StatusOK && recorder.Body.String() != "image" {
+				t.Errorf("body = %q, want the stored file", recorder.Body.String())
+			}
+		})
+	}
+}
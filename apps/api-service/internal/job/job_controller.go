@@ -0,0 +1,42 @@
+package job
+
+import (
+	"errors"
+	"flash/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service *Service
+}
+
+func NewController(db *gorm.DB) *Controller {
+	return &Controller{Service: NewService(db)}
+}
+
+func (controller Controller) Show(context *gin.Context) {
+	jobID, err := strconv.ParseUint(context.Param("id"), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "Invalid job ID")
+		context.Abort()
+		return
+	}
+
+	job, err := controller.Service.Show(jobID, context.GetUint64("user_id"))
+	if err != nil {
+		if errors.Is(err, ErrJobNotFound) {
+			utils.APIRespondError(context, http.StatusNotFound, err.Error())
+			context.Abort()
+			return
+		}
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, job)
+}
@@ -0,0 +1,11 @@
+package job
+
+type EnqueuePayload struct {
+	UserID *uint64
+	Type   string
+	// Reference identifies the thing a job works on, e.g. "project:12". A pending job with the same type and
+	// reference is reused instead of queueing a duplicate.
+	Reference   string
+	Payload     any
+	MaxAttempts int
+}
@@ -0,0 +1,198 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"flash/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+)
+
+const (
+	TypeParseDocument = "parse_document"
+	TypeOGImage       = "og_image"
+	TypeDisplayPoster = "display_poster"
+	TypeAccountExport = "account_export"
+)
+
+const defaultMaxAttempts = 3
+
+var ErrJobNotFound = errors.New("job not found")
+
+type Service struct {
+	DB *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+func (service Service) Enqueue(payload EnqueuePayload) (*models.Job, error) {
+	if payload.Reference != "" {
+		var existing models.Job
+		err := service.DB.
+			Where("type = ? AND reference = ? AND status = ?", payload.Type, payload.Reference, StatusPending).
+			Order("id DESC").
+			Take(&existing).Error
+		if err == nil {
+			return &existing, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	encoded, err := json.Marshal(payload.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAttempts := payload.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	job := models.Job{
+		UserID:      payload.UserID,
+		Type:        payload.Type,
+		Status:      StatusPending,
+		Payload:     encoded,
+		MaxAttempts: maxAttempts,
+		AvailableAt: time.Now(),
+	}
+	if payload.Reference != "" {
+		job.Reference = &payload.Reference
+	}
+
+	if err := service.DB.Create(&job).Error; err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (service Service) Show(jobID uint64, userID uint64) (*models.Job, error) {
+	var job models.Job
+	if err := service.DB.Where("id = ? AND user_id = ?", jobID, userID).Take(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// claim reserves the next due job of the given types. Jobs left in processing longer than staleAfter belong to a
+// worker that died mid-run and are picked up again.
+func (service Service) claim(types []string, staleAfter time.Duration) (*models.Job, error) {
+	var job models.Job
+	now := time.Now()
+
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		if err := dueJobs(tx, types, now, staleAfter).Take(&job).Error; err != nil {
+			return err
+		}
+
+		job.Status = StatusProcessing
+		job.Attempts++
+		job.ReservedAt = &now
+
+		return tx.Model(&job).Updates(map[string]any{
+			"status":      job.Status,
+			"attempts":    job.Attempts,
+			"reserved_at": now,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// dueJobs selects the jobs claim may take, oldest first. Rows another worker has locked are skipped rather than
+// waited on, so workers never block each other or take the same job.
+func dueJobs(tx *gorm.DB, types []string, now time.Time, staleAfter time.Duration) *gorm.DB {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("type IN ?", types).
+		Where("(status = ? AND available_at <= ?) OR (status = ? AND reserved_at <= ?)",
+			StatusPending, now, StatusProcessing, now.Add(-staleAfter)).
+		Order("available_at ASC, id ASC")
+}
+
+func (service Service) complete(job *models.Job, result any) error {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	raw := json.RawMessage(encoded)
+	now := time.Now()
+	job.Status = StatusCompleted
+	job.Result = &raw
+	job.Error = nil
+	job.FinishedAt = &now
+
+	return service.DB.Model(job).Updates(map[string]any{
+		"status":      job.Status,
+		"result":      raw,
+		"error":       nil,
+		"finished_at": now,
+	}).Error
+}
+
+// fail either schedules a retry with backoff or, once attempts run out, marks the job failed. It reports whether
+// the failure is final.
+func (service Service) fail(job *models.Job, cause error) (bool, error) {
+	message := cause.Error()
+	job.Error = &message
+
+	if job.Attempts < job.MaxAttempts {
+		job.Status = StatusPending
+		job.AvailableAt = time.Now().Add(backoff(job.Attempts))
+
+		return false, service.DB.Model(job).Updates(map[string]any{
+			"status":       job.Status,
+			"error":        message,
+			"available_at": job.AvailableAt,
+			"reserved_at":  nil,
+		}).Error
+	}
+
+	now := time.Now()
+	job.Status = StatusFailed
+	job.FinishedAt = &now
+
+	return true, service.DB.Model(job).Updates(map[string]any{
+		"status":      job.Status,
+		"error":       message,
+		"finished_at": now,
+	}).Error
+}
+
+// backoff doubles the wait after every attempt: 15s, 30s, 1m, ... capped at 10 minutes.
+func backoff(attempts int) time.Duration {
+	delay := 15 * time.Second
+	for i := 1; i < attempts && delay < 10*time.Minute; i++ {
+		delay *= 2
+	}
+
+	if delay > 10*time.Minute {
+		return 10 * time.Minute
+	}
+
+	return delay
+}
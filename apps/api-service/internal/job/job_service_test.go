@@ -0,0 +1,220 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"flash/models"
+	"flash/shared/testdb"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func newQueue(t *testing.T) *Service {
+	t.Helper()
+	return NewService(testdb.Open(t, &models.Job{}))
+}
+
+func TestEnqueueReusesAPendingJobWithTheSameReference(t *testing.T) {
+	service := newQueue(t)
+
+	first, err := service.Enqueue(EnqueuePayload{Type: TypeOGImage, Reference: "project:1", Payload: map[string]int{"project_id": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Status != StatusPending || first.MaxAttempts != defaultMaxAttempts {
+		t.Fatalf("Enqueue() = %+v", first)
+	}
+
+	again, err := service.Enqueue(EnqueuePayload{Type: TypeOGImage, Reference: "project:1"})
+	if err != nil || again.ID != first.ID {
+		t.Fatalf("second Enqueue() = %+v, %v, want job %d again", again, err, first.ID)
+	}
+
+	other, err := service.Enqueue(EnqueuePayload{Type: TypeOGImage, Reference: "project:2"})
+	if err != nil || other.ID == first.ID {
+		t.Fatalf("Enqueue() for another project = %+v, %v, want a new job", other, err)
+	}
+}
+
+func TestClaim(t *testing.T) {
+	service := newQueue(t)
+	now := time.Now()
+	reservedLongAgo := now.Add(-time.Hour)
+	reservedJustNow := now.Add(-time.Second)
+
+	testdb.Create(t, service.DB,
+		&models.Job{ID: 1, Type: TypeOGImage, Status: StatusPending, MaxAttempts: 3, AvailableAt: now.Add(time.Minute)},
+		&models.Job{ID: 2, Type: TypeDisplayPoster, Status: StatusPending, MaxAttempts: 3, AvailableAt: now.Add(-time.Hour)},
+		&models.Job{ID: 3, Type: TypeOGImage, Status: StatusPending, MaxAttempts: 3, AvailableAt: now.Add(-time.Minute)},
+		&models.Job{ID: 4, Type: TypeOGImage, Status: StatusProcessing, Attempts: 1, MaxAttempts: 3, AvailableAt: now.Add(-2 * time.Hour), ReservedAt: &reservedLongAgo},
+		&models.Job{ID: 5, Type: TypeOGImage, Status: StatusProcessing, Attempts: 1, MaxAttempts: 3, AvailableAt: now.Add(-3 * time.Hour), ReservedAt: &reservedJustNow},
+		&models.Job{ID: 6, Type: TypeOGImage, Status: StatusCompleted, MaxAttempts: 3, AvailableAt: now.Add(-4 * time.Hour)},
+	)
+
+	// The oldest due job goes first: the one a dead worker left behind, then the pending one. Jobs of other types,
+	// jobs not yet due, jobs a live worker holds and finished jobs are never claimed.
+	for _, want := range []struct {
+		id       uint64
+		attempts int
+	}{{id: 4, attempts: 2}, {id: 3, attempts: 1}} {
+		claimed, err := service.claim([]string{TypeOGImage}, 10*time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claimed == nil || claimed.ID != want.id {
+			t.Fatalf("claim() = %+v, want job %d", claimed, want.id)
+		}
+		if claimed.Status != StatusProcessing || claimed.Attempts != want.attempts || claimed.ReservedAt == nil {
+			t.Fatalf("claimed job = %+v, want processing on attempt %d", claimed, want.attempts)
+		}
+	}
+
+	claimed, err := service.claim([]string{TypeOGImage}, 10*time.Minute)
+	if err != nil || claimed != nil {
+		t.Fatalf("claim() with nothing due = %+v, %v, want nil", claimed, err)
+	}
+}
+
+func TestClaimSkipsLockedRows(t *testing.T) {
+	// SQLite has no row locks, so the locking clause is checked in the SQL MySQL would get.
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user:pass@tcp(127.0.0.1:3306)/flash", SkipInitializeWithVersion: true}), &gorm.Config{
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var job models.Job
+		return dueJobs(tx, []string{TypeOGImage}, time.Now(), time.Minute).Take(&job)
+	})
+	if !strings.HasSuffix(sql, "FOR UPDATE SKIP LOCKED") {
+		t.Fatalf("claim query = %s, want it to end in FOR UPDATE SKIP LOCKED", sql)
+	}
+}
+
+func TestFailRetriesWithBackoffUntilAttemptsRunOut(t *testing.T) {
+	service := newQueue(t)
+	testdb.Create(t, service.DB, &models.Job{ID: 1, Type: TypeOGImage, Status: StatusPending, MaxAttempts: 2, AvailableAt: time.Now()})
+
+	claimed, err := service.claim([]string{TypeOGImage}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	final, err := service.fail(claimed, errors.New("browser crashed"))
+	if err != nil || final {
+		t.Fatalf("fail() on attempt 1 = %v, %v, want a retry", final, err)
+	}
+
+	var stored models.Job
+	if err := service.DB.First(&stored, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != StatusPending || stored.ReservedAt != nil || stored.Error == nil || *stored.Error != "browser crashed" {
+		t.Fatalf("job after a failed attempt = %+v", stored)
+	}
+	if wait := time.Until(stored.AvailableAt); wait < 10*time.Second || wait > 15*time.Second {
+		t.Fatalf("retry available in %v, want about 15s", wait)
+	}
+
+	// Not due yet: the backoff holds the job back.
+	if again, err := service.claim([]string{TypeOGImage}, time.Minute); err != nil || again != nil {
+		t.Fatalf("claim() during backoff = %+v, %v, want nil", again, err)
+	}
+
+	if err := service.DB.Model(&stored).Update("available_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	claimed, err = service.claim([]string{TypeOGImage}, time.Minute)
+	if err != nil || claimed == nil || claimed.Attempts != 2 {
+		t.Fatalf("claim() after backoff = %+v, %v, want attempt 2", claimed, err)
+	}
+	final, err = service.fail(claimed, errors.New("browser crashed again"))
+	if err != nil || !final {
+		t.Fatalf("fail() on the last attempt = %v, %v, want final", final, err)
+	}
+
+	if err := service.DB.First(&stored, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != StatusFailed || stored.FinishedAt == nil {
+		t.Fatalf("job after the last attempt = %+v, want failed", stored)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 15 * time.Second},
+		{attempts: 2, want: 30 * time.Second},
+		{attempts: 3, want: time.Minute},
+		{attempts: 6, want: 8 * time.Minute},
+		{attempts: 7, want: 10 * time.Minute},
+		{attempts: 30, want: 10 * time.Minute},
+	}
+
+	for _, test := range tests {
+		if got := backoff(test.attempts); got != test.want {
+			t.Errorf("backoff(%d) = %v, want %v", test.attempts, got, test.want)
+		}
+	}
+}
+
+func TestWorkerPoolProcess(t *testing.T) {
+	service := newQueue(t)
+	pool := &WorkerPool{Service: service, Timeout: time.Minute, handlers: map[string]handler{}}
+
+	var failures []error
+	calls := 0
+	pool.Register(TypeOGImage, func(ctx context.Context, job *models.Job) (any, error) {
+		calls++
+		if calls == 1 {
+			panic("nil map")
+		}
+		return map[string]string{"url": "https://cdn.test/og.png"}, nil
+	}, func(job *models.Job, err error) {
+		failures = append(failures, err)
+	})
+
+	testdb.Create(t, service.DB,
+		&models.Job{ID: 1, Type: TypeOGImage, Status: StatusPending, MaxAttempts: 2, AvailableAt: time.Now()},
+		// A job a worker died on after its last attempt fails without running again.
+		&models.Job{ID: 2, Type: TypeOGImage, Status: StatusProcessing, Attempts: 2, MaxAttempts: 2, AvailableAt: time.Now().Add(-time.Hour)},
+	)
+
+	// A panic counts as a failed attempt.
+	claimed, _ := service.claim([]string{TypeOGImage}, time.Hour)
+	pool.process(context.Background(), claimed)
+	if err := service.DB.Model(&models.Job{}).Where("id = ?", 1).Update("available_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	claimed, _ = service.claim([]string{TypeOGImage}, time.Hour)
+	pool.process(context.Background(), claimed)
+
+	var done models.Job
+	if err := service.DB.First(&done, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != StatusCompleted || done.Result == nil || string(*done.Result) != `{"url":"https://cdn.test/og.png"}` {
+		t.Fatalf("job 1 = %+v, want completed with the handler's result", done)
+	}
+
+	if err := service.DB.Model(&models.Job{}).Where("id = ?", 2).Update("reserved_at", time.Now().Add(-2*time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	claimed, _ = service.claim([]string{TypeOGImage}, time.Hour)
+	pool.process(context.Background(), claimed)
+
+	if calls != 2 {
+		t.Fatalf("handler ran %d times, want 2", calls)
+	}
+	if len(failures) != 1 || !errors.Is(failures[0], errWorkerLost) {
+		t.Fatalf("failures = %v, want job 2 lost", failures)
+	}
+}
@@ -0,0 +1,150 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"flash/models"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HandlerFunc runs a job and returns the result stored on it for GET /jobs/:id.
+type HandlerFunc func(ctx context.Context, job *models.Job) (any, error)
+
+// FailureFunc is called once a job has used up its attempts.
+type FailureFunc func(job *models.Job, err error)
+
+type handler struct {
+	handle    HandlerFunc
+	onFailure FailureFunc
+}
+
+var errWorkerLost = errors.New("job was interrupted and has no attempts left")
+
+// WorkerPool polls the jobs table and runs due jobs on a fixed number of goroutines inside the API process.
+type WorkerPool struct {
+	Service      *Service
+	Concurrency  int
+	PollInterval time.Duration
+	Timeout      time.Duration
+
+	handlers map[string]handler
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func NewWorkerPool(db *gorm.DB, concurrency int) *WorkerPool {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	return &WorkerPool{
+		Service:      NewService(db),
+		Concurrency:  concurrency,
+		PollInterval: 2 * time.Second,
+		Timeout:      5 * time.Minute,
+		handlers:     map[string]handler{},
+	}
+}
+
+func (pool *WorkerPool) Register(jobType string, handle HandlerFunc, onFailure FailureFunc) {
+	pool.handlers[jobType] = handler{handle: handle, onFailure: onFailure}
+}
+
+func (pool *WorkerPool) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.cancel = cancel
+
+	types := make([]string, 0, len(pool.handlers))
+	for jobType := range pool.handlers {
+		types = append(types, jobType)
+	}
+	if len(types) == 0 {
+		return
+	}
+
+	for i := 0; i < pool.Concurrency; i++ {
+		pool.wg.Add(1)
+		go pool.run(ctx, types)
+	}
+}
+
+func (pool *WorkerPool) Stop() {
+	if pool.cancel != nil {
+		pool.cancel()
+	}
+	pool.wg.Wait()
+}
+
+func (pool *WorkerPool) run(ctx context.Context, types []string) {
+	defer pool.wg.Done()
+
+	for {
+		job, err := pool.Service.claim(types, pool.Timeout+time.Minute)
+		if err != nil {
+			log.Printf("[ERROR] Failed to claim job: %v", err)
+		}
+
+		if job != nil {
+			pool.process(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pool.PollInterval):
+		}
+	}
+}
+
+func (pool *WorkerPool) process(ctx context.Context, job *models.Job) {
+	entry := pool.handlers[job.Type]
+
+	var result any
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		err = errWorkerLost
+	} else {
+		result, err = pool.execute(ctx, entry, job)
+	}
+
+	if err == nil {
+		if err := pool.Service.complete(job, result); err != nil {
+			log.Printf("[ERROR] Failed to complete job %d: %v", job.ID, err)
+		}
+		return
+	}
+
+	final, updateErr := pool.Service.fail(job, err)
+	if updateErr != nil {
+		log.Printf("[ERROR] Failed to record failure for job %d: %v", job.ID, updateErr)
+	}
+
+	if !final {
+		log.Printf("[WARN] Job %d (%s) attempt %d failed, retrying: %v", job.ID, job.Type, job.Attempts, err)
+		return
+	}
+
+	log.Printf("[ERROR] Job %d (%s) failed: %v", job.ID, job.Type, err)
+	if entry.onFailure != nil {
+		entry.onFailure(job, err)
+	}
+}
+
+func (pool *WorkerPool) execute(ctx context.Context, entry handler, job *models.Job) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, pool.Timeout)
+	defer cancel()
+
+	return entry.handle(ctx, job)
+}
@@ -1,10 +1,14 @@
 package linktree
 
-import "flash/models"
+import (
+	"flash/models"
 
-func (s *Service) syncContentItems(linktreeID uint64, projectID int64, links []LinktreeLinkRequest, sections []LinktreeSectionRequest) error {
+	"gorm.io/gorm"
+)
+
+func (s *Service) syncContentItems(db *gorm.DB, linktreeID uint64, projectID int64, links []LinktreeLinkRequest, sections []LinktreeSectionRequest) error {
 	var existingItems []models.LinktreeLink
-	s.DB.Where("linktree_id = ?", linktreeID).Find(&existingItems)
+	db.Where("linktree_id = ?", linktreeID).Find(&existingItems)
 
 	existingMap := make(map[uint64]*models.LinktreeLink)
 	for i := range existingItems {
@@ -70,7 +74,7 @@ func (s *Service) syncContentItems(linktreeID uint64, projectID int64, links []L
 			item.SupportQRImageURL = req.SupportQRImageURL
 		}
 
-		if err := s.DB.Save(&item).Error; err != nil {
+		if err := db.Save(&item).Error; err != nil {
 			return err
 		}
 	}
@@ -129,14 +133,14 @@ func (s *Service) syncContentItems(linktreeID uint64, projectID int64, links []L
 			item.SupportQRImageURL = req.SupportQRImageURL
 		}
 
-		if err := s.DB.Save(&item).Error; err != nil {
+		if err := db.Save(&item).Error; err != nil {
 			return err
 		}
 	}
 
 	for id, item := range existingMap {
 		if !processedIDs[id] {
-			s.DB.Delete(item)
+			db.Delete(item)
 		}
 	}
 
@@ -3,6 +3,7 @@ package linktree
 import (
 	"encoding/json"
 	"flash/internal/project"
+	"flash/shared/access"
 	"flash/utils"
 	"fmt"
 	"net/http"
@@ -85,30 +86,21 @@ func (c *Controller) Save(context *gin.Context) {
 		}
 	}
 
-	linktree, err := c.Service.Save(payload)
+	linktree, err := c.Service.Save(utils.RequestActor(context), payload)
 	if err != nil {
-		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		utils.APIRespondError(context, access.HTTPStatus(err, http.StatusInternalServerError), err.Error())
 		context.Abort()
 		return
 	}
 
+	editorID := context.GetUint64("user_id")
 	utils.APIRespondSuccess(context, http.StatusOK, gin.H{
 		"linktree": linktree,
 	})
 
-	go func(projectID int64) {
-		defer func() {
-			if r := recover(); r != nil {
-				fmt.Printf("Recovered from panic in SaveOGImage: %v\n", r)
-			}
-		}()
-
-		_, err := c.ProjectService.SaveOGImage(projectID)
-
-		if err != nil {
-			fmt.Printf("Background OG Image generation failed for project %d: %v\n", projectID, err)
-		}
-	}(payload.ProjectID)
+	if _, err := c.ProjectService.EnqueueOGImage(payload.ProjectID, &editorID); err != nil {
+		fmt.Printf("Failed to queue OG image for project %d: %v\n", payload.ProjectID, err)
+	}
 }
 
 func (c *Controller) Get(context *gin.Context) {
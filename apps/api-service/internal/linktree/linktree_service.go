@@ -2,8 +2,10 @@ package linktree
 
 import (
 	"encoding/json"
+	"flash/internal/project"
 	"flash/models"
 	objectStorage "flash/sdk/object_storage"
+	"flash/shared/access"
 	"fmt"
 	"mime/multipart"
 	"time"
@@ -33,7 +35,14 @@ func (s *Service) Get(projectID int64) (*models.Linktree, error) {
 	return &linktree, nil
 }
 
-func (s *Service) Save(payload Payload) (*models.Linktree, error) {
+func (s *Service) Save(actor access.Actor, payload Payload) (*models.Linktree, error) {
+	proj, err := actor.Authorize(s.DB, uint64(payload.ProjectID), access.PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	// Rows belong to the project owner, whichever collaborator saves them.
+	payload.UserID = int64(proj.UserID)
+
 	var themeRaw *json.RawMessage
 	var themeName *string
 
@@ -53,7 +62,7 @@ func (s *Service) Save(payload Payload) (*models.Linktree, error) {
 			ProjectID: uint64(payload.ProjectID),
 		}
 	} else {
-		if err := s.DB.First(&linktree, *payload.LinktreeID).Error; err != nil {
+		if err := s.DB.Where("project_id = ?", payload.ProjectID).First(&linktree, *payload.LinktreeID).Error; err != nil {
 			return nil, err
 		}
 	}
@@ -81,11 +90,13 @@ func (s *Service) Save(payload Payload) (*models.Linktree, error) {
 		linktree.LogoURL = nil
 	}
 
-	if err := s.DB.Save(&linktree).Error; err != nil {
-		return nil, err
-	}
-
-	if err := s.syncContentItems(linktree.ID, payload.ProjectID, payload.Links, payload.Sections); err != nil {
+	err = project.SaveWithRevision(s.DB, linktree.ProjectID, &actor.UserID, func(tx *gorm.DB) error {
+		if err := tx.Save(&linktree).Error; err != nil {
+			return err
+		}
+		return s.syncContentItems(tx, linktree.ID, payload.ProjectID, payload.Links, payload.Sections)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -3,6 +3,7 @@ package menu
 import (
 	"encoding/json"
 	"flash/internal/project"
+	"flash/shared/access"
 	"flash/utils"
 	"fmt"
 	"net/http"
@@ -71,25 +72,19 @@ func (c *Controller) Save(context *gin.Context) {
 		}
 	}
 
-	menu, err := c.Service.Save(payload)
+	menu, err := c.Service.Save(utils.RequestActor(context), payload)
 	if err != nil {
-		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		utils.APIRespondError(context, access.HTTPStatus(err, http.StatusInternalServerError), err.Error())
 		context.Abort()
 		return
 	}
 
+	editorID := context.GetUint64("user_id")
 	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"menu": menu})
 
-	go func(projectID int64) {
-		defer func() {
-			if r := recover(); r != nil {
-				fmt.Printf("Recovered from panic in menu SaveOGImage: %v\n", r)
-			}
-		}()
-		if _, err := c.ProjectService.SaveOGImage(projectID); err != nil {
-			fmt.Printf("Background OG Image generation failed for menu project %d: %v\n", projectID, err)
-		}
-	}(payload.ProjectID)
+	if _, err := c.ProjectService.EnqueueOGImage(payload.ProjectID, &editorID); err != nil {
+		fmt.Printf("Failed to queue OG image for project %d: %v\n", payload.ProjectID, err)
+	}
 }
 
 func (c *Controller) Get(context *gin.Context) {
@@ -116,12 +111,13 @@ func (c *Controller) GenerateDisplayPoster(context *gin.Context) {
 		return
 	}
 
-	result, err := c.Service.GenerateDisplayPoster(req)
+	userID := context.GetUint64("user_id")
+	queued, err := c.Service.EnqueueDisplayPoster(req, &userID)
 	if err != nil {
 		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
 		context.Abort()
 		return
 	}
 
-	utils.APIRespondSuccess(context, http.StatusOK, result)
+	utils.APIRespondSuccess(context, http.StatusAccepted, gin.H{"job": queued})
 }
@@ -11,11 +11,10 @@ import (
 	"io"
 	"math"
 	"net/http"
-	"net/url"
-	"os"
 	"strings"
 	"time"
 
+	"flash/internal/project"
 	"flash/models"
 	"flash/utils"
 
@@ -101,7 +100,7 @@ func (s *Service) persistDisplayPoster(
 	query := s.DB.Model(&models.Menu{})
 	switch {
 	case request.MenuID != nil:
-		query = query.Where("id = ?", *request.MenuID)
+		query = query.Where("id = ? AND project_id = ?", *request.MenuID, request.ProjectID)
 	default:
 		query = query.Where("project_id = ?", request.ProjectID)
 	}
@@ -150,11 +149,7 @@ func (s *Service) persistDisplayPoster(
 		return err
 	}
 
-	if previousPath := storagePathFromURL(previousImageURL); previousPath != "" {
-		if _, err := s.ObjectStorage.Delete(previousPath); err != nil {
-			fmt.Printf("Failed to delete replaced display poster %q: %v\n", previousPath, err)
-		}
-	}
+	project.ReleaseAssets(s.DB, s.ObjectStorage, uint64(request.ProjectID), previousImageURL)
 
 	return nil
 }
@@ -175,32 +170,6 @@ func stringValue(value *string) string {
 	return *value
 }
 
-func storagePathFromURL(raw string) string {
-	value := strings.TrimSpace(raw)
-	if value == "" {
-		return ""
-	}
-
-	publicBase := strings.TrimRight(strings.TrimSpace(os.Getenv("R2_PUBLIC_URL")), "/")
-	if publicBase != "" {
-		publicPrefix := publicBase + "/"
-		if strings.HasPrefix(value, publicPrefix) {
-			return strings.TrimPrefix(value, publicPrefix)
-		}
-	}
-
-	parsed, err := url.Parse(value)
-	if err == nil && parsed.Scheme != "" && parsed.Host != "" {
-		return strings.TrimPrefix(parsed.Path, "/")
-	}
-
-	if strings.Contains(value, "://") {
-		return ""
-	}
-
-	return strings.TrimPrefix(value, "/")
-}
-
 func normalizeDisplayPosterSettings(settings DisplayPosterSettingsRequest) DisplayPosterSettingsRequest {
 	if settings.Template == "" {
 		settings.Template = "clean"
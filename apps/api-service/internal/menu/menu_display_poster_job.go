@@ -0,0 +1,26 @@
+package menu
+
+import (
+	"context"
+	"encoding/json"
+	"flash/internal/job"
+	"flash/models"
+)
+
+// EnqueueDisplayPoster queues poster rendering; the request is replayed as-is by the worker.
+func (s *Service) EnqueueDisplayPoster(request GenerateDisplayPosterRequest, userID *uint64) (*models.Job, error) {
+	return job.NewService(s.DB).Enqueue(job.EnqueuePayload{
+		UserID:  userID,
+		Type:    job.TypeDisplayPoster,
+		Payload: request,
+	})
+}
+
+func (s *Service) HandleDisplayPosterJob(ctx context.Context, queued *models.Job) (any, error) {
+	var request GenerateDisplayPosterRequest
+	if err := json.Unmarshal(queued.Payload, &request); err != nil {
+		return nil, err
+	}
+
+	return s.GenerateDisplayPoster(request)
+}
@@ -2,8 +2,10 @@ package menu
 
 import (
 	"encoding/json"
+	"flash/internal/project"
 	"flash/models"
 	objectStorage "flash/sdk/object_storage"
+	"flash/shared/access"
 	"fmt"
 	"mime/multipart"
 	"time"
@@ -33,12 +35,19 @@ func (s *Service) Get(projectID int64) (*models.Menu, error) {
 	return &menu, nil
 }
 
-func (s *Service) Save(payload Payload) (*models.Menu, error) {
+func (s *Service) Save(actor access.Actor, payload Payload) (*models.Menu, error) {
+	proj, err := actor.Authorize(s.DB, uint64(payload.ProjectID), access.PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	// Rows belong to the project owner, whichever collaborator saves them.
+	payload.UserID = int64(proj.UserID)
+
 	var menu models.Menu
 	isNew := payload.MenuID == nil
 	if isNew {
 		menu = models.Menu{ProjectID: uint64(payload.ProjectID), UserID: uint64(payload.UserID)}
-	} else if err := s.DB.First(&menu, *payload.MenuID).Error; err != nil {
+	} else if err := s.DB.Where("project_id = ?", payload.ProjectID).First(&menu, *payload.MenuID).Error; err != nil {
 		return nil, err
 	}
 
@@ -92,11 +101,13 @@ func (s *Service) Save(payload Payload) (*models.Menu, error) {
 		menu.CoverImageURL = nil
 	}
 
-	if err := s.DB.Save(&menu).Error; err != nil {
-		return nil, err
-	}
-
-	if err := s.syncContent(menu.ID, payload.ProjectID, payload.Categories, payload.Items); err != nil {
+	err = project.SaveWithRevision(s.DB, menu.ProjectID, &actor.UserID, func(tx *gorm.DB) error {
+		if err := tx.Save(&menu).Error; err != nil {
+			return err
+		}
+		return s.syncContent(tx, menu.ID, payload.ProjectID, payload.Categories, payload.Items)
+	})
+	if err != nil {
 		return nil, err
 	}
 
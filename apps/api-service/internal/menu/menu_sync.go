@@ -7,17 +7,15 @@ import (
 	"gorm.io/gorm"
 )
 
-func (s *Service) syncContent(menuID uint64, projectID int64, categoryRequests []MenuCategoryRequest, itemRequests []MenuItemRequest) error {
-	return s.DB.Transaction(func(tx *gorm.DB) error {
-		categoryIDsByKey, keepCategoryIDs, err := s.upsertCategories(tx, menuID, projectID, categoryRequests)
-		if err != nil {
-			return err
-		}
-		if err := s.deleteMissingCategories(tx, menuID, keepCategoryIDs); err != nil {
-			return err
-		}
-		return s.syncItems(tx, menuID, projectID, itemRequests, categoryIDsByKey)
-	})
+func (s *Service) syncContent(tx *gorm.DB, menuID uint64, projectID int64, categoryRequests []MenuCategoryRequest, itemRequests []MenuItemRequest) error {
+	categoryIDsByKey, keepCategoryIDs, err := s.upsertCategories(tx, menuID, projectID, categoryRequests)
+	if err != nil {
+		return err
+	}
+	if err := s.deleteMissingCategories(tx, menuID, keepCategoryIDs); err != nil {
+		return err
+	}
+	return s.syncItems(tx, menuID, projectID, itemRequests, categoryIDsByKey)
 }
 
 func (s *Service) upsertCategories(tx *gorm.DB, menuID uint64, projectID int64, requests []MenuCategoryRequest) (map[string]uint64, []uint64, error) {
@@ -103,5 +103,5 @@ func (controller Controller) Create(context *gin.Context) {
 		return
 	}
 
-	utils.APIRespondSuccess(context, http.StatusOK, response)
+	utils.APIRespondSuccess(context, http.StatusAccepted, response)
 }
@@ -1,14 +1,14 @@
 package parsed_file
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"mime/multipart"
 	"time"
 
 	"flash/internal/document"
+	"flash/internal/job"
 	"flash/models"
 	"flash/utils"
 
@@ -56,34 +56,14 @@ func (service *Service) List(payload ListPayload) ([]models.ParsedFile, int64, e
 	return records, total, nil
 }
 
+// Create stores the upload as a pending parsed file and queues the actual parsing, which can take longer than the
+// request is allowed to live.
 func (service *Service) Create(payload CreatePayload) (*models.ParsedFile, error) {
 	if len(payload.Files) == 0 {
 		return nil, fmt.Errorf("no files provided")
 	}
 
-	filePayloads := make([]document.FilePayload, 0, len(payload.Files))
-	for _, file := range payload.Files {
-		opened, err := file.Open()
-		if err != nil {
-			return nil, err
-		}
-		defer opened.Close()
-
-		buf := bytes.NewBuffer(nil)
-		if _, err := io.Copy(buf, opened); err != nil {
-			return nil, err
-		}
-
-		filePayloads = append(filePayloads, document.FilePayload{
-			Name: file.Filename,
-			File: bytes.NewReader(buf.Bytes()),
-		})
-	}
-
-	parsedData, err := service.DocumentService.ParseJSON(document.Payload{
-		Type:  payload.SourceType,
-		Files: filePayloads,
-	})
+	jobFiles, err := service.DocumentService.StageParseFiles(payload.UserID, payload.Files)
 	if err != nil {
 		return nil, err
 	}
@@ -98,19 +78,106 @@ func (service *Service) Create(payload CreatePayload) (*models.ParsedFile, error
 		ProjectType: payload.ProjectType,
 		SourceType:  payload.SourceType,
 		SourceName:  sourceName,
-		Status:      "completed",
-		ParsedData:  parsedData,
+		Status:      job.StatusPending,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
-	if err := service.DB.Create(&record).Error; err != nil {
+	err = service.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+
+		queued, err := job.NewService(tx).Enqueue(job.EnqueuePayload{
+			UserID: &payload.UserID,
+			Type:   job.TypeParseDocument,
+			Payload: document.ParseJobPayload{
+				ParsedFileID: &record.ID,
+				Type:         payload.SourceType,
+				Files:        jobFiles,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		record.JobID = &queued.ID
+		return tx.Model(&record).Update("job_id", queued.ID).Error
+	})
+	if err != nil {
+		service.DocumentService.ReleaseParseFiles(jobFiles)
 		return nil, err
 	}
 
 	return &record, nil
 }
 
+// HandleParseJob runs a queued parse. Jobs tied to a parsed file move it through processing to completed; jobs
+// queued straight from POST /documents only keep the result on the job.
+func (service *Service) HandleParseJob(ctx context.Context, queued *models.Job) (any, error) {
+	var payload document.ParseJobPayload
+	if err := json.Unmarshal(queued.Payload, &payload); err != nil {
+		return nil, err
+	}
+
+	input, err := service.DocumentService.OpenParseJob(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.ParsedFileID == nil {
+		result, err := service.DocumentService.ParseForType(input)
+		if err != nil {
+			return nil, err
+		}
+		service.DocumentService.ReleaseParseFiles(payload.Files)
+		return result, nil
+	}
+
+	db := service.DB.WithContext(ctx)
+	if err := db.Model(&models.ParsedFile{}).Where("id = ?", *payload.ParsedFileID).
+		Update("status", job.StatusProcessing).Error; err != nil {
+		return nil, err
+	}
+
+	parsedData, err := service.DocumentService.ParseJSON(input)
+	if err != nil {
+		service.DB.Model(&models.ParsedFile{}).Where("id = ?", *payload.ParsedFileID).Updates(map[string]any{
+			"status": job.StatusPending,
+			"error":  err.Error(),
+		})
+		return nil, err
+	}
+
+	if err := db.Model(&models.ParsedFile{}).Where("id = ?", *payload.ParsedFileID).Updates(map[string]any{
+		"status":      job.StatusCompleted,
+		"parsed_data": parsedData,
+		"error":       nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+	service.DocumentService.ReleaseParseFiles(payload.Files)
+
+	return parsedData, nil
+}
+
+func (service *Service) HandleParseJobFailure(queued *models.Job, cause error) {
+	var payload document.ParseJobPayload
+	if err := json.Unmarshal(queued.Payload, &payload); err != nil {
+		return
+	}
+
+	service.DocumentService.ReleaseParseFiles(payload.Files)
+	if payload.ParsedFileID == nil {
+		return
+	}
+
+	service.DB.Model(&models.ParsedFile{}).Where("id = ?", *payload.ParsedFileID).Updates(map[string]any{
+		"status": job.StatusFailed,
+		"error":  cause.Error(),
+	})
+}
+
 func (service *Service) ToResponse(record models.ParsedFile) (map[string]any, error) {
 	var parsed any
 	if record.ParsedData != nil {
@@ -126,6 +193,8 @@ func (service *Service) ToResponse(record models.ParsedFile) (map[string]any, er
 		"source_type":  record.SourceType,
 		"source_name":  record.SourceName,
 		"status":       record.Status,
+		"job_id":       record.JobID,
+		"error":        record.Error,
 		"parsed_data":  parsed,
 		"created_at":   record.CreatedAt,
 		"updated_at":   record.UpdatedAt,
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"flash/internal/project"
 	objectStorage "flash/sdk/object_storage"
+	"flash/shared/access"
 	"flash/utils"
 	"fmt"
 	"net/http"
@@ -69,27 +70,17 @@ func (controller Controller) Save(context *gin.Context) {
 		}
 	}
 
-	portfolio, err := controller.Service.Save(payload)
+	portfolio, err := controller.Service.Save(utils.RequestActor(context), payload)
 	if err != nil {
-		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		utils.APIRespondError(context, access.HTTPStatus(err, http.StatusBadRequest), err.Error())
 		context.Abort()
 		return
 	}
 
-	go func(projectID int64) {
-		defer func() {
-			if r := recover(); r != nil {
-				fmt.Printf("Recovered from panic in SaveOGImage: %v\n", r)
-			}
-		}()
-
-		_, err := controller.ProjectService.SaveOGImage(projectID)
-
-		if err != nil {
-			fmt.Printf("Background OG Image generation failed for project %d: %v\n", projectID, err)
-		}
-
-	}(int64(payload.ProjectID))
+	editorID := context.GetUint64("user_id")
+	if _, err := controller.ProjectService.EnqueueOGImage(int64(payload.ProjectID), &editorID); err != nil {
+		fmt.Printf("Failed to queue OG image for project %d: %v\n", payload.ProjectID, err)
+	}
 
 	utils.APIRespondSuccess(context, http.StatusOK, gin.H{
 		"portfolio": portfolio,
@@ -123,9 +114,9 @@ func (controller Controller) Delete(context *gin.Context) {
 		return
 	}
 
-	err = controller.Service.Delete(uint64(portfolioID))
+	err = controller.Service.Delete(utils.RequestActor(context), uint64(portfolioID))
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		context.JSON(access.HTTPStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
 		return
 	}
 
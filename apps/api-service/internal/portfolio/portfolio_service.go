@@ -6,6 +6,7 @@ import (
 	Project "flash/internal/project"
 	"flash/models"
 	objectStorage "flash/sdk/object_storage"
+	"flash/shared/access"
 	"fmt"
 	"mime/multipart"
 	"time"
@@ -19,7 +20,14 @@ type Service struct {
 	ObjectStorage  objectStorage.Provider
 }
 
-func (service Service) Save(payload Payload) (*models.Portfolio, error) {
+func (service Service) Save(actor access.Actor, payload Payload) (*models.Portfolio, error) {
+	proj, err := actor.Authorize(service.DB, uint64(payload.ProjectID), access.PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	// Rows belong to the project owner, whichever collaborator saves them.
+	payload.UserID = int64(proj.UserID)
+
 	themeRaw, err := marshalTheme(*payload.Theme)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal theme: %w", err)
@@ -73,11 +81,13 @@ func (service Service) Save(payload Payload) (*models.Portfolio, error) {
 			portfolio.ResumeURL = &resumeURL
 		}
 
-		if err := service.DB.Create(&portfolio).Error; err != nil {
+		if err := Project.SaveWithRevision(service.DB, portfolio.ProjectID, &actor.UserID, func(tx *gorm.DB) error {
+			return tx.Create(&portfolio).Error
+		}); err != nil {
 			return nil, fmt.Errorf("failed to create portfolio: %w", err)
 		}
 	} else {
-		if err := service.DB.First(&portfolio, payload.PortfolioID).Error; err != nil {
+		if err := service.DB.Where("project_id = ?", payload.ProjectID).First(&portfolio, payload.PortfolioID).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				return nil, fmt.Errorf("portfolio with ID %d not found", payload.PortfolioID)
 			}
@@ -121,7 +131,7 @@ func (service Service) Save(payload Payload) (*models.Portfolio, error) {
 			portfolio.ResumeURL = nil
 		}
 
-		if err := service.DB.Transaction(func(tx *gorm.DB) error {
+		if err := Project.SaveWithRevision(service.DB, portfolio.ProjectID, &actor.UserID, func(tx *gorm.DB) error {
 			if err := tx.Model(&portfolio).Association("WorkExperiences").Clear(); err != nil {
 				return err
 			}
@@ -163,13 +173,17 @@ func (service Service) GetByIDWithPreloads(id uint64) (*models.Portfolio, error)
 	return &portfolio, nil
 }
 
-func (service Service) Delete(portfolioID uint64) error {
+func (service Service) Delete(actor access.Actor, portfolioID uint64) error {
 	portfolio, err := service.GetByIDWithPreloads(portfolioID)
 
 	if err != nil {
 		return err
 	}
 
+	if _, err := actor.Authorize(service.DB, portfolio.ProjectID, access.PermissionWrite); err != nil {
+		return err
+	}
+
 	if err := service.DB.Select(
 		"WorkExperiences", "Education", "Skills", "Showcases", "Showcases.Technologies",
 	).Delete(&portfolio).Error; err != nil {
@@ -0,0 +1,402 @@
+package project
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"flash/models"
+	objectStorage "flash/sdk/object_storage"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	archiveFormat = "kislap-project"
+	// archiveFormatVersion goes up whenever project.json changes shape; imports refuse newer versions.
+	archiveFormatVersion = 1
+	maxArchiveSize       = 200 << 20
+	maxArchiveAssetSize  = 25 << 20
+	maxArchiveEntries    = 2000
+	archiveManifestFile  = "manifest.json"
+	archiveProjectFile   = "project.json"
+
+	// maxArchiveUnpacked bounds the bytes an import reads out of an archive, whatever its headers claim.
+	maxArchiveUnpacked = 500 << 20
+)
+
+// archiveAssetTypes are the sniffed types an imported asset may have: the images and PDFs the upload endpoints
+// accept. Anything else could be served from our storage as, say, HTML.
+var archiveAssetTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/webp":      true,
+	"image/gif":       true,
+	"application/pdf": true,
+}
+
+var (
+	ErrArchiveInvalid     = errors.New("not a valid Kislap project archive")
+	ErrArchiveTooLarge    = errors.New("archive is too large")
+	ErrArchiveUnsupported = errors.New("archive was made by a newer version of Kislap")
+)
+
+// archiveManifest describes an exported project. Assets map each URL found in project.json to the file holding
+// its bytes, so the import can swap in the URLs of the re-uploaded copies.
+type archiveManifest struct {
+	Format        string             `json:"format"`
+	FormatVersion int                `json:"format_version"`
+	ExportedAt    time.Time          `json:"exported_at"`
+	Project       archiveProjectInfo `json:"project"`
+	Assets        []archiveAsset     `json:"assets"`
+	MissingAssets []string           `json:"missing_assets,omitempty"`
+}
+
+type archiveProjectInfo struct {
+	ID        uint64  `json:"id"`
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	SubDomain *string `json:"sub_domain,omitempty"`
+}
+
+type archiveAsset struct {
+	URL         string `json:"url"`
+	File        string `json:"file"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// ExportArchive writes the project as visitors see it, or its draft while it is not published, to a temporary zip
+// with the stored assets it references. Only objects under the project's own folder and its OG image are included;
+// a URL pointing at another project's object stays in project.json as a plain URL. The caller serves the file and
+// removes it.
+func (service Service) ExportArchive(projectID uint64) (*os.File, *models.Project, error) {
+	var project models.Project
+	if err := service.DB.First(&project, projectID).Error; err != nil {
+		return nil, nil, err
+	}
+
+	content, err := service.showContent(&project)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Owner details are not content.
+	content.User = nil
+	if content.Portfolio != nil {
+		content.Portfolio.User = models.User{}
+	}
+
+	base, _ := service.ObjectStorage.GetURL("")
+	projectPrefix := fmt.Sprintf("projects/%d/", project.ID)
+	ogImage := fmt.Sprintf("og_images/%d.png", project.ID)
+
+	var urls []string
+	seen := map[string]bool{}
+	if err := rewriteStrings(reflect.ValueOf(content), func(value string) (string, error) {
+		objectPath := archiveObjectPath(value, base)
+		if (strings.HasPrefix(objectPath, projectPrefix) || objectPath == ogImage) && !seen[value] {
+			seen[value] = true
+			urls = append(urls, value)
+		}
+		return value, nil
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	archive, err := os.CreateTemp("", "kislap-project-*.zip")
+	if err != nil {
+		return nil, nil, err
+	}
+	fail := func(err error) (*os.File, *models.Project, error) {
+		archive.Close()
+		os.Remove(archive.Name())
+		return nil, nil, err
+	}
+
+	manifest := archiveManifest{
+		Format:        archiveFormat,
+		FormatVersion: archiveFormatVersion,
+		ExportedAt:    time.Now(),
+		Project: archiveProjectInfo{
+			ID:        content.ID,
+			Name:      content.Name,
+			Type:      content.Type,
+			SubDomain: content.SubDomain,
+		},
+		Assets: []archiveAsset{},
+	}
+
+	writer := zip.NewWriter(archive)
+	for _, url := range urls {
+		file := "assets/" + archiveObjectPath(url, base)
+		copied, err := service.writeArchiveAsset(writer, archiveObjectPath(url, base), file)
+		if err != nil {
+			return fail(err)
+		}
+		if !copied {
+			manifest.MissingAssets = append(manifest.MissingAssets, url)
+			continue
+		}
+		manifest.Assets = append(manifest.Assets, archiveAsset{URL: url, File: file})
+	}
+
+	if err := writeArchiveJSON(writer, archiveProjectFile, content); err != nil {
+		return fail(err)
+	}
+	if err := writeArchiveJSON(writer, archiveManifestFile, manifest); err != nil {
+		return fail(err)
+	}
+	if err := writer.Close(); err != nil {
+		return fail(err)
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return fail(err)
+	}
+
+	return archive, &project, nil
+}
+
+func (service Service) writeArchiveAsset(writer *zip.Writer, objectPath string, file string) (bool, error) {
+	content, err := service.ObjectStorage.Download(objectPath)
+	if errors.Is(err, objectStorage.ErrObjectNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("download %s: %w", objectPath, err)
+	}
+	defer content.Close()
+
+	entry, err := writer.Create(file)
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(entry, content); err != nil {
+		return false, fmt.Errorf("download %s: %w", objectPath, err)
+	}
+
+	return true, nil
+}
+
+// ImportArchive recreates an exported project as a new, unpublished project of the caller. Assets are uploaded
+// again under the new project and every row gets a fresh ID.
+func (service Service) ImportArchive(userID uint64, file *multipart.FileHeader, payload DuplicatePayload) (*models.Project, error) {
+	if file.Size > maxArchiveSize {
+		return nil, ErrArchiveTooLarge
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	reader, err := zip.NewReader(src, file.Size)
+	if err != nil {
+		return nil, ErrArchiveInvalid
+	}
+	if len(reader.File) > maxArchiveEntries {
+		return nil, ErrArchiveTooLarge
+	}
+
+	// The headers are checked up front to turn away obvious bombs; the importer also counts what it actually reads.
+	var unpacked uint64
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, entry := range reader.File {
+		unpacked += entry.UncompressedSize64
+		files[entry.Name] = entry
+	}
+	if unpacked > maxArchiveUnpacked {
+		return nil, ErrArchiveTooLarge
+	}
+
+	var manifest archiveManifest
+	if err := readArchiveJSON(files[archiveManifestFile], &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Format != archiveFormat {
+		return nil, ErrArchiveInvalid
+	}
+	if manifest.FormatVersion > archiveFormatVersion {
+		return nil, ErrArchiveUnsupported
+	}
+
+	var source models.Project
+	if err := readArchiveJSON(files[archiveProjectFile], &source); err != nil {
+		return nil, err
+	}
+	switch source.Type {
+	case "portfolio", "biz", "linktree", "menu", "waitlist":
+	default:
+		return nil, fmt.Errorf("%w: unknown project type %q", ErrArchiveInvalid, source.Type)
+	}
+	denormalizeLinktreeContent(&source)
+
+	importer := &archiveImporter{
+		assetUploader: assetUploader{storage: service.ObjectStorage, copied: map[string]string{}},
+		files:         files,
+		assets:        make(map[string]archiveAsset, len(manifest.Assets)),
+		fromPrefix:    fmt.Sprintf("projects/%d/", manifest.Project.ID),
+	}
+	for _, asset := range manifest.Assets {
+		importer.assets[asset.URL] = asset
+	}
+
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		name = source.Name
+	}
+
+	return service.insertCopy(&source, userID, nil, name, payload.SubDomain, importer)
+}
+
+// archiveImporter uploads the archive's assets as they are met in the content. Files keep their path inside
+// the exported project's folder; assets from elsewhere in storage go under imported/.
+type archiveImporter struct {
+	assetUploader
+	files      map[string]*zip.File
+	assets     map[string]archiveAsset
+	fromPrefix string
+	unpacked   int64
+}
+
+func (importer *archiveImporter) rewriteAll(value any) error {
+	return rewriteStrings(reflect.ValueOf(value), importer.rewrite)
+}
+
+func (importer *archiveImporter) rewrite(value string) (string, error) {
+	if url, ok := importer.copied[value]; ok {
+		return url, nil
+	}
+
+	asset, ok := importer.assets[value]
+	if !ok {
+		return value, nil
+	}
+
+	entry := importer.files[asset.File]
+	if entry == nil || !strings.HasPrefix(asset.File, "assets/") || strings.Contains(asset.File, "..") {
+		return "", fmt.Errorf("%w: missing asset %s", ErrArchiveInvalid, asset.File)
+	}
+	if entry.UncompressedSize64 > maxArchiveAssetSize {
+		return "", fmt.Errorf("%w: %s", ErrArchiveTooLarge, asset.File)
+	}
+
+	data, err := readArchiveEntry(entry, maxArchiveAssetSize)
+	if err != nil {
+		return "", err
+	}
+	importer.unpacked += int64(len(data))
+	if importer.unpacked > maxArchiveUnpacked {
+		return "", ErrArchiveTooLarge
+	}
+
+	// The type is sniffed from the bytes; the manifest's content type is the archive's word only.
+	contentType := http.DetectContentType(data)
+	if !archiveAssetTypes[contentType] {
+		return "", fmt.Errorf("%w: %s is not an image or PDF", ErrArchiveInvalid, asset.File)
+	}
+
+	relativePath := strings.TrimPrefix(asset.File, "assets/")
+	if trimmed, ok := strings.CutPrefix(relativePath, importer.fromPrefix); ok {
+		relativePath = trimmed
+	} else {
+		relativePath = "imported/" + relativePath
+	}
+
+	return importer.upload(value, relativePath, data, contentType)
+}
+
+// denormalizeLinktreeContent undoes normalizeLinktreeContent: sections are stored as typed rows next to links.
+func denormalizeLinktreeContent(project *models.Project) {
+	if project.Linktree == nil || len(project.Linktree.Sections) == 0 {
+		return
+	}
+
+	for _, section := range project.Linktree.Sections {
+		link := models.LinktreeLink{
+			PlacementOrder:    section.PlacementOrder,
+			Type:              section.Type,
+			Description:       section.Description,
+			AppURL:            section.AppURL,
+			ImageURL:          section.ImageURL,
+			IconKey:           section.IconKey,
+			AccentColor:       section.AccentColor,
+			QuoteText:         section.QuoteText,
+			QuoteAuthor:       section.QuoteAuthor,
+			BannerText:        section.BannerText,
+			SupportNote:       section.SupportNote,
+			SupportQRImageURL: section.SupportQRImageURL,
+			CTALabel:          section.CTALabel,
+		}
+		if section.Title != nil {
+			link.Title = *section.Title
+		}
+		if section.URL != nil {
+			link.URL = *section.URL
+		}
+		project.Linktree.Links = append(project.Linktree.Links, link)
+	}
+	project.Linktree.Sections = nil
+}
+
+// archiveObjectPath returns the storage path of a URL the provider handed out, or "" for anything else.
+func archiveObjectPath(value string, base string) string {
+	if base == "" || !strings.HasPrefix(value, base) || strings.ContainsAny(value, "?#") {
+		return ""
+	}
+
+	objectPath := strings.TrimPrefix(strings.TrimPrefix(value, base), "/")
+	if objectPath == "" || strings.Contains(objectPath, "..") {
+		return ""
+	}
+	return objectPath
+}
+
+func writeArchiveJSON(writer *zip.Writer, name string, value any) error {
+	entry, err := writer.Create(name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func readArchiveJSON(entry *zip.File, target any) error {
+	if entry == nil {
+		return ErrArchiveInvalid
+	}
+
+	data, err := readArchiveEntry(entry, maxArchiveAssetSize)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrArchiveInvalid, entry.Name, err)
+	}
+	return nil
+}
+
+// readArchiveEntry reads at most limit bytes; the size in the zip header is not trusted.
+func readArchiveEntry(entry *zip.File, limit int64) ([]byte, error) {
+	content, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrArchiveInvalid, entry.Name)
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(io.LimitReader(content, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrArchiveInvalid, entry.Name)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: %s", ErrArchiveTooLarge, entry.Name)
+	}
+	return data, nil
+}
@@ -0,0 +1,248 @@
+package project
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flash/models"
+	objectStorage "flash/sdk/object_storage"
+	"flash/shared/testdb"
+	"io"
+	"mime/multipart"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// pngBytes starts with the PNG signature, which is all content sniffing looks at.
+func pngBytes(name string) []byte {
+	return append([]byte("\x89PNG\r\n\x1a\n"), name...)
+}
+
+func openArchiveDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db := openDuplicateDB(t)
+	if err := db.AutoMigrate(&models.LinktreeSection{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// archiveUpload wraps archive bytes in the file header a multipart upload of them would produce.
+func archiveUpload(t *testing.T, data []byte) *multipart.FileHeader {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "project.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["file"][0]
+}
+
+// buildArchive zips a manifest, a project and asset files the way an export lays them out.
+func buildArchive(t *testing.T, manifest archiveManifest, project models.Project, assets map[string][]byte) []byte {
+	t.Helper()
+
+	buffer := &bytes.Buffer{}
+	writer := zip.NewWriter(buffer)
+	for name, data := range assets {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.Write(data)
+	}
+	if err := writeArchiveJSON(writer, archiveProjectFile, project); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeArchiveJSON(writer, archiveManifestFile, manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	db := openArchiveDB(t)
+	storage := objectStorage.NewInMemoryProvider(duplicateStorageBase)
+	service := NewService(db, storage)
+
+	urls := map[string]*string{}
+	for _, path := range []string{"projects/1/links/logo.png", "projects/1/links/card.png", "og_images/1.png", "projects/99/theirs.png"} {
+		url, err := storage.Upload(path, bytes.NewReader(pngBytes(path)), "image/png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls[path] = &url
+	}
+	missing := duplicateStorageBase + "/projects/1/links/gone.png"
+	subDomain := "links"
+
+	testdb.Create(t, db,
+		&models.Project{ID: 1, UserID: 1, Name: "Links", Slug: "links", SubDomain: &subDomain, Type: "linktree", OGImageURL: urls["og_images/1.png"]},
+		&models.Linktree{ID: 5, ProjectID: 1, UserID: 1, Name: "Links", LogoURL: urls["projects/1/links/logo.png"]},
+		&models.LinktreeLink{LinktreeID: 5, Title: "Card", URL: "https://ada.dev", ImageURL: urls["projects/1/links/card.png"], PlacementOrder: 1},
+		&models.LinktreeLink{LinktreeID: 5, Title: "Borrowed", URL: "https://bob.dev", ImageURL: urls["projects/99/theirs.png"], PlacementOrder: 2},
+		&models.LinktreeLink{LinktreeID: 5, Title: "Gone", URL: "https://ada.dev/gone", ImageURL: &missing, PlacementOrder: 3},
+	)
+
+	file, _, err := service.ExportArchive(1)
+	if err != nil {
+		t.Fatalf("ExportArchive() error = %v", err)
+	}
+	t.Cleanup(func() {
+		file.Close()
+		os.Remove(file.Name())
+	})
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range archive.File {
+		names = append(names, entry.Name)
+	}
+	slices.Sort(names)
+	// Another project's object is not the project's to export; its URL stays in project.json as it is.
+	want := []string{
+		"assets/og_images/1.png", "assets/projects/1/links/card.png", "assets/projects/1/links/logo.png",
+		archiveManifestFile, archiveProjectFile,
+	}
+	if !slices.Equal(names, want) {
+		t.Fatalf("archive holds %v, want %v", names, want)
+	}
+
+	var manifest archiveManifest
+	entry, _ := archive.Open(archiveManifestFile)
+	if err := json.NewDecoder(entry).Decode(&manifest); err != nil {
+		t.Fatal(err)
+	}
+	entry.Close()
+	if manifest.Project.ID != 1 || len(manifest.Assets) != 3 || !slices.Equal(manifest.MissingAssets, []string{missing}) {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+
+	imported, err := service.ImportArchive(2, archiveUpload(t, data), DuplicatePayload{SubDomain: "links-copy"})
+	if err != nil {
+		t.Fatalf("ImportArchive() error = %v", err)
+	}
+	if imported.ID == 1 || imported.UserID != 2 || imported.Name != "Links" || imported.Published || *imported.SubDomain != "links-copy" {
+		t.Fatalf("imported = %+v, want an unpublished project for user 2 on links-copy", imported)
+	}
+
+	var linktree models.Linktree
+	if err := db.Preload("Links", func(db *gorm.DB) *gorm.DB {
+		return db.Order("placement_order ASC")
+	}).Where("project_id = ?", imported.ID).First(&linktree).Error; err != nil {
+		t.Fatal(err)
+	}
+	if linktree.ID == 5 || linktree.UserID != 2 || len(linktree.Links) != 3 {
+		t.Fatalf("imported linktree = %+v", linktree)
+	}
+
+	// Assets of the exported project land in the new project's folder, stored as what their bytes say they are.
+	prefix := duplicateStorageBase + "/projects/" + strconv.FormatUint(imported.ID, 10) + "/"
+	for url, source := range map[string]string{
+		*linktree.LogoURL:           "projects/1/links/logo.png",
+		*linktree.Links[0].ImageURL: "projects/1/links/card.png",
+	} {
+		if !strings.HasPrefix(url, prefix) {
+			t.Errorf("%s was imported to %s, want a path under %s", source, url, prefix)
+			continue
+		}
+		stored, ok := storage.Get(strings.TrimPrefix(url, duplicateStorageBase+"/"))
+		if !ok || !bytes.Equal(stored.Content, pngBytes(source)) || stored.ContentType != "image/png" {
+			t.Errorf("%s was not imported as the PNG the archive holds", url)
+		}
+	}
+	// The OG image is rendered again when the new project is published.
+	if imported.OGImageURL != nil {
+		t.Errorf("OG image = %s, want none until the import is published", *imported.OGImageURL)
+	}
+	if *linktree.Links[1].ImageURL != *urls["projects/99/theirs.png"] || *linktree.Links[2].ImageURL != missing {
+		t.Errorf("URLs that were not exported changed: %s, %s", *linktree.Links[1].ImageURL, *linktree.Links[2].ImageURL)
+	}
+}
+
+func TestImportArchiveRejectsUnsafeArchives(t *testing.T) {
+	logo := duplicateStorageBase + "/projects/1/logo.png"
+	manifest := archiveManifest{
+		Format:        archiveFormat,
+		FormatVersion: archiveFormatVersion,
+		Project:       archiveProjectInfo{ID: 1, Name: "Links", Type: "linktree"},
+		Assets:        []archiveAsset{{URL: logo, File: "assets/projects/1/logo.png", ContentType: "image/png"}},
+	}
+	project := models.Project{ID: 1, Name: "Links", Type: "linktree", Linktree: &models.Linktree{Name: "Links", LogoURL: &logo}}
+
+	// A zip entry whose header claims more than the whole archive may unpack to; the bytes are never read.
+	bomb := &bytes.Buffer{}
+	writer := zip.NewWriter(bomb)
+	raw, err := writer.CreateRaw(&zip.FileHeader{Name: "assets/filler.bin", Method: zip.Store, UncompressedSize64: maxArchiveUnpacked + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw.Write([]byte("filler"))
+	writeArchiveJSON(writer, archiveProjectFile, project)
+	writeArchiveJSON(writer, archiveManifestFile, manifest)
+	writer.Close()
+
+	tests := []struct {
+		name    string
+		archive []byte
+		wantErr error
+	}{
+		{
+			name:    "HTML labelled as an image",
+			archive: buildArchive(t, manifest, project, map[string][]byte{"assets/projects/1/logo.png": []byte("<!DOCTYPE html><script>alert(1)</script>")}),
+			wantErr: ErrArchiveInvalid,
+		},
+		{
+			name:    "SVG",
+			archive: buildArchive(t, manifest, project, map[string][]byte{"assets/projects/1/logo.png": []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`)}),
+			wantErr: ErrArchiveInvalid,
+		},
+		{name: "unpacks too large", archive: bomb.Bytes(), wantErr: ErrArchiveTooLarge},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := openArchiveDB(t)
+			storage := objectStorage.NewInMemoryProvider(duplicateStorageBase)
+			service := NewService(db, storage)
+
+			if _, err := service.ImportArchive(2, archiveUpload(t, test.archive), DuplicatePayload{SubDomain: "links-copy"}); !errors.Is(err, test.wantErr) {
+				t.Fatalf("ImportArchive() error = %v, want %v", err, test.wantErr)
+			}
+			if paths := storage.Paths(); len(paths) != 0 {
+				t.Errorf("storage holds %v after a rejected import", paths)
+			}
+			var projects int64
+			db.Model(&models.Project{}).Count(&projects)
+			if projects != 0 {
+				t.Errorf("%d projects created by a rejected import", projects)
+			}
+		})
+	}
+}
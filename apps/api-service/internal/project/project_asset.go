@@ -0,0 +1,74 @@
+package project
+
+import (
+	"bytes"
+	"flash/models"
+	objectStorage "flash/sdk/object_storage"
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ReleaseAssets deletes objects a save replaced or removed, once nothing of the project points at them anymore.
+// Revisions, the published one included, keep their objects alive: restoring or serving them needs the files.
+// Revision pruning releases those once their revisions are gone, and the trash purge removes whatever is left
+// with the project. Call it after the save has committed.
+func ReleaseAssets(db *gorm.DB, storage objectStorage.Provider, projectID uint64, urls ...string) {
+	if len(urls) == 0 {
+		return
+	}
+
+	go func() {
+		for _, url := range urls {
+			if err := releaseAsset(db, storage, projectID, url); err != nil {
+				log.Printf("[WARN] Failed to release asset %s: %v", url, err)
+			}
+		}
+	}()
+}
+
+func releaseAsset(db *gorm.DB, storage objectStorage.Provider, projectID uint64, url string) error {
+	base, _ := storage.GetURL("")
+	objectPath := archiveObjectPath(strings.TrimSpace(url), base)
+
+	// Only objects in the project's own folder are the project's to delete.
+	if !strings.HasPrefix(objectPath, fmt.Sprintf("projects/%d/", projectID)) {
+		return nil
+	}
+
+	referenced, err := assetReferenced(db, projectID, objectPath)
+	if err != nil || referenced {
+		return err
+	}
+
+	_, err = storage.Delete(objectPath)
+	return err
+}
+
+// assetReferenced reports whether the project's current content or any of its revisions mentions objectPath.
+func assetReferenced(db *gorm.DB, projectID uint64, objectPath string) (bool, error) {
+	var revisions int64
+	if err := db.Model(&models.ProjectRevision{}).
+		// Left unescaped, "_" in a path matches any character: a false hit only keeps the object.
+		// The cast is a no-op on MySQL's longtext; SQLite stores the snapshot as a blob, which LIKE never matches.
+		Where("project_id = ? AND CAST(snapshot AS CHAR) LIKE ?", projectID, "%"+objectPath+"%").
+		Count(&revisions).Error; err != nil {
+		return false, err
+	}
+	if revisions > 0 {
+		return true, nil
+	}
+
+	var project models.Project
+	if err := db.First(&project, projectID).Error; err != nil {
+		return false, err
+	}
+
+	snapshot, err := contentSnapshot(db, &project)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Contains(snapshot, []byte(objectPath)), nil
+}
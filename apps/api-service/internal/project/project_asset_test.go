@@ -0,0 +1,56 @@
+package project
+
+import (
+	"flash/models"
+	objectStorage "flash/sdk/object_storage"
+	"flash/shared/testdb"
+	"strings"
+	"testing"
+)
+
+func TestReleaseAsset(t *testing.T) {
+	const base = "https://cdn.test"
+
+	tests := []struct {
+		name     string
+		path     string
+		current  string
+		snapshot string
+		deleted  bool
+	}{
+		{name: "unreferenced", path: "projects/1/linktree/old.png", current: "projects/1/linktree/new.png", deleted: true},
+		{name: "kept by a revision", path: "projects/1/linktree/old.png", current: "projects/1/linktree/new.png", snapshot: `{"logo_url":"` + base + `/projects/1/linktree/old.png"}`},
+		{name: "still in the content", path: "projects/1/linktree/old.png", current: "projects/1/linktree/old.png"},
+		{name: "another project's object", path: "projects/2/linktree/old.png", current: "projects/1/linktree/new.png"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := testdb.Open(t, &models.Project{}, &models.ProjectRevision{}, &models.Linktree{}, &models.LinktreeLink{})
+			storage := objectStorage.NewInMemoryProvider(base)
+			url, err := storage.Upload(test.path, strings.NewReader("image"), "image/png")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			logoURL := base + "/" + test.current
+			testdb.Create(t, db,
+				&models.Project{ID: 1, Name: "Links", Slug: "links", Type: "linktree"},
+				&models.Linktree{ProjectID: 1, Name: "Links", LogoURL: &logoURL},
+			)
+			if test.snapshot != "" {
+				// Inserted as text: SQLite keeps a []byte as a blob, which LIKE never matches, unlike MySQL's longtext.
+				if err := db.Exec("INSERT INTO project_revisions (project_id, revision, type, snapshot) VALUES (?, ?, ?, ?)", 1, 1, "linktree", test.snapshot).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := releaseAsset(db, storage, 1, url); err != nil {
+				t.Fatalf("releaseAsset() error = %v", err)
+			}
+			if _, stored := storage.Get(test.path); stored == test.deleted {
+				t.Errorf("object stored = %v, want %v", stored, !test.deleted)
+			}
+		})
+	}
+}
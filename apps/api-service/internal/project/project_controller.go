@@ -1,9 +1,14 @@
 package project
 
 import (
+	"errors"
 	objectStorage "flash/sdk/object_storage"
+	"flash/shared/access"
 	"flash/utils"
+	"fmt"
+	"math"
 	"net/http"
+	"os"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -55,7 +60,12 @@ func (controller Controller) Create(context *gin.Context) {
 
 	project, err := controller.Service.Create(userID, request.ToServicePayload())
 	if err != nil {
-		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		if errors.Is(err, ErrEmailNotVerified) {
+			utils.APIRespondError(context, http.StatusForbidden, err.Error())
+			context.Abort()
+			return
+		}
+		utils.APIRespondError(context, access.HTTPStatus(err, http.StatusBadRequest), err.Error())
 		context.Abort()
 		return
 	}
@@ -73,9 +83,13 @@ func (controller Controller) ShowByID(context *gin.Context) {
 		return
 	}
 
-	project, err := controller.Service.Show(projectID)
+	project, err := controller.Service.Show(utils.RequestActor(context), projectID)
 	if err != nil {
-		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.APIRespondError(context, http.StatusNotFound, "Project not found")
+		} else {
+			utils.APIRespondError(context, access.HTTPStatus(err, http.StatusBadRequest), err.Error())
+		}
 		context.Abort()
 		return
 	}
@@ -102,7 +116,11 @@ func (controller Controller) ShowBySubDomain(context *gin.Context) {
 
 	project, err := controller.Service.ShowBySubDomain(subDomain)
 	if err != nil {
-		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		if errors.Is(err, ErrProjectNotPublished) || errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.APIRespondError(context, http.StatusNotFound, err.Error())
+		} else {
+			utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		}
 		context.Abort()
 		return
 	}
@@ -128,10 +146,15 @@ func (controller Controller) Update(context *gin.Context) {
 		return
 	}
 
-	project, err := controller.Service.Update(projectID, request.ToServicePayload())
+	project, err := controller.Service.Update(utils.RequestActor(context), projectID, request.ToServicePayload())
 
 	if err != nil {
-		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		if errors.Is(err, ErrEmailNotVerified) {
+			utils.APIRespondError(context, http.StatusForbidden, err.Error())
+			context.Abort()
+			return
+		}
+		utils.APIRespondError(context, access.HTTPStatus(err, http.StatusBadRequest), err.Error())
 		context.Abort()
 		return
 	}
@@ -149,9 +172,9 @@ func (controller Controller) Delete(context *gin.Context) {
 		return
 	}
 
-	project, err := controller.Service.Delete(id)
+	project, err := controller.Service.Delete(utils.RequestActor(context), id)
 	if err != nil {
-		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		utils.APIRespondError(context, access.HTTPStatus(err, http.StatusBadRequest), err.Error())
 		context.Abort()
 		return
 	}
@@ -160,16 +183,20 @@ func (controller Controller) Delete(context *gin.Context) {
 }
 
 func (controller Controller) CheckDomain(context *gin.Context) {
-	subDomain := context.Param("sub-domain")
+	check, err := controller.Service.CheckSubDomain(context.Param("sub-domain"), context.GetUint64("user_id"), context.Query("name"))
+	if err != nil {
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
 
-	ok, err := controller.Service.CheckDomain(subDomain)
-	if !ok {
-		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+	if !check.Available {
+		utils.APIRespond(context, http.StatusBadRequest, false, check.Reasons[0].Message, check)
 		context.Abort()
 		return
 	}
 
-	utils.APIRespondSuccess(context, http.StatusOK, subDomain)
+	utils.APIRespondSuccess(context, http.StatusOK, check)
 }
 
 func (controller Controller) Publish(context *gin.Context) {
@@ -190,10 +217,15 @@ func (controller Controller) Publish(context *gin.Context) {
 		return
 	}
 
-	project, err := controller.Service.Publish(projectID, request.ToPublishServicePayload())
+	project, err := controller.Service.Publish(utils.RequestActor(context), projectID, request.ToPublishServicePayload())
 
 	if err != nil {
-		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		if errors.Is(err, ErrEmailNotVerified) {
+			utils.APIRespondError(context, http.StatusForbidden, err.Error())
+			context.Abort()
+			return
+		}
+		utils.APIRespondError(context, access.HTTPStatus(err, http.StatusBadRequest), err.Error())
 		context.Abort()
 		return
 	}
@@ -231,16 +263,399 @@ func (controller Controller) PublicStats(context *gin.Context) {
 }
 
 func (controller Controller) SaveOGImage(context *gin.Context) {
-	idStr := context.Param("id")
-	projectID, err := strconv.Atoi(idStr)
+	projectID := context.GetUint64("project_id")
+	userID := context.GetUint64("user_id")
 
-	project, err := controller.Service.SaveOGImage(int64(projectID))
+	queued, err := controller.Service.EnqueueOGImage(int64(projectID), &userID)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusAccepted, gin.H{"job": queued})
+}
+
+func (controller Controller) ListRevisions(context *gin.Context) {
+	projectID := context.GetUint64("project_id")
 
+	page, _ := strconv.Atoi(context.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(context.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	revisions, total, err := controller.Service.ListRevisions(projectID, page, limit)
 	if err != nil {
 		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
 		context.Abort()
 		return
 	}
 
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{
+		"data": revisions,
+		"meta": gin.H{
+			"page":      page,
+			"limit":     limit,
+			"total":     total,
+			"last_page": int(math.Ceil(float64(total) / float64(limit))),
+		},
+	})
+}
+
+func (controller Controller) ShowRevision(context *gin.Context) {
+	revisionID, err := strconv.ParseUint(context.Param("revision_id"), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	revision, err := controller.Service.ShowRevision(context.GetUint64("project_id"), revisionID)
+	if err != nil {
+		respondRevisionError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, revision)
+}
+
+func (controller Controller) DiffRevisions(context *gin.Context) {
+	fromID, err := strconv.ParseUint(context.Query("from"), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "Invalid 'from' revision ID")
+		context.Abort()
+		return
+	}
+
+	toID, err := strconv.ParseUint(context.Query("to"), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "Invalid 'to' revision ID")
+		context.Abort()
+		return
+	}
+
+	diff, err := controller.Service.DiffRevisions(context.GetUint64("project_id"), fromID, toID)
+	if err != nil {
+		respondRevisionError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, diff)
+}
+
+func (controller Controller) RestoreRevision(context *gin.Context) {
+	revisionID, err := strconv.ParseUint(context.Param("revision_id"), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	userID := context.GetUint64("user_id")
+
+	revision, err := controller.Service.RestoreRevision(context.GetUint64("project_id"), revisionID, &userID)
+	if err != nil {
+		respondRevisionError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, revision)
+}
+
+func respondRevisionError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrRevisionNotFound):
+		utils.APIRespondError(context, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrRevisionTypeMismatch):
+		utils.APIRespondError(context, http.StatusConflict, err.Error())
+	default:
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+	}
+	context.Abort()
+}
+
+func (controller Controller) ShowByHost(context *gin.Context) {
+	project, err := controller.Service.ShowByHost(context.Param("host"))
+	if err != nil {
+		respondDomainError(context, err)
+		return
+	}
+
 	utils.APIRespondSuccess(context, http.StatusOK, project)
 }
+
+func (controller Controller) ListDomains(context *gin.Context) {
+	domains, err := controller.Service.ListDomains(context.GetUint64("project_id"))
+	if err != nil {
+		respondDomainError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, domains)
+}
+
+func (controller Controller) AddDomain(context *gin.Context) {
+	var request AddDomainRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	domain, err := controller.Service.AddDomain(context.GetUint64("project_id"), request.Host)
+	if err != nil {
+		respondDomainError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusCreated, domain)
+}
+
+func (controller Controller) VerifyDomain(context *gin.Context) {
+	domainID, err := strconv.ParseUint(context.Param("domain_id"), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "Invalid domain ID")
+		context.Abort()
+		return
+	}
+
+	domain, err := controller.Service.VerifyDomain(context.Request.Context(), context.GetUint64("project_id"), domainID)
+	if errors.Is(err, ErrDomainVerificationFailed) {
+		// Not a failed request: the domain comes back with last_error explaining what is missing.
+		utils.APIRespondSuccess(context, http.StatusOK, domain)
+		return
+	}
+	if err != nil {
+		respondDomainError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, domain)
+}
+
+func (controller Controller) RemoveDomain(context *gin.Context) {
+	domainID, err := strconv.ParseUint(context.Param("domain_id"), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "Invalid domain ID")
+		context.Abort()
+		return
+	}
+
+	if err := controller.Service.RemoveDomain(context.GetUint64("project_id"), domainID); err != nil {
+		respondDomainError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"removed": true})
+}
+
+func respondDomainError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrDomainNotFound), errors.Is(err, ErrProjectNotPublished), errors.Is(err, gorm.ErrRecordNotFound):
+		utils.APIRespondError(context, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrDomainExists), errors.Is(err, ErrTooManyDomains):
+		utils.APIRespondError(context, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrDomainInvalid), errors.Is(err, ErrDomainReserved):
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+	default:
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+	}
+	context.Abort()
+}
+
+func (controller Controller) ListSubDomainHistory(context *gin.Context) {
+	history, err := controller.Service.ListSubDomainHistory(context.GetUint64("project_id"))
+	if err != nil {
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, history)
+}
+
+func (controller Controller) ResolveSubDomainRedirect(context *gin.Context) {
+	redirect, err := controller.Service.ResolveSubDomainRedirect(context.Param("sub-domain"))
+	if errors.Is(err, ErrNoSubDomainRedirect) {
+		utils.APIRespondError(context, http.StatusNotFound, err.Error())
+		context.Abort()
+		return
+	}
+	if err != nil {
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, redirect)
+}
+
+func (controller Controller) Duplicate(context *gin.Context) {
+	var request DuplicateProjectRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	project, err := controller.Service.Duplicate(context.GetUint64("project_id"), context.GetUint64("user_id"), request.ToDuplicatePayload())
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusCreated, project)
+}
+
+func (controller Controller) ListTemplates(context *gin.Context) {
+	templates, err := controller.Service.ListTemplates(context.Query("type"))
+	if err != nil {
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, templates)
+}
+
+func (controller Controller) CreateFromTemplate(context *gin.Context) {
+	var request DuplicateProjectRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	project, err := controller.Service.CreateFromTemplate(context.GetUint64("user_id"), context.Param("key"), request.ToDuplicatePayload())
+	if errors.Is(err, ErrTemplateNotFound) {
+		utils.APIRespondError(context, http.StatusNotFound, err.Error())
+		context.Abort()
+		return
+	}
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusCreated, project)
+}
+
+func (controller Controller) Export(context *gin.Context) {
+	archive, project, err := controller.Service.ExportArchive(context.GetUint64("project_id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.APIRespondError(context, http.StatusNotFound, "Project not found")
+		context.Abort()
+		return
+	}
+	if err != nil {
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	info, err := archive.Stat()
+	if err != nil {
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+
+	filename := fmt.Sprintf("project-%d.kislap.zip", project.ID)
+	if project.Slug != "" {
+		filename = project.Slug + ".kislap.zip"
+	}
+
+	context.DataFromReader(http.StatusOK, info.Size(), "application/zip", archive, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", filename),
+	})
+}
+
+func (controller Controller) Import(context *gin.Context) {
+	var request ImportProjectRequest
+	if err := context.ShouldBind(&request); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	file, err := context.FormFile("file")
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "No file provided")
+		context.Abort()
+		return
+	}
+
+	project, err := controller.Service.ImportArchive(context.GetUint64("user_id"), file, request.ToDuplicatePayload())
+	if errors.Is(err, ErrArchiveTooLarge) {
+		utils.APIRespondError(context, http.StatusRequestEntityTooLarge, err.Error())
+		context.Abort()
+		return
+	}
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusCreated, project)
+}
+
+func (controller Controller) ListTrash(context *gin.Context) {
+	trashed, err := controller.Service.ListTrash(context.GetUint64("user_id"))
+	if err != nil {
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, trashed)
+}
+
+func (controller Controller) Restore(context *gin.Context) {
+	projectID, err := strconv.ParseUint(context.Param("id"), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "Invalid project ID")
+		context.Abort()
+		return
+	}
+
+	// The body is optional; it only matters when the old subdomain was taken in the meantime.
+	var request RestoreProjectRequest
+	if context.Request.ContentLength > 0 {
+		if err := context.ShouldBindJSON(&request); err != nil {
+			utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+			context.Abort()
+			return
+		}
+	}
+
+	project, err := controller.Service.Restore(projectID, context.GetUint64("user_id"), context.GetString("user_role"), request.SubDomain)
+	if err != nil {
+		respondTrashError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, project)
+}
+
+func respondTrashError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.APIRespondError(context, http.StatusNotFound, "Project not found")
+	case errors.Is(err, access.ErrProjectForbidden):
+		utils.APIRespondError(context, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrProjectNotInTrash), errors.Is(err, ErrRestoreSubDomainAvailable):
+		utils.APIRespondError(context, http.StatusConflict, err.Error())
+	default:
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+	}
+	context.Abort()
+}
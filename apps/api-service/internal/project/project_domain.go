@@ -0,0 +1,333 @@
+package project
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flash/models"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/idna"
+	"gorm.io/gorm"
+)
+
+const (
+	// PlatformDomain hosts every site at <sub_domain>.PlatformDomain; it can't be claimed as a custom domain.
+	PlatformDomain = "kislap.app"
+	// domainChallengePrefix is prepended to the host to name the TXT record that proves ownership.
+	domainChallengePrefix = "_kislap-challenge."
+	domainChallengeValue  = "kislap-verification="
+	maxDomainsPerProject  = 5
+	domainLookupTimeout   = 5 * time.Second
+	// Pending domains are re-checked in the background for a week, so owners don't have to come back once DNS
+	// has propagated.
+	pendingDomainWindow   = 7 * 24 * time.Hour
+	pendingDomainInterval = 10 * time.Minute
+	pendingDomainBatch    = 50
+)
+
+var (
+	ErrDomainNotFound           = errors.New("domain not found")
+	ErrDomainInvalid            = errors.New("enter a valid domain name, e.g. www.example.com")
+	ErrDomainReserved           = errors.New("this domain can't be used as a custom domain")
+	ErrDomainExists             = errors.New("domain is already added to this project")
+	ErrTooManyDomains           = fmt.Errorf("a project can have at most %d custom domains", maxDomainsPerProject)
+	ErrDomainVerificationFailed = errors.New("domain verification failed")
+)
+
+// TXTResolver looks up TXT records. *net.Resolver satisfies it; tests can swap in a stub.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+func (service Service) txtResolver() TXTResolver {
+	if service.Resolver != nil {
+		return service.Resolver
+	}
+	return net.DefaultResolver
+}
+
+func (service Service) ListDomains(projectID uint64) ([]DomainResponse, error) {
+	var domains []models.ProjectDomain
+	if err := service.DB.Where("project_id = ?", projectID).Order("id ASC").Find(&domains).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]DomainResponse, 0, len(domains))
+	for _, domain := range domains {
+		responses = append(responses, domainResponse(domain))
+	}
+	return responses, nil
+}
+
+// AddDomain registers a host for the project and hands back the TXT record to publish. The same host may be
+// pending on several projects; whichever proves ownership first gets it.
+func (service Service) AddDomain(projectID uint64, rawHost string) (*DomainResponse, error) {
+	host, err := NormalizeHost(rawHost)
+	if err != nil {
+		return nil, err
+	}
+	if isPlatformHost(host) {
+		return nil, ErrDomainReserved
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	domain := models.ProjectDomain{ProjectID: projectID, Host: host, VerificationToken: token}
+	err = service.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []models.ProjectDomain
+		if err := tx.Where("project_id = ?", projectID).Find(&existing).Error; err != nil {
+			return err
+		}
+		for _, item := range existing {
+			if item.Host == host {
+				return ErrDomainExists
+			}
+		}
+		if len(existing) >= maxDomainsPerProject {
+			return ErrTooManyDomains
+		}
+
+		return tx.Create(&domain).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := domainResponse(domain)
+	return &response, nil
+}
+
+// VerifyDomain checks the TXT record now. On success the host moves to this project, taking it away from any
+// project that verified it before: the TXT record shows who controls the DNS today.
+func (service Service) VerifyDomain(ctx context.Context, projectID uint64, domainID uint64) (*DomainResponse, error) {
+	var domain models.ProjectDomain
+	if err := service.DB.Where("id = ? AND project_id = ?", domainID, projectID).First(&domain).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDomainNotFound
+		}
+		return nil, err
+	}
+
+	if err := service.checkDomain(ctx, &domain); err != nil {
+		return nil, err
+	}
+
+	response := domainResponse(domain)
+	if domain.VerifiedAt == nil {
+		return &response, fmt.Errorf("%w: %s", ErrDomainVerificationFailed, *domain.LastError)
+	}
+	return &response, nil
+}
+
+func (service Service) RemoveDomain(projectID uint64, domainID uint64) error {
+	result := service.DB.Where("id = ? AND project_id = ?", domainID, projectID).Delete(&models.ProjectDomain{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDomainNotFound
+	}
+	return nil
+}
+
+// RunDomainVerification re-checks recently added domains that haven't verified yet.
+func (service Service) RunDomainVerification(ctx context.Context) error {
+	now := time.Now()
+
+	var pending []models.ProjectDomain
+	if err := service.DB.WithContext(ctx).
+		Where("verified_at IS NULL AND created_at > ?", now.Add(-pendingDomainWindow)).
+		Where("last_checked_at IS NULL OR last_checked_at < ?", now.Add(-pendingDomainInterval)).
+		Order("last_checked_at ASC").
+		Limit(pendingDomainBatch).
+		Find(&pending).Error; err != nil {
+		return err
+	}
+
+	var errs []error
+	for index := range pending {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := service.checkDomain(ctx, &pending[index]); err != nil {
+			errs = append(errs, fmt.Errorf("domain %d: %w", pending[index].ID, err))
+			continue
+		}
+		if pending[index].VerifiedAt != nil {
+			log.Printf("[INFO] Verified custom domain %s for project %d", pending[index].Host, pending[index].ProjectID)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkDomain looks up the challenge record and stores the outcome on the domain. A missing or wrong record is
+// not an error here; it is recorded in last_error.
+func (service Service) checkDomain(ctx context.Context, domain *models.ProjectDomain) error {
+	lookupCtx, cancel := context.WithTimeout(ctx, domainLookupTimeout)
+	defer cancel()
+
+	now := time.Now()
+	domain.LastCheckedAt = &now
+
+	records, lookupErr := service.txtResolver().LookupTXT(lookupCtx, domainChallengePrefix+domain.Host)
+	if !hasChallengeRecord(records, domain.VerificationToken) {
+		message := fmt.Sprintf("TXT record %s%s with value %s%s was not found", domainChallengePrefix, domain.Host, domainChallengeValue, domain.VerificationToken)
+		var dnsErr *net.DNSError
+		if lookupErr != nil && !(errors.As(lookupErr, &dnsErr) && dnsErr.IsNotFound) {
+			message = "DNS lookup failed, try again in a few minutes"
+		}
+		domain.LastError = &message
+
+		return service.DB.Model(domain).Updates(map[string]any{
+			"last_checked_at": now,
+			"last_error":      message,
+		}).Error
+	}
+
+	domain.VerifiedAt = &now
+	domain.LastError = nil
+
+	return service.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ProjectDomain{}).
+			Where("host = ? AND id <> ? AND verified_at IS NOT NULL", domain.Host, domain.ID).
+			Update("verified_at", nil).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(domain).Updates(map[string]any{
+			"verified_at":     now,
+			"last_checked_at": now,
+			"last_error":      nil,
+		}).Error
+	})
+}
+
+// ShowByHost serves a site by the host it is visited on: a <sub_domain>.kislap.app address or a verified custom
+// domain.
+func (service Service) ShowByHost(rawHost string) (*models.Project, error) {
+	host, err := NormalizeHost(rawHost)
+	if err != nil {
+		return nil, err
+	}
+
+	if subDomain, ok := strings.CutSuffix(host, "."+PlatformDomain); ok && !strings.Contains(subDomain, ".") {
+		return service.ShowBySubDomain(subDomain)
+	}
+
+	var domain models.ProjectDomain
+	if err := service.DB.Where("host = ? AND verified_at IS NOT NULL", host).First(&domain).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDomainNotFound
+		}
+		return nil, err
+	}
+
+	var project models.Project
+	if err := service.DB.First(&project, domain.ProjectID).Error; err != nil {
+		return nil, err
+	}
+
+	return service.showPublic(&project)
+}
+
+// NormalizeHost lowercases a host and strips what people tend to paste along with it: a scheme, a path, a port
+// and a trailing dot. Internationalized names come back as punycode.
+func NormalizeHost(raw string) (string, error) {
+	host := strings.ToLower(strings.TrimSpace(raw))
+	if strings.Contains(host, "://") {
+		parsed, err := url.Parse(host)
+		if err != nil {
+			return "", ErrDomainInvalid
+		}
+		host = parsed.Host
+	}
+	if index := strings.IndexAny(host, "/?#"); index >= 0 {
+		host = host[:index]
+	}
+	if splitHost, _, err := net.SplitHostPort(host); err == nil {
+		host = splitHost
+	}
+	host = strings.TrimSuffix(host, ".")
+
+	// Internationalized names are stored in their punycode form, the one DNS and browsers' Host headers use.
+	host, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", ErrDomainInvalid
+	}
+
+	if len(host) == 0 || len(host) > 253 || net.ParseIP(host) != nil {
+		return "", ErrDomainInvalid
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 {
+		return "", ErrDomainInvalid
+	}
+	for _, label := range labels {
+		if !validHostLabel(label) {
+			return "", ErrDomainInvalid
+		}
+	}
+	// Top-level domains are never all digits.
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return "", ErrDomainInvalid
+	}
+
+	return host, nil
+}
+
+func validHostLabel(label string) bool {
+	if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for _, char := range label {
+		if (char < 'a' || char > 'z') && (char < '0' || char > '9') && char != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func isPlatformHost(host string) bool {
+	return host == PlatformDomain || strings.HasSuffix(host, "."+PlatformDomain) ||
+		host == "localhost" || strings.HasSuffix(host, ".localhost")
+}
+
+func hasChallengeRecord(records []string, token string) bool {
+	for _, record := range records {
+		if strings.TrimSpace(record) == domainChallengeValue+token {
+			return true
+		}
+	}
+	return false
+}
+
+func domainResponse(domain models.ProjectDomain) DomainResponse {
+	return DomainResponse{
+		ProjectDomain: domain,
+		VerificationRecord: DNSRecord{
+			Type:  "TXT",
+			Name:  domainChallengePrefix + domain.Host,
+			Value: domainChallengeValue + domain.VerificationToken,
+		},
+	}
+}
+
+func randomToken() (string, error) {
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buffer), nil
+}
@@ -0,0 +1,138 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"flash/models"
+	"flash/shared/testdb"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubResolver answers TXT lookups from a map, or with err for every name.
+type stubResolver struct {
+	records map[string][]string
+	err     error
+}
+
+func (stub stubResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if stub.err != nil {
+		return nil, stub.err
+	}
+	records, ok := stub.records[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func TestNormalizeHost(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "www.example.com", want: "www.example.com"},
+		{raw: "  WWW.Example.COM ", want: "www.example.com"},
+		{raw: "https://www.example.com/menu?table=4", want: "www.example.com"},
+		{raw: "www.example.com:8443", want: "www.example.com"},
+		{raw: "http://www.example.com:8080/", want: "www.example.com"},
+		{raw: "www.example.com.", want: "www.example.com"},
+		{raw: "münchen.example", want: "xn--mnchen-3ya.example"},
+		{raw: "https://MÜNCHEN.example:443", want: "xn--mnchen-3ya.example"},
+		{raw: "xn--mnchen-3ya.example", want: "xn--mnchen-3ya.example"},
+		{raw: ""},
+		{raw: "localhost"},
+		{raw: "192.168.1.10"},
+		{raw: "[::1]:443"},
+		{raw: "exa_mple.com"},
+		{raw: "-shop.example.com"},
+		{raw: "shop.example.123"},
+		{raw: "shop..example.com"},
+		{raw: strings.Repeat("a", 64) + ".example.com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.raw, func(t *testing.T) {
+			host, err := NormalizeHost(test.raw)
+			if test.want == "" {
+				if !errors.Is(err, ErrDomainInvalid) {
+					t.Fatalf("NormalizeHost(%q) = %q, %v, want ErrDomainInvalid", test.raw, host, err)
+				}
+				return
+			}
+			if err != nil || host != test.want {
+				t.Fatalf("NormalizeHost(%q) = %q, %v, want %q", test.raw, host, err, test.want)
+			}
+		})
+	}
+}
+
+func TestVerifyDomain(t *testing.T) {
+	const challenge = "_kislap-challenge.shop.example.com"
+
+	tests := []struct {
+		name      string
+		resolver  stubResolver
+		verified  bool
+		wantError string
+	}{
+		{
+			name:     "matching record",
+			resolver: stubResolver{records: map[string][]string{challenge: {"v=spf1 -all", " kislap-verification=token-1 "}}},
+			verified: true,
+		},
+		{
+			name:      "mismatched record",
+			resolver:  stubResolver{records: map[string][]string{challenge: {"kislap-verification=someone-else"}}},
+			wantError: "kislap-verification=token-1 was not found",
+		},
+		{
+			name:      "no record",
+			resolver:  stubResolver{records: map[string][]string{}},
+			wantError: "kislap-verification=token-1 was not found",
+		},
+		{
+			name:      "lookup error",
+			resolver:  stubResolver{err: &net.DNSError{Err: "server misbehaving", Name: challenge, IsTemporary: true}},
+			wantError: "DNS lookup failed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := testdb.Open(t, &models.ProjectDomain{})
+			previouslyVerified := time.Now().Add(-time.Hour)
+			testdb.Create(t, db,
+				&models.ProjectDomain{ID: 1, ProjectID: 1, Host: "shop.example.com", VerificationToken: "token-1"},
+				&models.ProjectDomain{ID: 2, ProjectID: 2, Host: "shop.example.com", VerificationToken: "token-2", VerifiedAt: &previouslyVerified},
+			)
+			service := Service{DB: db, Resolver: test.resolver}
+
+			response, err := service.VerifyDomain(context.Background(), 1, 1)
+			if test.verified {
+				if err != nil || response.VerifiedAt == nil {
+					t.Fatalf("VerifyDomain() = %+v, %v, want verified", response, err)
+				}
+			} else if !errors.Is(err, ErrDomainVerificationFailed) || !strings.Contains(err.Error(), test.wantError) {
+				t.Fatalf("VerifyDomain() error = %v, want ErrDomainVerificationFailed with %q", err, test.wantError)
+			}
+
+			var domains []models.ProjectDomain
+			if err := db.Order("id").Find(&domains).Error; err != nil {
+				t.Fatal(err)
+			}
+			if domains[0].LastCheckedAt == nil {
+				t.Error("the check was not recorded")
+			}
+			if (domains[0].VerifiedAt != nil) != test.verified {
+				t.Errorf("stored verified_at = %v, want verified = %v", domains[0].VerifiedAt, test.verified)
+			}
+			// Verifying moves the host away from the project that held it; a failed check leaves it alone.
+			if (domains[1].VerifiedAt == nil) != test.verified {
+				t.Errorf("previous owner verified_at = %v, want it cleared only on success", domains[1].VerifiedAt)
+			}
+		})
+	}
+}
@@ -1,5 +1,11 @@
 package project
 
+import (
+	"flash/models"
+	"flash/shared/subdomain"
+	"time"
+)
+
 const TypeResume = "resume"
 
 type CreateUpdateProjectRequest struct {
@@ -11,13 +17,28 @@ type CreateUpdateProjectRequest struct {
 }
 
 type PublishProjectRequest struct {
-	Published bool `json:"published"`
+	Published   bool       `json:"published"`
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
 }
 
 type SaveOGImageRequest struct {
 	URL string `json:"url" binding:"required"`
 }
 
+// DuplicateProjectRequest is used both to duplicate a project and to start one from a template. Name defaults to
+// the original's name with " (copy)", or the template's name.
+type DuplicateProjectRequest struct {
+	Name      string `json:"name" binding:"max=255"`
+	SubDomain string `json:"sub_domain" binding:"required"`
+}
+
+// ImportProjectRequest is the form sent with an archive file. Name defaults to the exported project's name.
+type ImportProjectRequest struct {
+	Name      string `form:"name" binding:"max=255"`
+	SubDomain string `form:"sub_domain" binding:"required"`
+}
+
 type Payload struct {
 	Name        string
 	Description string
@@ -27,13 +48,89 @@ type Payload struct {
 }
 
 type PublishProjectPayload struct {
-	Published bool
+	Published   bool
+	PublishAt   *time.Time
+	UnpublishAt *time.Time
 }
 
 func (r CreateUpdateProjectRequest) ToServicePayload() Payload {
 	return Payload(r)
 }
 
+type DuplicatePayload struct {
+	Name      string
+	SubDomain string
+}
+
+func (r DuplicateProjectRequest) ToDuplicatePayload() DuplicatePayload {
+	return DuplicatePayload(r)
+}
+
+func (r ImportProjectRequest) ToDuplicatePayload() DuplicatePayload {
+	return DuplicatePayload(r)
+}
+
 func (r PublishProjectRequest) ToPublishServicePayload() PublishProjectPayload {
 	return PublishProjectPayload(r)
 }
+
+type RevisionChange struct {
+	Path string `json:"path"`
+	Op   string `json:"op"`
+	From any    `json:"from,omitempty"`
+	To   any    `json:"to,omitempty"`
+}
+
+type RevisionDiff struct {
+	From    int              `json:"from"`
+	To      int              `json:"to"`
+	Changes []RevisionChange `json:"changes"`
+}
+
+type AddDomainRequest struct {
+	Host string `json:"host" binding:"required,max=253"`
+}
+
+// DNSRecord is what the owner has to publish for a custom domain to verify.
+type DNSRecord struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type DomainResponse struct {
+	models.ProjectDomain
+	VerificationRecord DNSRecord `json:"verification_record"`
+}
+
+// SubDomainHistoryResponse is a previous subdomain. Other users can claim it once HeldUntil has passed.
+type SubDomainHistoryResponse struct {
+	SubDomain  string    `json:"sub_domain"`
+	ReleasedAt time.Time `json:"released_at"`
+	HeldUntil  time.Time `json:"held_until"`
+}
+
+type SubDomainRedirectResponse struct {
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	ProjectID  uint64    `json:"project_id"`
+	ReleasedAt time.Time `json:"released_at"`
+}
+
+// SubDomainCheck is the verdict on a subdomain. Suggestions are only filled in when it can't be used.
+type SubDomainCheck struct {
+	SubDomain   string             `json:"sub_domain"`
+	Available   bool               `json:"available"`
+	Reasons     []subdomain.Reason `json:"reasons"`
+	Suggestions []string           `json:"suggestions"`
+}
+
+// TrashedProjectResponse is a deleted project that can still be restored until PurgeAt.
+type TrashedProjectResponse struct {
+	models.Project
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+type RestoreProjectRequest struct {
+	SubDomain string `json:"sub_domain"`
+}
@@ -0,0 +1,543 @@
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flash/models"
+	objectStorage "flash/sdk/object_storage"
+	"flash/utils"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Duplicate copies a project's draft content into a new, unpublished project on the given subdomain. Uploaded
+// files are copied too, so the two projects never share storage objects. Visitor data such as waitlist signups,
+// analytics and revisions stays with the original.
+func (service Service) Duplicate(sourceID uint64, userID uint64, payload DuplicatePayload) (*models.Project, error) {
+	var source models.Project
+	if err := service.DB.First(&source, sourceID).Error; err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		name = source.Name + " (copy)"
+	}
+
+	return service.duplicateProject(&source, userID, source.WorkspaceID, name, payload.SubDomain)
+}
+
+func (service Service) duplicateProject(source *models.Project, userID uint64, workspaceID *uint64, name string, subDomain string) (*models.Project, error) {
+	if err := hydrateContent(service.DB, source.Type).First(source, source.ID).Error; err != nil {
+		return nil, err
+	}
+
+	// The poster is rendered from the menu and belongs to the original; the copy renders its own.
+	if source.Menu != nil {
+		source.Menu.DisplayPosterImageURL = nil
+	}
+
+	return service.insertCopy(source, userID, workspaceID, name, subDomain, newObjectCopier(service.ObjectStorage, source.ID))
+}
+
+// insertCopy creates a new, unpublished project holding source's content under fresh IDs. The rewriter points
+// asset URLs at the new project's own copies; whatever it uploaded is removed again if the insert fails.
+func (service Service) insertCopy(source *models.Project, userID uint64, workspaceID *uint64, name string, subDomain string, rewriter contentRewriter) (*models.Project, error) {
+	subDomain = strings.ToLower(strings.TrimSpace(subDomain))
+	if _, err := service.CheckDomain(subDomain, userID); err != nil {
+		return nil, err
+	}
+
+	var duplicate models.Project
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		slug, err := uniqueSlug(tx, name)
+		if err != nil {
+			return err
+		}
+
+		duplicate = models.Project{
+			UserID:      userID,
+			WorkspaceID: workspaceID,
+			Name:        name,
+			Description: source.Description,
+			Slug:        slug,
+			SubDomain:   &subDomain,
+			Type:        source.Type,
+		}
+		if err := tx.Omit(clause.Associations).Create(&duplicate).Error; err != nil {
+			return err
+		}
+
+		rewriter.setTarget(duplicate.ID)
+		return copyContent(tx, source, &duplicate, rewriter)
+	})
+	if err != nil {
+		rewriter.cleanup()
+		return nil, err
+	}
+
+	return &duplicate, nil
+}
+
+func copyContent(tx *gorm.DB, source *models.Project, target *models.Project, rewriter contentRewriter) error {
+	switch source.Type {
+	case "biz":
+		return copyBiz(tx, source.Biz, target, rewriter)
+	case "linktree":
+		return copyLinktree(tx, source.Linktree, target, rewriter)
+	case "menu":
+		return copyMenu(tx, source.Menu, target, rewriter)
+	case "waitlist":
+		return copyWaitlist(tx, source.Waitlist, target, rewriter)
+	default:
+		return copyPortfolio(tx, source.Portfolio, target, rewriter)
+	}
+}
+
+func copyPortfolio(tx *gorm.DB, portfolio *models.Portfolio, target *models.Project, rewriter contentRewriter) error {
+	if portfolio == nil {
+		return nil
+	}
+
+	portfolio.User = models.User{}
+	portfolio.Project = models.Project{}
+	if err := rewriter.rewriteAll(portfolio); err != nil {
+		return err
+	}
+
+	portfolio.ID = 0
+	portfolio.ProjectID = target.ID
+	portfolio.UserID = target.UserID
+	if err := tx.Omit(clause.Associations).Create(portfolio).Error; err != nil {
+		return err
+	}
+
+	for index := range portfolio.WorkExperiences {
+		portfolio.WorkExperiences[index].ID = 0
+		portfolio.WorkExperiences[index].PortfolioID = portfolio.ID
+	}
+	for index := range portfolio.Education {
+		portfolio.Education[index].ID = 0
+		portfolio.Education[index].PortfolioID = portfolio.ID
+	}
+	for index := range portfolio.Skills {
+		portfolio.Skills[index].ID = 0
+		portfolio.Skills[index].PortfolioID = portfolio.ID
+	}
+	for index := range portfolio.Showcases {
+		portfolio.Showcases[index].ID = 0
+		portfolio.Showcases[index].PortfolioID = portfolio.ID
+	}
+
+	if err := createRows(tx, portfolio.WorkExperiences); err != nil {
+		return err
+	}
+	if err := createRows(tx, portfolio.Education); err != nil {
+		return err
+	}
+	if err := createRows(tx, portfolio.Skills); err != nil {
+		return err
+	}
+	if err := createRows(tx, portfolio.Showcases); err != nil {
+		return err
+	}
+
+	// Technologies hang off showcases, which only have IDs now.
+	var technologies []models.ShowcaseTechnology
+	for _, showcase := range portfolio.Showcases {
+		showcaseID := showcase.ID
+		for _, technology := range showcase.ShowcaseTechnologies {
+			technology.ID = 0
+			technology.ShowcaseID = &showcaseID
+			technology.Showcase = nil
+			technology.Portfolio = nil
+			if technology.PortfolioID != nil {
+				technology.PortfolioID = &portfolio.ID
+			}
+			technologies = append(technologies, technology)
+		}
+	}
+	return createRows(tx, technologies)
+}
+
+func copyBiz(tx *gorm.DB, biz *models.Biz, target *models.Project, rewriter contentRewriter) error {
+	if biz == nil {
+		return nil
+	}
+
+	if err := rewriter.rewriteAll(biz); err != nil {
+		return err
+	}
+
+	biz.ID = 0
+	biz.ProjectID = target.ID
+	biz.UserID = target.UserID
+	if err := tx.Omit(clause.Associations).Create(biz).Error; err != nil {
+		return err
+	}
+
+	for index := range biz.Services {
+		biz.Services[index].ID = 0
+		biz.Services[index].BizID = biz.ID
+	}
+	for index := range biz.Products {
+		biz.Products[index].ID = 0
+		biz.Products[index].BizID = biz.ID
+	}
+	for index := range biz.Testimonials {
+		biz.Testimonials[index].ID = 0
+		biz.Testimonials[index].BizID = biz.ID
+	}
+	for index := range biz.SocialLinks {
+		biz.SocialLinks[index].ID = 0
+		biz.SocialLinks[index].BizID = biz.ID
+	}
+	for index := range biz.FAQs {
+		biz.FAQs[index].ID = 0
+		biz.FAQs[index].BizID = biz.ID
+	}
+	for index := range biz.Gallery {
+		biz.Gallery[index].ID = 0
+		biz.Gallery[index].BizID = biz.ID
+	}
+
+	if err := createRows(tx, biz.Services); err != nil {
+		return err
+	}
+	if err := createRows(tx, biz.Products); err != nil {
+		return err
+	}
+	if err := createRows(tx, biz.Testimonials); err != nil {
+		return err
+	}
+	if err := createRows(tx, biz.SocialLinks); err != nil {
+		return err
+	}
+	if err := createRows(tx, biz.FAQs); err != nil {
+		return err
+	}
+	return createRows(tx, biz.Gallery)
+}
+
+func copyLinktree(tx *gorm.DB, linktree *models.Linktree, target *models.Project, rewriter contentRewriter) error {
+	if linktree == nil {
+		return nil
+	}
+
+	if err := rewriter.rewriteAll(linktree); err != nil {
+		return err
+	}
+
+	linktree.ID = 0
+	linktree.ProjectID = target.ID
+	linktree.UserID = target.UserID
+	if err := tx.Omit(clause.Associations).Create(linktree).Error; err != nil {
+		return err
+	}
+
+	// Sections are stored alongside links, so this copies both.
+	for index := range linktree.Links {
+		linktree.Links[index].ID = 0
+		linktree.Links[index].LinktreeID = linktree.ID
+	}
+	return createRows(tx, linktree.Links)
+}
+
+func copyMenu(tx *gorm.DB, menu *models.Menu, target *models.Project, rewriter contentRewriter) error {
+	if menu == nil {
+		return nil
+	}
+
+	if err := rewriter.rewriteAll(menu); err != nil {
+		return err
+	}
+
+	menu.ID = 0
+	menu.ProjectID = target.ID
+	menu.UserID = target.UserID
+	if err := tx.Omit(clause.Associations).Create(menu).Error; err != nil {
+		return err
+	}
+
+	categoryIDs := make(map[uint64]uint64, len(menu.Categories))
+	oldCategoryIDs := make([]uint64, len(menu.Categories))
+	for index := range menu.Categories {
+		// Client keys only tie items to categories while editing; fresh ones keep imported copies from clashing.
+		clientKey, err := randomToken()
+		if err != nil {
+			return err
+		}
+		oldCategoryIDs[index] = menu.Categories[index].ID
+		menu.Categories[index].ID = 0
+		menu.Categories[index].MenuID = menu.ID
+		menu.Categories[index].ClientKey = "category-" + clientKey
+	}
+	if err := createRows(tx, menu.Categories); err != nil {
+		return err
+	}
+	for index, category := range menu.Categories {
+		categoryIDs[oldCategoryIDs[index]] = category.ID
+	}
+
+	for index := range menu.Items {
+		menu.Items[index].ID = 0
+		menu.Items[index].MenuID = menu.ID
+		menu.Items[index].MenuCategoryID = categoryIDs[menu.Items[index].MenuCategoryID]
+	}
+	return createRows(tx, menu.Items)
+}
+
+func copyWaitlist(tx *gorm.DB, waitlist *models.Waitlist, target *models.Project, rewriter contentRewriter) error {
+	if waitlist == nil {
+		return nil
+	}
+
+	if err := rewriter.rewriteAll(waitlist); err != nil {
+		return err
+	}
+
+	waitlist.ID = 0
+	waitlist.ProjectID = target.ID
+	waitlist.UserID = target.UserID
+	waitlist.SignupCount = 0
+	return tx.Omit(clause.Associations).Create(waitlist).Error
+}
+
+// uniqueSlug appends -2, -3, ... to the slugified name until it is free. Soft-deleted projects still hold their
+// slug, so they are counted.
+func uniqueSlug(tx *gorm.DB, name string) (string, error) {
+	base := utils.Slugify(name, 200)
+	if base == "" {
+		base = "project"
+	}
+
+	slug := base
+	for suffix := 2; ; suffix++ {
+		var count int64
+		if err := tx.Unscoped().Model(&models.Project{}).Where("slug = ?", slug).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, suffix)
+	}
+}
+
+// contentRewriter points the asset URLs in copied content at the new project's own objects.
+type contentRewriter interface {
+	setTarget(projectID uint64)
+	rewriteAll(value any) error
+	cleanup()
+}
+
+// assetUploader stores assets under projects/<target>/ and remembers what it stored, so a failed copy can be
+// cleaned up and an asset referenced twice is stored once.
+type assetUploader struct {
+	storage  objectStorage.Provider
+	toPrefix string
+	copied   map[string]string
+	uploaded []string
+}
+
+func (uploader *assetUploader) setTarget(projectID uint64) {
+	uploader.toPrefix = fmt.Sprintf("projects/%d/", projectID)
+}
+
+func (uploader *assetUploader) upload(originalURL string, relativePath string, data []byte, contentType string) (string, error) {
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(relativePath))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	newPath := uploader.toPrefix + relativePath
+	url, err := uploader.storage.Upload(newPath, bytes.NewReader(data), contentType)
+	if err != nil {
+		return "", fmt.Errorf("upload %s: %w", newPath, err)
+	}
+
+	uploader.uploaded = append(uploader.uploaded, newPath)
+	uploader.copied[originalURL] = url
+	return url, nil
+}
+
+// cleanup removes what was uploaded when the copy could not be saved.
+func (uploader *assetUploader) cleanup() {
+	for _, uploaded := range uploader.uploaded {
+		if _, err := uploader.storage.Delete(uploaded); err != nil {
+			log.Printf("[WARN] Failed to remove copied object %s: %v", uploaded, err)
+		}
+	}
+}
+
+// objectCopier copies the objects a project's content points at from projects/<source>/ to projects/<target>/
+// and hands back the new URLs. URLs outside the source project's folder are kept as they are.
+type objectCopier struct {
+	assetUploader
+	base       string
+	fromPrefix string
+}
+
+func newObjectCopier(storage objectStorage.Provider, sourceID uint64) *objectCopier {
+	base, _ := storage.GetURL("")
+	return &objectCopier{
+		assetUploader: assetUploader{storage: storage, copied: map[string]string{}},
+		base:          base,
+		fromPrefix:    fmt.Sprintf("projects/%d/", sourceID),
+	}
+}
+
+// rewriteAll replaces every string in value, JSON columns included, that points at one of the source's objects.
+func (copier *objectCopier) rewriteAll(value any) error {
+	return rewriteStrings(reflect.ValueOf(value), copier.rewrite)
+}
+
+func (copier *objectCopier) rewrite(value string) (string, error) {
+	if copier.base == "" || !strings.HasPrefix(value, copier.base) {
+		return value, nil
+	}
+
+	objectPath := strings.TrimPrefix(strings.TrimPrefix(value, copier.base), "/")
+	if !strings.HasPrefix(objectPath, copier.fromPrefix) || strings.ContainsAny(objectPath, "?#") {
+		return value, nil
+	}
+	if url, ok := copier.copied[value]; ok {
+		return url, nil
+	}
+
+	content, err := copier.storage.Download(objectPath)
+	if errors.Is(err, objectStorage.ErrObjectNotFound) {
+		return value, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("download %s: %w", objectPath, err)
+	}
+	defer content.Close()
+
+	// Buffered because not every provider can upload from a stream of unknown length.
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", fmt.Errorf("download %s: %w", objectPath, err)
+	}
+
+	return copier.upload(value, strings.TrimPrefix(objectPath, copier.fromPrefix), data, "")
+}
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	timeType       = reflect.TypeOf(time.Time{})
+	deletedAtType  = reflect.TypeOf(gorm.DeletedAt{})
+)
+
+// rewriteStrings walks exported fields, slices and pointers and replaces each string with rewrite's result.
+// json.RawMessage values are decoded and walked as well.
+func rewriteStrings(value reflect.Value, rewrite func(string) (string, error)) error {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return rewriteStrings(value.Elem(), rewrite)
+	case reflect.Struct:
+		if value.Type() == timeType || value.Type() == deletedAtType {
+			return nil
+		}
+		for index := 0; index < value.NumField(); index++ {
+			if !value.Type().Field(index).IsExported() {
+				continue
+			}
+			if err := rewriteStrings(value.Field(index), rewrite); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		if value.Type() == rawMessageType {
+			return rewriteRawJSON(value, rewrite)
+		}
+		for index := 0; index < value.Len(); index++ {
+			if err := rewriteStrings(value.Index(index), rewrite); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		if !value.CanSet() {
+			return nil
+		}
+		rewritten, err := rewrite(value.String())
+		if err != nil {
+			return err
+		}
+		value.SetString(rewritten)
+	}
+	return nil
+}
+
+func rewriteRawJSON(value reflect.Value, rewrite func(string) (string, error)) error {
+	raw := value.Bytes()
+	if len(raw) == 0 || !value.CanSet() {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		// Not ours to fix; copy it as it is.
+		return nil
+	}
+
+	changed := false
+	rewritten, err := rewriteJSONValue(decoded, func(text string) (string, error) {
+		result, err := rewrite(text)
+		if result != text {
+			changed = true
+		}
+		return result, err
+	})
+	if err != nil || !changed {
+		return err
+	}
+
+	encoded, err := json.Marshal(rewritten)
+	if err != nil {
+		return err
+	}
+	value.SetBytes(encoded)
+	return nil
+}
+
+func rewriteJSONValue(value any, rewrite func(string) (string, error)) (any, error) {
+	switch typed := value.(type) {
+	case string:
+		return rewrite(typed)
+	case []any:
+		for index, item := range typed {
+			rewritten, err := rewriteJSONValue(item, rewrite)
+			if err != nil {
+				return nil, err
+			}
+			typed[index] = rewritten
+		}
+	case map[string]any:
+		for key, item := range typed {
+			rewritten, err := rewriteJSONValue(item, rewrite)
+			if err != nil {
+				return nil, err
+			}
+			typed[key] = rewritten
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,281 @@
+package project
+
+import (
+	"encoding/json"
+	"errors"
+	"flash/models"
+	objectStorage "flash/sdk/object_storage"
+	"flash/shared/testdb"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+const duplicateStorageBase = "https://cdn.test"
+
+func openDuplicateDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	return testdb.Open(t,
+		&models.User{}, &models.Project{}, &models.ProjectSubDomainHistory{}, &models.ReservedSubDomain{}, &models.ProjectTemplate{},
+		&models.Portfolio{}, &models.WorkExperience{}, &models.Education{}, &models.Showcase{}, &models.ShowcaseTechnology{}, &models.Skill{},
+		&models.Linktree{}, &models.LinktreeLink{},
+		&models.Menu{}, &models.MenuCategory{}, &models.MenuItem{},
+	)
+}
+
+// storeObjects uploads each path and returns its public URL.
+func storeObjects(t *testing.T, storage objectStorage.Provider, paths ...string) map[string]*string {
+	t.Helper()
+
+	urls := map[string]*string{}
+	for _, path := range paths {
+		url, err := storage.Upload(path, strings.NewReader("content of "+path), "image/png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls[path] = &url
+	}
+	return urls
+}
+
+func objectContent(t *testing.T, storage objectStorage.Provider, url string) string {
+	t.Helper()
+
+	content, err := storage.Download(strings.TrimPrefix(url, duplicateStorageBase+"/"))
+	if err != nil {
+		t.Fatalf("download %s: %v", url, err)
+	}
+	defer content.Close()
+
+	data, _ := io.ReadAll(content)
+	return string(data)
+}
+
+func TestDuplicateCopiesAMenu(t *testing.T) {
+	db := openDuplicateDB(t)
+	storage := objectStorage.NewInMemoryProvider(duplicateStorageBase)
+	service := NewService(db, storage)
+
+	urls := storeObjects(t, storage, "projects/1/menu/logo.png", "projects/1/menu/latte.png", "projects/1/menu/poster.png", "projects/1/menu/room.png")
+	elsewhere := "https://images.example.com/beans.png"
+	gallery := json.RawMessage(`[{"url":"` + *urls["projects/1/menu/room.png"] + `"},{"url":"` + elsewhere + `"}]`)
+	subDomain := "cafe"
+
+	testdb.Create(t, db,
+		&models.Project{ID: 1, UserID: 1, Name: "Cafe", Slug: "cafe", SubDomain: &subDomain, Type: "menu", Published: true},
+		&models.Menu{ID: 5, ProjectID: 1, UserID: 1, Name: "Cafe", LogoURL: urls["projects/1/menu/logo.png"],
+			DisplayPosterImageURL: urls["projects/1/menu/poster.png"], GalleryImages: &gallery},
+		&models.MenuCategory{ID: 7, MenuID: 5, ClientKey: "category-a", Name: "Coffee", PlacementOrder: 1},
+		&models.MenuCategory{ID: 8, MenuID: 5, ClientKey: "category-b", Name: "Pastry", PlacementOrder: 2},
+		&models.MenuItem{MenuID: 5, MenuCategoryID: 7, Name: "Latte", ImageURL: urls["projects/1/menu/latte.png"], PlacementOrder: 1},
+		&models.MenuItem{MenuID: 5, MenuCategoryID: 8, Name: "Croissant", ImageURL: &elsewhere, PlacementOrder: 2},
+		// The same object twice is copied once.
+		&models.MenuItem{MenuID: 5, MenuCategoryID: 7, Name: "Iced latte", ImageURL: urls["projects/1/menu/latte.png"], PlacementOrder: 3},
+	)
+
+	duplicate, err := service.Duplicate(1, 2, DuplicatePayload{SubDomain: "cafe-copy"})
+	if err != nil {
+		t.Fatalf("Duplicate() error = %v", err)
+	}
+	if duplicate.Name != "Cafe (copy)" || duplicate.Slug != "cafe-copy" || duplicate.UserID != 2 ||
+		duplicate.Published || *duplicate.SubDomain != "cafe-copy" {
+		t.Fatalf("duplicate = %+v, want an unpublished copy for user 2 on cafe-copy", duplicate)
+	}
+
+	var menu models.Menu
+	if err := db.Preload("Categories").Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("placement_order ASC")
+	}).Where("project_id = ?", duplicate.ID).First(&menu).Error; err != nil {
+		t.Fatal(err)
+	}
+	if menu.ID == 5 || menu.UserID != 2 || len(menu.Categories) != 2 || len(menu.Items) != 3 {
+		t.Fatalf("copied menu = %+v", menu)
+	}
+	if menu.DisplayPosterImageURL != nil {
+		t.Error("the copy kept the original's display poster")
+	}
+
+	// Items follow their category onto its copy, and the copies get client keys of their own.
+	categoryNames := map[uint64]string{}
+	for _, category := range menu.Categories {
+		if category.ID == 7 || category.ID == 8 || category.ClientKey == "category-a" || category.ClientKey == "category-b" {
+			t.Errorf("category %q kept the original's ID or client key", category.Name)
+		}
+		categoryNames[category.ID] = category.Name
+	}
+	for item, want := range map[string]string{"Latte": "Coffee", "Croissant": "Pastry", "Iced latte": "Coffee"} {
+		index := slices.IndexFunc(menu.Items, func(copied models.MenuItem) bool { return copied.Name == item })
+		if got := categoryNames[menu.Items[index].MenuCategoryID]; got != want {
+			t.Errorf("%s is in %q, want %q", item, got, want)
+		}
+	}
+
+	// Assets point at the copy's own objects; anything outside the source project is left alone.
+	copiedLogo := duplicateStorageBase + "/projects/2/menu/logo.png"
+	if *menu.LogoURL != copiedLogo || objectContent(t, storage, copiedLogo) != "content of projects/1/menu/logo.png" {
+		t.Errorf("logo = %s, want a copy at %s", *menu.LogoURL, copiedLogo)
+	}
+	if *menu.Items[0].ImageURL != duplicateStorageBase+"/projects/2/menu/latte.png" || *menu.Items[2].ImageURL != *menu.Items[0].ImageURL {
+		t.Errorf("latte images = %s, %s", *menu.Items[0].ImageURL, *menu.Items[2].ImageURL)
+	}
+	if *menu.Items[1].ImageURL != elsewhere {
+		t.Errorf("external image = %s, want it unchanged", *menu.Items[1].ImageURL)
+	}
+	wantGallery := `[{"url":"` + duplicateStorageBase + `/projects/2/menu/room.png"},{"url":"` + elsewhere + `"}]`
+	if string(*menu.GalleryImages) != wantGallery {
+		t.Errorf("gallery = %s, want %s", *menu.GalleryImages, wantGallery)
+	}
+	if _, ok := storage.Get("projects/2/menu/poster.png"); ok {
+		t.Error("the display poster was copied")
+	}
+
+	// The original is untouched.
+	var original models.Menu
+	if err := db.Preload("Items").First(&original, 5).Error; err != nil {
+		t.Fatal(err)
+	}
+	if *original.LogoURL != *urls["projects/1/menu/logo.png"] || original.DisplayPosterImageURL == nil || len(original.Items) != 3 {
+		t.Errorf("original menu changed: %+v", original)
+	}
+}
+
+func TestDuplicateCopiesAPortfolio(t *testing.T) {
+	db := openDuplicateDB(t)
+	storage := objectStorage.NewInMemoryProvider(duplicateStorageBase)
+	service := NewService(db, storage)
+
+	urls := storeObjects(t, storage, "projects/1/avatar.png")
+	empty, subDomain, showcaseID := "", "ada", uint64(4)
+	testdb.Create(t, db,
+		&models.User{ID: 1, FirstName: "Ada", Email: "ada@example.com", Password: &empty},
+		&models.Project{ID: 1, UserID: 1, Name: "Ada", Slug: "ada", SubDomain: &subDomain, Type: "portfolio"},
+		&models.Portfolio{ID: 3, ProjectID: 1, UserID: 1, Name: "Ada", AvatarURL: urls["projects/1/avatar.png"]},
+		&models.WorkExperience{PortfolioID: 3, Company: "Analytical Engines"},
+		&models.Skill{PortfolioID: 3, Name: "Go"},
+		&models.Showcase{ID: showcaseID, PortfolioID: 3, Name: "Notes"},
+		&models.ShowcaseTechnology{ShowcaseID: &showcaseID, Name: "Punch cards"},
+	)
+
+	duplicate, err := service.Duplicate(1, 1, DuplicatePayload{Name: "Ada again", SubDomain: "ada-again"})
+	if err != nil {
+		t.Fatalf("Duplicate() error = %v", err)
+	}
+
+	var portfolio models.Portfolio
+	if err := db.Preload("WorkExperiences").Preload("Skills").Preload("Showcases.ShowcaseTechnologies").
+		Where("project_id = ?", duplicate.ID).First(&portfolio).Error; err != nil {
+		t.Fatal(err)
+	}
+	if portfolio.ID == 3 || len(portfolio.WorkExperiences) != 1 || len(portfolio.Skills) != 1 || len(portfolio.Showcases) != 1 {
+		t.Fatalf("copied portfolio = %+v", portfolio)
+	}
+	showcase := portfolio.Showcases[0]
+	if showcase.ID == 4 || len(showcase.ShowcaseTechnologies) != 1 || showcase.ShowcaseTechnologies[0].Name != "Punch cards" {
+		t.Fatalf("copied showcase = %+v, want a new showcase with its technology", showcase)
+	}
+	if *portfolio.AvatarURL != duplicateStorageBase+"/projects/2/avatar.png" {
+		t.Errorf("avatar = %s, want the copy's own object", *portfolio.AvatarURL)
+	}
+
+	var technologies int64
+	db.Model(&models.ShowcaseTechnology{}).Count(&technologies)
+	if technologies != 2 {
+		t.Errorf("%d technologies, want the original's and the copy's", technologies)
+	}
+}
+
+func TestDuplicateRemovesCopiedObjectsWhenItFails(t *testing.T) {
+	db := openDuplicateDB(t)
+	storage := objectStorage.NewInMemoryProvider(duplicateStorageBase)
+	service := NewService(db, storage)
+
+	urls := storeObjects(t, storage, "projects/1/links/logo.png", "projects/1/links/card.png")
+	subDomain := "links"
+	testdb.Create(t, db,
+		&models.Project{ID: 1, UserID: 1, Name: "Links", Slug: "links", SubDomain: &subDomain, Type: "linktree"},
+		&models.Linktree{ID: 2, ProjectID: 1, UserID: 1, Name: "Links", LogoURL: urls["projects/1/links/logo.png"]},
+		&models.LinktreeLink{LinktreeID: 2, Title: "Card", URL: "https://example.com", ImageURL: urls["projects/1/links/card.png"]},
+	)
+
+	// The objects are copied before the links are inserted, which then fails.
+	if err := db.Migrator().DropTable(&models.LinktreeLink{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.Duplicate(1, 1, DuplicatePayload{SubDomain: "links-copy"}); err == nil {
+		t.Fatal("Duplicate() succeeded without a links table")
+	}
+
+	paths := storage.Paths()
+	slices.Sort(paths)
+	if want := []string{"projects/1/links/card.png", "projects/1/links/logo.png"}; !slices.Equal(paths, want) {
+		t.Errorf("storage holds %v, want the copies removed", paths)
+	}
+	var projects int64
+	db.Model(&models.Project{}).Count(&projects)
+	if projects != 1 {
+		t.Errorf("%d projects, want the copy rolled back", projects)
+	}
+}
+
+func TestDuplicateChecksTheSubDomain(t *testing.T) {
+	db := openDuplicateDB(t)
+	service := NewService(db, objectStorage.NewInMemoryProvider(duplicateStorageBase))
+	createLinktree(t, db, 1, "links", "Links")
+
+	if _, err := service.Duplicate(1, 1, DuplicatePayload{SubDomain: "links"}); err == nil {
+		t.Fatal("Duplicate() onto a taken subdomain succeeded")
+	}
+
+	var projects int64
+	db.Model(&models.Project{}).Count(&projects)
+	if projects != 1 {
+		t.Errorf("%d projects, want no copy", projects)
+	}
+}
+
+func TestCreateFromTemplate(t *testing.T) {
+	db := openDuplicateDB(t)
+	storage := objectStorage.NewInMemoryProvider(duplicateStorageBase)
+	service := NewService(db, storage)
+
+	urls := storeObjects(t, storage, "projects/1/links/logo.png")
+	subDomain, workspaceID, sourceID := "template-links", uint64(9), uint64(1)
+	testdb.Create(t, db,
+		&models.Project{ID: 1, UserID: 1, WorkspaceID: &workspaceID, Name: "Template", Slug: "template-links", SubDomain: &subDomain, Type: "linktree"},
+		&models.Linktree{ProjectID: 1, UserID: 1, Name: "Template", LogoURL: urls["projects/1/links/logo.png"]},
+		&models.ProjectTemplate{Key: "creator-links", Name: "Creator links", Type: "linktree", SourceProjectID: &sourceID},
+		&models.ProjectTemplate{Key: "retired", Name: "Retired", Type: "linktree", SourceProjectID: &sourceID},
+		&models.ProjectTemplate{Key: "empty", Name: "Empty", Type: "linktree"},
+	)
+	if err := db.Model(&models.ProjectTemplate{}).Where("`key` = ?", "retired").Update("is_active", false).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"missing", "retired", "empty"} {
+		if _, err := service.CreateFromTemplate(2, key, DuplicatePayload{SubDomain: "bobs-links"}); !errors.Is(err, ErrTemplateNotFound) {
+			t.Errorf("CreateFromTemplate(%q) error = %v, want %v", key, err, ErrTemplateNotFound)
+		}
+	}
+
+	// User 2 has no access to the template's project, and the copy is theirs alone.
+	project, err := service.CreateFromTemplate(2, "creator-links", DuplicatePayload{SubDomain: "bobs-links"})
+	if err != nil {
+		t.Fatalf("CreateFromTemplate() error = %v", err)
+	}
+	if project.Name != "Creator links" || project.UserID != 2 || project.WorkspaceID != nil || project.Type != "linktree" {
+		t.Fatalf("project = %+v, want a personal copy named after the template", project)
+	}
+
+	var linktree models.Linktree
+	if err := db.Where("project_id = ?", project.ID).First(&linktree).Error; err != nil {
+		t.Fatal(err)
+	}
+	if linktree.UserID != 2 || *linktree.LogoURL != duplicateStorageBase+"/projects/2/links/logo.png" {
+		t.Errorf("linktree = %+v, want user 2's copy with its own logo", linktree)
+	}
+}
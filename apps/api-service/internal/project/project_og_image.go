@@ -0,0 +1,40 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"flash/internal/job"
+	"flash/models"
+	"fmt"
+)
+
+type OGImageJobPayload struct {
+	ProjectID int64 `json:"project_id"`
+}
+
+// EnqueueOGImage queues a screenshot of the project's public site. Saves in quick succession share one pending job.
+func (service Service) EnqueueOGImage(projectID int64, userID *uint64) (*models.Job, error) {
+	return job.NewService(service.DB).Enqueue(job.EnqueuePayload{
+		UserID:    userID,
+		Type:      job.TypeOGImage,
+		Reference: fmt.Sprintf("project:%d", projectID),
+		Payload:   OGImageJobPayload{ProjectID: projectID},
+	})
+}
+
+func (service Service) HandleOGImageJob(ctx context.Context, queued *models.Job) (any, error) {
+	var payload OGImageJobPayload
+	if err := json.Unmarshal(queued.Payload, &payload); err != nil {
+		return nil, err
+	}
+
+	project, err := service.SaveOGImage(payload.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"project_id":   project.ID,
+		"og_image_url": project.OGImageURL,
+	}, nil
+}
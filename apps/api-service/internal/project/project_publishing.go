@@ -0,0 +1,158 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flash/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const RevisionSourcePublish = "publish"
+
+var (
+	ErrInvalidPublishSchedule = errors.New("unpublish_at must be in the future and after publish_at")
+	ErrEmailNotVerified       = errors.New("verify your email address before publishing")
+	ErrProjectNotPublished    = errors.New("project is not published")
+)
+
+// requireVerifiedEmail gates every path that puts a site online, including scheduling one.
+func requireVerifiedEmail(db *gorm.DB, userID uint64) error {
+	var user models.User
+	if err := db.Select("id", "email_verified_at").First(&user, userID).Error; err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
+
+// promoteDraft snapshots the live rows, which act as the draft, and makes that snapshot what the public site serves.
+func promoteDraft(tx *gorm.DB, project *models.Project, userID *uint64) error {
+	revision, err := recordRevision(tx, project.ID, userID, RevisionSourcePublish, nil)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return tx.Model(project).Updates(map[string]any{
+		"published":             true,
+		"published_revision_id": revision.ID,
+		"published_at":          now,
+	}).Error
+}
+
+// loadPublishedContent swaps the project's builder content for the pinned published snapshot.
+// The project row itself (name, sub domain, OG image) always reflects the current values.
+func (service Service) loadPublishedContent(project *models.Project) error {
+	var revision models.ProjectRevision
+	if err := service.DB.First(&revision, *project.PublishedRevisionID).Error; err != nil {
+		return err
+	}
+
+	var snapshot models.Project
+	if err := json.Unmarshal(revision.Snapshot, &snapshot); err != nil {
+		return fmt.Errorf("failed to read published snapshot: %w", err)
+	}
+
+	project.Portfolio = snapshot.Portfolio
+	project.Biz = snapshot.Biz
+	project.Linktree = snapshot.Linktree
+	project.Menu = snapshot.Menu
+	project.Waitlist = snapshot.Waitlist
+
+	if project.Portfolio != nil {
+		if err := service.DB.First(&project.Portfolio.User, project.Portfolio.UserID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunScheduledPublishing applies every publish_at and unpublish_at that has come due.
+func (service Service) RunScheduledPublishing(ctx context.Context) error {
+	now := time.Now()
+	db := service.DB.WithContext(ctx)
+
+	var dueIDs []uint64
+	if err := db.Model(&models.Project{}).
+		Where("publish_at IS NOT NULL AND publish_at <= ?", now).
+		Pluck("id", &dueIDs).Error; err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, projectID := range dueIDs {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var project models.Project
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, projectID).Error; err != nil {
+				return err
+			}
+
+			if project.PublishAt == nil || project.PublishAt.After(now) {
+				return nil
+			}
+
+			if err := promoteDraft(tx, &project, nil); err != nil {
+				return err
+			}
+
+			return tx.Model(&project).Update("publish_at", nil).Error
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("publish project %d: %w", projectID, err))
+		}
+	}
+
+	if err := db.Model(&models.Project{}).
+		Where("unpublish_at IS NOT NULL AND unpublish_at <= ?", now).
+		Updates(map[string]any{
+			"published":    false,
+			"unpublish_at": nil,
+		}).Error; err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// PinLegacyPublished pins the draft of every site published before revisions existed. They have no snapshot
+// to serve until it runs, so the server runs it once at startup, before it takes requests.
+func (service Service) PinLegacyPublished(ctx context.Context) error {
+	db := service.DB.WithContext(ctx)
+
+	var legacyIDs []uint64
+	if err := db.Model(&models.Project{}).
+		Where("published = ? AND published_revision_id IS NULL", true).
+		Pluck("id", &legacyIDs).Error; err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, projectID := range legacyIDs {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var project models.Project
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, projectID).Error; err != nil {
+				return err
+			}
+
+			if !project.Published || project.PublishedRevisionID != nil {
+				return nil
+			}
+
+			return promoteDraft(tx, &project, nil)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pin published project %d: %w", projectID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
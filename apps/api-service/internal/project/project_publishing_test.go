@@ -0,0 +1,191 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"flash/models"
+	"flash/shared/access"
+	"flash/shared/testdb"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func openPublishingDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.Open(t, &models.Project{}, &models.ProjectRevision{}, &models.Linktree{}, &models.LinktreeLink{})
+}
+
+func createLinktree(t *testing.T, db *gorm.DB, id uint64, subDomain string, name string) {
+	t.Helper()
+	testdb.Create(t, db,
+		&models.Project{ID: id, Name: name, Slug: subDomain, SubDomain: &subDomain, Type: "linktree"},
+		&models.Linktree{ProjectID: id, Name: name},
+	)
+}
+
+func TestShowBySubDomainServesOnlyPublishedContent(t *testing.T) {
+	db := openPublishingDB(t)
+	service := NewService(db, nil)
+
+	createLinktree(t, db, 1, "draft", "Draft")
+	if _, err := service.ShowBySubDomain("draft"); !errors.Is(err, ErrProjectNotPublished) {
+		t.Fatalf("never published: error = %v, want ErrProjectNotPublished", err)
+	}
+
+	createLinktree(t, db, 2, "live", "Published")
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return promoteDraft(tx, &models.Project{ID: 2}, nil)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.Linktree{}).Where("project_id = ?", 2).Update("name", "Unsaved edit").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := service.ShowBySubDomain("live")
+	if err != nil {
+		t.Fatalf("published: error = %v", err)
+	}
+	if project.Linktree == nil || project.Linktree.Name != "Published" {
+		t.Fatalf("published: served %+v, want the published snapshot", project.Linktree)
+	}
+
+	if err := db.Model(&models.Project{}).Where("id = ?", 2).Update("published", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.ShowBySubDomain("live"); !errors.Is(err, ErrProjectNotPublished) {
+		t.Fatalf("unpublished: error = %v, want ErrProjectNotPublished", err)
+	}
+}
+
+func TestPinLegacyPublished(t *testing.T) {
+	db := openPublishingDB(t)
+	service := NewService(db, nil)
+
+	createLinktree(t, db, 1, "legacy", "Legacy")
+	if err := db.Model(&models.Project{}).Where("id = ?", 1).Update("published", true).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.PinLegacyPublished(context.Background()); err != nil {
+		t.Fatalf("PinLegacyPublished() error = %v", err)
+	}
+
+	project, err := service.ShowBySubDomain("legacy")
+	if err != nil {
+		t.Fatalf("ShowBySubDomain() error = %v", err)
+	}
+	if project.PublishedRevisionID == nil || project.Linktree == nil || project.Linktree.Name != "Legacy" {
+		t.Fatalf("served %+v, want the pinned draft", project)
+	}
+}
+
+func TestCreatePublishedPinsASnapshot(t *testing.T) {
+	db := openTrashDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.ProjectRevision{}); err != nil {
+		t.Fatal(err)
+	}
+	service := NewService(db, nil)
+
+	password := ""
+	verifiedAt := time.Now()
+	testdb.Create(t, db, &models.User{ID: 1, FirstName: "Owner", Email: "owner@example.com", Password: &password, EmailVerifiedAt: &verifiedAt})
+
+	project, err := service.Create(1, Payload{Name: "Shop", SubDomain: "shop", Type: "linktree", Published: true})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if project.PublishedRevisionID == nil {
+		t.Fatal("Create() published the project without a snapshot to serve")
+	}
+	if _, err := service.ShowBySubDomain("shop"); err != nil {
+		t.Fatalf("ShowBySubDomain() error = %v", err)
+	}
+}
+
+func TestPublishCanScheduleOnlyTheTakeDown(t *testing.T) {
+	db := openPublishingDB(t)
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatal(err)
+	}
+	service := NewService(db, nil)
+	owner := access.Actor{UserID: 1, Role: "user"}
+
+	createLinktree(t, db, 1, "live", "Published")
+	if err := db.Model(&models.Project{}).Where("id = ?", 1).Update("user_id", 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return promoteDraft(tx, &models.Project{ID: 1}, nil)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.Linktree{}).Where("project_id = ?", 1).Update("name", "Unsaved edit").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	unpublishAt := time.Now().Add(time.Hour)
+	project, err := service.Publish(owner, 1, PublishProjectPayload{UnpublishAt: &unpublishAt})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if !project.Published || project.UnpublishAt == nil {
+		t.Fatalf("Publish() = %+v, want published with a take-down scheduled", project)
+	}
+
+	served, err := service.ShowBySubDomain("live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if served.Linktree == nil || served.Linktree.Name != "Published" {
+		t.Fatalf("served %+v, want the snapshot published before the schedule", served.Linktree)
+	}
+
+	// There is nothing to take down on a site that isn't up.
+	if err := db.Model(&models.Project{}).Where("id = ?", 1).Update("published", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Publish(owner, 1, PublishProjectPayload{UnpublishAt: &unpublishAt}); !errors.Is(err, ErrProjectNotPublished) {
+		t.Fatalf("Publish() on an unpublished project: error = %v, want %v", err, ErrProjectNotPublished)
+	}
+}
+
+func TestPublishingRequiresAVerifiedEmail(t *testing.T) {
+	db := openTrashDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.ProjectRevision{}); err != nil {
+		t.Fatal(err)
+	}
+	service := NewService(db, nil)
+	owner := access.Actor{UserID: 1, Role: "user"}
+
+	password := ""
+	testdb.Create(t, db, &models.User{ID: 1, FirstName: "Owner", Email: "owner@example.com", Password: &password})
+
+	if _, err := service.Create(1, Payload{Name: "Shop", SubDomain: "shop", Type: "linktree", Published: true}); !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("Create() published: error = %v, want %v", err, ErrEmailNotVerified)
+	}
+
+	project, err := service.Create(1, Payload{Name: "Shop", SubDomain: "shop", Type: "linktree"})
+	if err != nil {
+		t.Fatalf("Create() as a draft: %v", err)
+	}
+
+	publishAt := time.Now().Add(time.Hour)
+	for name, payload := range map[string]PublishProjectPayload{
+		"now":       {Published: true},
+		"scheduled": {PublishAt: &publishAt},
+	} {
+		if _, err := service.Publish(owner, int(project.ID), payload); !errors.Is(err, ErrEmailNotVerified) {
+			t.Fatalf("Publish() %s: error = %v, want %v", name, err, ErrEmailNotVerified)
+		}
+	}
+
+	if err := db.Model(&models.User{}).Where("id = ?", 1).Update("email_verified_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Publish(owner, int(project.ID), PublishProjectPayload{Published: true}); err != nil {
+		t.Fatalf("Publish() once verified: %v", err)
+	}
+}
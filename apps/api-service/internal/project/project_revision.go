@@ -0,0 +1,586 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flash/models"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	RevisionSourceBaseline = "baseline"
+	RevisionSourceSave     = "save"
+	RevisionSourceRestore  = "restore"
+)
+
+var (
+	ErrRevisionNotFound     = errors.New("revision not found")
+	ErrRevisionTypeMismatch = errors.New("revision belongs to a different project type")
+)
+
+// revisionRetention is how many of a project's newest revisions pruning keeps. The published one is always kept.
+const revisionRetention = 50
+
+// Timestamps change on every save, so they are left out of diffs.
+var revisionDiffIgnoredKeys = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"deleted_at": true,
+}
+
+// SaveWithRevision runs save and records the content it leaves behind as a revision, all in one transaction,
+// so an edit never lands without its history. A project with no history yet first gets a baseline revision
+// of its content as it was before the save, so the first edit can be diffed and undone too.
+func SaveWithRevision(db *gorm.DB, projectID uint64, userID *uint64, save func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Saves to the same project queue up here, so two first saves can't both take the baseline.
+		var project models.Project
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, projectID).Error; err != nil {
+			return err
+		}
+
+		var revisions int64
+		if err := tx.Model(&models.ProjectRevision{}).Where("project_id = ?", projectID).Count(&revisions).Error; err != nil {
+			return err
+		}
+		if revisions == 0 {
+			hasContent, err := hasContent(tx, &project)
+			if err != nil {
+				return err
+			}
+			if hasContent {
+				if _, err := recordRevision(tx, projectID, nil, RevisionSourceBaseline, nil); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := save(tx); err != nil {
+			return err
+		}
+
+		_, err := recordRevision(tx, projectID, userID, RevisionSourceSave, nil)
+		return err
+	})
+}
+
+func (service Service) ListRevisions(projectID uint64, page int, limit int) ([]models.ProjectRevision, int64, error) {
+	var revisions []models.ProjectRevision
+	var total int64
+
+	query := service.DB.Model(&models.ProjectRevision{}).Where("project_id = ?", projectID)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.
+		Omit("snapshot").
+		Order("revision DESC").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&revisions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return revisions, total, nil
+}
+
+func (service Service) ShowRevision(projectID uint64, revisionID uint64) (*models.ProjectRevision, error) {
+	var revision models.ProjectRevision
+
+	err := service.DB.Where("project_id = ? AND id = ?", projectID, revisionID).First(&revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+func (service Service) DiffRevisions(projectID uint64, fromID uint64, toID uint64) (*RevisionDiff, error) {
+	from, err := service.ShowRevision(projectID, fromID)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := service.ShowRevision(projectID, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	var fromValue, toValue any
+	if err := json.Unmarshal(from.Snapshot, &fromValue); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(to.Snapshot, &toValue); err != nil {
+		return nil, err
+	}
+
+	fromFields := map[string]any{}
+	toFields := map[string]any{}
+	flattenSnapshot("", fromValue, fromFields)
+	flattenSnapshot("", toValue, toFields)
+
+	changes := make([]RevisionChange, 0)
+	for path, before := range fromFields {
+		after, ok := toFields[path]
+		if !ok {
+			changes = append(changes, RevisionChange{Path: path, Op: "removed", From: before})
+			continue
+		}
+		if !reflect.DeepEqual(before, after) {
+			changes = append(changes, RevisionChange{Path: path, Op: "changed", From: before, To: after})
+		}
+	}
+	for path, after := range toFields {
+		if _, ok := fromFields[path]; !ok {
+			changes = append(changes, RevisionChange{Path: path, Op: "added", To: after})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return &RevisionDiff{
+		From:    from.Revision,
+		To:      to.Revision,
+		Changes: changes,
+	}, nil
+}
+
+// RestoreRevision replaces the project's content with the snapshot and records the result as a new revision,
+// all in one transaction.
+func (service Service) RestoreRevision(projectID uint64, revisionID uint64, userID *uint64) (*models.ProjectRevision, error) {
+	revision, err := service.ShowRevision(projectID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot models.Project
+	if err := json.Unmarshal(revision.Snapshot, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to read revision snapshot: %w", err)
+	}
+
+	var restored *models.ProjectRevision
+	err = service.DB.Transaction(func(tx *gorm.DB) error {
+		var project models.Project
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, projectID).Error; err != nil {
+			return err
+		}
+
+		if project.Type != revision.Type {
+			return ErrRevisionTypeMismatch
+		}
+
+		if err := restoreContent(tx, projectID, &snapshot); err != nil {
+			return err
+		}
+
+		restored, err = recordRevision(tx, projectID, userID, RevisionSourceRestore, &revision.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+func recordRevision(tx *gorm.DB, projectID uint64, userID *uint64, source string, restoredFromID *uint64) (*models.ProjectRevision, error) {
+	var project models.Project
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, projectID).Error; err != nil {
+		return nil, err
+	}
+
+	snapshot, err := contentSnapshot(tx, &project)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest int
+	if err := tx.Model(&models.ProjectRevision{}).
+		Where("project_id = ?", projectID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&latest).Error; err != nil {
+		return nil, err
+	}
+
+	revision := models.ProjectRevision{
+		ProjectID:      projectID,
+		UserID:         userID,
+		Revision:       latest + 1,
+		Type:           project.Type,
+		Source:         source,
+		RestoredFromID: restoredFromID,
+		Snapshot:       snapshot,
+	}
+
+	if err := tx.Create(&revision).Error; err != nil {
+		return nil, err
+	}
+
+	revision.Snapshot = nil
+	return &revision, nil
+}
+
+// contentSnapshot loads the project's current content and encodes it the way revisions store it.
+func contentSnapshot(tx *gorm.DB, project *models.Project) ([]byte, error) {
+	if err := hydrateContent(tx, project.Type).First(project, project.ID).Error; err != nil {
+		return nil, err
+	}
+
+	// Owner details are not content and must never end up in the history.
+	project.User = nil
+	if project.Portfolio != nil {
+		project.Portfolio.User = models.User{}
+	}
+
+	return json.Marshal(project)
+}
+
+// RunRevisionPruning drops revisions past the retention of every project, then releases the objects that only
+// the dropped revisions still pointed at.
+func (service Service) RunRevisionPruning(ctx context.Context) error {
+	db := service.DB.WithContext(ctx)
+
+	var projectIDs []uint64
+	if err := db.Model(&models.ProjectRevision{}).
+		Group("project_id").
+		Having("COUNT(*) > ?", revisionRetention).
+		Pluck("project_id", &projectIDs).Error; err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, projectID := range projectIDs {
+		if err := service.pruneRevisions(db, projectID); err != nil {
+			errs = append(errs, fmt.Errorf("prune revisions of project %d: %w", projectID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (service Service) pruneRevisions(db *gorm.DB, projectID uint64) error {
+	var pruned []models.ProjectRevision
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Trashed projects keep their history until the purge, and may still be restored.
+		var project models.Project
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, projectID).Error; err != nil {
+			return err
+		}
+
+		var oldestKept []int
+		if err := tx.Model(&models.ProjectRevision{}).
+			Where("project_id = ?", projectID).
+			Order("revision DESC").
+			Offset(revisionRetention-1).
+			Limit(1).
+			Pluck("revision", &oldestKept).Error; err != nil {
+			return err
+		}
+		if len(oldestKept) == 0 {
+			return nil
+		}
+
+		query := tx.Where("project_id = ? AND revision < ?", projectID, oldestKept[0])
+		if project.PublishedRevisionID != nil {
+			query = query.Where("id <> ?", *project.PublishedRevisionID)
+		}
+		if err := query.Find(&pruned).Error; err != nil {
+			return err
+		}
+		if len(pruned) == 0 {
+			return nil
+		}
+
+		ids := make([]uint64, 0, len(pruned))
+		for _, revision := range pruned {
+			ids = append(ids, revision.ID)
+		}
+		return tx.Delete(&models.ProjectRevision{}, ids).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	base, _ := service.ObjectStorage.GetURL("")
+	var urls []string
+	seen := map[string]bool{}
+	for _, revision := range pruned {
+		var snapshot models.Project
+		if err := json.Unmarshal(revision.Snapshot, &snapshot); err != nil {
+			return fmt.Errorf("failed to read revision snapshot: %w", err)
+		}
+		if err := rewriteStrings(reflect.ValueOf(&snapshot), func(value string) (string, error) {
+			if archiveObjectPath(value, base) != "" && !seen[value] {
+				seen[value] = true
+				urls = append(urls, value)
+			}
+			return value, nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	// releaseAsset keeps anything the content or a remaining revision still uses.
+	var errs []error
+	for _, url := range urls {
+		if err := releaseAsset(db, service.ObjectStorage, projectID, url); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// hasContent reports whether the builder has saved anything for the project yet.
+func hasContent(tx *gorm.DB, project *models.Project) (bool, error) {
+	var root any
+	switch project.Type {
+	case "biz":
+		root = &models.Biz{}
+	case "linktree":
+		root = &models.Linktree{}
+	case "menu":
+		root = &models.Menu{}
+	case "waitlist":
+		root = &models.Waitlist{}
+	default:
+		root = &models.Portfolio{}
+	}
+
+	var count int64
+	err := tx.Model(root).Where("project_id = ?", project.ID).Count(&count).Error
+	return count > 0, err
+}
+
+func restoreContent(tx *gorm.DB, projectID uint64, snapshot *models.Project) error {
+	switch snapshot.Type {
+	case "biz":
+		return restoreBiz(tx, projectID, snapshot.Biz)
+	case "linktree":
+		return restoreLinktree(tx, projectID, snapshot.Linktree)
+	case "menu":
+		return restoreMenu(tx, projectID, snapshot.Menu)
+	case "waitlist":
+		return restoreWaitlist(tx, projectID, snapshot.Waitlist)
+	default:
+		return restorePortfolio(tx, projectID, snapshot.Portfolio)
+	}
+}
+
+func restorePortfolio(tx *gorm.DB, projectID uint64, portfolio *models.Portfolio) error {
+	if portfolio == nil {
+		return nil
+	}
+
+	portfolioIDs, err := rootIDs(tx, &models.Portfolio{}, projectID)
+	if err != nil {
+		return err
+	}
+
+	if len(portfolioIDs) > 0 {
+		showcaseIDs := tx.Unscoped().Model(&models.Showcase{}).Select("id").Where("portfolio_id IN ?", portfolioIDs)
+		if err := tx.Unscoped().
+			Where("showcase_id IN (?) OR portfolio_id IN ?", showcaseIDs, portfolioIDs).
+			Delete(&models.ShowcaseTechnology{}).Error; err != nil {
+			return err
+		}
+
+		for _, model := range []any{&models.Showcase{}, &models.WorkExperience{}, &models.Education{}, &models.Skill{}} {
+			if err := tx.Unscoped().Where("portfolio_id IN ?", portfolioIDs).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := saveRoot(tx, &models.Portfolio{}, projectID, portfolio.ID, portfolio); err != nil {
+		return err
+	}
+
+	var technologies []models.ShowcaseTechnology
+	for _, showcase := range portfolio.Showcases {
+		technologies = append(technologies, showcase.ShowcaseTechnologies...)
+	}
+
+	if err := createRows(tx, portfolio.WorkExperiences); err != nil {
+		return err
+	}
+	if err := createRows(tx, portfolio.Education); err != nil {
+		return err
+	}
+	if err := createRows(tx, portfolio.Showcases); err != nil {
+		return err
+	}
+	if err := createRows(tx, technologies); err != nil {
+		return err
+	}
+	return createRows(tx, portfolio.Skills)
+}
+
+func restoreBiz(tx *gorm.DB, projectID uint64, biz *models.Biz) error {
+	if biz == nil {
+		return nil
+	}
+
+	bizIDs, err := rootIDs(tx, &models.Biz{}, projectID)
+	if err != nil {
+		return err
+	}
+
+	if len(bizIDs) > 0 {
+		for _, model := range []any{&models.Service{}, &models.Product{}, &models.Testimonial{}, &models.BizSocialLink{}, &models.BizFAQ{}, &models.BizGallery{}} {
+			if err := tx.Unscoped().Where("biz_id IN ?", bizIDs).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := saveRoot(tx, &models.Biz{}, projectID, biz.ID, biz); err != nil {
+		return err
+	}
+
+	if err := createRows(tx, biz.Services); err != nil {
+		return err
+	}
+	if err := createRows(tx, biz.Products); err != nil {
+		return err
+	}
+	if err := createRows(tx, biz.Testimonials); err != nil {
+		return err
+	}
+	if err := createRows(tx, biz.SocialLinks); err != nil {
+		return err
+	}
+	if err := createRows(tx, biz.FAQs); err != nil {
+		return err
+	}
+	return createRows(tx, biz.Gallery)
+}
+
+func restoreLinktree(tx *gorm.DB, projectID uint64, linktree *models.Linktree) error {
+	if linktree == nil {
+		return nil
+	}
+
+	linktreeIDs, err := rootIDs(tx, &models.Linktree{}, projectID)
+	if err != nil {
+		return err
+	}
+
+	if len(linktreeIDs) > 0 {
+		if err := tx.Unscoped().Where("linktree_id IN ?", linktreeIDs).Delete(&models.LinktreeLink{}).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := saveRoot(tx, &models.Linktree{}, projectID, linktree.ID, linktree); err != nil {
+		return err
+	}
+
+	return createRows(tx, linktree.Links)
+}
+
+func restoreMenu(tx *gorm.DB, projectID uint64, menu *models.Menu) error {
+	if menu == nil {
+		return nil
+	}
+
+	menuIDs, err := rootIDs(tx, &models.Menu{}, projectID)
+	if err != nil {
+		return err
+	}
+
+	if len(menuIDs) > 0 {
+		if err := tx.Unscoped().Where("menu_id IN ?", menuIDs).Delete(&models.MenuItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("menu_id IN ?", menuIDs).Delete(&models.MenuCategory{}).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := saveRoot(tx, &models.Menu{}, projectID, menu.ID, menu); err != nil {
+		return err
+	}
+
+	// Items point at categories, so categories go in first.
+	if err := createRows(tx, menu.Categories); err != nil {
+		return err
+	}
+	return createRows(tx, menu.Items)
+}
+
+func restoreWaitlist(tx *gorm.DB, projectID uint64, waitlist *models.Waitlist) error {
+	if waitlist == nil {
+		return nil
+	}
+
+	return saveRoot(tx, &models.Waitlist{}, projectID, waitlist.ID, waitlist)
+}
+
+func rootIDs(tx *gorm.DB, model any, projectID uint64) ([]uint64, error) {
+	var ids []uint64
+	err := tx.Unscoped().Model(model).Where("project_id = ?", projectID).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// saveRoot writes the snapshot row back under its original ID, reviving it if it was soft deleted,
+// and soft deletes any other root the project has picked up since.
+func saveRoot(tx *gorm.DB, model any, projectID uint64, rootID uint64, root any) error {
+	if err := tx.Where("project_id = ? AND id <> ?", projectID, rootID).Delete(model).Error; err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Omit(clause.Associations).Save(root).Error
+}
+
+func createRows[T any](tx *gorm.DB, rows []T) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return tx.Omit(clause.Associations).Create(&rows).Error
+}
+
+// flattenSnapshot turns a decoded snapshot into path/value pairs. Array entries that carry an id are keyed
+// by it, so reordering or inserting a row does not show up as a change to every row after it.
+func flattenSnapshot(path string, value any, fields map[string]any) {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, child := range typed {
+			if revisionDiffIgnoredKeys[key] {
+				continue
+			}
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			flattenSnapshot(childPath, child, fields)
+		}
+	case []any:
+		for index, child := range typed {
+			childPath := fmt.Sprintf("%s[%d]", path, index)
+			if row, ok := child.(map[string]any); ok {
+				if id, ok := row["id"]; ok {
+					childPath = fmt.Sprintf("%s[id=%v]", path, id)
+				}
+			}
+			flattenSnapshot(childPath, child, fields)
+		}
+	default:
+		fields[path] = typed
+	}
+}
@@ -0,0 +1,233 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"flash/models"
+	objectStorage "flash/sdk/object_storage"
+	"flash/shared/testdb"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func renameLinktree(name string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Model(&models.Linktree{}).Where("project_id = ?", 1).Update("name", name).Error
+	}
+}
+
+func revisionSources(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+
+	var sources []string
+	if err := db.Model(&models.ProjectRevision{}).Order("revision ASC").Pluck("source", &sources).Error; err != nil {
+		t.Fatal(err)
+	}
+	return sources
+}
+
+func TestSaveWithRevision(t *testing.T) {
+	db := openPublishingDB(t)
+	createLinktree(t, db, 1, "shop", "Before")
+	editorID := uint64(7)
+
+	if err := SaveWithRevision(db, 1, &editorID, renameLinktree("After")); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveWithRevision(db, 1, &editorID, renameLinktree("Again")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the first save takes a baseline of what was there before it.
+	want := []string{RevisionSourceBaseline, RevisionSourceSave, RevisionSourceSave}
+	if got := revisionSources(t, db); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("revision sources = %v, want %v", got, want)
+	}
+
+	// A failed save leaves neither the edit nor a revision behind.
+	failed := errors.New("upload failed")
+	err := SaveWithRevision(db, 1, &editorID, func(tx *gorm.DB) error {
+		if err := renameLinktree("Lost")(tx); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("SaveWithRevision() error = %v, want %v", err, failed)
+	}
+	if got := revisionSources(t, db); len(got) != 3 {
+		t.Fatalf("revisions after a failed save = %v, want 3", got)
+	}
+	var linktree models.Linktree
+	if err := db.Where("project_id = ?", 1).First(&linktree).Error; err != nil || linktree.Name != "Again" {
+		t.Fatalf("linktree after a failed save = %q, %v, want Again", linktree.Name, err)
+	}
+}
+
+func TestSaveWithRevisionSkipsTheBaselineForANewProject(t *testing.T) {
+	db := openPublishingDB(t)
+	testdb.Create(t, db, &models.Project{ID: 1, Name: "New", Slug: "new", Type: "linktree"})
+
+	err := SaveWithRevision(db, 1, nil, func(tx *gorm.DB) error {
+		return tx.Create(&models.Linktree{ProjectID: 1, Name: "First"}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := revisionSources(t, db); len(got) != 1 || got[0] != RevisionSourceSave {
+		t.Fatalf("revision sources = %v, want [save]", got)
+	}
+}
+
+func TestDiffAndRestoreRevisions(t *testing.T) {
+	db := openPublishingDB(t)
+	service := NewService(db, nil)
+	createLinktree(t, db, 1, "shop", "Before")
+	testdb.Create(t, db, &models.LinktreeLink{ID: 10, LinktreeID: 1, Title: "Menu"})
+
+	err := SaveWithRevision(db, 1, nil, func(tx *gorm.DB) error {
+		if err := renameLinktree("After")(tx); err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.LinktreeLink{}, 10).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.LinktreeLink{ID: 11, LinktreeID: 1, Title: "Shop"}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var revisions []models.ProjectRevision
+	if err := db.Order("revision ASC").Find(&revisions).Error; err != nil || len(revisions) != 2 {
+		t.Fatalf("revisions = %d, %v, want a baseline and a save", len(revisions), err)
+	}
+	baseline, saved := revisions[0], revisions[1]
+
+	diff, err := service.DiffRevisions(1, baseline.ID, saved.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := map[string]string{}
+	for _, change := range diff.Changes {
+		ops[change.Path] = change.Op
+	}
+	for path, op := range map[string]string{
+		"linktree.name":               "changed",
+		"linktree.links[id=10].title": "removed",
+		"linktree.links[id=11].title": "added",
+	} {
+		if ops[path] != op {
+			t.Errorf("diff %s = %q, want %q (all changes: %v)", path, ops[path], op, ops)
+		}
+	}
+	if _, ok := ops["linktree.updated_at"]; ok {
+		t.Error("diff includes timestamps")
+	}
+
+	editorID := uint64(7)
+	restored, err := service.RestoreRevision(1, baseline.ID, &editorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Revision != 3 || restored.Source != RevisionSourceRestore || restored.RestoredFromID == nil || *restored.RestoredFromID != baseline.ID {
+		t.Fatalf("RestoreRevision() = %+v", restored)
+	}
+
+	var linktree models.Linktree
+	if err := db.Preload("Links").Where("project_id = ?", 1).First(&linktree).Error; err != nil {
+		t.Fatal(err)
+	}
+	if linktree.Name != "Before" || len(linktree.Links) != 1 || linktree.Links[0].ID != 10 {
+		t.Fatalf("restored linktree = %q with %+v, want Before with link 10", linktree.Name, linktree.Links)
+	}
+
+	// Restoring is undoable like any other change.
+	diff, err = service.DiffRevisions(1, baseline.ID, restored.ID)
+	if err != nil || len(diff.Changes) != 0 {
+		t.Fatalf("baseline against its restore = %+v, %v, want no changes", diff, err)
+	}
+}
+
+func TestRestoreRevisionRejectsAnotherProjectType(t *testing.T) {
+	db := openPublishingDB(t)
+	service := NewService(db, nil)
+	createLinktree(t, db, 1, "shop", "Before")
+	if err := SaveWithRevision(db, 1, nil, renameLinktree("After")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.Project{}).Where("id = ?", 1).Update("type", "menu").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var baseline models.ProjectRevision
+	if err := db.Where("source = ?", RevisionSourceBaseline).First(&baseline).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.RestoreRevision(1, baseline.ID, nil); !errors.Is(err, ErrRevisionTypeMismatch) {
+		t.Fatalf("RestoreRevision() error = %v, want %v", err, ErrRevisionTypeMismatch)
+	}
+	if _, err := service.RestoreRevision(1, 999, nil); !errors.Is(err, ErrRevisionNotFound) {
+		t.Fatalf("unknown revision: error = %v, want %v", err, ErrRevisionNotFound)
+	}
+}
+
+func TestRunRevisionPruning(t *testing.T) {
+	const base = "https://cdn.test"
+	db := openPublishingDB(t)
+	storage := objectStorage.NewInMemoryProvider(base)
+	service := NewService(db, storage)
+
+	for _, path := range []string{"projects/1/old.png", "projects/1/pinned.png", "projects/1/kept.png", "projects/1/current.png"} {
+		if _, err := storage.Upload(path, strings.NewReader("image"), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logoURL := base + "/projects/1/current.png"
+	testdb.Create(t, db,
+		&models.Project{ID: 1, Name: "Links", Slug: "links", Type: "linktree"},
+		&models.Linktree{ProjectID: 1, Name: "Links", LogoURL: &logoURL},
+	)
+
+	logos := map[int]string{1: "old.png", 2: "pinned.png", 3: "kept.png", revisionRetention + 5: "kept.png"}
+	for revision := 1; revision <= revisionRetention+5; revision++ {
+		snapshot := `{"type":"linktree"}`
+		if logo, ok := logos[revision]; ok {
+			snapshot = fmt.Sprintf(`{"type":"linktree","linktree":{"logo_url":"%s/projects/1/%s"}}`, base, logo)
+		}
+		if err := db.Exec("INSERT INTO project_revisions (id, project_id, revision, type, snapshot) VALUES (?, ?, ?, ?, CAST(? AS BLOB))",
+			revision, 1, revision, "linktree", snapshot).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Model(&models.Project{}).Where("id = ?", 1).Update("published_revision_id", 2).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.RunRevisionPruning(context.Background()); err != nil {
+		t.Fatalf("RunRevisionPruning() error = %v", err)
+	}
+
+	var kept []int
+	if err := db.Model(&models.ProjectRevision{}).Order("revision ASC").Pluck("revision", &kept).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != revisionRetention+1 || kept[0] != 2 || kept[1] != 6 {
+		t.Fatalf("kept %d revisions starting %v, want the published one and the newest %d", len(kept), kept[:2], revisionRetention)
+	}
+
+	for path, stored := range map[string]bool{
+		"projects/1/old.png":     false,
+		"projects/1/pinned.png":  true,
+		"projects/1/kept.png":    true,
+		"projects/1/current.png": true,
+	} {
+		if _, ok := storage.Get(path); ok != stored {
+			t.Errorf("%s stored = %v, want %v", path, ok, stored)
+		}
+	}
+}
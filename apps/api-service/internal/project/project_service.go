@@ -5,17 +5,22 @@ import (
 	"errors"
 	"flash/models"
 	objectStorage "flash/sdk/object_storage"
+	"flash/shared/access"
 	"fmt"
 	"strings"
+	"time"
 
 	"flash/utils"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Service struct {
 	DB            *gorm.DB
 	ObjectStorage objectStorage.Provider
+	// Resolver verifies custom domains; nil uses the system resolver.
+	Resolver TXTResolver
 }
 
 type PublicStats struct {
@@ -45,7 +50,7 @@ func (service Service) List(userID *uint64, page int, limit int, projectType str
 
 	if userID != nil {
 		query := service.DB.
-			Where("user_id = ?", userID)
+			Where("user_id = ? OR workspace_id IN (?)", *userID, access.MemberWorkspaceIDs(service.DB, *userID))
 
 		if projectType != "" {
 			query = query.Where("type = ?", projectType)
@@ -110,10 +115,17 @@ func (service Service) PublicStats() (*PublicStats, error) {
 }
 
 func (service Service) Create(userID uint64, payload Payload) (*models.Project, error) {
-	if _, err := service.CheckDomain(payload.SubDomain); err != nil {
+	payload.SubDomain = strings.ToLower(strings.TrimSpace(payload.SubDomain))
+	if _, err := service.CheckDomain(payload.SubDomain, userID); err != nil {
 		return nil, err
 	}
 
+	if payload.Published {
+		if err := requireVerifiedEmail(service.DB, userID); err != nil {
+			return nil, err
+		}
+	}
+
 	slug := utils.Slugify(payload.Name, 0)
 	newProj := models.Project{
 		UserID:      userID,
@@ -125,24 +137,35 @@ func (service Service) Create(userID uint64, payload Payload) (*models.Project,
 		Published:   payload.Published,
 	}
 
-	if err := service.DB.Create(&newProj).Error; err != nil {
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newProj).Error; err != nil {
+			return err
+		}
+		if payload.Published {
+			return promoteDraft(tx, &newProj, &userID)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return &newProj, nil
 }
 
-func (service Service) Update(projectID int, payload Payload) (*models.Project, error) {
-	var existingProj models.Project
-	if err := service.DB.First(&existingProj, projectID).Error; err != nil {
+func (service Service) Update(actor access.Actor, projectID int, payload Payload) (*models.Project, error) {
+	proj, err := actor.Authorize(service.DB, uint64(projectID), access.PermissionWrite)
+	if err != nil {
 		return nil, err
 	}
+	existingProj := *proj
 
+	payload.SubDomain = strings.ToLower(strings.TrimSpace(payload.SubDomain))
 	oldSubDomain := *existingProj.SubDomain
-	isSubdomainChanging := oldSubDomain != payload.SubDomain
+	isSubdomainChanging := !strings.EqualFold(oldSubDomain, payload.SubDomain)
 
 	if isSubdomainChanging {
-		if _, err := service.CheckDomain(payload.SubDomain); err != nil {
+		if _, err := service.CheckDomain(payload.SubDomain, existingProj.UserID); err != nil {
 			return nil, err
 		}
 	}
@@ -152,40 +175,60 @@ func (service Service) Update(projectID int, payload Payload) (*models.Project,
 	existingProj.SubDomain = &payload.SubDomain
 	existingProj.Slug = utils.Slugify(payload.Name, 0)
 	existingProj.Type = payload.Type
+	isPublishing := payload.Published && !existingProj.Published
+	if isPublishing {
+		if err := requireVerifiedEmail(service.DB, existingProj.UserID); err != nil {
+			return nil, err
+		}
+	}
 	existingProj.Published = payload.Published
 
-	if err := service.DB.Save(&existingProj).Error; err != nil {
+	if err := service.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&existingProj).Error; err != nil {
+			return err
+		}
+		if isPublishing {
+			if err := promoteDraft(tx, &existingProj, &actor.UserID); err != nil {
+				return err
+			}
+		}
+		if isSubdomainChanging {
+			return recordSubDomainRelease(tx, &existingProj, oldSubDomain, time.Now())
+		}
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 
+	if isPublishing {
+		if err := service.DB.Transaction(func(tx *gorm.DB) error {
+			return promoteDraft(tx, &existingProj, nil)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
 	return &existingProj, nil
 }
 
-func (service Service) CheckDomain(subDomain string) (bool, error) {
-	subDomain = strings.ToLower(subDomain)
-
-	var reserved models.ReservedSubDomain
-	err := service.DB.Where("sub_domain = ?", subDomain).First(&reserved).Error
-
-	if err == nil {
-		return false, errors.New("this subdomain is reserved by the system")
-	}
-
-	var count int64
-	if err := service.DB.Model(&models.Project{}).
-		Where("sub_domain = ?", subDomain).
-		Count(&count).Error; err != nil {
+// CheckDomain reports whether userID can take subDomain, with the first reason as the error when it can't.
+func (service Service) CheckDomain(subDomain string, userID uint64) (bool, error) {
+	reasons, err := service.subDomainReasons(strings.ToLower(strings.TrimSpace(subDomain)), userID)
+	if err != nil {
 		return false, err
 	}
-
-	if count > 0 {
-		return false, errors.New("subdomain already taken")
+	if len(reasons) > 0 {
+		return false, errors.New(reasons[0].Message)
 	}
 
 	return true, nil
 }
 
-func (service Service) Show(projectID int) (*models.Project, error) {
+func (service Service) Show(actor access.Actor, projectID int) (*models.Project, error) {
+	if _, err := actor.Authorize(service.DB, uint64(projectID), access.PermissionRead); err != nil {
+		return nil, err
+	}
+
 	var proj models.Project
 
 	if err := service.DB.
@@ -255,6 +298,14 @@ func (service Service) ShowBySlug(slug string, level string) (*models.Project, e
 		normalizeLinktreeContent(&project)
 	}
 
+	if level == "full" && project.Type == "waitlist" {
+		if err := service.DB.
+			Preload("Waitlist").
+			First(&project, project.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+
 	if level == "full" && project.Type == "menu" {
 		if err := service.DB.
 			Preload("Menu").
@@ -279,88 +330,106 @@ func (service Service) ShowBySubDomain(subDomain string) (*models.Project, error
 		return nil, err
 	}
 
-	query := service.DB.Model(&models.Project{})
+	return service.showPublic(&project)
+}
 
-	switch project.Type {
-	case "biz":
-		query = query.
-			Preload("Biz").
-			Preload("Biz.Services", func(db *gorm.DB) *gorm.DB {
-				return db.Order("placement_order ASC")
-			}).
-			Preload("Biz.Products", func(db *gorm.DB) *gorm.DB {
-				return db.Order("placement_order ASC")
-			}).
-			Preload("Biz.Testimonials", func(db *gorm.DB) *gorm.DB {
-				return db.Order("placement_order ASC")
-			}).
-			Preload("Biz.SocialLinks").
-			Preload("Biz.FAQs", func(db *gorm.DB) *gorm.DB {
-				return db.Order("placement_order ASC")
-			}).
-			Preload("Biz.Gallery", func(db *gorm.DB) *gorm.DB {
-				return db.Order("placement_order ASC")
-			})
-	case "linktree":
-		query = query.
-			Preload("Linktree").
-			Preload("Linktree.Links", func(db *gorm.DB) *gorm.DB {
-				return db.Order("placement_order ASC")
-			})
-	case "menu":
-		query = query.
-			Preload("Menu").
-			Preload("Menu.Categories", func(db *gorm.DB) *gorm.DB {
-				return db.Order("placement_order ASC")
-			}).
-			Preload("Menu.Items", func(db *gorm.DB) *gorm.DB {
-				return db.Order("placement_order ASC")
-			})
-	default:
-		query = query.
-			Preload("Portfolio").
-			Preload("Portfolio.User").
-			Preload("Portfolio.WorkExperiences", func(db *gorm.DB) *gorm.DB {
-				return db.Order("placement_order ASC")
-			}).
-			Preload("Portfolio.Education", func(db *gorm.DB) *gorm.DB {
-				return db.Order("placement_order ASC")
-			}).
-			Preload("Portfolio.Showcases", func(db *gorm.DB) *gorm.DB {
-				return db.Order("placement_order ASC")
-			}).
-			Preload("Portfolio.Showcases.ShowcaseTechnologies").
-			Preload("Portfolio.Skills")
+// showPublic loads what a visitor sees: the published revision. A draft is never served publicly.
+func (service Service) showPublic(project *models.Project) (*models.Project, error) {
+	if !project.Published || project.PublishedRevisionID == nil {
+		return nil, ErrProjectNotPublished
 	}
 
-	if err := query.First(&project, project.ID).Error; err != nil {
+	return service.showContent(project)
+}
+
+// showContent loads the published revision if there is one, the draft otherwise.
+func (service Service) showContent(project *models.Project) (*models.Project, error) {
+	if project.Published && project.PublishedRevisionID != nil {
+		if err := service.loadPublishedContent(project); err != nil {
+			return nil, err
+		}
+	} else if err := hydrateContent(service.DB, project.Type).First(project, project.ID).Error; err != nil {
 		return nil, err
 	}
-	normalizeLinktreeContent(&project)
+	normalizeLinktreeContent(project)
 
-	return &project, nil
+	if project.Waitlist != nil {
+		if err := service.DB.Model(&models.WaitlistSignup{}).
+			Where("waitlist_id = ?", project.Waitlist.ID).
+			Count(&project.Waitlist.SignupCount).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return project, nil
 }
 
-func (service Service) Delete(projectID int) (*models.Project, error) {
-	var proj models.Project
-	if err := service.DB.First(&proj, projectID).Error; err != nil {
+func (service Service) Delete(actor access.Actor, projectID int) (*models.Project, error) {
+	proj, err := actor.Authorize(service.DB, uint64(projectID), access.PermissionManage)
+	if err != nil {
 		return nil, err
 	}
 
-	if err := service.DB.Delete(&proj).Error; err != nil {
+	if err := service.DB.Transaction(func(tx *gorm.DB) error {
+		return moveToTrash(tx, proj)
+	}); err != nil {
 		return nil, err
 	}
 
-	return &proj, nil
+	return proj, nil
 }
 
-func (service Service) Publish(projectID int, payload PublishProjectPayload) (*models.Project, error) {
-	var proj models.Project
-	if err := service.DB.First(&proj, projectID).Error; err != nil {
+// Publish promotes the current draft to the live site, takes the project offline, or schedules either.
+func (service Service) Publish(actor access.Actor, projectID int, payload PublishProjectPayload) (*models.Project, error) {
+	if _, err := actor.Authorize(service.DB, uint64(projectID), access.PermissionWrite); err != nil {
 		return nil, err
 	}
 
-	if err := service.DB.Model(&proj).Update("published", payload.Published).Error; err != nil {
+	now := time.Now()
+	userID := &actor.UserID
+
+	if payload.UnpublishAt != nil {
+		if !payload.UnpublishAt.After(now) || (payload.PublishAt != nil && !payload.UnpublishAt.After(*payload.PublishAt)) {
+			return nil, ErrInvalidPublishSchedule
+		}
+	}
+
+	var proj models.Project
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&proj, projectID).Error; err != nil {
+			return err
+		}
+
+		if payload.Published || payload.PublishAt != nil {
+			if err := requireVerifiedEmail(tx, actor.UserID); err != nil {
+				return err
+			}
+		}
+
+		updates := map[string]any{
+			"publish_at":   nil,
+			"unpublish_at": payload.UnpublishAt,
+		}
+
+		switch {
+		case payload.PublishAt != nil && payload.PublishAt.After(now):
+			updates["publish_at"] = payload.PublishAt
+		case payload.Published || payload.PublishAt != nil:
+			if err := promoteDraft(tx, &proj, userID); err != nil {
+				return err
+			}
+		case payload.UnpublishAt != nil:
+			// Only the take-down is being scheduled; what is live stays as it is until then.
+			if !proj.Published {
+				return ErrProjectNotPublished
+			}
+		default:
+			updates["published"] = false
+		}
+
+		return tx.Model(&proj).Updates(updates).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -404,6 +473,67 @@ func (service Service) SaveOGImage(projectID int64) (*models.Project, error) {
 	return &project, nil
 }
 
+// hydrateContent preloads the builder content for a project type, the way public sites render it.
+func hydrateContent(db *gorm.DB, projectType string) *gorm.DB {
+	query := db.Model(&models.Project{})
+
+	switch projectType {
+	case "biz":
+		query = query.
+			Preload("Biz").
+			Preload("Biz.Services", func(db *gorm.DB) *gorm.DB {
+				return db.Order("placement_order ASC")
+			}).
+			Preload("Biz.Products", func(db *gorm.DB) *gorm.DB {
+				return db.Order("placement_order ASC")
+			}).
+			Preload("Biz.Testimonials", func(db *gorm.DB) *gorm.DB {
+				return db.Order("placement_order ASC")
+			}).
+			Preload("Biz.SocialLinks").
+			Preload("Biz.FAQs", func(db *gorm.DB) *gorm.DB {
+				return db.Order("placement_order ASC")
+			}).
+			Preload("Biz.Gallery", func(db *gorm.DB) *gorm.DB {
+				return db.Order("placement_order ASC")
+			})
+	case "linktree":
+		query = query.
+			Preload("Linktree").
+			Preload("Linktree.Links", func(db *gorm.DB) *gorm.DB {
+				return db.Order("placement_order ASC")
+			})
+	case "waitlist":
+		query = query.Preload("Waitlist")
+	case "menu":
+		query = query.
+			Preload("Menu").
+			Preload("Menu.Categories", func(db *gorm.DB) *gorm.DB {
+				return db.Order("placement_order ASC")
+			}).
+			Preload("Menu.Items", func(db *gorm.DB) *gorm.DB {
+				return db.Order("placement_order ASC")
+			})
+	default:
+		query = query.
+			Preload("Portfolio").
+			Preload("Portfolio.User").
+			Preload("Portfolio.WorkExperiences", func(db *gorm.DB) *gorm.DB {
+				return db.Order("placement_order ASC")
+			}).
+			Preload("Portfolio.Education", func(db *gorm.DB) *gorm.DB {
+				return db.Order("placement_order ASC")
+			}).
+			Preload("Portfolio.Showcases", func(db *gorm.DB) *gorm.DB {
+				return db.Order("placement_order ASC")
+			}).
+			Preload("Portfolio.Showcases.ShowcaseTechnologies").
+			Preload("Portfolio.Skills")
+	}
+
+	return query
+}
+
 func normalizeLinktreeContent(project *models.Project) {
 	if project == nil || project.Linktree == nil || len(project.Linktree.Links) == 0 {
 		return
@@ -0,0 +1,65 @@
+package project
+
+import (
+	"errors"
+	"flash/models"
+	"flash/shared/access"
+	"flash/shared/testdb"
+	"testing"
+)
+
+// The routes run ProjectAccessMiddleware too, but the service must hold on its own.
+func TestServiceChecksProjectRoles(t *testing.T) {
+	db := openTrashDB(t)
+	if err := db.AutoMigrate(&models.WorkspaceMember{}); err != nil {
+		t.Fatal(err)
+	}
+	service := NewService(db, nil)
+
+	workspaceID := uint64(5)
+	subDomain := "shop"
+	testdb.Create(t, db,
+		&models.Project{ID: 1, UserID: 1, WorkspaceID: &workspaceID, Name: "Shop", Slug: "shop", SubDomain: &subDomain, Type: "linktree"},
+		&models.WorkspaceMember{WorkspaceID: workspaceID, UserID: 2, Role: access.RoleEditor},
+		&models.WorkspaceMember{WorkspaceID: workspaceID, UserID: 3, Role: access.RoleViewer},
+	)
+
+	owner := access.Actor{UserID: 1, Role: "user"}
+	editor := access.Actor{UserID: 2, Role: "user"}
+	viewer := access.Actor{UserID: 3, Role: "user"}
+	outsider := access.Actor{UserID: 4, Role: "user"}
+	support := access.Actor{UserID: 4, Role: access.RoleSupport}
+
+	tests := []struct {
+		name    string
+		call    func() error
+		wantErr error
+	}{
+		{name: "viewer reads", call: func() error { _, err := service.Show(viewer, 1); return err }},
+		{name: "support reads", call: func() error { _, err := service.Show(support, 1); return err }},
+		{name: "outsider cannot read", call: func() error { _, err := service.Show(outsider, 1); return err }, wantErr: access.ErrProjectForbidden},
+		{name: "unknown project", call: func() error { _, err := service.Show(owner, 99); return err }, wantErr: access.ErrProjectNotFound},
+		{name: "viewer cannot update", call: func() error {
+			_, err := service.Update(viewer, 1, Payload{Name: "Renamed", SubDomain: subDomain, Type: "linktree"})
+			return err
+		}, wantErr: access.ErrProjectForbidden},
+		{name: "support cannot unpublish", call: func() error {
+			_, err := service.Publish(support, 1, PublishProjectPayload{})
+			return err
+		}, wantErr: access.ErrProjectForbidden},
+		{name: "editor cannot delete", call: func() error { _, err := service.Delete(editor, 1); return err }, wantErr: access.ErrProjectForbidden},
+		{name: "editor updates", call: func() error {
+			_, err := service.Update(editor, 1, Payload{Name: "Renamed", SubDomain: subDomain, Type: "linktree"})
+			return err
+		}},
+		{name: "owner deletes", call: func() error { _, err := service.Delete(owner, 1); return err }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.call(); !errors.Is(err, test.wantErr) {
+				t.Fatalf("error = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package project
+
+import (
+	"errors"
+	"flash/models"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// defaultSubDomainCooldown is how long a subdomain given up by a project stays held for its owner before anyone else
+// can claim it. SUBDOMAIN_RELEASE_COOLDOWN_DAYS overrides it.
+const defaultSubDomainCooldown = 30 * 24 * time.Hour
+
+var (
+	ErrSubDomainCoolingDown = errors.New("this subdomain was recently released")
+	ErrNoSubDomainRedirect  = errors.New("no redirect for this subdomain")
+)
+
+// recordSubDomainRelease remembers the subdomain a project is giving up.
+func recordSubDomainRelease(tx *gorm.DB, project *models.Project, subDomain string, releasedAt time.Time) error {
+	if subDomain == "" {
+		return nil
+	}
+
+	return tx.Create(&models.ProjectSubDomainHistory{
+		ProjectID:  project.ID,
+		UserID:     project.UserID,
+		SubDomain:  strings.ToLower(subDomain),
+		ReleasedAt: releasedAt,
+	}).Error
+}
+
+// checkSubDomainCooldown fails while someone other than userID released subDomain within the cooldown.
+func (service Service) checkSubDomainCooldown(subDomain string, userID uint64) error {
+	cooldown := subDomainCooldown()
+
+	var held models.ProjectSubDomainHistory
+	err := service.DB.
+		Where("sub_domain = ? AND user_id <> ? AND released_at > ?", subDomain, userID, time.Now().Add(-cooldown)).
+		Order("released_at DESC").
+		First(&held).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w and can be claimed after %s", ErrSubDomainCoolingDown, held.ReleasedAt.Add(cooldown).Format("January 2, 2006"))
+}
+
+// ListSubDomainHistory returns the subdomains a project used before, most recent first.
+func (service Service) ListSubDomainHistory(projectID uint64) ([]SubDomainHistoryResponse, error) {
+	var history []models.ProjectSubDomainHistory
+	if err := service.DB.Where("project_id = ?", projectID).Order("released_at DESC").Find(&history).Error; err != nil {
+		return nil, err
+	}
+
+	cooldown := subDomainCooldown()
+	responses := make([]SubDomainHistoryResponse, 0, len(history))
+	for _, entry := range history {
+		responses = append(responses, SubDomainHistoryResponse{
+			SubDomain:  entry.SubDomain,
+			ReleasedAt: entry.ReleasedAt,
+			HeldUntil:  entry.ReleasedAt.Add(cooldown),
+		})
+	}
+
+	return responses, nil
+}
+
+// ResolveSubDomainRedirect tells the edge app where an old subdomain now lives. A subdomain that is in use again,
+// by the same project or another one, is not redirected.
+func (service Service) ResolveSubDomainRedirect(subDomain string) (*SubDomainRedirectResponse, error) {
+	subDomain = strings.ToLower(strings.TrimSpace(subDomain))
+
+	var live int64
+	if err := service.DB.Model(&models.Project{}).Where("sub_domain = ?", subDomain).Count(&live).Error; err != nil {
+		return nil, err
+	}
+	if live > 0 {
+		return nil, ErrNoSubDomainRedirect
+	}
+
+	// The latest release wins; projects deleted since then no longer redirect.
+	var entry models.ProjectSubDomainHistory
+	err := service.DB.
+		Joins("JOIN projects ON projects.id = project_sub_domain_histories.project_id AND projects.deleted_at IS NULL").
+		Where("project_sub_domain_histories.sub_domain = ?", subDomain).
+		Order("project_sub_domain_histories.released_at DESC").
+		First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoSubDomainRedirect
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var project models.Project
+	if err := service.DB.First(&project, entry.ProjectID).Error; err != nil {
+		return nil, err
+	}
+	if project.SubDomain == nil || *project.SubDomain == "" {
+		return nil, ErrNoSubDomainRedirect
+	}
+
+	return &SubDomainRedirectResponse{
+		From:       subDomain,
+		To:         *project.SubDomain,
+		ProjectID:  project.ID,
+		ReleasedAt: entry.ReleasedAt,
+	}, nil
+}
+
+func subDomainCooldown() time.Duration {
+	if days, err := strconv.Atoi(os.Getenv("SUBDOMAIN_RELEASE_COOLDOWN_DAYS")); err == nil && days >= 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return defaultSubDomainCooldown
+}
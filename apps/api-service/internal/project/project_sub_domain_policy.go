@@ -0,0 +1,180 @@
+package project
+
+import (
+	"errors"
+	"flash/models"
+	"flash/shared/subdomain"
+	"flash/utils"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reason codes for checks that need the database; the rest come from the subdomain package.
+const (
+	ReasonReserved         = "reserved"
+	ReasonTaken            = "taken"
+	ReasonRecentlyReleased = "recently_released"
+)
+
+const maxSubDomainSuggestions = 3
+
+// subDomainSuggestionSuffixes are tried after the bare name, before falling back to numbers.
+var subDomainSuggestionSuffixes = []string{"site", "online", "hq", "ph"}
+
+// CheckSubDomain runs every subdomain rule for userID. When subDomain can't be used it suggests free alternatives
+// built from name, or from subDomain itself when name is empty.
+func (service Service) CheckSubDomain(subDomain string, userID uint64, name string) (*SubDomainCheck, error) {
+	subDomain = strings.ToLower(strings.TrimSpace(subDomain))
+
+	reasons, err := service.subDomainReasons(subDomain, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &SubDomainCheck{
+		SubDomain:   subDomain,
+		Available:   len(reasons) == 0,
+		Reasons:     reasons,
+		Suggestions: []string{},
+	}
+	if check.Reasons == nil {
+		check.Reasons = []subdomain.Reason{}
+	}
+
+	if !check.Available {
+		if strings.TrimSpace(name) == "" {
+			name = subDomain
+		}
+		if check.Suggestions, err = service.suggestSubDomains(name, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return check, nil
+}
+
+// subDomainReasons lists everything that stops userID from taking subDomain. Availability is only looked up
+// for names that pass the rules.
+func (service Service) subDomainReasons(subDomain string, userID uint64) ([]subdomain.Reason, error) {
+	reasons := subdomain.Validate(subDomain)
+
+	protected, err := service.protectedSubDomains()
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(protected, subDomain) {
+		reasons = append(reasons, subdomain.Reason{Code: ReasonReserved, Message: "this subdomain is reserved by the system"})
+	} else if name, ok := subdomain.Impersonates(subDomain, protected); ok {
+		reasons = append(reasons, subdomain.Reason{
+			Code:    subdomain.ReasonImpersonation,
+			Message: fmt.Sprintf("subdomain looks too much like the reserved name %q", name),
+		})
+	}
+	if len(reasons) > 0 {
+		return reasons, nil
+	}
+
+	var count int64
+	if err := service.DB.Model(&models.Project{}).Where("sub_domain = ?", subDomain).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return []subdomain.Reason{{Code: ReasonTaken, Message: "subdomain already taken"}}, nil
+	}
+
+	err = service.checkSubDomainCooldown(subDomain, userID)
+	if errors.Is(err, ErrSubDomainCoolingDown) {
+		return []subdomain.Reason{{Code: ReasonRecentlyReleased, Message: err.Error()}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// protectedSubDomains is the reserved_sub_domains table plus the names the platform always keeps.
+func (service Service) protectedSubDomains() ([]string, error) {
+	var reserved []string
+	if err := service.DB.Model(&models.ReservedSubDomain{}).Pluck("sub_domain", &reserved).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range reserved {
+		reserved[i] = strings.ToLower(reserved[i])
+	}
+	return append(reserved, subdomain.ProtectedNames...), nil
+}
+
+// suggestSubDomains slugifies name and returns up to maxSubDomainSuggestions variants userID could take.
+func (service Service) suggestSubDomains(name string, userID uint64) ([]string, error) {
+	maxBase := subdomain.MaxLength - len("-online")
+	base := subdomain.Suggestion(utils.Slugify(name, maxBase), maxBase)
+	if base == "" {
+		return []string{}, nil
+	}
+
+	protected, err := service.protectedSubDomains()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []string{base}
+	for _, suffix := range subDomainSuggestionSuffixes {
+		candidates = append(candidates, base+"-"+suffix)
+	}
+	for n := 2; n <= 9; n++ {
+		candidates = append(candidates, base+"-"+strconv.Itoa(n))
+	}
+	candidates = slices.DeleteFunc(candidates, func(candidate string) bool {
+		if len(subdomain.Validate(candidate)) > 0 || slices.Contains(protected, candidate) {
+			return true
+		}
+		_, impersonates := subdomain.Impersonates(candidate, protected)
+		return impersonates
+	})
+	if len(candidates) == 0 {
+		return []string{}, nil
+	}
+
+	unavailable, err := service.unavailableSubDomains(candidates, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := []string{}
+	for _, candidate := range candidates {
+		if !unavailable[candidate] {
+			suggestions = append(suggestions, candidate)
+		}
+		if len(suggestions) == maxSubDomainSuggestions {
+			break
+		}
+	}
+
+	return suggestions, nil
+}
+
+// unavailableSubDomains returns which of candidates are in use or still held for someone else.
+func (service Service) unavailableSubDomains(candidates []string, userID uint64) (map[string]bool, error) {
+	var taken []string
+	if err := service.DB.Model(&models.Project{}).Where("sub_domain IN ?", candidates).Pluck("sub_domain", &taken).Error; err != nil {
+		return nil, err
+	}
+
+	var held []string
+	if err := service.DB.Model(&models.ProjectSubDomainHistory{}).
+		Where("sub_domain IN ? AND user_id <> ? AND released_at > ?", candidates, userID, time.Now().Add(-subDomainCooldown())).
+		Pluck("sub_domain", &held).Error; err != nil {
+		return nil, err
+	}
+
+	unavailable := make(map[string]bool, len(taken)+len(held))
+	for _, subDomain := range append(taken, held...) {
+		unavailable[strings.ToLower(subDomain)] = true
+	}
+	return unavailable, nil
+}
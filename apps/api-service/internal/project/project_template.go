@@ -0,0 +1,57 @@
+package project
+
+import (
+	"errors"
+	"flash/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrTemplateNotFound = errors.New("template not found")
+
+// ListTemplates returns the active catalog, optionally for one project type. Templates without a source project
+// have nothing to copy and are left out.
+func (service Service) ListTemplates(projectType string) ([]models.ProjectTemplate, error) {
+	query := service.DB.Where("is_active = ? AND source_project_id IS NOT NULL", true)
+	if projectType != "" {
+		query = query.Where("type = ?", projectType)
+	}
+
+	var templates []models.ProjectTemplate
+	if err := query.Order("placement_order ASC, id ASC").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// CreateFromTemplate starts a personal project as a copy of the template's source project. The source usually
+// belongs to someone else, so this deliberately skips the project access check.
+func (service Service) CreateFromTemplate(userID uint64, key string, payload DuplicatePayload) (*models.Project, error) {
+	var template models.ProjectTemplate
+	if err := service.DB.Where("`key` = ? AND is_active = ?", key, true).First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	if template.SourceProjectID == nil {
+		return nil, ErrTemplateNotFound
+	}
+
+	var source models.Project
+	if err := service.DB.First(&source, *template.SourceProjectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		name = template.Name
+	}
+
+	return service.duplicateProject(&source, userID, nil, name, payload.SubDomain)
+}
@@ -0,0 +1,266 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"flash/internal/account"
+	"flash/models"
+	"flash/shared/access"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// defaultTrashRetention is how long a deleted project can be restored before it is purged for good.
+// PROJECT_TRASH_RETENTION_DAYS overrides it.
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// trashPurgeBatchSize bounds how many projects one scheduler run purges.
+const trashPurgeBatchSize = 20
+
+var (
+	ErrProjectNotInTrash         = errors.New("project is not in the trash")
+	ErrRestoreSubDomainAvailable = errors.New("the project's subdomain is no longer available, send a new sub_domain to restore it")
+)
+
+// trashedRow is a table hanging off a project, reached through foreignKey or, when that is empty, project_id.
+type trashedRow struct {
+	model      any
+	foreignKey string
+}
+
+// trashedRows lists every soft-deletable row under a project.
+var trashedRows = []trashedRow{
+	{model: &models.Portfolio{}},
+	{model: &models.Biz{}},
+	{model: &models.Linktree{}},
+	{model: &models.Menu{}},
+	{model: &models.Waitlist{}},
+	{model: &models.Appointment{}},
+	{model: &models.Showcase{}, foreignKey: "portfolio_id"},
+	{model: &models.WorkExperience{}, foreignKey: "portfolio_id"},
+	{model: &models.Education{}, foreignKey: "portfolio_id"},
+	{model: &models.Skill{}, foreignKey: "portfolio_id"},
+	{model: &models.ShowcaseTechnology{}, foreignKey: "portfolio_id"},
+	{model: &models.ShowcaseTechnology{}, foreignKey: "showcase_id"},
+	{model: &models.Service{}, foreignKey: "biz_id"},
+	{model: &models.Product{}, foreignKey: "biz_id"},
+	{model: &models.Testimonial{}, foreignKey: "biz_id"},
+	{model: &models.BizSocialLink{}, foreignKey: "biz_id"},
+	{model: &models.BizFAQ{}, foreignKey: "biz_id"},
+	{model: &models.BizGallery{}, foreignKey: "biz_id"},
+	{model: &models.LinktreeLink{}, foreignKey: "linktree_id"},
+	{model: &models.LinktreeSection{}, foreignKey: "linktree_id"},
+	{model: &models.MenuCategory{}, foreignKey: "menu_id"},
+	{model: &models.MenuItem{}, foreignKey: "menu_id"},
+	{model: &models.MenuDisplayPoster{}, foreignKey: "menu_id"},
+}
+
+// moveToTrash soft-deletes the project and every row under it with one timestamp, so a restore brings back exactly
+// those rows and leaves anything deleted earlier deleted. Others can't claim the subdomain during the cooldown.
+func moveToTrash(tx *gorm.DB, project *models.Project) error {
+	// The columns hold whole seconds; truncating keeps the value comparable after a round trip.
+	now := time.Now().Truncate(time.Second)
+
+	if err := setGraphDeletedAt(tx, project.ID, now, "deleted_at IS NULL"); err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Model(&models.Project{}).Where("id = ?", project.ID).UpdateColumn("deleted_at", now).Error; err != nil {
+		return err
+	}
+	project.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+
+	if project.SubDomain == nil {
+		return nil
+	}
+	return recordSubDomainRelease(tx, project, *project.SubDomain, now)
+}
+
+// setGraphDeletedAt sets deleted_at to value on the rows under the project that match the condition.
+func setGraphDeletedAt(tx *gorm.DB, projectID uint64, value any, condition string, args ...any) error {
+	// Parent ids are read up front, soft-deleted or not, so the order of the updates doesn't matter.
+	parents := []struct {
+		foreignKey string
+		model      any
+	}{
+		{"portfolio_id", &models.Portfolio{}},
+		{"biz_id", &models.Biz{}},
+		{"linktree_id", &models.Linktree{}},
+		{"menu_id", &models.Menu{}},
+	}
+	parentIDs := make(map[string][]uint64, len(parents)+1)
+	for _, parent := range parents {
+		var ids []uint64
+		if err := tx.Unscoped().Model(parent.model).Where("project_id = ?", projectID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		parentIDs[parent.foreignKey] = ids
+	}
+	if portfolioIDs := parentIDs["portfolio_id"]; len(portfolioIDs) > 0 {
+		var ids []uint64
+		if err := tx.Unscoped().Model(&models.Showcase{}).Where("portfolio_id IN ?", portfolioIDs).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		parentIDs["showcase_id"] = ids
+	}
+
+	for _, row := range trashedRows {
+		query := tx.Unscoped().Model(row.model).Where(condition, args...)
+		if row.foreignKey == "" {
+			query = query.Where("project_id = ?", projectID)
+		} else if ids := parentIDs[row.foreignKey]; len(ids) > 0 {
+			query = query.Where(row.foreignKey+" IN ?", ids)
+		} else {
+			continue
+		}
+
+		if err := query.UpdateColumn("deleted_at", value).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListTrash returns the deleted projects the user owns or can see through a workspace, most recently deleted first.
+func (service Service) ListTrash(userID uint64) ([]TrashedProjectResponse, error) {
+	var projects []models.Project
+	if err := service.DB.Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Where("user_id = ? OR workspace_id IN (?)", userID, access.MemberWorkspaceIDs(service.DB, userID)).
+		Order("deleted_at DESC").
+		Find(&projects).Error; err != nil {
+		return nil, err
+	}
+
+	retention := trashRetention()
+	trashed := make([]TrashedProjectResponse, 0, len(projects))
+	for _, project := range projects {
+		trashed = append(trashed, TrashedProjectResponse{
+			Project: project,
+			PurgeAt: project.DeletedAt.Time.Add(retention),
+		})
+	}
+
+	return trashed, nil
+}
+
+// Restore brings a trashed project back with everything that was deleted along with it. The project keeps its
+// subdomain, or its lack of one, unless someone else has claimed it since; subDomain then picks a new one.
+func (service Service) Restore(projectID uint64, userID uint64, userRole string, subDomain string) (*models.Project, error) {
+	var project models.Project
+	if err := service.DB.Unscoped().First(&project, projectID).Error; err != nil {
+		return nil, err
+	}
+	if !project.DeletedAt.Valid {
+		return nil, ErrProjectNotInTrash
+	}
+
+	role, err := access.ProjectRole(service.DB, userID, userRole, &project)
+	if err != nil {
+		return nil, err
+	}
+	if !access.RoleAllows(role, access.PermissionManage) {
+		return nil, access.ErrProjectForbidden
+	}
+
+	// The original subdomain was accepted when it was claimed, so it only has to be free; rules added since don't
+	// apply to it. A new one goes through every check.
+	var original *string
+	if project.SubDomain != nil && *project.SubDomain != "" {
+		lowered := strings.ToLower(*project.SubDomain)
+		original = &lowered
+	}
+	target := original
+	if requested := strings.ToLower(strings.TrimSpace(subDomain)); requested != "" && (original == nil || requested != *original) {
+		if _, err := service.CheckDomain(requested, project.UserID); err != nil {
+			return nil, err
+		}
+		target = &requested
+	} else if original != nil {
+		if err := service.checkSubDomainFree(*original, project.UserID); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrRestoreSubDomainAvailable, err)
+		}
+	}
+	restoresOriginal := target == original
+
+	deletedAt := project.DeletedAt.Time
+	err = service.DB.Transaction(func(tx *gorm.DB) error {
+		if err := setGraphDeletedAt(tx, project.ID, nil, "deleted_at = ?", deletedAt); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Project{}).Where("id = ?", project.ID).UpdateColumns(map[string]any{
+			"deleted_at": nil,
+			"sub_domain": target,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Back on its own subdomain, the release recorded when it was trashed no longer applies.
+		if restoresOriginal && original != nil {
+			return tx.Where("project_id = ? AND sub_domain = ? AND released_at = ?", project.ID, *original, deletedAt).
+				Delete(&models.ProjectSubDomainHistory{}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	project.DeletedAt = gorm.DeletedAt{}
+	project.SubDomain = target
+	return &project, nil
+}
+
+// checkSubDomainFree reports whether a subdomain is neither held by a live project nor cooling down for another
+// user, without applying the naming policy.
+func (service Service) checkSubDomainFree(subDomain string, userID uint64) error {
+	var count int64
+	if err := service.DB.Model(&models.Project{}).Where("sub_domain = ?", subDomain).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("subdomain already taken")
+	}
+
+	return service.checkSubDomainCooldown(subDomain, userID)
+}
+
+// RunTrashPurge permanently deletes projects that have been in the trash longer than the retention period, their
+// storage objects included. A purge that fails is retried on the next run.
+func (service Service) RunTrashPurge(ctx context.Context) error {
+	var dueIDs []uint64
+	if err := service.DB.WithContext(ctx).Unscoped().Model(&models.Project{}).
+		Where("deleted_at IS NOT NULL AND deleted_at <= ?", time.Now().Add(-trashRetention())).
+		Order("deleted_at ASC").
+		Limit(trashPurgeBatchSize).
+		Pluck("id", &dueIDs).Error; err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, projectID := range dueIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := account.PurgeProject(ctx, service.DB, service.ObjectStorage, projectID); err != nil {
+			errs = append(errs, fmt.Errorf("purge project %d: %w", projectID, err))
+			continue
+		}
+		log.Printf("[INFO] Purged project %d from the trash", projectID)
+	}
+
+	return errors.Join(errs...)
+}
+
+func trashRetention() time.Duration {
+	if days, err := strconv.Atoi(os.Getenv("PROJECT_TRASH_RETENTION_DAYS")); err == nil && days >= 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return defaultTrashRetention
+}
@@ -0,0 +1,133 @@
+package project
+
+import (
+	"errors"
+	"flash/models"
+	"flash/shared/testdb"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func openTrashDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	tables := []any{&models.Project{}, &models.ProjectSubDomainHistory{}, &models.ReservedSubDomain{}}
+	seen := map[any]bool{}
+	for _, row := range trashedRows {
+		if !seen[row.model] {
+			seen[row.model] = true
+			tables = append(tables, row.model)
+		}
+	}
+	return testdb.Open(t, tables...)
+}
+
+// trashProject creates a project owned by user 1 and moves it to the trash.
+func trashProject(t *testing.T, db *gorm.DB, subDomain *string) *models.Project {
+	t.Helper()
+
+	project := &models.Project{ID: 1, UserID: 1, Name: "Shop", Slug: "shop", SubDomain: subDomain, Type: "linktree"}
+	testdb.Create(t, db, project, &models.Linktree{ProjectID: 1, UserID: 1, Name: "Shop"})
+	if err := db.Transaction(func(tx *gorm.DB) error { return moveToTrash(tx, project) }); err != nil {
+		t.Fatal(err)
+	}
+	return project
+}
+
+func storedSubDomain(t *testing.T, db *gorm.DB) *string {
+	t.Helper()
+
+	var project models.Project
+	if err := db.First(&project, 1).Error; err != nil {
+		t.Fatalf("project was not restored: %v", err)
+	}
+	return project.SubDomain
+}
+
+func TestRestoreKeepsALegacySubDomain(t *testing.T) {
+	db := openTrashDB(t)
+	service := NewService(db, nil)
+
+	// Double hyphens at positions three and four are refused for new names but existed before the policy.
+	legacy := "ab--cd"
+	trashProject(t, db, &legacy)
+
+	if _, err := service.Restore(1, 1, "", ""); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if subDomain := storedSubDomain(t, db); subDomain == nil || *subDomain != legacy {
+		t.Errorf("sub_domain = %v, want %q", subDomain, legacy)
+	}
+
+	var releases int64
+	db.Model(&models.ProjectSubDomainHistory{}).Where("project_id = ?", 1).Count(&releases)
+	if releases != 0 {
+		t.Errorf("release history rows = %d, want the trash release removed", releases)
+	}
+}
+
+func TestRestoreKeepsANullSubDomain(t *testing.T) {
+	db := openTrashDB(t)
+	service := NewService(db, nil)
+	trashProject(t, db, nil)
+
+	project, err := service.Restore(1, 1, "", "")
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if project.SubDomain != nil {
+		t.Errorf("returned sub_domain = %q, want nil", *project.SubDomain)
+	}
+	if subDomain := storedSubDomain(t, db); subDomain != nil {
+		t.Errorf("stored sub_domain = %q, want NULL", *subDomain)
+	}
+}
+
+func TestRestoreWhenTheOriginalSubDomainIsUnavailable(t *testing.T) {
+	tests := []struct {
+		name  string
+		claim func(t *testing.T, db *gorm.DB)
+	}{
+		{
+			name: "taken by a live project",
+			claim: func(t *testing.T, db *gorm.DB) {
+				taken := "shop"
+				testdb.Create(t, db, &models.Project{ID: 2, UserID: 2, Name: "Other", Slug: "other", SubDomain: &taken, Type: "linktree"})
+			},
+		},
+		{
+			name: "cooling down for another user",
+			claim: func(t *testing.T, db *gorm.DB) {
+				testdb.Create(t, db, &models.ProjectSubDomainHistory{ProjectID: 3, UserID: 2, SubDomain: "shop", ReleasedAt: time.Now()})
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := openTrashDB(t)
+			service := NewService(db, nil)
+
+			original := "shop"
+			trashProject(t, db, &original)
+			test.claim(t, db)
+
+			if _, err := service.Restore(1, 1, "", ""); !errors.Is(err, ErrRestoreSubDomainAvailable) {
+				t.Fatalf("Restore() error = %v, want ErrRestoreSubDomainAvailable", err)
+			}
+
+			// A new subdomain goes through the full policy.
+			if _, err := service.Restore(1, 1, "", "ab--cd"); err == nil {
+				t.Fatal("Restore() onto a name the policy refuses succeeded")
+			}
+			if _, err := service.Restore(1, 1, "", "Shop-Again"); err != nil {
+				t.Fatalf("Restore() onto a new subdomain error = %v", err)
+			}
+			if subDomain := storedSubDomain(t, db); subDomain == nil || *subDomain != "shop-again" {
+				t.Errorf("sub_domain = %v, want %q", subDomain, "shop-again")
+			}
+		})
+	}
+}
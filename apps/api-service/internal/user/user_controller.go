@@ -1,6 +1,11 @@
 package user
 
 import (
+	"errors"
+	"flash/internal/auth"
+	objectStorage "flash/sdk/object_storage"
+	"flash/utils"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"net/http"
@@ -10,9 +15,11 @@ type Controller struct {
 	Service *Service
 }
 
-func NewController(db *gorm.DB) *Controller {
+func NewController(db *gorm.DB, objectStorage objectStorage.Provider) *Controller {
 	service := &Service{
-		DB: db,
+		DB:            db,
+		Auth:          &auth.Service{DB: db},
+		ObjectStorage: objectStorage,
 	}
 	return &Controller{Service: service}
 }
@@ -39,3 +46,90 @@ func (controller *Controller) Register(context *gin.Context) {
 
 	context.JSON(http.StatusOK, gin.H{"success": user})
 }
+
+func (controller *Controller) Profile(context *gin.Context) {
+	user, err := controller.Service.Profile(context.GetUint64("user_id"))
+	if err != nil {
+		respondProfileError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, user)
+}
+
+func (controller *Controller) UpdateProfile(context *gin.Context) {
+	var request UpdateProfileRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	user, err := controller.Service.UpdateProfile(context.GetUint64("user_id"), request)
+	if err != nil {
+		respondProfileError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, user)
+}
+
+func (controller *Controller) UploadAvatar(context *gin.Context) {
+	file, err := context.FormFile("avatar")
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	user, err := controller.Service.UploadAvatar(context.GetUint64("user_id"), file)
+	if err != nil {
+		respondProfileError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, user)
+}
+
+func (controller *Controller) RemoveAvatar(context *gin.Context) {
+	user, err := controller.Service.RemoveAvatar(context.GetUint64("user_id"))
+	if err != nil {
+		respondProfileError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, user)
+}
+
+func (controller *Controller) ChangePassword(context *gin.Context) {
+	var request ChangePasswordRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	err := controller.Service.ChangePassword(context.GetUint64("user_id"), context.GetUint64("session_id"), request.CurrentPassword, request.NewPassword)
+	if err != nil {
+		respondProfileError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"changed": true})
+}
+
+func respondProfileError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.APIRespondError(context, http.StatusNotFound, "user not found")
+	case errors.Is(err, auth.ErrInvalidPassword), errors.Is(err, ErrPasswordUnchanged), errors.Is(err, ErrNameRequired):
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrPasswordReauthRequired):
+		utils.APIRespondError(context, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrAvatarTooLarge):
+		utils.APIRespondError(context, http.StatusRequestEntityTooLarge, err.Error())
+	default:
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+	}
+	context.Abort()
+}
@@ -0,0 +1,15 @@
+package user
+
+// UpdateProfileRequest only changes the fields that are sent. An empty mobile_number clears it.
+type UpdateProfileRequest struct {
+	FirstName    *string `json:"first_name" binding:"omitempty,max=255"`
+	LastName     *string `json:"last_name" binding:"omitempty,max=255"`
+	MobileNumber *string `json:"mobile_number" binding:"omitempty,max=20"`
+	Newsletter   *bool   `json:"newsletter"`
+}
+
+type ChangePasswordRequest struct {
+	// CurrentPassword is ignored for accounts that signed up through a provider and never had a password.
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" binding:"required,min=8,max=72"`
+}
@@ -0,0 +1,205 @@
+package user
+
+import (
+	"errors"
+	"flash/internal/auth"
+	"flash/models"
+	"flash/utils"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// maxAvatarSize bounds avatar uploads; they are shown at a few dozen pixels.
+const maxAvatarSize = 2 << 20
+
+// passwordReauthWindow is how recently an account without a password must have signed in to set one.
+const passwordReauthWindow = 10 * time.Minute
+
+var (
+	ErrNameRequired           = errors.New("first and last name cannot be empty")
+	ErrAvatarTooLarge         = errors.New("avatar must be under 2MB")
+	ErrPasswordUnchanged      = errors.New("new password must be different from the current one")
+	ErrPasswordReauthRequired = errors.New("sign in again to set a password")
+)
+
+var avatarExtensions = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/webp": "webp",
+	"image/gif":  "gif",
+}
+
+func (service Service) Profile(userID uint64) (*models.User, error) {
+	var user models.User
+	if err := service.DB.Preload("Identities").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (service Service) UpdateProfile(userID uint64, request UpdateProfileRequest) (*models.User, error) {
+	updates := map[string]any{}
+
+	if request.FirstName != nil {
+		firstName := strings.TrimSpace(*request.FirstName)
+		if firstName == "" {
+			return nil, ErrNameRequired
+		}
+		updates["first_name"] = firstName
+	}
+	if request.LastName != nil {
+		lastName := strings.TrimSpace(*request.LastName)
+		if lastName == "" {
+			return nil, ErrNameRequired
+		}
+		updates["last_name"] = lastName
+	}
+	if request.MobileNumber != nil {
+		if mobileNumber := strings.TrimSpace(*request.MobileNumber); mobileNumber != "" {
+			updates["mobile_number"] = mobileNumber
+		} else {
+			updates["mobile_number"] = nil
+		}
+	}
+	if request.Newsletter != nil {
+		updates["newsletter"] = *request.Newsletter
+	}
+
+	if len(updates) > 0 {
+		if err := service.DB.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return service.Profile(userID)
+}
+
+// UploadAvatar stores the image under users/<id>/avatar/ and removes the one it replaces. Avatars that came from
+// GitHub or Google live elsewhere and are left alone.
+func (service Service) UploadAvatar(userID uint64, file *multipart.FileHeader) (*models.User, error) {
+	if file.Size > maxAvatarSize {
+		return nil, ErrAvatarTooLarge
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	contentType, err := utils.ValidateRequestImage(src, maxAvatarSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := service.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("%s%d.%s", avatarPrefix(userID), time.Now().UnixNano(), avatarExtensions[contentType])
+	url, err := service.ObjectStorage.Upload(path, src, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("storage upload failed: %w", err)
+	}
+
+	if err := service.DB.Model(&user).Update("image_url", url).Error; err != nil {
+		return nil, err
+	}
+	service.deleteStoredAvatar(userID, user.ImageURL)
+
+	return service.Profile(userID)
+}
+
+func (service Service) RemoveAvatar(userID uint64) (*models.User, error) {
+	var user models.User
+	if err := service.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	if user.ImageURL != nil {
+		if err := service.DB.Model(&user).Update("image_url", nil).Error; err != nil {
+			return nil, err
+		}
+		service.deleteStoredAvatar(userID, user.ImageURL)
+	}
+
+	return service.Profile(userID)
+}
+
+// ChangePassword sets a new password and signs out every other device, including a legacy refresh token. Accounts without a password, i.e. created
+// through GitHub or Google, set a first one from a session they signed in to within passwordReauthWindow, so a stolen long-lived session can't
+// add a password and keep the account.
+func (service Service) ChangePassword(userID uint64, currentSessionID uint64, currentPassword string, newPassword string) error {
+	var user models.User
+	if err := service.DB.First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	if user.Password != nil && *user.Password != "" {
+		if bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(currentPassword)) != nil {
+			return auth.ErrInvalidPassword
+		}
+		if bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(newPassword)) == nil {
+			return ErrPasswordUnchanged
+		}
+	} else {
+		err := auth.Service{DB: service.DB}.RequireRecentSignIn(userID, currentSessionID, passwordReauthWindow)
+		if errors.Is(err, auth.ErrRecentSignInRequired) {
+			return ErrPasswordReauthRequired
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return service.DB.Transaction(func(tx *gorm.DB) error {
+		// The legacy refresh_token column predates sessions and would otherwise keep working after the change.
+		if err := tx.Model(&user).Updates(map[string]any{
+			"password":      string(hashedPassword),
+			"refresh_token": nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		_, err := auth.Service{DB: tx}.RevokeAllSessions(userID, currentSessionID)
+		return err
+	})
+}
+
+func avatarPrefix(userID uint64) string {
+	return fmt.Sprintf("users/%d/avatar/", userID)
+}
+
+// deleteStoredAvatar removes a previous avatar if it is one of ours. A failure only leaves an unused object behind.
+func (service Service) deleteStoredAvatar(userID uint64, imageURL *string) {
+	if imageURL == nil {
+		return
+	}
+
+	base, err := service.ObjectStorage.GetURL("")
+	if err != nil || base == "" || !strings.HasPrefix(*imageURL, base) {
+		return
+	}
+
+	path := strings.TrimPrefix(strings.TrimPrefix(*imageURL, base), "/")
+	if !strings.HasPrefix(path, avatarPrefix(userID)) || strings.Contains(path, "..") {
+		return
+	}
+
+	if _, err := service.ObjectStorage.Delete(path); err != nil {
+		log.Printf("[WARN] Failed to delete previous avatar %s: %v", path, err)
+	}
+}
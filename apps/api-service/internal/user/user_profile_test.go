@@ -0,0 +1,92 @@
+package user
+
+import (
+	"errors"
+	"flash/models"
+	"flash/shared/testdb"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestChangePasswordSignsOutEverywhereElse(t *testing.T) {
+	db := testdb.Open(t, &models.User{}, &models.UserSession{})
+	service := Service{DB: db}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, legacyToken := string(hash), "legacy-refresh-token"
+	expiresAt := time.Now().Add(time.Hour)
+	testdb.Create(t, db,
+		&models.User{ID: 1, FirstName: "Ada", Email: "ada@example.com", Password: &password, RefreshToken: &legacyToken},
+		&models.UserSession{ID: 1, UserID: 1, TokenHash: "current", ExpiresAt: expiresAt},
+		&models.UserSession{ID: 2, UserID: 1, TokenHash: "other", ExpiresAt: expiresAt},
+	)
+
+	if err := service.ChangePassword(1, 1, "correct horse", "battery staple"); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+
+	var user models.User
+	if err := db.First(&user, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.RefreshToken != nil {
+		t.Errorf("legacy refresh token = %q, want it cleared", *user.RefreshToken)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte("battery staple")) != nil {
+		t.Error("new password was not stored")
+	}
+
+	var sessions []models.UserSession
+	if err := db.Order("id").Find(&sessions).Error; err != nil {
+		t.Fatal(err)
+	}
+	if sessions[0].RevokedAt != nil || sessions[1].RevokedAt == nil {
+		t.Errorf("revoked = [%v %v], want only the other session revoked", sessions[0].RevokedAt, sessions[1].RevokedAt)
+	}
+}
+
+func TestChangePasswordWithoutAPassword(t *testing.T) {
+	tests := []struct {
+		name       string
+		sessionAge time.Duration
+		revoked    bool
+		wantErr    error
+	}{
+		{name: "right after signing in", sessionAge: time.Minute},
+		{name: "on an old session", sessionAge: time.Hour, wantErr: ErrPasswordReauthRequired},
+		{name: "on a revoked session", sessionAge: time.Minute, revoked: true, wantErr: ErrPasswordReauthRequired},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := testdb.Open(t, &models.User{}, &models.UserSession{})
+			service := Service{DB: db}
+
+			empty := ""
+			session := &models.UserSession{ID: 5, UserID: 1, TokenHash: "current", ExpiresAt: time.Now().Add(time.Hour), CreatedAt: time.Now().Add(-test.sessionAge)}
+			if test.revoked {
+				revokedAt := time.Now()
+				session.RevokedAt = &revokedAt
+			}
+			testdb.Create(t, db, &models.User{ID: 1, FirstName: "Ada", Email: "ada@example.com", Password: &empty}, session)
+
+			err := service.ChangePassword(1, 5, "", "battery staple")
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("ChangePassword() error = %v, want %v", err, test.wantErr)
+			}
+
+			var user models.User
+			if err := db.First(&user, 1).Error; err != nil {
+				t.Fatal(err)
+			}
+			if set := *user.Password != ""; set != (test.wantErr == nil) {
+				t.Errorf("password set = %v, want %v", set, test.wantErr == nil)
+			}
+		})
+	}
+}
@@ -2,14 +2,19 @@ package user
 
 import (
 	"errors"
+	"flash/internal/auth"
 	"flash/models"
+	objectStorage "flash/sdk/object_storage"
+	"log"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type Service struct {
-	DB *gorm.DB
+	DB            *gorm.DB
+	Auth          *auth.Service
+	ObjectStorage objectStorage.Provider
 }
 
 func (service Service) Register(firstName string, lastName string, mobileNumber string, email string, password string) (*models.User, error) {
@@ -40,6 +45,11 @@ func (service Service) Register(firstName string, lastName string, mobileNumber
 		return nil, err
 	}
 
+	// The account is usable right away; a failed email only means the user has to ask for another link.
+	if err := service.Auth.SendVerificationEmail(user); err != nil {
+		log.Printf("[WARN] Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
 	emptyPassword := ""
 	user.Password = &emptyPassword
 
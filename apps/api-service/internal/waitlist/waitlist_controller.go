@@ -0,0 +1,219 @@
+package waitlist
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flash/internal/project"
+	"flash/shared/access"
+	"flash/utils"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	objectStorage "flash/sdk/object_storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service        *Service
+	ProjectService *project.Service
+}
+
+func NewController(db *gorm.DB, objectStorage objectStorage.Provider) *Controller {
+	return &Controller{
+		Service:        NewService(db),
+		ProjectService: project.NewService(db, objectStorage),
+	}
+}
+
+func (c *Controller) Save(context *gin.Context) {
+	if err := context.Request.ParseMultipartForm(32 << 20); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "File upload error: "+err.Error())
+		context.Abort()
+		return
+	}
+
+	jsonBody := context.Request.FormValue("json_body")
+	if jsonBody == "" {
+		utils.APIRespondError(context, http.StatusBadRequest, "Missing 'json_body'")
+		context.Abort()
+		return
+	}
+
+	var req CreateUpdateWaitlistRequest
+	if err := json.Unmarshal([]byte(jsonBody), &req); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		context.Abort()
+		return
+	}
+
+	payload := req.ToServicePayload()
+
+	waitlist, err := c.Service.Save(utils.RequestActor(context), payload)
+	if err != nil {
+		utils.APIRespondError(context, access.HTTPStatus(err, http.StatusInternalServerError), err.Error())
+		context.Abort()
+		return
+	}
+
+	editorID := context.GetUint64("user_id")
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"waitlist": waitlist})
+
+	if _, err := c.ProjectService.EnqueueOGImage(payload.ProjectID, &editorID); err != nil {
+		fmt.Printf("Failed to queue OG image for project %d: %v\n", payload.ProjectID, err)
+	}
+}
+
+func (c *Controller) Get(context *gin.Context) {
+	projectID, err := strconv.ParseInt(context.Param("project_id"), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "Invalid project ID")
+		return
+	}
+
+	waitlist, err := c.Service.Get(projectID)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusNotFound, "Waitlist not found")
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"waitlist": waitlist})
+}
+
+func (c *Controller) Signup(context *gin.Context) {
+	var request CreateSignupRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	userAgent := context.GetHeader("User-Agent")
+
+	status, err := c.Service.Signup(request, context.ClientIP(), &userAgent)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrDailyWaitlistSignupLimitReached):
+			utils.APIRespondError(context, http.StatusTooManyRequests, "You have reached the daily signup limit for this IP address.")
+		case errors.Is(err, ErrAlreadySignedUp):
+			utils.APIRespondError(context, http.StatusConflict, err.Error())
+		case errors.Is(err, ErrWaitlistNotFound):
+			utils.APIRespondError(context, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrMissingRequiredField):
+			utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		default:
+			utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		}
+		context.Abort()
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusCreated, status)
+}
+
+func (c *Controller) Status(context *gin.Context) {
+	status, err := c.Service.Status(context.Param("referral_code"))
+	if err != nil {
+		if errors.Is(err, ErrSignupNotFound) {
+			utils.APIRespondError(context, http.StatusNotFound, err.Error())
+			context.Abort()
+			return
+		}
+
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+		context.Abort()
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, status)
+}
+
+func (c *Controller) ListSignups(context *gin.Context) {
+	projectID, err := strconv.ParseInt(context.Param("project_id"), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "Invalid project ID")
+		return
+	}
+
+	page, _ := strconv.Atoi(context.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(context.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	signups, total, err := c.Service.ListSignups(projectID, page, limit)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{
+		"data": signups,
+		"meta": gin.H{
+			"page":      page,
+			"limit":     limit,
+			"total":     total,
+			"last_page": int(math.Ceil(float64(total) / float64(limit))),
+		},
+	})
+}
+
+func (c *Controller) ExportSignups(context *gin.Context) {
+	projectID, err := strconv.ParseInt(context.Param("project_id"), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, "Invalid project ID")
+		return
+	}
+
+	signups, fields, err := c.Service.ExportSignups(projectID)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusNotFound, "Waitlist not found")
+		return
+	}
+
+	context.Header("Content-Type", "text/csv; charset=utf-8")
+	context.Header("Content-Disposition", fmt.Sprintf("attachment; filename=waitlist-%d.csv", projectID))
+	context.Status(http.StatusOK)
+
+	writer := csv.NewWriter(context.Writer)
+
+	header := []string{"position", "email", "name", "referral_code", "referral_count", "signed_up_at"}
+	for _, field := range fields {
+		header = append(header, field.Label)
+	}
+	_ = writer.Write(header)
+
+	for _, signup := range signups {
+		answers := map[string]string{}
+		if signup.Answers != nil {
+			_ = json.Unmarshal(*signup.Answers, &answers)
+		}
+
+		name := ""
+		if signup.Name != nil {
+			name = *signup.Name
+		}
+
+		row := []string{
+			strconv.FormatInt(signup.Position, 10),
+			signup.Email,
+			name,
+			signup.ReferralCode,
+			strconv.Itoa(signup.ReferralCount),
+			signup.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+		for _, field := range fields {
+			row = append(row, answers[field.Key])
+		}
+		_ = writer.Write(row)
+	}
+
+	writer.Flush()
+}
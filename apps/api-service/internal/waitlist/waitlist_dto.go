@@ -0,0 +1,83 @@
+package waitlist
+
+type ThemeRequest struct {
+	Preset string                       `json:"preset"`
+	Styles map[string]map[string]string `json:"styles"`
+}
+
+type FieldRequest struct {
+	Key         string   `json:"key"`
+	Label       string   `json:"label"`
+	Type        string   `json:"type"`
+	Placeholder *string  `json:"placeholder,omitempty"`
+	Options     []string `json:"options,omitempty"`
+	Required    bool     `json:"required"`
+}
+
+type CreateUpdateWaitlistRequest struct {
+	WaitlistID *int64 `json:"waitlist_id"`
+	ProjectID  int64  `json:"project_id" binding:"required"`
+	UserID     int64  `json:"user_id"`
+
+	Headline       *string `json:"headline"`
+	Description    *string `json:"description"`
+	CTALabel       *string `json:"cta_label"`
+	SuccessMessage *string `json:"success_message"`
+
+	LayoutName string         `json:"layout_name"`
+	Theme      *ThemeRequest  `json:"theme"`
+	Fields     []FieldRequest `json:"fields"`
+
+	ReferralsEnabled *bool `json:"referrals_enabled"`
+	ReferralBoost    *int  `json:"referral_boost"`
+}
+
+type Payload struct {
+	WaitlistID *int64
+	ProjectID  int64
+	UserID     int64
+
+	Headline       *string
+	Description    *string
+	CTALabel       *string
+	SuccessMessage *string
+
+	LayoutName string
+	Theme      *ThemeRequest
+	Fields     []FieldRequest
+
+	ReferralsEnabled *bool
+	ReferralBoost    *int
+}
+
+func (request *CreateUpdateWaitlistRequest) ToServicePayload() Payload {
+	return Payload{
+		WaitlistID:       request.WaitlistID,
+		ProjectID:        request.ProjectID,
+		UserID:           request.UserID,
+		Headline:         request.Headline,
+		Description:      request.Description,
+		CTALabel:         request.CTALabel,
+		SuccessMessage:   request.SuccessMessage,
+		LayoutName:       request.LayoutName,
+		Theme:            request.Theme,
+		Fields:           request.Fields,
+		ReferralsEnabled: request.ReferralsEnabled,
+		ReferralBoost:    request.ReferralBoost,
+	}
+}
+
+type CreateSignupRequest struct {
+	ProjectID    uint64            `json:"project_id" binding:"required"`
+	Email        string            `json:"email" binding:"required,email,max=255"`
+	Name         *string           `json:"name,omitempty" binding:"omitempty,max=255"`
+	Answers      map[string]string `json:"answers,omitempty"`
+	ReferralCode *string           `json:"referral_code,omitempty"`
+}
+
+type SignupStatusResponse struct {
+	ReferralCode  string `json:"referral_code"`
+	ReferralCount int    `json:"referral_count"`
+	Position      int64  `json:"position"`
+	Total         int64  `json:"total"`
+}
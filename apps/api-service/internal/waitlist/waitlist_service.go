@@ -0,0 +1,391 @@
+package waitlist
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flash/internal/project"
+	"flash/models"
+	"flash/shared/access"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const dailySignupLimitPerIP = 5
+
+var (
+	ErrDailyWaitlistSignupLimitReached = errors.New("daily waitlist signup limit reached")
+	ErrAlreadySignedUp                 = errors.New("this email is already on the waitlist")
+	ErrWaitlistNotFound                = errors.New("waitlist not found")
+	ErrSignupNotFound                  = errors.New("waitlist signup not found")
+	ErrMissingRequiredField            = errors.New("missing required field")
+)
+
+type Service struct {
+	DB *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+func (s *Service) Get(projectID int64) (*models.Waitlist, error) {
+	var waitlist models.Waitlist
+	if err := s.DB.Where("project_id = ?", projectID).First(&waitlist).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.Model(&models.WaitlistSignup{}).
+		Where("waitlist_id = ?", waitlist.ID).
+		Count(&waitlist.SignupCount).Error; err != nil {
+		return nil, err
+	}
+
+	return &waitlist, nil
+}
+
+func (s *Service) Save(actor access.Actor, payload Payload) (*models.Waitlist, error) {
+	proj, err := actor.Authorize(s.DB, uint64(payload.ProjectID), access.PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	// Rows belong to the project owner, whichever collaborator saves them.
+	payload.UserID = int64(proj.UserID)
+
+	var waitlist models.Waitlist
+	isNew := payload.WaitlistID == nil
+
+	if isNew {
+		waitlist = models.Waitlist{
+			ProjectID:        uint64(payload.ProjectID),
+			UserID:           uint64(payload.UserID),
+			ReferralsEnabled: true,
+			ReferralBoost:    5,
+		}
+	} else if err := s.DB.Where("project_id = ?", payload.ProjectID).First(&waitlist, *payload.WaitlistID).Error; err != nil {
+		return nil, err
+	}
+
+	waitlist.Headline = normalizeOptionalString(payload.Headline)
+	waitlist.Description = normalizeOptionalString(payload.Description)
+	waitlist.CTALabel = normalizeOptionalString(payload.CTALabel)
+	waitlist.SuccessMessage = normalizeOptionalString(payload.SuccessMessage)
+	waitlist.Fields = marshalJSON(normalizeFields(payload.Fields))
+
+	if payload.LayoutName != "" {
+		waitlist.LayoutName = &payload.LayoutName
+	}
+
+	if payload.Theme != nil {
+		waitlist.ThemeObject = marshalJSON(payload.Theme)
+		waitlist.ThemeName = &payload.Theme.Preset
+	}
+
+	if payload.ReferralsEnabled != nil {
+		waitlist.ReferralsEnabled = *payload.ReferralsEnabled
+	}
+	if payload.ReferralBoost != nil && *payload.ReferralBoost >= 0 {
+		waitlist.ReferralBoost = *payload.ReferralBoost
+	}
+
+	err = project.SaveWithRevision(s.DB, waitlist.ProjectID, &actor.UserID, func(tx *gorm.DB) error {
+		return tx.Save(&waitlist).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(int64(waitlist.ProjectID))
+}
+
+// Signup queues an email on the project's waitlist. Signups are ordered by priority, which starts
+// at the join order and drops by the waitlist's referral boost each time someone uses their code.
+func (s *Service) Signup(request CreateSignupRequest, ipAddress string, userAgent *string) (*SignupStatusResponse, error) {
+	startOfDay := time.Now().Truncate(24 * time.Hour)
+
+	var signupCount int64
+	if err := s.DB.Model(&models.WaitlistSignup{}).
+		Where("ip_address = ?", ipAddress).
+		Where("created_at >= ?", startOfDay).
+		Count(&signupCount).Error; err != nil {
+		return nil, err
+	}
+
+	if signupCount >= dailySignupLimitPerIP {
+		return nil, ErrDailyWaitlistSignupLimitReached
+	}
+
+	var waitlist models.Waitlist
+	if err := s.DB.Where("project_id = ?", request.ProjectID).First(&waitlist).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWaitlistNotFound
+		}
+		return nil, err
+	}
+
+	// Draft and unpublished pages don't take signups.
+	var published int64
+	if err := s.DB.Model(&models.Project{}).
+		Where("id = ? AND published = ?", waitlist.ProjectID, true).
+		Count(&published).Error; err != nil {
+		return nil, err
+	}
+	if published == 0 {
+		return nil, ErrWaitlistNotFound
+	}
+
+	email := strings.ToLower(strings.TrimSpace(request.Email))
+
+	var existing int64
+	if err := s.DB.Model(&models.WaitlistSignup{}).
+		Where("waitlist_id = ? AND email = ?", waitlist.ID, email).
+		Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, ErrAlreadySignedUp
+	}
+
+	answers, err := collectAnswers(waitlist.Fields, request.Answers)
+	if err != nil {
+		return nil, err
+	}
+
+	referralCode, err := generateReferralCode()
+	if err != nil {
+		return nil, err
+	}
+
+	signup := models.WaitlistSignup{
+		WaitlistID:   waitlist.ID,
+		ProjectID:    waitlist.ProjectID,
+		Email:        email,
+		Name:         normalizeOptionalString(request.Name),
+		Answers:      marshalJSON(answers),
+		ReferralCode: referralCode,
+		IPAddress:    ipAddress,
+		UserAgent:    normalizeOptionalString(userAgent),
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		var total int64
+		if err := tx.Model(&models.WaitlistSignup{}).Where("waitlist_id = ?", waitlist.ID).Count(&total).Error; err != nil {
+			return err
+		}
+		signup.Priority = int(total) + 1
+
+		if waitlist.ReferralsEnabled && request.ReferralCode != nil && strings.TrimSpace(*request.ReferralCode) != "" {
+			var referrer models.WaitlistSignup
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("waitlist_id = ? AND referral_code = ?", waitlist.ID, strings.TrimSpace(*request.ReferralCode)).
+				First(&referrer).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			if err == nil {
+				signup.ReferredByID = &referrer.ID
+				if err := tx.Model(&referrer).Updates(map[string]any{
+					"referral_count": gorm.Expr("referral_count + 1"),
+					"priority":       gorm.Expr("priority - ?", waitlist.ReferralBoost),
+				}).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.Create(&signup).Error
+	})
+	// Two requests for the same address can both pass the count above; the unique index settles it.
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ErrAlreadySignedUp
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Status(signup.ReferralCode)
+}
+
+func (s *Service) Status(referralCode string) (*SignupStatusResponse, error) {
+	var signup models.WaitlistSignup
+	if err := s.DB.Where("referral_code = ?", referralCode).First(&signup).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSignupNotFound
+		}
+		return nil, err
+	}
+
+	position, err := s.position(&signup)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	if err := s.DB.Model(&models.WaitlistSignup{}).Where("waitlist_id = ?", signup.WaitlistID).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	return &SignupStatusResponse{
+		ReferralCode:  signup.ReferralCode,
+		ReferralCount: signup.ReferralCount,
+		Position:      position,
+		Total:         total,
+	}, nil
+}
+
+func (s *Service) ListSignups(projectID int64, page int, limit int) ([]models.WaitlistSignup, int64, error) {
+	var signups []models.WaitlistSignup
+	var total int64
+
+	query := s.DB.Model(&models.WaitlistSignup{}).Where("project_id = ?", projectID)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.
+		Order("priority ASC").
+		Order("id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&signups).Error; err != nil {
+		return nil, 0, err
+	}
+
+	for i := range signups {
+		signups[i].Position = int64(offset + i + 1)
+	}
+
+	return signups, total, nil
+}
+
+// ExportSignups returns every signup in queue order together with the field keys to use as columns.
+func (s *Service) ExportSignups(projectID int64) ([]models.WaitlistSignup, []FieldRequest, error) {
+	waitlist, err := s.Get(projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var signups []models.WaitlistSignup
+	if err := s.DB.
+		Where("waitlist_id = ?", waitlist.ID).
+		Order("priority ASC").
+		Order("id ASC").
+		Find(&signups).Error; err != nil {
+		return nil, nil, err
+	}
+
+	for i := range signups {
+		signups[i].Position = int64(i + 1)
+	}
+
+	return signups, decodeFields(waitlist.Fields), nil
+}
+
+func (s *Service) position(signup *models.WaitlistSignup) (int64, error) {
+	var ahead int64
+	if err := s.DB.Model(&models.WaitlistSignup{}).
+		Where("waitlist_id = ?", signup.WaitlistID).
+		Where("priority < ? OR (priority = ? AND id < ?)", signup.Priority, signup.Priority, signup.ID).
+		Count(&ahead).Error; err != nil {
+		return 0, err
+	}
+
+	return ahead + 1, nil
+}
+
+func collectAnswers(rawFields *json.RawMessage, submitted map[string]string) (map[string]string, error) {
+	answers := map[string]string{}
+
+	for _, field := range decodeFields(rawFields) {
+		value := strings.TrimSpace(submitted[field.Key])
+		if value == "" {
+			if field.Required {
+				return nil, fmt.Errorf("%w: %s", ErrMissingRequiredField, field.Label)
+			}
+			continue
+		}
+		answers[field.Key] = value
+	}
+
+	return answers, nil
+}
+
+func normalizeFields(fields []FieldRequest) []FieldRequest {
+	normalized := make([]FieldRequest, 0, len(fields))
+	seen := map[string]bool{}
+
+	for _, field := range fields {
+		field.Key = strings.TrimSpace(field.Key)
+		if field.Key == "" || seen[field.Key] {
+			continue
+		}
+		seen[field.Key] = true
+
+		field.Label = strings.TrimSpace(field.Label)
+		if field.Label == "" {
+			field.Label = field.Key
+		}
+		if field.Type == "" {
+			field.Type = "text"
+		}
+
+		normalized = append(normalized, field)
+	}
+
+	return normalized
+}
+
+func decodeFields(raw *json.RawMessage) []FieldRequest {
+	if raw == nil {
+		return nil
+	}
+
+	var fields []FieldRequest
+	if err := json.Unmarshal(*raw, &fields); err != nil {
+		return nil
+	}
+
+	return fields
+}
+
+func marshalJSON(value any) *json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil || string(encoded) == "null" || string(encoded) == "[]" || string(encoded) == "{}" {
+		return nil
+	}
+	raw := json.RawMessage(encoded)
+	return &raw
+}
+
+func normalizeOptionalString(value *string) *string {
+	if value == nil {
+		return nil
+	}
+
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+
+	return &trimmed
+}
+
+func generateReferralCode() (string, error) {
+	buffer := make([]byte, 6)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+
+	return strings.ToUpper(hex.EncodeToString(buffer)), nil
+}
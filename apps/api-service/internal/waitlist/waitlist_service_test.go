@@ -0,0 +1,136 @@
+package waitlist
+
+import (
+	"encoding/json"
+	"errors"
+	"flash/models"
+	"flash/shared/testdb"
+	"fmt"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// newWaitlist returns a service with a published waitlist on project 1 that asks for a required "company" field.
+func newWaitlist(t *testing.T) *Service {
+	t.Helper()
+
+	db := testdb.Open(t, &models.Project{}, &models.Waitlist{}, &models.WaitlistSignup{})
+	fields := json.RawMessage(`[{"key":"company","label":"Company","type":"text","required":true}]`)
+	testdb.Create(t, db,
+		&models.Project{ID: 1, UserID: 1, Name: "Launch", Slug: "launch", Type: "waitlist", Published: true},
+		&models.Project{ID: 2, UserID: 1, Name: "Draft", Slug: "draft", Type: "waitlist"},
+		&models.Waitlist{ID: 1, ProjectID: 1, UserID: 1, Fields: &fields, ReferralsEnabled: true, ReferralBoost: 5},
+		&models.Waitlist{ID: 2, ProjectID: 2, UserID: 1},
+	)
+
+	return NewService(db)
+}
+
+func signupRequest(projectID uint64, email string) CreateSignupRequest {
+	return CreateSignupRequest{ProjectID: projectID, Email: email, Answers: map[string]string{"company": "Acme"}}
+}
+
+func TestSignup(t *testing.T) {
+	service := newWaitlist(t)
+
+	first, err := service.Signup(signupRequest(1, " Ada@Example.com "), "10.0.0.1", nil)
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	if first.Position != 1 || first.Total != 1 || first.ReferralCode == "" {
+		t.Fatalf("Signup() = %+v", first)
+	}
+
+	var stored models.WaitlistSignup
+	if err := service.DB.Where("referral_code = ?", first.ReferralCode).First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Email != "ada@example.com" || string(*stored.Answers) != `{"company":"Acme"}` {
+		t.Fatalf("stored signup = %q, %s", stored.Email, *stored.Answers)
+	}
+
+	tests := []struct {
+		name    string
+		request CreateSignupRequest
+		wantErr error
+	}{
+		{name: "same address again", request: signupRequest(1, "ADA@example.com"), wantErr: ErrAlreadySignedUp},
+		{name: "missing required field", request: CreateSignupRequest{ProjectID: 1, Email: "bob@example.com"}, wantErr: ErrMissingRequiredField},
+		{name: "unpublished project", request: signupRequest(2, "bob@example.com"), wantErr: ErrWaitlistNotFound},
+		{name: "no waitlist", request: signupRequest(3, "bob@example.com"), wantErr: ErrWaitlistNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := service.Signup(test.request, "10.0.0.2", nil); !errors.Is(err, test.wantErr) {
+				t.Fatalf("Signup() error = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestSignupMapsDuplicateKeyToAlreadySignedUp(t *testing.T) {
+	service := newWaitlist(t)
+
+	// Play the other request in the race: the same address lands between the count and the insert.
+	raced := false
+	err := service.DB.Callback().Create().Before("gorm:create").Register("test:race", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*models.WaitlistSignup); !ok || raced {
+			return
+		}
+		raced = true
+		tx.Exec("INSERT INTO waitlist_signups (waitlist_id, project_id, email, referral_code, priority, ip_address) VALUES (1, 1, 'ada@example.com', 'RACE', 1, '10.0.0.9')")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.Signup(signupRequest(1, "ada@example.com"), "10.0.0.1", nil); !errors.Is(err, ErrAlreadySignedUp) {
+		t.Fatalf("Signup() error = %v, want %v", err, ErrAlreadySignedUp)
+	}
+	if !raced {
+		t.Fatal("the racing insert never ran")
+	}
+}
+
+func TestSignupReferralMovesTheReferrerUp(t *testing.T) {
+	service := newWaitlist(t)
+
+	var codes []string
+	for i := 0; i < 3; i++ {
+		status, err := service.Signup(signupRequest(1, fmt.Sprintf("user%d@example.com", i)), fmt.Sprintf("10.0.0.%d", i), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		codes = append(codes, status.ReferralCode)
+	}
+
+	referred := signupRequest(1, "friend@example.com")
+	referred.ReferralCode = &codes[2]
+	if _, err := service.Signup(referred, "10.0.0.9", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := service.Status(codes[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.ReferralCount != 1 || status.Position != 1 || status.Total != 4 {
+		t.Fatalf("referrer status = %+v, want 1 referral at position 1 of 4", status)
+	}
+}
+
+func TestSignupDailyLimitPerIP(t *testing.T) {
+	service := newWaitlist(t)
+
+	for i := 0; i < dailySignupLimitPerIP; i++ {
+		if _, err := service.Signup(signupRequest(1, fmt.Sprintf("user%d@example.com", i)), "10.0.0.1", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := service.Signup(signupRequest(1, "late@example.com"), "10.0.0.1", nil); !errors.Is(err, ErrDailyWaitlistSignupLimitReached) {
+		t.Fatalf("Signup() error = %v, want %v", err, ErrDailyWaitlistSignupLimitReached)
+	}
+}
@@ -0,0 +1,292 @@
+package workspace
+
+import (
+	"errors"
+	"flash/shared/access"
+	"flash/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Controller struct {
+	Service *Service
+}
+
+func NewController(db *gorm.DB) *Controller {
+	return &Controller{Service: NewService(db)}
+}
+
+func (controller Controller) List(context *gin.Context) {
+	workspaces, err := controller.Service.List(context.GetUint64("user_id"))
+	if err != nil {
+		respondWorkspaceError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, workspaces)
+}
+
+func (controller Controller) Create(context *gin.Context) {
+	var request CreateUpdateWorkspaceRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	workspace, err := controller.Service.Create(context.GetUint64("user_id"), request)
+	if err != nil {
+		respondWorkspaceError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, workspace)
+}
+
+func (controller Controller) Show(context *gin.Context) {
+	workspaceID, ok := paramID(context, "id")
+	if !ok {
+		return
+	}
+
+	workspace, err := controller.Service.Show(context.GetUint64("user_id"), workspaceID)
+	if err != nil {
+		respondWorkspaceError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, workspace)
+}
+
+func (controller Controller) Update(context *gin.Context) {
+	workspaceID, ok := paramID(context, "id")
+	if !ok {
+		return
+	}
+
+	var request CreateUpdateWorkspaceRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	workspace, err := controller.Service.Update(context.GetUint64("user_id"), workspaceID, request)
+	if err != nil {
+		respondWorkspaceError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, workspace)
+}
+
+func (controller Controller) Delete(context *gin.Context) {
+	workspaceID, ok := paramID(context, "id")
+	if !ok {
+		return
+	}
+
+	if err := controller.Service.Delete(context.GetUint64("user_id"), workspaceID); err != nil {
+		respondWorkspaceError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"deleted": true})
+}
+
+func (controller Controller) ListMembers(context *gin.Context) {
+	workspaceID, ok := paramID(context, "id")
+	if !ok {
+		return
+	}
+
+	members, err := controller.Service.ListMembers(context.GetUint64("user_id"), workspaceID)
+	if err != nil {
+		respondWorkspaceError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, members)
+}
+
+func (controller Controller) UpdateMember(context *gin.Context) {
+	workspaceID, ok := paramID(context, "id")
+	if !ok {
+		return
+	}
+	memberID, ok := paramID(context, "member_id")
+	if !ok {
+		return
+	}
+
+	var request UpdateMemberRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	member, err := controller.Service.UpdateMember(context.GetUint64("user_id"), workspaceID, memberID, request)
+	if err != nil {
+		respondWorkspaceError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, member)
+}
+
+func (controller Controller) RemoveMember(context *gin.Context) {
+	workspaceID, ok := paramID(context, "id")
+	if !ok {
+		return
+	}
+	memberID, ok := paramID(context, "member_id")
+	if !ok {
+		return
+	}
+
+	if err := controller.Service.RemoveMember(context.GetUint64("user_id"), workspaceID, memberID); err != nil {
+		respondWorkspaceError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"deleted": true})
+}
+
+func (controller Controller) Invite(context *gin.Context) {
+	workspaceID, ok := paramID(context, "id")
+	if !ok {
+		return
+	}
+
+	var request InviteMemberRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	invitation, err := controller.Service.Invite(context.GetUint64("user_id"), workspaceID, request)
+	if err != nil {
+		respondWorkspaceError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, invitation)
+}
+
+func (controller Controller) ListInvitations(context *gin.Context) {
+	workspaceID, ok := paramID(context, "id")
+	if !ok {
+		return
+	}
+
+	invitations, err := controller.Service.ListInvitations(context.GetUint64("user_id"), workspaceID)
+	if err != nil {
+		respondWorkspaceError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, invitations)
+}
+
+func (controller Controller) RevokeInvitation(context *gin.Context) {
+	workspaceID, ok := paramID(context, "id")
+	if !ok {
+		return
+	}
+	invitationID, ok := paramID(context, "invitation_id")
+	if !ok {
+		return
+	}
+
+	if err := controller.Service.RevokeInvitation(context.GetUint64("user_id"), workspaceID, invitationID); err != nil {
+		respondWorkspaceError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, gin.H{"deleted": true})
+}
+
+func (controller Controller) AcceptInvitation(context *gin.Context) {
+	var request AcceptInvitationRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return
+	}
+
+	member, err := controller.Service.AcceptInvitation(context.GetUint64("user_id"), request.Token)
+	if err != nil {
+		respondWorkspaceError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, member)
+}
+
+func (controller Controller) AttachProject(context *gin.Context) {
+	workspaceID, ok := paramID(context, "id")
+	if !ok {
+		return
+	}
+	projectID, ok := paramID(context, "project_id")
+	if !ok {
+		return
+	}
+
+	project, err := controller.Service.AttachProject(context.GetUint64("user_id"), context.GetString("user_role"), workspaceID, projectID)
+	if err != nil {
+		respondWorkspaceError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, project)
+}
+
+func (controller Controller) DetachProject(context *gin.Context) {
+	workspaceID, ok := paramID(context, "id")
+	if !ok {
+		return
+	}
+	projectID, ok := paramID(context, "project_id")
+	if !ok {
+		return
+	}
+
+	project, err := controller.Service.DetachProject(context.GetUint64("user_id"), context.GetString("user_role"), workspaceID, projectID)
+	if err != nil {
+		respondWorkspaceError(context, err)
+		return
+	}
+
+	utils.APIRespondSuccess(context, http.StatusOK, project)
+}
+
+func paramID(context *gin.Context, name string) (uint64, bool) {
+	id, err := strconv.ParseUint(context.Param(name), 10, 64)
+	if err != nil {
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+		context.Abort()
+		return 0, false
+	}
+
+	return id, true
+}
+
+func respondWorkspaceError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrWorkspaceForbidden), errors.Is(err, ErrInvitationEmail), errors.Is(err, access.ErrProjectForbidden):
+		utils.APIRespondError(context, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrWorkspaceNotFound), errors.Is(err, ErrMemberNotFound), errors.Is(err, access.ErrProjectNotFound):
+		utils.APIRespondError(context, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrAlreadyMember):
+		utils.APIRespondError(context, http.StatusConflict, err.Error())
+	default:
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+	}
+	context.Abort()
+}
@@ -0,0 +1,27 @@
+package workspace
+
+type CreateUpdateWorkspaceRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+type InviteMemberRequest struct {
+	Email string `json:"email" binding:"required,email,max=255"`
+	Role  string `json:"role" binding:"required,oneof=editor viewer"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=editor viewer"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type InvitationResponse struct {
+	ID          uint64 `json:"id"`
+	WorkspaceID uint64 `json:"workspace_id"`
+	Email       string `json:"email"`
+	Role        string `json:"role"`
+	Token       string `json:"token"`
+	ExpiresAt   string `json:"expires_at"`
+}
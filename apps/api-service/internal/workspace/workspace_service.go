@@ -0,0 +1,396 @@
+package workspace
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flash/models"
+	"flash/shared/access"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const invitationLifeSpan = 7 * 24 * time.Hour
+
+var (
+	ErrWorkspaceNotFound  = errors.New("workspace not found")
+	ErrWorkspaceForbidden = errors.New("you do not have access to this workspace")
+	ErrMemberNotFound     = errors.New("workspace member not found")
+	ErrOwnerMembership    = errors.New("the workspace owner cannot be changed or removed")
+	ErrInvitationInvalid  = errors.New("invitation is invalid or has expired")
+	ErrInvitationEmail    = errors.New("invitation was sent to a different email address")
+	ErrAlreadyMember      = errors.New("user is already a member of this workspace")
+)
+
+type Service struct {
+	DB *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+func (service Service) Create(userID uint64, request CreateUpdateWorkspaceRequest) (*models.Workspace, error) {
+	workspace := models.Workspace{
+		OwnerID: userID,
+		Name:    strings.TrimSpace(request.Name),
+	}
+
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&workspace).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.WorkspaceMember{
+			WorkspaceID: workspace.ID,
+			UserID:      userID,
+			Role:        access.RoleOwner,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return service.Show(userID, workspace.ID)
+}
+
+func (service Service) List(userID uint64) ([]models.Workspace, error) {
+	var workspaces []models.Workspace
+
+	if err := service.DB.
+		Where("id IN (?)", access.MemberWorkspaceIDs(service.DB, userID)).
+		Preload("Members.User").
+		Order("created_at DESC").
+		Find(&workspaces).Error; err != nil {
+		return nil, err
+	}
+
+	return workspaces, nil
+}
+
+func (service Service) Show(userID uint64, workspaceID uint64) (*models.Workspace, error) {
+	if _, err := service.memberRole(userID, workspaceID); err != nil {
+		return nil, err
+	}
+
+	var workspace models.Workspace
+	if err := service.DB.Preload("Members.User").First(&workspace, workspaceID).Error; err != nil {
+		return nil, err
+	}
+
+	return &workspace, nil
+}
+
+func (service Service) Update(userID uint64, workspaceID uint64, request CreateUpdateWorkspaceRequest) (*models.Workspace, error) {
+	if err := service.requireRole(userID, workspaceID, access.PermissionManage); err != nil {
+		return nil, err
+	}
+
+	if err := service.DB.Model(&models.Workspace{}).
+		Where("id = ?", workspaceID).
+		Update("name", strings.TrimSpace(request.Name)).Error; err != nil {
+		return nil, err
+	}
+
+	return service.Show(userID, workspaceID)
+}
+
+func (service Service) Delete(userID uint64, workspaceID uint64) error {
+	if err := service.requireRole(userID, workspaceID, access.PermissionManage); err != nil {
+		return err
+	}
+
+	return service.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Project{}).
+			Where("workspace_id = ?", workspaceID).
+			Update("workspace_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id = ?", workspaceID).Delete(&models.WorkspaceMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id = ?", workspaceID).Delete(&models.WorkspaceInvitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Workspace{}, workspaceID).Error
+	})
+}
+
+func (service Service) ListMembers(userID uint64, workspaceID uint64) ([]models.WorkspaceMember, error) {
+	if _, err := service.memberRole(userID, workspaceID); err != nil {
+		return nil, err
+	}
+
+	var members []models.WorkspaceMember
+	if err := service.DB.
+		Preload("User").
+		Where("workspace_id = ?", workspaceID).
+		Order("id ASC").
+		Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (service Service) UpdateMember(userID uint64, workspaceID uint64, memberID uint64, request UpdateMemberRequest) (*models.WorkspaceMember, error) {
+	if err := service.requireRole(userID, workspaceID, access.PermissionManage); err != nil {
+		return nil, err
+	}
+
+	member, err := service.findMember(workspaceID, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	if member.Role == access.RoleOwner {
+		return nil, ErrOwnerMembership
+	}
+
+	member.Role = request.Role
+	if err := service.DB.Save(member).Error; err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// RemoveMember lets the owner remove anyone but themselves, and lets any member leave.
+func (service Service) RemoveMember(userID uint64, workspaceID uint64, memberID uint64) error {
+	member, err := service.findMember(workspaceID, memberID)
+	if err != nil {
+		return err
+	}
+
+	if member.Role == access.RoleOwner {
+		return ErrOwnerMembership
+	}
+
+	if member.UserID != userID {
+		if err := service.requireRole(userID, workspaceID, access.PermissionManage); err != nil {
+			return err
+		}
+	}
+
+	return service.DB.Delete(member).Error
+}
+
+func (service Service) Invite(userID uint64, workspaceID uint64, request InviteMemberRequest) (*InvitationResponse, error) {
+	if err := service.requireRole(userID, workspaceID, access.PermissionManage); err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(request.Email))
+
+	var count int64
+	if err := service.DB.Model(&models.WorkspaceMember{}).
+		Joins("JOIN users ON users.id = workspace_members.user_id").
+		Where("workspace_members.workspace_id = ? AND LOWER(users.email) = ?", workspaceID, email).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrAlreadyMember
+	}
+
+	token, tokenHash, err := generateInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := models.WorkspaceInvitation{
+		WorkspaceID: workspaceID,
+		InvitedBy:   userID,
+		Email:       email,
+		Role:        request.Role,
+		TokenHash:   tokenHash,
+		ExpiresAt:   time.Now().Add(invitationLifeSpan),
+	}
+
+	if err := service.DB.Create(&invitation).Error; err != nil {
+		return nil, err
+	}
+
+	return &InvitationResponse{
+		ID:          invitation.ID,
+		WorkspaceID: invitation.WorkspaceID,
+		Email:       invitation.Email,
+		Role:        invitation.Role,
+		Token:       token,
+		ExpiresAt:   invitation.ExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+func (service Service) ListInvitations(userID uint64, workspaceID uint64) ([]models.WorkspaceInvitation, error) {
+	if err := service.requireRole(userID, workspaceID, access.PermissionManage); err != nil {
+		return nil, err
+	}
+
+	var invitations []models.WorkspaceInvitation
+	if err := service.DB.
+		Where("workspace_id = ? AND accepted_at IS NULL AND expires_at > ?", workspaceID, time.Now()).
+		Order("created_at DESC").
+		Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func (service Service) RevokeInvitation(userID uint64, workspaceID uint64, invitationID uint64) error {
+	if err := service.requireRole(userID, workspaceID, access.PermissionManage); err != nil {
+		return err
+	}
+
+	return service.DB.
+		Where("workspace_id = ? AND id = ? AND accepted_at IS NULL", workspaceID, invitationID).
+		Delete(&models.WorkspaceInvitation{}).Error
+}
+
+func (service Service) AcceptInvitation(userID uint64, token string) (*models.WorkspaceMember, error) {
+	var user models.User
+	if err := service.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var member models.WorkspaceMember
+	err := service.DB.Transaction(func(tx *gorm.DB) error {
+		var invitation models.WorkspaceInvitation
+		if err := tx.
+			Where("token_hash = ? AND accepted_at IS NULL AND expires_at > ?", hashInvitationToken(token), time.Now()).
+			First(&invitation).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvitationInvalid
+			}
+			return err
+		}
+
+		if !strings.EqualFold(invitation.Email, user.Email) {
+			return ErrInvitationEmail
+		}
+
+		err := tx.Where("workspace_id = ? AND user_id = ?", invitation.WorkspaceID, userID).First(&member).Error
+		if err == nil {
+			return ErrAlreadyMember
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		member = models.WorkspaceMember{
+			WorkspaceID: invitation.WorkspaceID,
+			UserID:      userID,
+			Role:        invitation.Role,
+		}
+		if err := tx.Create(&member).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&invitation).Update("accepted_at", &now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// AttachProject moves a project the user owns into a workspace they can edit.
+func (service Service) AttachProject(userID uint64, userRole string, workspaceID uint64, projectID uint64) (*models.Project, error) {
+	if err := service.requireRole(userID, workspaceID, access.PermissionWrite); err != nil {
+		return nil, err
+	}
+
+	project, _, err := access.AuthorizeProject(service.DB, userID, userRole, projectID, access.PermissionManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.DB.Model(project).Update("workspace_id", workspaceID).Error; err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (service Service) DetachProject(userID uint64, userRole string, workspaceID uint64, projectID uint64) (*models.Project, error) {
+	project, _, err := access.AuthorizeProject(service.DB, userID, userRole, projectID, access.PermissionManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.WorkspaceID == nil || *project.WorkspaceID != workspaceID {
+		return nil, access.ErrProjectNotFound
+	}
+
+	if err := service.DB.Model(project).Update("workspace_id", nil).Error; err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (service Service) memberRole(userID uint64, workspaceID uint64) (string, error) {
+	var workspace models.Workspace
+	if err := service.DB.Select("id").First(&workspace, workspaceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrWorkspaceNotFound
+		}
+		return "", err
+	}
+
+	var member models.WorkspaceMember
+	err := service.DB.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrWorkspaceForbidden
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return member.Role, nil
+}
+
+func (service Service) requireRole(userID uint64, workspaceID uint64, permission access.Permission) error {
+	role, err := service.memberRole(userID, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	if !access.RoleAllows(role, permission) {
+		return ErrWorkspaceForbidden
+	}
+
+	return nil
+}
+
+func (service Service) findMember(workspaceID uint64, memberID uint64) (*models.WorkspaceMember, error) {
+	var member models.WorkspaceMember
+	if err := service.DB.Where("workspace_id = ? AND id = ?", workspaceID, memberID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+func generateInvitationToken() (string, string, error) {
+	buffer := make([]byte, 32)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", "", err
+	}
+
+	token := hex.EncodeToString(buffer)
+	return token, hashInvitationToken(token), nil
+}
+
+func hashInvitationToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
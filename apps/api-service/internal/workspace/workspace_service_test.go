@@ -0,0 +1,211 @@
+package workspace
+
+import (
+	"errors"
+	"flash/models"
+	"flash/shared/access"
+	"flash/shared/testdb"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// newWorkspace returns a service with a workspace owned by user 1, where user 2 is an editor and user 3 a viewer.
+// User 4 is signed up but not a member.
+func newWorkspace(t *testing.T) (Service, *models.Workspace) {
+	t.Helper()
+
+	db := testdb.Open(t, &models.User{}, &models.Workspace{}, &models.WorkspaceMember{}, &models.WorkspaceInvitation{}, &models.Project{})
+	password := ""
+	testdb.Create(t, db,
+		&models.User{ID: 1, FirstName: "Owner", Email: "owner@example.com", Password: &password},
+		&models.User{ID: 2, FirstName: "Editor", Email: "editor@example.com", Password: &password},
+		&models.User{ID: 3, FirstName: "Viewer", Email: "viewer@example.com", Password: &password},
+		&models.User{ID: 4, FirstName: "Guest", Email: "guest@example.com", Password: &password},
+	)
+
+	service := Service{DB: db}
+	workspace, err := service.Create(1, CreateUpdateWorkspaceRequest{Name: " Studio "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testdb.Create(t, db,
+		&models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: 2, Role: access.RoleEditor},
+		&models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: 3, Role: access.RoleViewer},
+	)
+
+	return service, workspace
+}
+
+func memberID(t *testing.T, db *gorm.DB, workspaceID uint64, userID uint64) uint64 {
+	t.Helper()
+
+	var member models.WorkspaceMember
+	if err := db.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&member).Error; err != nil {
+		t.Fatal(err)
+	}
+	return member.ID
+}
+
+func TestCreateMakesTheCreatorOwner(t *testing.T) {
+	service, workspace := newWorkspace(t)
+
+	if workspace.Name != "Studio" || workspace.OwnerID != 1 {
+		t.Fatalf("Create() = %+v", workspace)
+	}
+	role, err := service.memberRole(1, workspace.ID)
+	if err != nil || role != access.RoleOwner {
+		t.Fatalf("creator role = %q, %v, want owner", role, err)
+	}
+}
+
+func TestWorkspaceRoles(t *testing.T) {
+	service, workspace := newWorkspace(t)
+	rename := CreateUpdateWorkspaceRequest{Name: "Renamed"}
+	invite := InviteMemberRequest{Email: "new@example.com", Role: access.RoleViewer}
+
+	tests := []struct {
+		name    string
+		call    func() error
+		wantErr error
+	}{
+		{name: "viewer reads", call: func() error { _, err := service.Show(3, workspace.ID); return err }},
+		{name: "outsider cannot read", call: func() error { _, err := service.Show(4, workspace.ID); return err }, wantErr: ErrWorkspaceForbidden},
+		{name: "unknown workspace", call: func() error { _, err := service.Show(1, 999); return err }, wantErr: ErrWorkspaceNotFound},
+		{name: "editor cannot rename", call: func() error { _, err := service.Update(2, workspace.ID, rename); return err }, wantErr: ErrWorkspaceForbidden},
+		{name: "editor cannot invite", call: func() error { _, err := service.Invite(2, workspace.ID, invite); return err }, wantErr: ErrWorkspaceForbidden},
+		{name: "viewer cannot list invitations", call: func() error { _, err := service.ListInvitations(3, workspace.ID); return err }, wantErr: ErrWorkspaceForbidden},
+		{name: "editor cannot delete", call: func() error { return service.Delete(2, workspace.ID) }, wantErr: ErrWorkspaceForbidden},
+		{name: "owner renames", call: func() error { _, err := service.Update(1, workspace.ID, rename); return err }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.call(); !errors.Is(err, test.wantErr) {
+				t.Fatalf("error = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestMembership(t *testing.T) {
+	service, workspace := newWorkspace(t)
+	ownerMember := memberID(t, service.DB, workspace.ID, 1)
+	editorMember := memberID(t, service.DB, workspace.ID, 2)
+	viewerMember := memberID(t, service.DB, workspace.ID, 3)
+
+	if _, err := service.UpdateMember(1, workspace.ID, ownerMember, UpdateMemberRequest{Role: access.RoleViewer}); !errors.Is(err, ErrOwnerMembership) {
+		t.Fatalf("demoting the owner: error = %v, want %v", err, ErrOwnerMembership)
+	}
+	if err := service.RemoveMember(1, workspace.ID, ownerMember); !errors.Is(err, ErrOwnerMembership) {
+		t.Fatalf("removing the owner: error = %v, want %v", err, ErrOwnerMembership)
+	}
+	if err := service.RemoveMember(2, workspace.ID, viewerMember); !errors.Is(err, ErrWorkspaceForbidden) {
+		t.Fatalf("editor removing a viewer: error = %v, want %v", err, ErrWorkspaceForbidden)
+	}
+
+	member, err := service.UpdateMember(1, workspace.ID, viewerMember, UpdateMemberRequest{Role: access.RoleEditor})
+	if err != nil || member.Role != access.RoleEditor {
+		t.Fatalf("UpdateMember() = %+v, %v", member, err)
+	}
+
+	// Anyone may leave.
+	if err := service.RemoveMember(2, workspace.ID, editorMember); err != nil {
+		t.Fatalf("editor leaving: %v", err)
+	}
+	if _, err := service.Show(2, workspace.ID); !errors.Is(err, ErrWorkspaceForbidden) {
+		t.Fatalf("Show() after leaving: error = %v, want %v", err, ErrWorkspaceForbidden)
+	}
+}
+
+func TestInvitations(t *testing.T) {
+	service, workspace := newWorkspace(t)
+
+	invitation, err := service.Invite(1, workspace.ID, InviteMemberRequest{Email: " Guest@Example.com ", Role: access.RoleEditor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invitation.Email != "guest@example.com" || invitation.Token == "" {
+		t.Fatalf("Invite() = %+v", invitation)
+	}
+
+	if _, err := service.Invite(1, workspace.ID, InviteMemberRequest{Email: "viewer@example.com", Role: access.RoleEditor}); !errors.Is(err, ErrAlreadyMember) {
+		t.Fatalf("inviting a member: error = %v, want %v", err, ErrAlreadyMember)
+	}
+
+	// The token only works for the address it was sent to.
+	if _, err := service.AcceptInvitation(3, invitation.Token); !errors.Is(err, ErrInvitationEmail) {
+		t.Fatalf("accepting someone else's invitation: error = %v, want %v", err, ErrInvitationEmail)
+	}
+	if _, err := service.AcceptInvitation(4, "not-a-token"); !errors.Is(err, ErrInvitationInvalid) {
+		t.Fatalf("unknown token: error = %v, want %v", err, ErrInvitationInvalid)
+	}
+
+	member, err := service.AcceptInvitation(4, invitation.Token)
+	if err != nil {
+		t.Fatalf("AcceptInvitation() error = %v", err)
+	}
+	if member.WorkspaceID != workspace.ID || member.Role != access.RoleEditor {
+		t.Fatalf("AcceptInvitation() = %+v", member)
+	}
+
+	if _, err := service.AcceptInvitation(4, invitation.Token); !errors.Is(err, ErrInvitationInvalid) {
+		t.Fatalf("reused token: error = %v, want %v", err, ErrInvitationInvalid)
+	}
+}
+
+func TestAcceptInvitationRejectsExpiredTokens(t *testing.T) {
+	service, workspace := newWorkspace(t)
+
+	invitation, err := service.Invite(1, workspace.ID, InviteMemberRequest{Email: "guest@example.com", Role: access.RoleViewer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DB.Model(&models.WorkspaceInvitation{}).Where("id = ?", invitation.ID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.AcceptInvitation(4, invitation.Token); !errors.Is(err, ErrInvitationInvalid) {
+		t.Fatalf("AcceptInvitation() error = %v, want %v", err, ErrInvitationInvalid)
+	}
+}
+
+func TestAttachProject(t *testing.T) {
+	service, workspace := newWorkspace(t)
+	testdb.Create(t, service.DB,
+		&models.Project{ID: 10, UserID: 2, Name: "Editor's", Slug: "editors", Type: "menu"},
+		&models.Project{ID: 11, UserID: 3, Name: "Viewer's", Slug: "viewers", Type: "menu"},
+		&models.Project{ID: 12, UserID: 1, Name: "Owner's", Slug: "owners", Type: "menu"},
+	)
+
+	// A viewer can't add projects, and nobody can move a project they don't own.
+	if _, err := service.AttachProject(3, "user", workspace.ID, 11); !errors.Is(err, ErrWorkspaceForbidden) {
+		t.Fatalf("viewer attaching: error = %v, want %v", err, ErrWorkspaceForbidden)
+	}
+	if _, err := service.AttachProject(2, "user", workspace.ID, 12); !errors.Is(err, access.ErrProjectForbidden) {
+		t.Fatalf("editor attaching the owner's project: error = %v, want %v", err, access.ErrProjectForbidden)
+	}
+
+	project, err := service.AttachProject(2, "user", workspace.ID, 10)
+	if err != nil {
+		t.Fatalf("AttachProject() error = %v", err)
+	}
+	if project.WorkspaceID == nil || *project.WorkspaceID != workspace.ID {
+		t.Fatalf("AttachProject() = %+v", project)
+	}
+
+	// The viewer now reaches the project through the workspace, read only.
+	role, err := access.ProjectRole(service.DB, 3, "user", project)
+	if err != nil || role != access.RoleViewer {
+		t.Fatalf("viewer role on attached project = %q, %v", role, err)
+	}
+
+	if _, err := service.DetachProject(2, "user", workspace.ID, 10); err != nil {
+		t.Fatalf("DetachProject() error = %v", err)
+	}
+	if _, err := access.ProjectRole(service.DB, 3, "user", &models.Project{ID: 10, UserID: 2}); !errors.Is(err, access.ErrProjectForbidden) {
+		t.Fatalf("viewer role after detach: error = %v, want %v", err, access.ErrProjectForbidden)
+	}
+}
@@ -1,14 +1,29 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"flash/database"
+	"flash/internal/account"
+	"flash/internal/document"
+	"flash/internal/job"
+	"flash/internal/menu"
+	"flash/internal/parsed_file"
+	"flash/internal/project"
 	"flash/middleware"
 	"flash/routes"
 	"flash/sdk/llm"
+	"flash/sdk/mailer"
 	objectStorage "flash/sdk/object_storage"
+	"flash/sdk/oidc"
+	sharedjwt "flash/shared/jwt"
+	"flash/shared/scheduler"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -26,6 +41,124 @@ func configureGin(env string) {
 	log.Printf("[INFO] 🚀 Application running in %s mode", env)
 }
 
+func initLLM(providerName string) llm.Provider {
+	switch providerName {
+	case "gemini":
+		log.Printf("[INFO] ✅ LLM initialized using: Google Gemini (%s)", os.Getenv("LLM_MODEL"))
+		return &llm.GeminiSDK{
+			ApiKey: os.Getenv("LLM_API_KEY"),
+			Model:  os.Getenv("LLM_MODEL"),
+		}
+	case "local":
+		baseURL := os.Getenv("LLM_BASE_URL")
+		if baseURL == "" {
+			baseURL = "http://localhost:11434/v1"
+		}
+		log.Printf("[INFO] ✅ LLM initialized using: OpenAI-compatible server at %s (%s)", baseURL, os.Getenv("LLM_MODEL"))
+		return &llm.OpenAICompatibleSDK{
+			BaseURL: baseURL,
+			ApiKey:  os.Getenv("LLM_API_KEY"),
+			Model:   os.Getenv("LLM_MODEL"),
+		}
+	case "fixture":
+		dir := os.Getenv("LLM_FIXTURE_DIR")
+		if dir == "" {
+			dir = "./misc/llm-fixtures"
+		}
+		fixture := &llm.FixtureSDK{Dir: dir}
+		if upstream := os.Getenv("LLM_FIXTURE_UPSTREAM"); upstream != "" && upstream != "fixture" {
+			fixture.Upstream = initLLM(upstream)
+			log.Printf("[INFO] ✅ LLM initialized using: fixtures in %s, recording misses from %s", dir, upstream)
+		} else {
+			log.Printf("[INFO] ✅ LLM initialized using: fixtures in %s (replay only)", dir)
+		}
+		return fixture
+	default:
+		log.Printf("[INFO] ✅ LLM initialized using: OpenAI (%s)", os.Getenv("LLM_MODEL"))
+		return &llm.OpenAISDK{
+			ApiKey: os.Getenv("LLM_API_KEY"),
+			Model:  os.Getenv("LLM_MODEL"),
+		}
+	}
+}
+
+func initObjectStorage(providerName string) objectStorage.Provider {
+	switch providerName {
+	case "s3":
+		log.Printf("[INFO] ✅ Object Storage initialized (S3-compatible: %s)", os.Getenv("S3_ENDPOINT"))
+		return &objectStorage.S3SDK{
+			Endpoint:        os.Getenv("S3_ENDPOINT"), // Empty for AWS, e.g. http://localhost:9000 for MinIO
+			Region:          os.Getenv("S3_REGION"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			BucketName:      os.Getenv("S3_BUCKET_NAME"),
+			BaseURL:         os.Getenv("S3_PUBLIC_URL"),
+			UsePathStyle:    os.Getenv("S3_USE_PATH_STYLE") == "true",
+		}
+	case "local":
+		rootDir := os.Getenv("LOCAL_STORAGE_DIR")
+		if rootDir == "" {
+			rootDir = "./storage"
+		}
+		baseURL := os.Getenv("LOCAL_STORAGE_PUBLIC_URL")
+		if baseURL == "" {
+			baseURL = "http://localhost:5000/files"
+		}
+		signingKey := os.Getenv("LOCAL_STORAGE_SIGNING_KEY")
+		if signingKey == "" {
+			log.Println("[WARN] LOCAL_STORAGE_SIGNING_KEY is not set, signed URLs will not survive a restart")
+			signingKey = randomKey()
+		}
+		log.Printf("[INFO] ✅ Object Storage initialized (Local filesystem: %s)", rootDir)
+		return &objectStorage.LocalFSProvider{
+			RootDir:           rootDir,
+			BaseURL:           baseURL,
+			SigningKey:        signingKey,
+			RequireSignedURLs: os.Getenv("LOCAL_STORAGE_REQUIRE_SIGNED_URLS") == "true",
+		}
+	case "memory":
+		log.Println("[WARN] Object Storage is in-memory, uploads are lost on restart")
+		return objectStorage.NewInMemoryProvider(os.Getenv("MEMORY_STORAGE_PUBLIC_URL"))
+	default:
+		log.Println("[INFO] ✅ Object Storage initialized (Cloudflare R2)")
+		return &objectStorage.CloudflareR2SDK{
+			AccountID:       os.Getenv("R2_ACCOUNT_ID"),
+			AccessKeyID:     os.Getenv("R2_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("R2_SECRET_ACCESS_KEY"),
+			BucketName:      os.Getenv("R2_BUCKET_NAME"),
+			BaseURL:         os.Getenv("R2_PUBLIC_URL"), // Optional: e.g. https://cdn.kislap.app
+		}
+	}
+}
+
+func initMailer(providerName string) mailer.Provider {
+	switch providerName {
+	case "smtp":
+		log.Printf("[INFO] ✅ Mailer initialized (SMTP: %s)", os.Getenv("SMTP_HOST"))
+		return &mailer.SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+	case "memory":
+		log.Println("[WARN] Mailer is in-memory, emails are only kept until restart")
+		return mailer.NewInMemoryMailer()
+	default:
+		log.Println("[INFO] ✅ Mailer initialized (log only, emails are written to the application log)")
+		return mailer.LogMailer{}
+	}
+}
+
+func randomKey() string {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatalf("[FATAL] Failed to generate signing key: %v", err)
+	}
+	return hex.EncodeToString(key)
+}
+
 func main() {
 	// 1. Load Environment
 	if err := godotenv.Load(); err != nil {
@@ -47,44 +180,90 @@ func main() {
 	databaseClient := database.Default(dsn)
 	log.Println("[INFO] ✅ Database connected successfully.")
 
-	// 3. Initialize LLM Provider
+	// 3. Load JWT Signing Keys
+	log.Println("[INFO] 🔑 Loading JWT signing keys...")
+	keySet, err := sharedjwt.LoadKeySet(os.Getenv)
+	if err != nil {
+		if envDev == "" || envDev == "production" {
+			log.Fatalf("[FATAL] %v", err)
+		}
+		log.Printf("[WARN] %v, using an ephemeral key; tokens will not survive a restart", err)
+		if keySet, err = sharedjwt.EphemeralKeySet(); err != nil {
+			log.Fatalf("[FATAL] Failed to generate JWT key: %v", err)
+		}
+	}
+	if err := sharedjwt.Configure(keySet); err != nil {
+		log.Fatalf("[FATAL] %v", err)
+	}
+	log.Printf("[INFO] ✅ %d JWT key(s) loaded, signing with %q", len(keySet.Keys), keySet.ActiveID)
+
+	// 4. Load OIDC Providers
+	log.Println("[INFO] 🪪 Loading OIDC providers...")
+	oidcRegistry, err := oidc.LoadRegistry(os.Getenv)
+	if err != nil {
+		log.Fatalf("[FATAL] %v", err)
+	}
+	oidc.Default(oidcRegistry)
+	log.Printf("[INFO] ✅ %d OIDC provider(s) configured", len(oidcRegistry.List()))
+
+	// 5. Initialize LLM Provider
 	log.Println("[INFO] 🧠 Initializing LLM Provider...")
-	var llmProvider llm.Provider
-	providerName := os.Getenv("LLM_PROVIDER")
+	llmProvider := llm.Default(initLLM(os.Getenv("LLM_PROVIDER")))
 
-	if providerName == "gemini" {
-		llmProvider = llm.Default(&llm.GeminiSDK{
-			ApiKey: os.Getenv("LLM_API_KEY"),
-			Model:  os.Getenv("LLM_MODEL"),
-		})
-		log.Printf("[INFO] ✅ LLM initialized using: Google Gemini (%s)", os.Getenv("LLM_MODEL"))
-	} else {
-		llmProvider = llm.Default(&llm.OpenAISDK{
-			ApiKey: os.Getenv("LLM_API_KEY"),
-			Model:  os.Getenv("LLM_MODEL"),
-		})
-		log.Printf("[INFO] ✅ LLM initialized using: OpenAI (%s)", os.Getenv("LLM_MODEL"))
+	if retries, err := strconv.Atoi(os.Getenv("LLM_STRUCTURED_MAX_RETRIES")); err == nil && retries > 0 {
+		llm.MaxStructuredRetries = retries
 	}
 
-	// 4. Initialize Object Storage (Cloudflare R2)
+	// 6. Initialize Object Storage
 	log.Println("[INFO] ☁️ Initializing Object Storage...")
-	var objectStorageProvider objectStorage.Provider
-
-	// Direct initialization since you only use Cloudflare R2
-	objectStorageProvider = &objectStorage.CloudflareR2SDK{
-		AccountID:       os.Getenv("R2_ACCOUNT_ID"),
-		AccessKeyID:     os.Getenv("R2_ACCESS_KEY_ID"),
-		SecretAccessKey: os.Getenv("R2_SECRET_ACCESS_KEY"),
-		BucketName:      os.Getenv("R2_BUCKET_NAME"),
-		BaseURL:         os.Getenv("R2_PUBLIC_URL"), // Optional: e.g. https://cdn.kislap.app
+	objectStorageProvider := initObjectStorage(os.Getenv("OBJECT_STORAGE_PROVIDER"))
+
+	// 7. Initialize Mailer
+	log.Println("[INFO] ✉️ Initializing Mailer...")
+	mailer.Default(initMailer(os.Getenv("MAILER_PROVIDER")))
+
+	// 8. Start Scheduler
+	log.Println("[INFO] ⏱️ Starting Scheduler...")
+	taskScheduler := scheduler.New()
+	projectService := project.NewService(databaseClient, objectStorageProvider)
+	if err := projectService.PinLegacyPublished(context.Background()); err != nil {
+		log.Printf("[WARN] Failed to pin legacy published projects: %v", err)
+	}
+	taskScheduler.Every("scheduled-publishing", time.Minute, projectService.RunScheduledPublishing)
+	taskScheduler.Every("revision-pruning", time.Hour, projectService.RunRevisionPruning)
+	taskScheduler.Every("domain-verification", time.Minute, projectService.RunDomainVerification)
+	taskScheduler.Every("project-trash-purge", time.Hour, projectService.RunTrashPurge)
+	accountService := account.NewService(databaseClient, objectStorageProvider)
+	taskScheduler.Every("account-deletion", time.Hour, accountService.RunScheduledDeletions)
+	taskScheduler.Every("account-export-cleanup", time.Hour, accountService.PruneExports)
+	taskScheduler.Start()
+	defer taskScheduler.Stop()
+	log.Println("[INFO] ✅ Scheduler started")
+
+	// 9. Start Job Workers
+	log.Println("[INFO] 🛠️ Starting Job Workers...")
+	workerCount, err := strconv.Atoi(os.Getenv("JOB_WORKERS"))
+	if err != nil || workerCount <= 0 {
+		workerCount = 2
 	}
-	log.Println("[INFO] ✅ Object Storage initialized (Cloudflare R2)")
+	documentService := &document.Service{DB: databaseClient, LLM: llmProvider, ObjectStorage: objectStorageProvider}
+	parsedFileService := parsed_file.NewService(databaseClient, documentService)
+	menuService := menu.NewService(databaseClient, objectStorageProvider)
+
+	workerPool := job.NewWorkerPool(databaseClient, workerCount)
+	workerPool.Register(job.TypeParseDocument, parsedFileService.HandleParseJob, parsedFileService.HandleParseJobFailure)
+	workerPool.Register(job.TypeOGImage, projectService.HandleOGImageJob, nil)
+	workerPool.Register(job.TypeDisplayPoster, menuService.HandleDisplayPosterJob, nil)
+	workerPool.Register(job.TypeAccountExport, accountService.HandleExportJob, nil)
+	workerPool.Start()
+	defer workerPool.Stop()
+	log.Printf("[INFO] ✅ %d job workers started", workerCount)
 
-	// 5. Start Server
+	// 10. Start Server
 	log.Println("[INFO] 📡 Starting HTTP Server on :5000...")
 	router := gin.Default()
 	router.MaxMultipartMemory = 50 << 20 // 50 MiB
-	router.Use(middleware.CORSMiddleware())
+	router.Use(middleware.CORSMiddleware(databaseClient))
 
 	// Pass the new storageProvider to your routes
 	routes.RegisterRoutes(router, databaseClient, llmProvider, objectStorageProvider)
@@ -1,9 +1,11 @@
 package middleware
 
 import (
+	"flash/models"
 	"flash/utils"
 	"net/http"
 	"strings"
+	"time"
 
 	sharedjwt "flash/shared/jwt"
 
@@ -34,6 +36,12 @@ func AccessTokenValidatorMiddleware(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		if sharedjwt.TokenType(validatedToken) == sharedjwt.TokenTypeRefresh {
+			utils.APIRespondError(c, http.StatusUnauthorized, "Refresh tokens cannot be used for API access.")
+			c.Abort()
+			return
+		}
+
 		user, err := sharedjwt.ExtractUser(validatedToken, db)
 		if err != nil {
 			utils.APIRespondError(c, http.StatusUnauthorized, err.Error())
@@ -41,7 +49,24 @@ func AccessTokenValidatorMiddleware(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		sessionID := sharedjwt.SessionID(validatedToken)
+		if sessionID != 0 && !sessionActive(db, sessionID, user.ID) {
+			utils.APIRespondError(c, http.StatusUnauthorized, "Session has been revoked.")
+			c.Abort()
+			return
+		}
+
 		c.Set("user_id", user.ID)
+		c.Set("session_id", sessionID)
+		c.Set("user_role", user.Role)
 		c.Next()
 	}
 }
+
+func sessionActive(db *gorm.DB, sessionID uint64, userID uint64) bool {
+	var count int64
+	db.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, time.Now()).
+		Count(&count)
+	return count > 0
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"flash/models"
+	"flash/shared/access"
+	"flash/shared/apikey"
+	"flash/shared/testdb"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// createAPIKey stores a key for the user and returns the bearer credential.
+func createAPIKey(t *testing.T, db *gorm.DB, userID uint64, scopes []string, change func(key *models.APIKey)) string {
+	t.Helper()
+
+	key, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := &models.APIKey{UserID: userID, Name: "script", Prefix: prefix, KeyHash: hash, Scopes: scopes}
+	if change != nil {
+		change(row)
+	}
+	testdb.Create(t, db, row)
+
+	return key
+}
+
+func TestAPIKeyOrAccessTokenMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testdb.Open(t, &models.User{}, &models.APIKey{}, &models.Project{}, &models.Workspace{}, &models.WorkspaceMember{})
+
+	empty := ""
+	testdb.Create(t, db,
+		&models.User{ID: 1, FirstName: "Owner", Email: "owner@example.com", Password: &empty, Role: "default"},
+		&models.User{ID: 2, FirstName: "Admin", Email: "admin@example.com", Password: &empty, Role: access.RoleAdmin},
+		&models.Project{ID: 100, UserID: 1, Name: "Menu", Slug: "menu", Type: "menu"},
+		&models.Project{ID: 200, UserID: 2, Name: "Admin's own", Slug: "admins-own", Type: "menu"},
+	)
+
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	keys := map[string]string{
+		"read":     createAPIKey(t, db, 1, []string{apikey.ScopeProjectsRead}, nil),
+		"write":    createAPIKey(t, db, 1, []string{apikey.ScopeProjectsWrite}, nil),
+		"menu":     createAPIKey(t, db, 1, []string{apikey.ScopeMenuWrite}, nil),
+		"expiring": createAPIKey(t, db, 1, []string{apikey.ScopeProjectsRead}, func(key *models.APIKey) { key.ExpiresAt = &future }),
+		"expired":  createAPIKey(t, db, 1, []string{apikey.ScopeProjectsRead}, func(key *models.APIKey) { key.ExpiresAt = &past }),
+		"revoked":  createAPIKey(t, db, 1, []string{apikey.ScopeProjectsRead}, func(key *models.APIKey) { key.RevokedAt = &past }),
+		"admin":    createAPIKey(t, db, 2, []string{apikey.ScopeProjectsWrite}, nil),
+		"unknown":  apikey.Prefix + "0000000000000000000000000000000000000000000000",
+	}
+
+	ok := func(context *gin.Context) {
+		context.String(http.StatusOK, strconv.FormatUint(context.GetUint64("user_id"), 10))
+	}
+	router := gin.New()
+	router.GET("/projects/:id", APIKeyOrAccessTokenMiddleware(db, apikey.ScopeProjectsRead), ProjectAccessMiddleware(db, ProjectFromParam("id"), access.PermissionRead), ok)
+	router.PUT("/projects/:id", APIKeyOrAccessTokenMiddleware(db, apikey.ScopeProjectsWrite), ProjectAccessMiddleware(db, ProjectFromParam("id"), access.PermissionWrite), ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		want   int
+	}{
+		{"read key reads", http.MethodGet, "/projects/100", "read", http.StatusOK},
+		{"read key cannot write", http.MethodPut, "/projects/100", "read", http.StatusForbidden},
+		{"write key reads", http.MethodGet, "/projects/100", "write", http.StatusOK},
+		{"write key writes", http.MethodPut, "/projects/100", "write", http.StatusOK},
+		{"other resource's scope", http.MethodGet, "/projects/100", "menu", http.StatusForbidden},
+		{"key before its expiry", http.MethodGet, "/projects/100", "expiring", http.StatusOK},
+		{"expired key", http.MethodGet, "/projects/100", "expired", http.StatusUnauthorized},
+		{"revoked key", http.MethodGet, "/projects/100", "revoked", http.StatusUnauthorized},
+		{"unknown key", http.MethodGet, "/projects/100", "unknown", http.StatusUnauthorized},
+		{"admin key on their own project", http.MethodPut, "/projects/200", "admin", http.StatusOK},
+		{"admin key on someone else's project", http.MethodGet, "/projects/100", "admin", http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.path, nil)
+			request.Header.Set("Authorization", "Bearer "+keys[test.key])
+			recorder := httptest.NewRecorder()
+
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.want {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.want, recorder.Body.String())
+			}
+		})
+	}
+
+	var used models.APIKey
+	if err := db.Where("key_hash = ?", apikey.Hash(keys["read"])).First(&used).Error; err != nil {
+		t.Fatal(err)
+	}
+	if used.LastUsedAt == nil || used.LastUsedIP == nil {
+		t.Fatalf("key = %+v, want its last use recorded", used)
+	}
+}
@@ -1,17 +1,123 @@
 package middleware
 
 import (
+	"flash/models"
 	"log" // Added for logging
+	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func CORSMiddleware() gin.HandlerFunc {
-	return cors.New(cors.Config{
+const (
+	// Custom domain lookups are cached so preflights don't each cost a query. A domain removed or taken over
+	// keeps working for at most customOriginTTL.
+	customOriginTTL     = 5 * time.Minute
+	customOriginMaxSize = 10000
+)
+
+// customOriginCache remembers which hosts are verified custom domains, including the ones that aren't.
+type customOriginCache struct {
+	mu      sync.Mutex
+	entries map[string]customOriginEntry
+}
+
+type customOriginEntry struct {
+	allowed   bool
+	expiresAt time.Time
+}
+
+func (cache *customOriginCache) allowed(db *gorm.DB, host string) bool {
+	now := time.Now()
+
+	cache.mu.Lock()
+	entry, ok := cache.entries[host]
+	cache.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.allowed
+	}
+
+	var count int64
+	if err := db.Model(&models.ProjectDomain{}).Where("host = ? AND verified_at IS NOT NULL", host).Count(&count).Error; err != nil {
+		log.Printf("[CORS] Failed to look up custom domain %s: %v", host, err)
+		return false
+	}
+
+	cache.mu.Lock()
+	if len(cache.entries) >= customOriginMaxSize {
+		cache.entries = map[string]customOriginEntry{}
+	}
+	cache.entries[host] = customOriginEntry{allowed: count > 0, expiresAt: now.Add(customOriginTTL)}
+	cache.mu.Unlock()
+
+	return count > 0
+}
+
+// customOriginRoute is an endpoint a site on a custom domain calls for itself. A path ending in "/" matches
+// everything under it.
+type customOriginRoute struct {
+	method string
+	path   string
+}
+
+// customOriginRoutes are the public endpoints a published site calls: reading itself and the forms visitors submit.
+// Custom domains are verified by whoever owns them, so they get nothing else and never credentials.
+var customOriginRoutes = []customOriginRoute{
+	{http.MethodGet, "/api/projects/show/host/"},
+	{http.MethodGet, "/api/projects/redirect/sub-domain/"},
+	{http.MethodGet, "/api/waitlist-signups/"},
+	{http.MethodPost, "/api/page-activities"},
+	{http.MethodPost, "/api/appointments"},
+	{http.MethodPost, "/api/waitlist-signups"},
+}
+
+// firstPartyOrigin reports whether origin is a Kislap app or local development.
+func firstPartyOrigin(origin string) bool {
+	parsedOrigin, err := url.Parse(origin)
+	if err != nil || parsedOrigin.Hostname() == "" {
+		return false
+	}
+
+	host := strings.ToLower(parsedOrigin.Hostname())
+
+	return host == "localhost" ||
+		host == "kislap.app" ||
+		host == "builder.kislap.app" ||
+		host == "api.kislap.app" ||
+		strings.HasSuffix(host, ".localhost") ||
+		strings.HasSuffix(host, ".kislap.app")
+}
+
+// isCustomOriginRoute matches a request, or the request a preflight asks about, against customOriginRoutes.
+func isCustomOriginRoute(request *http.Request) bool {
+	method := request.Method
+	if method == http.MethodOptions {
+		method = request.Header.Get("Access-Control-Request-Method")
+	}
+
+	for _, route := range customOriginRoutes {
+		if route.method != method {
+			continue
+		}
+		if strings.HasSuffix(route.path, "/") && strings.HasPrefix(request.URL.Path, route.path) || request.URL.Path == route.path {
+			return true
+		}
+	}
+	return false
+}
+
+// CORSMiddleware allows the Kislap apps and local development with credentials. Verified custom domains may only
+// read their own site and submit its forms through customOriginRoutes, without credentials: a script on a domain any user can verify
+// must never reach an endpoint that trusts the visitor's cookies.
+func CORSMiddleware(db *gorm.DB) gin.HandlerFunc {
+	customOrigins := &customOriginCache{entries: map[string]customOriginEntry{}}
+
+	firstParty := cors.New(cors.Config{
 		AllowOriginFunc: func(origin string) bool {
 			if origin == "" {
 				return false
@@ -19,21 +125,7 @@ func CORSMiddleware() gin.HandlerFunc {
 
 			log.Printf("[CORS] Checking Origin: %s", origin)
 
-			parsedOrigin, err := url.Parse(origin)
-			if err != nil || parsedOrigin.Hostname() == "" {
-				log.Printf("[CORS] BLOCKED invalid origin: %s", origin)
-				return false
-			}
-
-			host := strings.ToLower(parsedOrigin.Hostname())
-
-			allowed := host == "localhost" ||
-				host == "kislap.app" ||
-				host == "builder.kislap.app" ||
-				host == "api.kislap.app" ||
-				strings.HasSuffix(host, ".localhost") ||
-				strings.HasSuffix(host, ".kislap.app")
-
+			allowed := firstPartyOrigin(origin)
 			if allowed {
 				log.Printf("[CORS] Allowed: %s", origin)
 			} else {
@@ -58,4 +150,46 @@ func CORSMiddleware() gin.HandlerFunc {
 		AllowCredentials: true,
 		MaxAge:           24 * time.Hour,
 	})
+
+	customDomain := cors.New(cors.Config{
+		AllowOriginFunc: func(origin string) bool {
+			parsedOrigin, err := url.Parse(origin)
+			if err != nil || parsedOrigin.Hostname() == "" {
+				log.Printf("[CORS] BLOCKED invalid origin: %s", origin)
+				return false
+			}
+
+			// Custom domains are only served over HTTPS.
+			allowed := parsedOrigin.Scheme == "https" && customOrigins.allowed(db, strings.ToLower(parsedOrigin.Hostname()))
+			if allowed {
+				log.Printf("[CORS] Allowed custom domain: %s", origin)
+			} else {
+				log.Printf("[CORS] BLOCKED: %s", origin)
+			}
+
+			return allowed
+		},
+		AllowMethods: []string{"GET", "POST", "OPTIONS"},
+		AllowHeaders: []string{
+			"Content-Type",
+			"Origin",
+			"Accept",
+			"X-Requested-With",
+		},
+		ExposeHeaders: []string{
+			"Content-Length",
+		},
+		AllowCredentials: false,
+		MaxAge:           24 * time.Hour,
+	})
+
+	return func(context *gin.Context) {
+		origin := context.GetHeader("Origin")
+		if db != nil && origin != "" && !firstPartyOrigin(origin) && isCustomOriginRoute(context.Request) {
+			customDomain(context)
+			return
+		}
+
+		firstParty(context)
+	}
 }
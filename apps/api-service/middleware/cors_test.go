@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"flash/models"
+	"flash/shared/testdb"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testdb.Open(t, &models.ProjectDomain{})
+	verifiedAt := time.Now()
+	testdb.Create(t, db,
+		&models.ProjectDomain{ProjectID: 1, Host: "shop.example.com", VerifiedAt: &verifiedAt},
+		&models.ProjectDomain{ProjectID: 2, Host: "pending.example.com"},
+	)
+
+	router := gin.New()
+	router.Use(CORSMiddleware(db))
+	ok := func(context *gin.Context) { context.Status(http.StatusOK) }
+	router.POST("/api/auth/refresh", ok)
+	router.GET("/api/projects/show/host/:host", ok)
+	router.GET("/api/projects/redirect/sub-domain/:sub-domain", ok)
+	router.POST("/api/page-activities", ok)
+	router.GET("/api/page-activities/:id", ok)
+	router.POST("/api/appointments", ok)
+	router.GET("/api/appointments", ok)
+	router.POST("/api/waitlist-signups", ok)
+	router.GET("/api/waitlist-signups/:referral_code", ok)
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		origin          string
+		wantOrigin      bool
+		wantCredentials bool
+	}{
+		{name: "first party", method: http.MethodPost, path: "/api/auth/refresh", origin: "https://builder.kislap.app", wantOrigin: true, wantCredentials: true},
+		{name: "first party on a public path", method: http.MethodGet, path: "/api/projects/show/host/shop.example.com", origin: "https://kislap.app", wantOrigin: true, wantCredentials: true},
+		{name: "custom domain refreshing a session", method: http.MethodPost, path: "/api/auth/refresh", origin: "https://shop.example.com"},
+		{name: "custom domain reading its site", method: http.MethodGet, path: "/api/projects/show/host/shop.example.com", origin: "https://shop.example.com", wantOrigin: true},
+		{name: "custom domain resolving a redirect", method: http.MethodGet, path: "/api/projects/redirect/sub-domain/old", origin: "https://shop.example.com", wantOrigin: true},
+		{name: "custom domain recording a visit", method: http.MethodPost, path: "/api/page-activities", origin: "https://shop.example.com", wantOrigin: true},
+		{name: "custom domain reading analytics", method: http.MethodGet, path: "/api/page-activities/1", origin: "https://shop.example.com"},
+		{name: "custom domain booking an appointment", method: http.MethodPost, path: "/api/appointments", origin: "https://shop.example.com", wantOrigin: true},
+		{name: "custom domain listing appointments", method: http.MethodGet, path: "/api/appointments", origin: "https://shop.example.com"},
+		{name: "custom domain joining a waitlist", method: http.MethodPost, path: "/api/waitlist-signups", origin: "https://shop.example.com", wantOrigin: true},
+		{name: "custom domain checking a referral", method: http.MethodGet, path: "/api/waitlist-signups/ABC123", origin: "https://shop.example.com", wantOrigin: true},
+		{name: "custom domain over http", method: http.MethodGet, path: "/api/projects/show/host/shop.example.com", origin: "http://shop.example.com"},
+		{name: "unverified domain", method: http.MethodGet, path: "/api/projects/show/host/pending.example.com", origin: "https://pending.example.com"},
+		{name: "unknown origin", method: http.MethodPost, path: "/api/auth/refresh", origin: "https://evil.example"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.path, nil)
+			request.Header.Set("Origin", test.origin)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			allowOrigin := recorder.Header().Get("Access-Control-Allow-Origin")
+			if (allowOrigin == test.origin) != test.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want allowed = %v", allowOrigin, test.wantOrigin)
+			}
+			if credentials := recorder.Header().Get("Access-Control-Allow-Credentials") == "true"; credentials != test.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %v, want %v", credentials, test.wantCredentials)
+			}
+			if !test.wantOrigin && recorder.Code == http.StatusOK {
+				t.Errorf("status = %d, want the request blocked", recorder.Code)
+			}
+		})
+	}
+}
+
+func TestCORSMiddlewareCustomDomainPreflight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testdb.Open(t, &models.ProjectDomain{})
+	verifiedAt := time.Now()
+	testdb.Create(t, db, &models.ProjectDomain{ProjectID: 1, Host: "shop.example.com", VerifiedAt: &verifiedAt})
+
+	router := gin.New()
+	router.Use(CORSMiddleware(db))
+
+	preflight := func(path string, method string) http.Header {
+		request := httptest.NewRequest(http.MethodOptions, path, nil)
+		request.Header.Set("Origin", "https://shop.example.com")
+		request.Header.Set("Access-Control-Request-Method", method)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Header()
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		path := "/api/projects/show/host/shop.example.com"
+		if method == http.MethodPost {
+			path = "/api/page-activities"
+		}
+
+		headers := preflight(path, method)
+		if headers.Get("Access-Control-Allow-Origin") != "https://shop.example.com" {
+			t.Fatalf("%s preflight headers = %v, want the custom domain allowed", method, headers)
+		}
+		if headers.Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("%s preflight allowed credentials for a custom domain", method)
+		}
+		if methods := headers.Get("Access-Control-Allow-Methods"); methods != "GET,POST,OPTIONS" {
+			t.Errorf("Access-Control-Allow-Methods = %q, want only GET, POST and OPTIONS", methods)
+		}
+	}
+
+	// Asking to delete through a path that only takes POSTs from custom domains is turned down.
+	if origin := preflight("/api/page-activities", http.MethodDelete).Get("Access-Control-Allow-Origin"); origin != "" {
+		t.Errorf("DELETE preflight allowed %q", origin)
+	}
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flash/shared/access"
+	"flash/utils"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ProjectResolver finds the project a request targets.
+type ProjectResolver func(context *gin.Context, db *gorm.DB) (uint64, error)
+
+var errMissingProjectID = errors.New("missing project ID")
+
+// ProjectAccessMiddleware must run after AccessTokenValidatorMiddleware.
+func ProjectAccessMiddleware(db *gorm.DB, resolve ProjectResolver, permission access.Permission) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		if context.Request.Method == http.MethodOptions {
+			context.Next()
+			return
+		}
+
+		projectID, err := resolve(context, db)
+		if err != nil {
+			respondProjectAccessError(context, err)
+			return
+		}
+
+		project, role, err := access.AuthorizeProject(db, context.GetUint64("user_id"), context.GetString("user_role"), projectID, permission)
+		if err != nil {
+			respondProjectAccessError(context, err)
+			return
+		}
+
+		context.Set("project", project)
+		context.Set("project_id", project.ID)
+		context.Set("project_role", role)
+		context.Next()
+	}
+}
+
+func respondProjectAccessError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, access.ErrProjectForbidden):
+		utils.APIRespondError(context, http.StatusForbidden, err.Error())
+	case errors.Is(err, access.ErrProjectNotFound):
+		utils.APIRespondError(context, http.StatusNotFound, err.Error())
+	case errors.Is(err, errMissingProjectID):
+		utils.APIRespondError(context, http.StatusBadRequest, err.Error())
+	default:
+		utils.APIRespondError(context, http.StatusInternalServerError, err.Error())
+	}
+	context.Abort()
+}
+
+func ProjectFromParam(name string) ProjectResolver {
+	return func(context *gin.Context, db *gorm.DB) (uint64, error) {
+		return parseProjectID(context.Param(name))
+	}
+}
+
+func ProjectFromQuery(name string) ProjectResolver {
+	return func(context *gin.Context, db *gorm.DB) (uint64, error) {
+		return parseProjectID(context.Query(name))
+	}
+}
+
+func ProjectFromSlugParam(name string) ProjectResolver {
+	return func(context *gin.Context, db *gorm.DB) (uint64, error) {
+		return access.ProjectIDBySlug(db, context.Param(name))
+	}
+}
+
+// ProjectFromModelParam resolves the project through a row that carries a project_id, e.g. a portfolio.
+func ProjectFromModelParam(name string, model any) ProjectResolver {
+	return func(context *gin.Context, db *gorm.DB) (uint64, error) {
+		id, err := strconv.ParseUint(context.Param(name), 10, 64)
+		if err != nil {
+			return 0, errMissingProjectID
+		}
+		return access.ProjectIDOf(db, model, id)
+	}
+}
+
+// ProjectFromJSONBody reads project_id from a JSON body and restores the body for the controller.
+func ProjectFromJSONBody(field string) ProjectResolver {
+	return func(context *gin.Context, db *gorm.DB) (uint64, error) {
+		body, err := io.ReadAll(context.Request.Body)
+		if err != nil {
+			return 0, err
+		}
+		context.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		return projectIDFromJSON(body, field)
+	}
+}
+
+// ProjectFromMultipartJSONBody reads project_id from the "json_body" form value used by the builder saves.
+func ProjectFromMultipartJSONBody(field string) ProjectResolver {
+	return func(context *gin.Context, db *gorm.DB) (uint64, error) {
+		if err := context.Request.ParseMultipartForm(32 << 20); err != nil {
+			return 0, errMissingProjectID
+		}
+
+		return projectIDFromJSON([]byte(context.Request.FormValue("json_body")), field)
+	}
+}
+
+func projectIDFromJSON(body []byte, field string) (uint64, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return 0, errMissingProjectID
+	}
+
+	var projectID uint64
+	if err := json.Unmarshal(fields[field], &projectID); err != nil || projectID == 0 {
+		return 0, errMissingProjectID
+	}
+
+	return projectID, nil
+}
+
+func parseProjectID(value string) (uint64, error) {
+	projectID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || projectID == 0 {
+		return 0, errMissingProjectID
+	}
+	return projectID, nil
+}
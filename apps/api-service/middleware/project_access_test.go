@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"flash/models"
+	"flash/shared/access"
+	"flash/shared/testdb"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestProjectAccessMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testdb.Open(t, &models.Project{}, &models.Workspace{}, &models.WorkspaceMember{})
+
+	workspaceID := uint64(10)
+	testdb.Create(t, db,
+		&models.Workspace{ID: workspaceID, OwnerID: 1, Name: "Studio"},
+		&models.WorkspaceMember{WorkspaceID: workspaceID, UserID: 2, Role: access.RoleEditor},
+		&models.WorkspaceMember{WorkspaceID: workspaceID, UserID: 3, Role: access.RoleViewer},
+		&models.Project{ID: 100, UserID: 1, WorkspaceID: &workspaceID, Name: "Shared", Slug: "shared", Type: "menu"},
+	)
+
+	// Stands in for AccessTokenValidatorMiddleware, which sets the signed-in user.
+	signedIn := func(context *gin.Context) {
+		userID, _ := strconv.ParseUint(context.GetHeader("X-User"), 10, 64)
+		context.Set("user_id", userID)
+		context.Set("user_role", context.GetHeader("X-Role"))
+	}
+	ok := func(context *gin.Context) {
+		context.String(http.StatusOK, strconv.FormatUint(context.GetUint64("project_id"), 10))
+	}
+
+	router := gin.New()
+	router.GET("/projects/:id", signedIn, ProjectAccessMiddleware(db, ProjectFromParam("id"), access.PermissionRead), ok)
+	router.PUT("/projects/:id", signedIn, ProjectAccessMiddleware(db, ProjectFromParam("id"), access.PermissionWrite), ok)
+	router.DELETE("/projects/:id", signedIn, ProjectAccessMiddleware(db, ProjectFromParam("id"), access.PermissionManage), ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		user   string
+		role   string
+		want   int
+	}{
+		{"owner deletes", http.MethodDelete, "/projects/100", "1", "user", http.StatusOK},
+		{"editor updates", http.MethodPut, "/projects/100", "2", "user", http.StatusOK},
+		{"editor cannot delete", http.MethodDelete, "/projects/100", "2", "user", http.StatusForbidden},
+		{"viewer reads", http.MethodGet, "/projects/100", "3", "user", http.StatusOK},
+		{"viewer cannot update", http.MethodPut, "/projects/100", "3", "user", http.StatusForbidden},
+		{"second user cannot read", http.MethodGet, "/projects/100", "4", "user", http.StatusForbidden},
+		{"second user cannot update", http.MethodPut, "/projects/100", "4", "user", http.StatusForbidden},
+		{"super admin deletes", http.MethodDelete, "/projects/100", "4", access.RoleSuperAdmin, http.StatusOK},
+		{"support reads", http.MethodGet, "/projects/100", "4", access.RoleSupport, http.StatusOK},
+		{"support cannot update", http.MethodPut, "/projects/100", "4", access.RoleSupport, http.StatusForbidden},
+		{"unknown project", http.MethodGet, "/projects/999", "1", "user", http.StatusNotFound},
+		{"invalid id", http.MethodGet, "/projects/abc", "1", "user", http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.path, nil)
+			request.Header.Set("X-User", test.user)
+			request.Header.Set("X-Role", test.role)
+			recorder := httptest.NewRecorder()
+
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.want {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.want, recorder.Body.String())
+			}
+			if test.want == http.StatusOK && recorder.Body.String() != "100" {
+				t.Errorf("project_id = %s, want 100", recorder.Body.String())
+			}
+		})
+	}
+}
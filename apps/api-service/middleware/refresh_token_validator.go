@@ -26,6 +26,12 @@ func RefreshTokenValidatorMiddleware(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		if sharedjwt.TokenType(validatedToken) == sharedjwt.TokenTypeAccess {
+			utils.APIRespondError(context, http.StatusUnauthorized, "Invalid or expired refresh token.")
+			context.Abort()
+			return
+		}
+
 		user, err := sharedjwt.ExtractUser(validatedToken, db)
 		if err != nil {
 			utils.APIRespondError(context, http.StatusUnauthorized, err.Error())
@@ -35,6 +41,7 @@ func RefreshTokenValidatorMiddleware(db *gorm.DB) gin.HandlerFunc {
 
 		context.Set("user_id", user.ID)
 		context.Set("refresh_token", refreshToken)
+		context.Set("session_id", sharedjwt.SessionID(validatedToken))
 		context.Next()
 	}
 }
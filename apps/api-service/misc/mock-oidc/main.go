@@ -0,0 +1,34 @@
+// Command mock-oidc runs the mock OpenID Connect issuer so the OIDC login flow can be tried locally without a
+// real provider:
+//
+//	go run ./misc/mock-oidc
+//
+// then point an OIDC_PROVIDERS entry at the printed issuer.
+package main
+
+import (
+	"flash/sdk/oidc"
+	"log"
+	"os"
+	"os/signal"
+)
+
+func main() {
+	clientID := os.Getenv("MOCK_OIDC_CLIENT_ID")
+	if clientID == "" {
+		clientID = "kislap-local"
+	}
+
+	mock, err := oidc.NewMockServer(clientID)
+	if err != nil {
+		log.Fatalf("[FATAL] %v", err)
+	}
+	defer mock.Close()
+
+	log.Printf("[INFO] ✅ Mock OIDC issuer running at %s", mock.URL)
+	log.Printf("[INFO] Set OIDC_PROVIDERS=mock, OIDC_MOCK_ISSUER=%s and OIDC_MOCK_CLIENT_ID=%s", mock.URL, clientID)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	<-stop
+}
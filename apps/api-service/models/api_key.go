@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type APIKey struct {
+	ID         uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint64     `gorm:"index" json:"user_id"`
+	Name       string     `gorm:"size:100" json:"name"`
+	Prefix     string     `gorm:"size:16" json:"prefix"`
+	KeyHash    string     `gorm:"column:key_hash;size:64;uniqueIndex" json:"-"`
+	Scopes     []string   `gorm:"serializer:json;type:json" json:"scopes"`
+	ExpiresAt  *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"last_used_at"`
+	LastUsedIP *string    `gorm:"column:last_used_ip;size:45" json:"last_used_ip"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Job struct {
+	ID          uint64           `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      *uint64          `gorm:"index" json:"user_id,omitempty"`
+	Type        string           `gorm:"column:type;size:50;index" json:"type"`
+	Reference   *string          `gorm:"column:reference" json:"reference,omitempty"`
+	Status      string           `gorm:"column:status;size:30;default:pending" json:"status"`
+	Payload     json.RawMessage  `gorm:"column:payload;type:longtext" json:"-"`
+	Result      *json.RawMessage `gorm:"column:result;type:json" json:"result"`
+	Error       *string          `gorm:"column:error;type:text" json:"error"`
+	Attempts    int              `gorm:"column:attempts;default:0" json:"attempts"`
+	MaxAttempts int              `gorm:"column:max_attempts;default:3" json:"max_attempts"`
+	AvailableAt time.Time        `gorm:"column:available_at" json:"available_at"`
+	ReservedAt  *time.Time       `gorm:"column:reserved_at" json:"reserved_at,omitempty"`
+	FinishedAt  *time.Time       `gorm:"column:finished_at" json:"finished_at,omitempty"`
+	CreatedAt   time.Time        `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time        `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Job) TableName() string {
+	return "jobs"
+}
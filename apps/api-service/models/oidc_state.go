@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type OIDCState struct {
+	ID           uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Provider     string    `gorm:"size:50" json:"provider"`
+	StateHash    string    `gorm:"column:state_hash;size:64;uniqueIndex" json:"-"`
+	BrowserHash  string    `gorm:"column:browser_hash;size:64" json:"-"`
+	Nonce        string    `gorm:"size:64" json:"-"`
+	CodeVerifier string    `gorm:"column:code_verifier;size:128" json:"-"`
+	Intent       string    `gorm:"type:enum('login','link');default:login" json:"intent"`
+	UserID       *uint64   `gorm:"index" json:"user_id"`
+	ExpiresAt    time.Time `gorm:"column:expires_at" json:"expires_at"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (OIDCState) TableName() string {
+	return "oidc_states"
+}
@@ -18,6 +18,9 @@ type ParsedFile struct {
 	CreatedAt   time.Time       `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt   time.Time       `gorm:"autoUpdateTime" json:"updated_at"`
 	DeletedAt   gorm.DeletedAt  `gorm:"index" json:"deleted_at,omitempty"`
+
+	JobID *uint64 `gorm:"column:job_id" json:"job_id,omitempty"`
+	Error *string `gorm:"column:error;type:text" json:"error,omitempty"`
 }
 
 func (ParsedFile) TableName() string {
@@ -9,6 +9,7 @@ import (
 type Project struct {
 	ID          uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
 	UserID      uint64         `gorm:"index" json:"user_id"`
+	WorkspaceID *uint64        `gorm:"index" json:"workspace_id,omitempty"`
 	Name        string         `gorm:"size:255;not null" json:"name"`
 	Description string         `gorm:"type:text" json:"description,omitempty"`
 	Slug        string         `gorm:"size:255;uniqueIndex;not null" json:"slug"`
@@ -20,11 +21,17 @@ type Project struct {
 	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 
+	PublishedRevisionID *uint64    `gorm:"column:published_revision_id" json:"published_revision_id,omitempty"`
+	PublishedAt         *time.Time `gorm:"column:published_at" json:"published_at,omitempty"`
+	PublishAt           *time.Time `gorm:"column:publish_at;index" json:"publish_at,omitempty"`
+	UnpublishAt         *time.Time `gorm:"column:unpublish_at;index" json:"unpublish_at,omitempty"`
+
 	User      *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Portfolio *Portfolio `gorm:"foreignKey:ProjectID" json:"portfolio,omitempty"`
 	Biz       *Biz       `gorm:"foreignKey:ProjectID" json:"biz,omitempty"`
 	Linktree  *Linktree  `gorm:"foreignKey:ProjectID" json:"linktree,omitempty"`
 	Menu      *Menu      `gorm:"foreignKey:ProjectID" json:"menu,omitempty"`
+	Waitlist  *Waitlist  `gorm:"foreignKey:ProjectID" json:"waitlist,omitempty"`
 }
 
 func (Project) TableName() string {
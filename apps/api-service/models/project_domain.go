@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type ProjectDomain struct {
+	ID                uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProjectID         uint64     `gorm:"index" json:"project_id"`
+	Host              string     `gorm:"size:253" json:"host"`
+	VerificationToken string     `gorm:"column:verification_token;size:64" json:"verification_token"`
+	VerifiedAt        *time.Time `gorm:"column:verified_at" json:"verified_at"`
+	LastCheckedAt     *time.Time `gorm:"column:last_checked_at" json:"last_checked_at"`
+	LastError         *string    `gorm:"column:last_error;size:255" json:"last_error"`
+	CreatedAt         time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (ProjectDomain) TableName() string {
+	return "project_domains"
+}
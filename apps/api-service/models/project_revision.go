@@ -0,0 +1,24 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ProjectRevision struct {
+	ID             uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProjectID      uint64          `gorm:"index" json:"project_id"`
+	UserID         *uint64         `gorm:"index" json:"user_id,omitempty"`
+	Revision       int             `gorm:"column:revision" json:"revision"`
+	Type           string          `gorm:"column:type;size:50" json:"type"`
+	Source         string          `gorm:"column:source;size:50;default:save" json:"source"`
+	RestoredFromID *uint64         `gorm:"column:restored_from_id" json:"restored_from_id,omitempty"`
+	Snapshot       json.RawMessage `gorm:"column:snapshot;type:longtext" json:"snapshot,omitempty"`
+	CreatedAt      time.Time       `gorm:"autoCreateTime" json:"created_at"`
+
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (ProjectRevision) TableName() string {
+	return "project_revisions"
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ProjectSubDomainHistory is a subdomain a project used before. It keeps old links redirecting and holds the
+// subdomain for the owner until ReleasedAt plus the release cooldown.
+type ProjectSubDomainHistory struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProjectID  uint64    `gorm:"index" json:"project_id"`
+	UserID     uint64    `gorm:"index" json:"user_id"`
+	SubDomain  string    `gorm:"size:255" json:"sub_domain"`
+	ReleasedAt time.Time `gorm:"column:released_at" json:"released_at"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (ProjectSubDomainHistory) TableName() string {
+	return "project_sub_domain_histories"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type ProjectTemplate struct {
+	ID              uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Key             string    `gorm:"size:100;uniqueIndex" json:"key"`
+	Name            string    `gorm:"size:255" json:"name"`
+	Description     *string   `gorm:"type:text" json:"description"`
+	Type            string    `gorm:"size:50" json:"type"`
+	PreviewImageURL *string   `gorm:"column:preview_image_url;size:255" json:"preview_image_url"`
+	SourceProjectID *uint64   `gorm:"column:source_project_id" json:"-"`
+	PlacementOrder  int       `gorm:"default:0" json:"placement_order"`
+	IsActive        bool      `gorm:"default:true" json:"is_active"`
+	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (ProjectTemplate) TableName() string {
+	return "project_templates"
+}
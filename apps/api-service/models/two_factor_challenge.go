@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// TwoFactorChallenge is the half-finished login between a correct password and a correct second factor.
+type TwoFactorChallenge struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint64    `gorm:"index" json:"user_id"`
+	TokenHash string    `gorm:"column:token_hash;size:64;uniqueIndex" json:"-"`
+	Attempts  uint8     `gorm:"column:attempts;default:0" json:"attempts"`
+	ExpiresAt time.Time `gorm:"column:expires_at" json:"expires_at"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (TwoFactorChallenge) TableName() string {
+	return "two_factor_challenges"
+}
@@ -14,12 +14,21 @@ type User struct {
 	Password     *string        `gorm:"size:255;not null" json:"-"`
 	MobileNumber *string        `gorm:"size:20" json:"mobile_number,omitempty"`
 	Role         string         `gorm:"size:50;not null;default:default" json:"role"`
-	RefreshToken *string        `gorm:"size:255" json:"refresh_token,omitempty"`
+	RefreshToken *string        `gorm:"size:255" json:"-"`
 	ImageURL     *string        `gorm:"size:255" json:"image_url,omitempty"`
 	Newsletter   bool           `gorm:"type:int" json:"newsletter"`
-	Github       bool           `gorm:"type:int" json:"github"`
-	Google       bool           `gorm:"type:int" json:"google"`
 	CreatedAt    time.Time      `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt    time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	EmailVerifiedAt *time.Time `gorm:"column:email_verified_at" json:"email_verified_at"`
+
+	TwoFactorSecret      *string    `gorm:"column:two_factor_secret;size:64" json:"-"`
+	TwoFactorConfirmedAt *time.Time `gorm:"column:two_factor_confirmed_at" json:"two_factor_confirmed_at"`
+	TwoFactorLastStep    *uint64    `gorm:"column:two_factor_last_step" json:"-"`
+
+	DeletionRequestedAt *time.Time `gorm:"column:deletion_requested_at" json:"deletion_requested_at,omitempty"`
+	DeletionScheduledAt *time.Time `gorm:"column:deletion_scheduled_at" json:"deletion_scheduled_at,omitempty"`
+
+	Identities []UserIdentity `gorm:"foreignKey:UserID" json:"identities,omitempty"`
 }
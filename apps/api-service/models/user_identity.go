@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type UserIdentity struct {
+	ID             uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID         uint64     `gorm:"index" json:"user_id"`
+	Provider       string     `gorm:"size:50" json:"provider"`
+	ProviderUserID *string    `gorm:"column:provider_user_id;size:255" json:"-"`
+	Email          *string    `gorm:"size:255" json:"email"`
+	LastLoginAt    *time.Time `gorm:"column:last_login_at" json:"last_login_at"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type UserRecoveryCode struct {
+	ID        uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint64     `gorm:"index" json:"user_id"`
+	CodeHash  string     `gorm:"column:code_hash;size:64" json:"-"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (UserRecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
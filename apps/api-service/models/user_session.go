@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type UserSession struct {
+	ID                uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID            uint64     `gorm:"index" json:"user_id"`
+	TokenHash         string     `gorm:"column:token_hash;size:64;uniqueIndex" json:"-"`
+	PreviousTokenHash *string    `gorm:"column:previous_token_hash;size:64" json:"-"`
+	UserAgent         *string    `gorm:"column:user_agent;size:512" json:"user_agent"`
+	IPAddress         *string    `gorm:"column:ip_address;size:45" json:"ip_address"`
+	LastUsedAt        *time.Time `gorm:"column:last_used_at" json:"last_used_at"`
+	RotatedAt         *time.Time `gorm:"column:rotated_at" json:"-"`
+	ExpiresAt         time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	RevokedAt         *time.Time `gorm:"column:revoked_at" json:"revoked_at,omitempty"`
+	RevokedReason     *string    `gorm:"column:revoked_reason;size:50" json:"revoked_reason,omitempty"`
+	CreatedAt         time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+
+	Current bool `gorm:"-" json:"current"`
+}
+
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type UserToken struct {
+	ID        uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint64     `gorm:"index" json:"user_id"`
+	Purpose   string     `gorm:"type:enum('verify_email','reset_password','change_email')" json:"purpose"`
+	Email     string     `gorm:"size:255" json:"email"`
+	TokenHash string     `gorm:"column:token_hash;size:64;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
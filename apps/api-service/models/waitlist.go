@@ -0,0 +1,37 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Waitlist struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProjectID uint64 `gorm:"index" json:"project_id"`
+	UserID    uint64 `gorm:"index" json:"user_id"`
+
+	Headline       *string `gorm:"column:headline;size:255" json:"headline"`
+	Description    *string `gorm:"column:description;type:text" json:"description"`
+	CTALabel       *string `gorm:"column:cta_label;size:255" json:"cta_label"`
+	SuccessMessage *string `gorm:"column:success_message;type:text" json:"success_message"`
+
+	LayoutName  *string          `gorm:"column:layout_name;size:255;default:waitlist-default" json:"layout_name"`
+	ThemeName   *string          `gorm:"column:theme_name;size:255;default:default" json:"theme_name"`
+	ThemeObject *json.RawMessage `gorm:"column:theme_object;type:json" json:"theme_object"`
+	Fields      *json.RawMessage `gorm:"column:fields;type:json" json:"fields"`
+
+	ReferralsEnabled bool `gorm:"column:referrals_enabled;default:true" json:"referrals_enabled"`
+	ReferralBoost    int  `gorm:"column:referral_boost;default:5" json:"referral_boost"`
+
+	SignupCount int64 `gorm:"-" json:"signup_count"`
+
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+func (Waitlist) TableName() string {
+	return "waitlists"
+}
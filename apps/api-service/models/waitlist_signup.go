@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WaitlistSignup struct {
+	ID         uint64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	WaitlistID uint64 `gorm:"index;uniqueIndex:waitlist_signups_waitlist_id_email_unique,priority:1" json:"waitlist_id"`
+	ProjectID  uint64 `gorm:"index" json:"project_id"`
+
+	Email   string           `gorm:"column:email;size:255;uniqueIndex:waitlist_signups_waitlist_id_email_unique,priority:2" json:"email"`
+	Name    *string          `gorm:"column:name;size:255" json:"name,omitempty"`
+	Answers *json.RawMessage `gorm:"column:answers;type:json" json:"answers,omitempty"`
+
+	ReferralCode  string  `gorm:"column:referral_code;size:32;uniqueIndex" json:"referral_code"`
+	ReferredByID  *uint64 `gorm:"column:referred_by_id" json:"referred_by_id,omitempty"`
+	ReferralCount int     `gorm:"column:referral_count;default:0" json:"referral_count"`
+	Priority      int     `gorm:"column:priority;index" json:"-"`
+	Position      int64   `gorm:"-" json:"position"`
+
+	IPAddress string    `gorm:"column:ip_address;size:45;index" json:"ip_address"`
+	UserAgent *string   `gorm:"column:user_agent;type:text" json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (WaitlistSignup) TableName() string {
+	return "waitlist_signups"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Workspace struct {
+	ID        uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
+	OwnerID   uint64         `gorm:"index" json:"owner_id"`
+	Name      string         `gorm:"size:255;not null" json:"name"`
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	Owner   *User             `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
+	Members []WorkspaceMember `gorm:"foreignKey:WorkspaceID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"members,omitempty"`
+}
+
+func (Workspace) TableName() string {
+	return "workspaces"
+}
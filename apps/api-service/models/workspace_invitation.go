@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type WorkspaceInvitation struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	WorkspaceID uint64     `gorm:"index" json:"workspace_id"`
+	InvitedBy   uint64     `gorm:"column:invited_by" json:"invited_by"`
+	Email       string     `gorm:"size:255;index" json:"email"`
+	Role        string     `gorm:"type:enum('editor','viewer');default:viewer" json:"role"`
+	TokenHash   string     `gorm:"column:token_hash;size:64;uniqueIndex" json:"-"`
+	ExpiresAt   time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	AcceptedAt  *time.Time `gorm:"column:accepted_at" json:"accepted_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (WorkspaceInvitation) TableName() string {
+	return "workspace_invitations"
+}
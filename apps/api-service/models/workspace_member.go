@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type WorkspaceMember struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	WorkspaceID uint64    `gorm:"index" json:"workspace_id"`
+	UserID      uint64    `gorm:"index" json:"user_id"`
+	Role        string    `gorm:"type:enum('owner','editor','viewer');default:viewer" json:"role"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (WorkspaceMember) TableName() string {
+	return "workspace_members"
+}
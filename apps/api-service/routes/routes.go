@@ -1,12 +1,16 @@
 package routes
 
 import (
+	"flash/internal/account"
+	"flash/internal/api_key"
 	"flash/internal/appointment"
 	"flash/internal/auth"
 	"flash/internal/biz"
 	"flash/internal/dashboard"
 	"flash/internal/document"
+	"flash/internal/file"
 	"flash/internal/help_inquiry"
+	"flash/internal/job"
 	"flash/internal/linktree"
 	"flash/internal/marketing_analytics"
 	"flash/internal/menu"
@@ -15,9 +19,14 @@ import (
 	"flash/internal/portfolio"
 	"flash/internal/project"
 	"flash/internal/user"
+	"flash/internal/waitlist"
+	"flash/internal/workspace"
 	"flash/middleware"
+	"flash/models"
 	"flash/sdk/llm"
 	objectStorage "flash/sdk/object_storage"
+	"flash/shared/access"
+	"flash/shared/apikey"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -30,10 +39,14 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, llm llm.Provider, objectSto
 		})
 	})
 
+	router.GET("/.well-known/jwks.json", auth.NewController(db).JWKS)
+
+	registerFileRoutes(router, objectStorage)
+
 	api := router.Group("/api")
 	{
 		authController := auth.NewController(db)
-		userController := user.NewController(db)
+		userController := user.NewController(db, objectStorage)
 		projectController := project.NewController(db, objectStorage)
 		dashboardController := dashboard.NewController(db)
 		helpInquiryController := help_inquiry.NewController(db)
@@ -49,65 +62,167 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, llm llm.Provider, objectSto
 
 		linktreeController := linktree.NewController(db, objectStorage)
 		menuController := menu.NewController(db, objectStorage)
+		waitlistController := waitlist.NewController(db, objectStorage)
+		workspaceController := workspace.NewController(db)
+		jobController := job.NewController(db)
+		apiKeyController := api_key.NewController(db)
+		accountController := account.NewController(db, objectStorage)
+
+		projectReadAccess := middleware.ProjectAccessMiddleware(db, middleware.ProjectFromParam("id"), access.PermissionRead)
+		projectWriteAccess := middleware.ProjectAccessMiddleware(db, middleware.ProjectFromParam("id"), access.PermissionWrite)
+		projectManageAccess := middleware.ProjectAccessMiddleware(db, middleware.ProjectFromParam("id"), access.PermissionManage)
+		projectBodyWriteAccess := middleware.ProjectAccessMiddleware(db, middleware.ProjectFromMultipartJSONBody("project_id"), access.PermissionWrite)
 
 		api.POST("/auth/login", authController.Login)
 		api.POST("/auth/github", authController.GithubLogin)
 		api.POST("/auth/google", authController.GoogleLogin)
 		api.GET("/auth/refresh", middleware.RefreshTokenValidatorMiddleware(db), authController.Refresh)
 		api.GET("/auth/logout", middleware.AccessTokenValidatorMiddleware(db), authController.Logout)
+		api.GET("/auth/sessions", middleware.AccessTokenValidatorMiddleware(db), authController.ListSessions)
+		api.DELETE("/auth/sessions", middleware.AccessTokenValidatorMiddleware(db), authController.RevokeAllSessions)
+		api.DELETE("/auth/sessions/:id", middleware.AccessTokenValidatorMiddleware(db), authController.RevokeSession)
+		api.GET("/auth/identities", middleware.AccessTokenValidatorMiddleware(db), authController.ListIdentities)
+		api.POST("/auth/identities/:provider", middleware.AccessTokenValidatorMiddleware(db), authController.LinkIdentity)
+		api.DELETE("/auth/identities/:provider", middleware.AccessTokenValidatorMiddleware(db), authController.UnlinkIdentity)
+		api.GET("/auth/oidc/providers", authController.ListOIDCProviders)
+		api.GET("/auth/oidc/:provider/authorize", authController.AuthorizeOIDC)
+		api.POST("/auth/oidc/:provider/link", middleware.AccessTokenValidatorMiddleware(db), authController.AuthorizeOIDCLink)
+		api.POST("/auth/oidc/:provider/callback", authController.OIDCCallback)
+		api.POST("/auth/email/verify", authController.VerifyEmail)
+		api.POST("/auth/email/verification", middleware.AccessTokenValidatorMiddleware(db), authController.ResendVerificationEmail)
+		api.POST("/auth/email/change", middleware.AccessTokenValidatorMiddleware(db), authController.RequestEmailChange)
+		api.POST("/auth/email/change/confirm", authController.ConfirmEmailChange)
+		api.POST("/auth/password/forgot", authController.ForgotPassword)
+		api.POST("/auth/password/reset", authController.ResetPassword)
+		api.POST("/auth/two-factor/challenge", authController.TwoFactorChallenge)
+		api.GET("/auth/two-factor", middleware.AccessTokenValidatorMiddleware(db), authController.TwoFactorStatus)
+		api.POST("/auth/two-factor", middleware.AccessTokenValidatorMiddleware(db), authController.EnableTwoFactor)
+		api.POST("/auth/two-factor/confirm", middleware.AccessTokenValidatorMiddleware(db), authController.ConfirmTwoFactor)
+		api.POST("/auth/two-factor/recovery-codes", middleware.AccessTokenValidatorMiddleware(db), authController.RegenerateRecoveryCodes)
+		api.DELETE("/auth/two-factor", middleware.AccessTokenValidatorMiddleware(db), authController.DisableTwoFactor)
 
 		api.POST("/user", userController.Register)
+		api.GET("/user/profile", middleware.AccessTokenValidatorMiddleware(db), userController.Profile)
+		api.PUT("/user/profile", middleware.AccessTokenValidatorMiddleware(db), userController.UpdateProfile)
+		api.POST("/user/profile/avatar", middleware.AccessTokenValidatorMiddleware(db), userController.UploadAvatar)
+		api.DELETE("/user/profile/avatar", middleware.AccessTokenValidatorMiddleware(db), userController.RemoveAvatar)
+		api.PUT("/user/password", middleware.AccessTokenValidatorMiddleware(db), userController.ChangePassword)
 
-		api.GET("/projects/list", middleware.AccessTokenValidatorMiddleware(db), projectController.List)
+		api.GET("/api-keys", middleware.AccessTokenValidatorMiddleware(db), apiKeyController.List)
+		api.GET("/api-keys/scopes", middleware.AccessTokenValidatorMiddleware(db), apiKeyController.Scopes)
+		api.POST("/api-keys", middleware.AccessTokenValidatorMiddleware(db), apiKeyController.Create)
+		api.DELETE("/api-keys/:id", middleware.AccessTokenValidatorMiddleware(db), apiKeyController.Revoke)
+
+		api.POST("/account/exports", middleware.AccessTokenValidatorMiddleware(db), accountController.RequestExport)
+		api.GET("/account/exports", middleware.AccessTokenValidatorMiddleware(db), accountController.ListExports)
+		api.GET("/account/exports/:id/download", middleware.AccessTokenValidatorMiddleware(db), accountController.DownloadExport)
+		api.POST("/account/deletion", middleware.AccessTokenValidatorMiddleware(db), accountController.RequestDeletion)
+		api.DELETE("/account/deletion", middleware.AccessTokenValidatorMiddleware(db), accountController.CancelDeletion)
+
+		api.GET("/projects/list", middleware.APIKeyOrAccessTokenMiddleware(db, apikey.ScopeProjectsRead), projectController.List)
 		api.GET("/projects/list/public", projectController.PublicList)
 		api.GET("/projects/stats/public", projectController.PublicStats)
 		api.GET("/dashboard/public", dashboardController.PublicMetrics)
 		api.POST("/help-inquiries", helpInquiryController.Create)
 		api.GET("/marketing-analytics/overview", marketingAnalyticsController.Overview)
-		api.GET("/projects/show/:id", projectController.ShowByID)
-		api.GET("/projects/show/slug/:slug", middleware.AccessTokenValidatorMiddleware(db), projectController.ShowBySlug)
+		api.GET("/projects/show/:id", middleware.APIKeyOrAccessTokenMiddleware(db, apikey.ScopeProjectsRead), projectReadAccess, projectController.ShowByID)
+		api.GET("/projects/show/slug/:slug", middleware.APIKeyOrAccessTokenMiddleware(db, apikey.ScopeProjectsRead), middleware.ProjectAccessMiddleware(db, middleware.ProjectFromSlugParam("slug"), access.PermissionRead), projectController.ShowBySlug)
 		api.GET("/projects/show/sub-domain/:sub-domain", projectController.ShowBySubDomain)
+		api.GET("/projects/show/host/:host", projectController.ShowByHost)
+		api.GET("/projects/redirect/sub-domain/:sub-domain", projectController.ResolveSubDomainRedirect)
 		api.GET("/projects/check/sub-domain/:sub-domain", middleware.AccessTokenValidatorMiddleware(db), projectController.CheckDomain)
-		api.POST("/projects/og-image/:id", middleware.AccessTokenValidatorMiddleware(db), projectController.SaveOGImage)
+		api.POST("/projects/og-image/:id", middleware.AccessTokenValidatorMiddleware(db), projectWriteAccess, projectController.SaveOGImage)
 		api.POST("/projects", middleware.AccessTokenValidatorMiddleware(db), projectController.Create)
-		api.PUT("/projects/publish/:id", middleware.AccessTokenValidatorMiddleware(db), projectController.Publish)
-		api.PUT("/projects/:id", middleware.AccessTokenValidatorMiddleware(db), projectController.Update)
-		api.DELETE("/projects/:id", middleware.AccessTokenValidatorMiddleware(db), projectController.Delete)
+		api.GET("/projects/templates", projectController.ListTemplates)
+		api.POST("/projects/templates/:key/use", middleware.AccessTokenValidatorMiddleware(db), projectController.CreateFromTemplate)
+		api.POST("/projects/:id/duplicate", middleware.AccessTokenValidatorMiddleware(db), projectWriteAccess, projectController.Duplicate)
+		api.GET("/projects/:id/export", middleware.AccessTokenValidatorMiddleware(db), projectWriteAccess, projectController.Export)
+		api.POST("/projects/import", middleware.AccessTokenValidatorMiddleware(db), projectController.Import)
+		api.PUT("/projects/publish/:id", middleware.APIKeyOrAccessTokenMiddleware(db, apikey.ScopeProjectsWrite), projectWriteAccess, projectController.Publish)
+		api.PUT("/projects/:id", middleware.APIKeyOrAccessTokenMiddleware(db, apikey.ScopeProjectsWrite), projectWriteAccess, projectController.Update)
+		api.DELETE("/projects/:id", middleware.AccessTokenValidatorMiddleware(db), projectManageAccess, projectController.Delete)
+		api.GET("/projects/trash", middleware.AccessTokenValidatorMiddleware(db), projectController.ListTrash)
+		api.POST("/projects/trash/:id/restore", middleware.AccessTokenValidatorMiddleware(db), projectController.Restore)
+		api.GET("/projects/:id/revisions", middleware.AccessTokenValidatorMiddleware(db), projectReadAccess, projectController.ListRevisions)
+		api.GET("/projects/:id/revisions/diff", middleware.AccessTokenValidatorMiddleware(db), projectReadAccess, projectController.DiffRevisions)
+		api.GET("/projects/:id/revisions/:revision_id", middleware.AccessTokenValidatorMiddleware(db), projectReadAccess, projectController.ShowRevision)
+		api.POST("/projects/:id/revisions/:revision_id/restore", middleware.AccessTokenValidatorMiddleware(db), projectWriteAccess, projectController.RestoreRevision)
+		api.GET("/projects/:id/sub-domains", middleware.AccessTokenValidatorMiddleware(db), projectReadAccess, projectController.ListSubDomainHistory)
+		api.GET("/projects/:id/domains", middleware.AccessTokenValidatorMiddleware(db), projectReadAccess, projectController.ListDomains)
+		api.POST("/projects/:id/domains", middleware.AccessTokenValidatorMiddleware(db), projectManageAccess, projectController.AddDomain)
+		api.POST("/projects/:id/domains/:domain_id/verify", middleware.AccessTokenValidatorMiddleware(db), projectManageAccess, projectController.VerifyDomain)
+		api.DELETE("/projects/:id/domains/:domain_id", middleware.AccessTokenValidatorMiddleware(db), projectManageAccess, projectController.RemoveDomain)
 
 		api.POST("/documents", middleware.AccessTokenValidatorMiddleware(db), documentController.Parse)
 		api.GET("/parsed-files", middleware.AccessTokenValidatorMiddleware(db), parsedFileController.List)
 		api.POST("/parsed-files", middleware.AccessTokenValidatorMiddleware(db), parsedFileController.Create)
+		api.GET("/jobs/:id", middleware.AccessTokenValidatorMiddleware(db), jobController.Show)
 
 		// Portfolio
-		api.GET("/portfolios/:id", middleware.AccessTokenValidatorMiddleware(db), portfolioController.Get)
-		api.POST("/portfolios", middleware.AccessTokenValidatorMiddleware(db), portfolioController.Save)
-		api.DELETE("/portfolios/:id", middleware.AccessTokenValidatorMiddleware(db), portfolioController.Delete)
+		api.GET("/portfolios/:id", middleware.AccessTokenValidatorMiddleware(db), middleware.ProjectAccessMiddleware(db, middleware.ProjectFromModelParam("id", &models.Portfolio{}), access.PermissionRead), portfolioController.Get)
+		api.POST("/portfolios", middleware.AccessTokenValidatorMiddleware(db), projectBodyWriteAccess, portfolioController.Save)
+		api.DELETE("/portfolios/:id", middleware.AccessTokenValidatorMiddleware(db), middleware.ProjectAccessMiddleware(db, middleware.ProjectFromModelParam("id", &models.Portfolio{}), access.PermissionWrite), portfolioController.Delete)
 		api.POST("/appointments", appointmentController.Create)
 		api.GET("/appointments", middleware.AccessTokenValidatorMiddleware(db), appointmentController.List)
-		api.GET("/appointments/show/:id", middleware.AccessTokenValidatorMiddleware(db), appointmentController.Show)
-		api.PUT("/appointments/:id", middleware.AccessTokenValidatorMiddleware(db), appointmentController.Update)
-		api.DELETE("/appointments/:id", middleware.AccessTokenValidatorMiddleware(db), appointmentController.Delete)
+		api.GET("/appointments/show/:id", middleware.AccessTokenValidatorMiddleware(db), middleware.ProjectAccessMiddleware(db, middleware.ProjectFromModelParam("id", &models.Appointment{}), access.PermissionRead), appointmentController.Show)
+		api.PUT("/appointments/:id", middleware.AccessTokenValidatorMiddleware(db), middleware.ProjectAccessMiddleware(db, middleware.ProjectFromModelParam("id", &models.Appointment{}), access.PermissionWrite), middleware.ProjectAccessMiddleware(db, middleware.ProjectFromJSONBody("project_id"), access.PermissionWrite), appointmentController.Update)
+		api.DELETE("/appointments/:id", middleware.AccessTokenValidatorMiddleware(db), middleware.ProjectAccessMiddleware(db, middleware.ProjectFromModelParam("id", &models.Appointment{}), access.PermissionWrite), appointmentController.Delete)
 		api.POST("/page-activities", pageActivityController.Create)
 		api.POST("/marketing-analytics/session/start", marketingAnalyticsController.StartSession)
 		api.POST("/marketing-analytics/event", marketingAnalyticsController.TrackEvent)
 		api.POST("/marketing-analytics/session/heartbeat", marketingAnalyticsController.Heartbeat)
 		api.POST("/marketing-analytics/session/end", marketingAnalyticsController.EndSession)
-		api.GET("/page-activities/:id", middleware.AccessTokenValidatorMiddleware(db), pageActivityController.GetStats)
-		api.GET("/page-activities/:id/top-links", middleware.AccessTokenValidatorMiddleware(db), pageActivityController.GetTopLinks)
-		api.GET("/page-activities/:id/visits", middleware.AccessTokenValidatorMiddleware(db), pageActivityController.GetVisits)
-		api.GET("/page-activities/:id/recent-activities", middleware.AccessTokenValidatorMiddleware(db), pageActivityController.GetRecentActivities)
+		api.GET("/page-activities/:id", middleware.APIKeyOrAccessTokenMiddleware(db, apikey.ScopeAnalyticsRead), projectReadAccess, pageActivityController.GetStats)
+		api.GET("/page-activities/:id/top-links", middleware.APIKeyOrAccessTokenMiddleware(db, apikey.ScopeAnalyticsRead), projectReadAccess, pageActivityController.GetTopLinks)
+		api.GET("/page-activities/:id/visits", middleware.APIKeyOrAccessTokenMiddleware(db, apikey.ScopeAnalyticsRead), projectReadAccess, pageActivityController.GetVisits)
+		api.GET("/page-activities/:id/recent-activities", middleware.APIKeyOrAccessTokenMiddleware(db, apikey.ScopeAnalyticsRead), projectReadAccess, pageActivityController.GetRecentActivities)
 
 		// Biz
-		api.GET("/biz/:id", middleware.AccessTokenValidatorMiddleware(db), bizController.Get)
-		api.POST("/biz", middleware.AccessTokenValidatorMiddleware(db), bizController.Save)
+		api.GET("/biz/:id", middleware.AccessTokenValidatorMiddleware(db), middleware.ProjectAccessMiddleware(db, middleware.ProjectFromModelParam("id", &models.Biz{}), access.PermissionRead), bizController.Get)
+		api.POST("/biz", middleware.AccessTokenValidatorMiddleware(db), projectBodyWriteAccess, bizController.Save)
 
 		// Linktree
-		api.GET("/linktree/:id", middleware.AccessTokenValidatorMiddleware(db), linktreeController.Get)
-		api.POST("/linktree", middleware.AccessTokenValidatorMiddleware(db), linktreeController.Save)
+		api.GET("/linktree/:project_id", middleware.AccessTokenValidatorMiddleware(db), middleware.ProjectAccessMiddleware(db, middleware.ProjectFromParam("project_id"), access.PermissionRead), linktreeController.Get)
+		api.POST("/linktree", middleware.AccessTokenValidatorMiddleware(db), projectBodyWriteAccess, linktreeController.Save)
 
 		// Menu
-		api.GET("/menu/:id", middleware.AccessTokenValidatorMiddleware(db), menuController.Get)
-		api.POST("/menu", middleware.AccessTokenValidatorMiddleware(db), menuController.Save)
-		api.POST("/menu/display-poster", middleware.AccessTokenValidatorMiddleware(db), menuController.GenerateDisplayPoster)
+		api.GET("/menu/:project_id", middleware.APIKeyOrAccessTokenMiddleware(db, apikey.ScopeMenuRead), middleware.ProjectAccessMiddleware(db, middleware.ProjectFromParam("project_id"), access.PermissionRead), menuController.Get)
+		api.POST("/menu", middleware.APIKeyOrAccessTokenMiddleware(db, apikey.ScopeMenuWrite), projectBodyWriteAccess, menuController.Save)
+		api.POST("/menu/display-poster", middleware.AccessTokenValidatorMiddleware(db), middleware.ProjectAccessMiddleware(db, middleware.ProjectFromJSONBody("project_id"), access.PermissionWrite), menuController.GenerateDisplayPoster)
+
+		// Waitlist
+		api.GET("/waitlist/:project_id", middleware.AccessTokenValidatorMiddleware(db), middleware.ProjectAccessMiddleware(db, middleware.ProjectFromParam("project_id"), access.PermissionRead), waitlistController.Get)
+		api.POST("/waitlist", middleware.AccessTokenValidatorMiddleware(db), projectBodyWriteAccess, waitlistController.Save)
+		api.GET("/waitlist/:project_id/signups", middleware.AccessTokenValidatorMiddleware(db), middleware.ProjectAccessMiddleware(db, middleware.ProjectFromParam("project_id"), access.PermissionRead), waitlistController.ListSignups)
+		api.GET("/waitlist/:project_id/signups/export", middleware.AccessTokenValidatorMiddleware(db), middleware.ProjectAccessMiddleware(db, middleware.ProjectFromParam("project_id"), access.PermissionRead), waitlistController.ExportSignups)
+		api.POST("/waitlist-signups", waitlistController.Signup)
+		api.GET("/waitlist-signups/:referral_code", waitlistController.Status)
+
+		// Workspace
+		api.GET("/workspaces", middleware.AccessTokenValidatorMiddleware(db), workspaceController.List)
+		api.POST("/workspaces", middleware.AccessTokenValidatorMiddleware(db), workspaceController.Create)
+		api.POST("/workspaces/invitations/accept", middleware.AccessTokenValidatorMiddleware(db), workspaceController.AcceptInvitation)
+		api.GET("/workspaces/:id", middleware.AccessTokenValidatorMiddleware(db), workspaceController.Show)
+		api.PUT("/workspaces/:id", middleware.AccessTokenValidatorMiddleware(db), workspaceController.Update)
+		api.DELETE("/workspaces/:id", middleware.AccessTokenValidatorMiddleware(db), workspaceController.Delete)
+		api.GET("/workspaces/:id/members", middleware.AccessTokenValidatorMiddleware(db), workspaceController.ListMembers)
+		api.PUT("/workspaces/:id/members/:member_id", middleware.AccessTokenValidatorMiddleware(db), workspaceController.UpdateMember)
+		api.DELETE("/workspaces/:id/members/:member_id", middleware.AccessTokenValidatorMiddleware(db), workspaceController.RemoveMember)
+		api.GET("/workspaces/:id/invitations", middleware.AccessTokenValidatorMiddleware(db), workspaceController.ListInvitations)
+		api.POST("/workspaces/:id/invitations", middleware.AccessTokenValidatorMiddleware(db), workspaceController.Invite)
+		api.DELETE("/workspaces/:id/invitations/:invitation_id", middleware.AccessTokenValidatorMiddleware(db), workspaceController.RevokeInvitation)
+		api.PUT("/workspaces/:id/projects/:project_id", middleware.AccessTokenValidatorMiddleware(db), workspaceController.AttachProject)
+		api.DELETE("/workspaces/:id/projects/:project_id", middleware.AccessTokenValidatorMiddleware(db), workspaceController.DetachProject)
 	}
 }
+
+// registerFileRoutes serves objects kept on local disk; cloud providers hand out their own URLs.
+func registerFileRoutes(router *gin.Engine, storage objectStorage.Provider) {
+	localStorage, ok := storage.(*objectStorage.LocalFSProvider)
+	if !ok {
+		return
+	}
+
+	fileController := file.NewController(localStorage)
+	router.GET("/files/*filepath", fileController.Serve)
+}
@@ -0,0 +1,125 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OpenAICompatibleSDK talks to any server exposing OpenAI's /chat/completions, such as llama.cpp, Ollama, vLLM
+// or LM Studio. Media is fetched and inlined because local servers usually can't reach our storage: images as a
+// data URL image part, PDFs as a file part, since vision endpoints reject a PDF passed off as an image.
+type OpenAICompatibleSDK struct {
+	BaseURL string // e.g. http://localhost:11434/v1
+	ApiKey  string // optional for most local servers
+	Model   string
+
+	HTTPClient *http.Client
+}
+
+func (sdk *OpenAICompatibleSDK) Generate(prompt string, media *Media) (string, error) {
+	return sdk.complete(prompt, media, nil)
+}
+
+func (sdk *OpenAICompatibleSDK) GenerateJSON(prompt string, media *Media, format JSONFormat) (string, error) {
+	return sdk.complete(prompt, media, map[string]any{
+		"type": "json_schema",
+		"json_schema": map[string]any{
+			"name":   format.Name,
+			"schema": format.Schema,
+			"strict": true,
+		},
+	})
+}
+
+func (sdk *OpenAICompatibleSDK) complete(prompt string, media *Media, responseFormat map[string]any) (string, error) {
+	content := []map[string]any{{"type": "text", "text": prompt}}
+	if media != nil && media.URL != "" {
+		data, mimeType, err := fetchMedia(sdk.httpClient(), media)
+		if err != nil {
+			return "", err
+		}
+		content = append(content, mediaPart(data, mimeType))
+	}
+
+	payload := map[string]any{
+		"model":    sdk.Model,
+		"messages": []map[string]any{{"role": "user", "content": content}},
+	}
+	if responseFormat != nil {
+		payload["response_format"] = responseFormat
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal completion payload: %w", err)
+	}
+
+	url := strings.TrimRight(sdk.BaseURL, "/") + "/chat/completions"
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if sdk.ApiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+sdk.ApiKey)
+	}
+
+	resp, err := sdk.httpClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("LLM server error (%d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("no choices found in response")
+	}
+
+	return result.Choices[0].Message.Content, nil
+}
+
+func mediaPart(data []byte, mimeType string) map[string]any {
+	dataURL := fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data))
+
+	if mimeType == "application/pdf" {
+		return map[string]any{
+			"type": "file",
+			"file": map[string]any{
+				"filename":  "document.pdf",
+				"file_data": dataURL,
+			},
+		}
+	}
+
+	return map[string]any{
+		"type":      "image_url",
+		"image_url": map[string]any{"url": dataURL},
+	}
+}
+
+func (sdk *OpenAICompatibleSDK) httpClient() *http.Client {
+	if sdk.HTTPClient != nil {
+		return sdk.HTTPClient
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,181 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// completionsServer stands in for a local /chat/completions server that can't reach our storage.
+type completionsServer struct {
+	status int
+	reply  string
+
+	request *http.Request
+	body    []byte
+}
+
+func (server *completionsServer) RoundTrip(request *http.Request) (*http.Response, error) {
+	switch request.URL.Host + request.URL.Path {
+	case "media.test/menu.png":
+		return stubHTTPResponse(request, http.StatusOK, "image/png", stubImage), nil
+	case "media.test/resume.pdf":
+		return stubHTTPResponse(request, http.StatusOK, "application/pdf", stubPDF), nil
+	case "localhost:11434/v1/chat/completions":
+		server.request = request
+		server.body, _ = io.ReadAll(request.Body)
+		return stubHTTPResponse(request, server.status, "application/json", []byte(server.reply)), nil
+	}
+
+	return stubHTTPResponse(request, http.StatusNotFound, "text/plain", []byte("not found")), nil
+}
+
+type completionPayload struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role    string `json:"role"`
+		Content []struct {
+			Type     string `json:"type"`
+			Text     string `json:"text"`
+			ImageURL *struct {
+				URL string `json:"url"`
+			} `json:"image_url"`
+			File *struct {
+				Filename string `json:"filename"`
+				FileData string `json:"file_data"`
+			} `json:"file"`
+		} `json:"content"`
+	} `json:"messages"`
+	ResponseFormat *struct {
+		Type       string `json:"type"`
+		JSONSchema struct {
+			Name   string          `json:"name"`
+			Strict bool            `json:"strict"`
+			Schema json.RawMessage `json:"schema"`
+		} `json:"json_schema"`
+	} `json:"response_format"`
+}
+
+func newCompletionsServer(reply string) (*completionsServer, *OpenAICompatibleSDK) {
+	server := &completionsServer{status: http.StatusOK, reply: reply}
+	sdk := &OpenAICompatibleSDK{BaseURL: "http://localhost:11434/v1/", Model: "llama3.2-vision", HTTPClient: &http.Client{Transport: server}}
+	return server, sdk
+}
+
+func (server *completionsServer) payload(t *testing.T) completionPayload {
+	t.Helper()
+
+	var payload completionPayload
+	if err := json.Unmarshal(server.body, &payload); err != nil || len(payload.Messages) != 1 {
+		t.Fatalf("completion request body %s: %v", server.body, err)
+	}
+	return payload
+}
+
+func TestOpenAICompatibleInlinesMedia(t *testing.T) {
+	tests := []struct {
+		name     string
+		media    *Media
+		partType string
+		wantData string
+	}{
+		{name: "image", media: &Media{URL: "https://media.test/menu.png"}, partType: "image_url", wantData: "data:image/png;base64," + base64.StdEncoding.EncodeToString(stubImage)},
+		{name: "pdf", media: &Media{URL: "https://media.test/resume.pdf", MimeType: "application/pdf"}, partType: "file", wantData: "data:application/pdf;base64," + base64.StdEncoding.EncodeToString(stubPDF)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, sdk := newCompletionsServer(`{"choices":[{"message":{"content":"extracted"}}]}`)
+
+			text, err := sdk.Generate("Extract the content.", test.media)
+			if err != nil || text != "extracted" {
+				t.Fatalf("Generate() = %q, %v", text, err)
+			}
+
+			payload := server.payload(t)
+			content := payload.Messages[0].Content
+			if payload.Model != "llama3.2-vision" || payload.Messages[0].Role != "user" || len(content) != 2 {
+				t.Fatalf("request = %s", server.body)
+			}
+			if content[0].Type != "text" || content[0].Text != "Extract the content." {
+				t.Fatalf("first part = %+v, want the prompt", content[0])
+			}
+
+			part := content[1]
+			var data string
+			switch {
+			case part.ImageURL != nil:
+				data = part.ImageURL.URL
+			case part.File != nil:
+				data = part.File.FileData
+			}
+			if part.Type != test.partType || data != test.wantData {
+				t.Fatalf("media part = %s %q, want %s %q", part.Type, data, test.partType, test.wantData)
+			}
+			if server.request.Header.Get("Authorization") != "" {
+				t.Fatal("sent an Authorization header without an API key")
+			}
+		})
+	}
+}
+
+func TestOpenAICompatibleGenerateJSON(t *testing.T) {
+	server, sdk := newCompletionsServer(`{"choices":[{"message":{"content":"{\"title\":\"Lunch\"}"}}]}`)
+	sdk.ApiKey = "local-key"
+
+	schema := SchemaFor[structuredMenu]()
+	text, err := sdk.GenerateJSON("Extract the menu.", nil, JSONFormat{Name: "structuredMenu", Schema: schema})
+	if err != nil || text != `{"title":"Lunch"}` {
+		t.Fatalf("GenerateJSON() = %q, %v", text, err)
+	}
+
+	payload := server.payload(t)
+	if len(payload.Messages[0].Content) != 1 {
+		t.Fatalf("content = %+v, want only the prompt", payload.Messages[0].Content)
+	}
+	format := payload.ResponseFormat
+	wantSchema, _ := json.Marshal(schema)
+	if format == nil || format.Type != "json_schema" || format.JSONSchema.Name != "structuredMenu" || !format.JSONSchema.Strict ||
+		!bytes.Equal(format.JSONSchema.Schema, wantSchema) {
+		t.Fatalf("response_format = %+v", format)
+	}
+	if got := server.request.Header.Get("Authorization"); got != "Bearer local-key" {
+		t.Fatalf("Authorization = %q", got)
+	}
+}
+
+func TestOpenAICompatibleErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		reply   string
+		wantErr string
+	}{
+		{name: "server error", status: http.StatusBadRequest, reply: `{"error":"model not loaded"}`, wantErr: "LLM server error (400)"},
+		{name: "no choices", status: http.StatusOK, reply: `{"choices":[]}`, wantErr: "no choices found"},
+		{name: "invalid body", status: http.StatusOK, reply: `<html>`, wantErr: "failed to parse response"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, sdk := newCompletionsServer(test.reply)
+			server.status = test.status
+
+			if _, err := sdk.Generate("Extract.", nil); err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Generate() error = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+
+	server, sdk := newCompletionsServer(`{"choices":[{"message":{"content":"extracted"}}]}`)
+	if _, err := sdk.Generate("Extract.", &Media{URL: "https://media.test/missing.png"}); err == nil {
+		t.Fatal("Generate() with unreachable media succeeded")
+	}
+	if server.body != nil {
+		t.Fatal("the server was called although the media could not be fetched")
+	}
+}
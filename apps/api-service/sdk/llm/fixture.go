@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var ErrFixtureNotFound = errors.New("no recorded LLM response for prompt")
+
+// FixtureSDK replays recorded responses, so parsing can run offline and deterministically. Responses are stored
+// as <Dir>/<FixtureKey>.json. When Upstream is set, misses are forwarded to it and recorded.
+type FixtureSDK struct {
+	Dir      string
+	Upstream Provider
+
+	// HTTPClient fetches attachments that come without a Digest; defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+type Fixture struct {
+	Key         string    `json:"key"`
+	Prompt      string    `json:"prompt"`
+	Format      string    `json:"format,omitempty"`
+	MediaType   string    `json:"media_type,omitempty"`
+	MediaDigest string    `json:"media_digest,omitempty"`
+	Response    string    `json:"response"`
+	RecordedAt  time.Time `json:"recorded_at"`
+}
+
+func (sdk *FixtureSDK) Generate(prompt string, media *Media) (string, error) {
+	return sdk.replay(prompt, "", media, func() (string, error) {
+		return sdk.Upstream.Generate(prompt, media)
+	})
+}
+
+func (sdk *FixtureSDK) GenerateJSON(prompt string, media *Media, format JSONFormat) (string, error) {
+	return sdk.replay(prompt, format.Name, media, func() (string, error) {
+		return generateJSON(sdk.Upstream, prompt, media, format)
+	})
+}
+
+// FixtureKey is the hash a request is stored under. Structured requests include the format name, because the
+// same prompt can be asked for as free text or as JSON. Requests with an attachment include its MIME type and
+// content digest, because image-only uploads share one prompt; the URL is left out, since it changes per upload.
+func FixtureKey(prompt string, format string, mediaType string, mediaDigest string) string {
+	sum := sha256.Sum256([]byte(format + "\x00" + mediaType + "\x00" + mediaDigest + "\x00" + prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// MediaDigest is the hex sha256 callers put in Media.Digest.
+func MediaDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// describeMedia settles the MIME type and digest an attachment is keyed by, fetching it when the caller gave no
+// digest.
+func (sdk *FixtureSDK) describeMedia(media *Media) (string, string, error) {
+	if media == nil || media.URL == "" {
+		return "", "", nil
+	}
+	if media.Digest != "" {
+		return media.MimeType, media.Digest, nil
+	}
+
+	client := sdk.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	data, mimeType, err := fetchMedia(client, media)
+	if err != nil {
+		return "", "", err
+	}
+	return mimeType, MediaDigest(data), nil
+}
+
+func (sdk *FixtureSDK) replay(prompt string, format string, media *Media, generate func() (string, error)) (string, error) {
+	mediaType, mediaDigest, err := sdk.describeMedia(media)
+	if err != nil {
+		return "", err
+	}
+
+	key := FixtureKey(prompt, format, mediaType, mediaDigest)
+	path := filepath.Join(sdk.Dir, key+".json")
+
+	if content, err := os.ReadFile(path); err == nil {
+		var fixture Fixture
+		if err := json.Unmarshal(content, &fixture); err != nil {
+			return "", fmt.Errorf("invalid fixture %s: %w", path, err)
+		}
+		return fixture.Response, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	if sdk.Upstream == nil {
+		return "", fmt.Errorf("%w (key %s)", ErrFixtureNotFound, key)
+	}
+
+	response, err := generate()
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := json.MarshalIndent(Fixture{
+		Key:         key,
+		Prompt:      prompt,
+		Format:      format,
+		MediaType:   mediaType,
+		MediaDigest: mediaDigest,
+		Response:    response,
+		RecordedAt:  time.Now(),
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(sdk.Dir, 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, encoded, 0o644); err != nil {
+		return "", fmt.Errorf("failed to record fixture: %w", err)
+	}
+
+	return response, nil
+}
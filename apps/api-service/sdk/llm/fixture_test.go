@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// countingProvider answers with the call number, so a replay can be told apart from a fresh generation.
+type countingProvider struct {
+	calls int
+}
+
+func (provider *countingProvider) Generate(prompt string, media *Media) (string, error) {
+	provider.calls++
+	return fmt.Sprintf("response %d", provider.calls), nil
+}
+
+func TestFixtureSDKKeysOnMediaContent(t *testing.T) {
+	upstream := &countingProvider{}
+	fixtures := &FixtureSDK{Dir: t.TempDir(), Upstream: upstream}
+	const prompt = "No extractable text was available from the uploaded menu."
+
+	first, err := fixtures.Generate(prompt, &Media{URL: "https://cdn.test/menus/1.png", MimeType: "image/png", Digest: MediaDigest([]byte("menu one"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := fixtures.Generate(prompt, &Media{URL: "https://cdn.test/menus/2.png", MimeType: "image/png", Digest: MediaDigest([]byte("menu two"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatalf("different menus with the same prompt replayed the same response %q", first)
+	}
+
+	// The same content uploaded again under a new URL replays the recording.
+	replayed, err := fixtures.Generate(prompt, &Media{URL: "https://cdn.test/menus/3.png", MimeType: "image/png", Digest: MediaDigest([]byte("menu one"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed != first || upstream.calls != 2 {
+		t.Errorf("replay = %q after %d upstream calls, want %q after 2", replayed, upstream.calls, first)
+	}
+
+	if _, err := fixtures.Generate(prompt, &Media{URL: "https://cdn.test/menus/1.pdf", MimeType: "application/pdf", Digest: MediaDigest([]byte("menu one"))}); err != nil {
+		t.Fatal(err)
+	}
+	if upstream.calls != 3 {
+		t.Errorf("another MIME type replayed a recording, upstream calls = %d, want 3", upstream.calls)
+	}
+}
+
+func TestFixtureSDKFetchesMediaWithoutDigest(t *testing.T) {
+	upstream := &countingProvider{}
+	transport := &recordingTransport{bodies: map[string][]byte{}}
+	fixtures := &FixtureSDK{Dir: t.TempDir(), Upstream: upstream, HTTPClient: &http.Client{Transport: transport}}
+
+	recorded, err := fixtures.Generate("Extract.", &Media{URL: "https://media.test/menu.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayOnly := &FixtureSDK{Dir: fixtures.Dir, HTTPClient: fixtures.HTTPClient}
+	replayed, err := replayOnly.Generate("Extract.", &Media{URL: "https://media.test/menu.png", MimeType: "image/png", Digest: MediaDigest(stubImage)})
+	if err != nil || replayed != recorded {
+		t.Fatalf("replay = %q, %v, want %q", replayed, err, recorded)
+	}
+
+	if _, err := replayOnly.Generate("Extract.", &Media{URL: "https://media.test/resume.pdf"}); !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("unrecorded media error = %v, want ErrFixtureNotFound", err)
+	}
+	if _, err := replayOnly.Generate("Extract.", &Media{URL: "https://media.test/missing.png"}); err == nil {
+		t.Error("unfetchable media error = nil")
+	}
+}
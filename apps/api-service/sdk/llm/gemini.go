@@ -1,17 +1,25 @@
 package llm
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"net/http"
 	"sync"
 
 	"google.golang.org/genai"
 )
 
+// geminiInlineLimit keeps inline attachments under the 20MB request cap once base64 overhead is added.
+const geminiInlineLimit = 14 << 20
+
 type GeminiSDK struct {
 	ApiKey string
 	Model  string
 
+	// HTTPClient is used for the Gemini API and for fetching media; defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
 	once   sync.Once
 	client *genai.Client
 	err    error
@@ -21,8 +29,9 @@ func (g *GeminiSDK) init() {
 	g.once.Do(func() {
 		ctx := context.Background()
 		client, err := genai.NewClient(ctx, &genai.ClientConfig{
-			APIKey:  g.ApiKey,
-			Backend: genai.BackendGeminiAPI,
+			APIKey:     g.ApiKey,
+			Backend:    genai.BackendGeminiAPI,
+			HTTPClient: g.HTTPClient,
 		})
 		if err != nil {
 			g.err = fmt.Errorf("failed to init Gemini client: %w", err)
@@ -36,15 +45,63 @@ func (g *GeminiSDK) init() {
 }
 
 func (g *GeminiSDK) Generate(prompt string, media *Media) (string, error) {
+	return g.generate(prompt, media, nil)
+}
+
+// GenerateJSON switches Gemini into JSON mode with the schema as responseJsonSchema.
+func (g *GeminiSDK) GenerateJSON(prompt string, media *Media, format JSONFormat) (string, error) {
+	return g.generate(prompt, media, &genai.GenerateContentConfig{
+		ResponseMIMEType:   "application/json",
+		ResponseJsonSchema: format.Schema,
+	})
+}
+
+func (g *GeminiSDK) generate(prompt string, media *Media, config *genai.GenerateContentConfig) (string, error) {
 	g.init()
 	if g.err != nil {
 		return "", g.err
 	}
 
 	ctx := context.Background()
-	resp, err := g.client.Models.GenerateContent(ctx, g.Model, genai.Text(prompt), nil)
+	parts := []*genai.Part{genai.NewPartFromText(prompt)}
+	if media != nil && media.URL != "" {
+		part, err := g.mediaPart(ctx, media)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+
+	contents := []*genai.Content{genai.NewContentFromParts(parts, genai.RoleUser)}
+	resp, err := g.client.Models.GenerateContent(ctx, g.Model, contents, config)
 	if err != nil {
 		return "", fmt.Errorf("gemini GenerateContent error: %w", err)
 	}
 	return resp.Text(), nil
 }
+
+// mediaPart fetches the attachment and sends it inline, or through the Files API when it is too large to inline.
+func (g *GeminiSDK) mediaPart(ctx context.Context, media *Media) (*genai.Part, error) {
+	data, mimeType, err := fetchMedia(g.httpClient(), media)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) <= geminiInlineLimit {
+		return genai.NewPartFromBytes(data, mimeType), nil
+	}
+
+	file, err := g.client.Files.Upload(ctx, bytes.NewReader(data), &genai.UploadFileConfig{MIMEType: mimeType})
+	if err != nil {
+		return nil, fmt.Errorf("gemini file upload error: %w", err)
+	}
+
+	return genai.NewPartFromURI(file.URI, mimeType), nil
+}
+
+func (g *GeminiSDK) httpClient() *http.Client {
+	if g.HTTPClient != nil {
+		return g.HTTPClient
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,55 @@
+package llm
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// maxMediaBytes caps how much a provider will download for a single attachment.
+const maxMediaBytes = 50 << 20
+
+// fetchMedia downloads an attachment and settles its MIME type: the caller's Media.MimeType wins, then the
+// response's Content-Type, then content sniffing.
+func fetchMedia(client *http.Client, media *Media) ([]byte, string, error) {
+	resp, err := client.Get(media.URL)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch media: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to fetch media (%d)", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMediaBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read media: %w", err)
+	}
+	if len(data) > maxMediaBytes {
+		return nil, "", fmt.Errorf("media exceeds %d bytes", maxMediaBytes)
+	}
+
+	return data, mediaMimeType(media, resp.Header.Get("Content-Type"), data), nil
+}
+
+func mediaMimeType(media *Media, contentType string, data []byte) string {
+	if media != nil && media.MimeType != "" {
+		return media.MimeType
+	}
+
+	if contentType != "" {
+		if parsed, _, err := mime.ParseMediaType(contentType); err == nil && parsed != "application/octet-stream" {
+			return parsed
+		}
+	}
+
+	detected := http.DetectContentType(data)
+	return strings.TrimSpace(strings.Split(detected, ";")[0])
+}
+
+func isPDFMedia(media *Media) bool {
+	return media.MimeType == "application/pdf" || strings.HasSuffix(strings.ToLower(media.URL), ".pdf")
+}
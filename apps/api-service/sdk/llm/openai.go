@@ -15,19 +15,64 @@ import (
 type OpenAISDK struct {
 	ApiKey string
 	Model  string
+
+	// HTTPClient is used for every OpenAI request; defaults to http.DefaultClient.
+	HTTPClient *http.Client
 }
 
 func (sdk *OpenAISDK) Generate(prompt string, media *Media) (string, error) {
 	if media != nil && media.URL != "" {
-		return sdk.generateWithMedia(prompt, media)
+		return sdk.generateWithMedia(prompt, media, nil)
 	}
 
 	return sdk.generateTextOnly(prompt)
 }
 
+// GenerateJSON uses OpenAI's strict structured outputs, so the model is constrained to the schema while decoding.
+func (sdk *OpenAISDK) GenerateJSON(prompt string, media *Media, format JSONFormat) (string, error) {
+	if media != nil && media.URL != "" {
+		return sdk.generateWithMedia(prompt, media, &format)
+	}
+
+	client := openai.NewClient(
+		option.WithAPIKey(sdk.ApiKey),
+		option.WithHTTPClient(sdk.httpClient()),
+	)
+
+	model := openai.ChatModelGPT4o
+	if sdk.Model != "" {
+		model = sdk.Model
+	}
+
+	chatCompletion, err := client.Chat.Completions.New(context.TODO(), openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage(prompt),
+		},
+		Model: model,
+		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+				JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   format.Name,
+					Schema: format.Schema,
+					Strict: openai.Bool(true),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("openai structured completion error: %w", err)
+	}
+	if len(chatCompletion.Choices) == 0 {
+		return "", fmt.Errorf("openai returned no choices")
+	}
+
+	return chatCompletion.Choices[0].Message.Content, nil
+}
+
 func (sdk *OpenAISDK) generateTextOnly(prompt string) (string, error) {
 	client := openai.NewClient(
 		option.WithAPIKey(sdk.ApiKey),
+		option.WithHTTPClient(sdk.httpClient()),
 	)
 
 	chatCompletion, err := client.Chat.Completions.New(context.TODO(), openai.ChatCompletionNewParams{
@@ -44,11 +89,12 @@ func (sdk *OpenAISDK) generateTextOnly(prompt string) (string, error) {
 	return chatCompletion.Choices[0].Message.Content, nil
 }
 
-func (sdk *OpenAISDK) generateWithMedia(prompt string, media *Media) (string, error) {
+func (sdk *OpenAISDK) generateWithMedia(prompt string, media *Media, format *JSONFormat) (string, error) {
 	type contentItem struct {
 		Type     string `json:"type"`
 		Text     string `json:"text,omitempty"`
 		ImageUrl string `json:"image_url,omitempty"`
+		FileUrl  string `json:"file_url,omitempty"`
 	}
 
 	type inputItem struct {
@@ -56,9 +102,26 @@ func (sdk *OpenAISDK) generateWithMedia(prompt string, media *Media) (string, er
 		Content []contentItem `json:"content"`
 	}
 
+	type textFormat struct {
+		Type   string `json:"type"`
+		Name   string `json:"name"`
+		Schema Schema `json:"schema"`
+		Strict bool   `json:"strict"`
+	}
+
+	type textConfig struct {
+		Format textFormat `json:"format"`
+	}
+
 	type requestPayload struct {
 		Model string      `json:"model"`
 		Input []inputItem `json:"input"`
+		Text  *textConfig `json:"text,omitempty"`
+	}
+
+	mediaItem := contentItem{Type: "input_image", ImageUrl: media.URL}
+	if isPDFMedia(media) {
+		mediaItem = contentItem{Type: "input_file", FileUrl: media.URL}
 	}
 
 	payload := requestPayload{
@@ -68,12 +131,18 @@ func (sdk *OpenAISDK) generateWithMedia(prompt string, media *Media) (string, er
 				Role: "user",
 				Content: []contentItem{
 					{Type: "input_text", Text: prompt},
-					{Type: "input_image", ImageUrl: media.URL},
+					mediaItem,
 				},
 			},
 		},
 	}
 
+	if format != nil {
+		payload.Text = &textConfig{
+			Format: textFormat{Type: "json_schema", Name: format.Name, Schema: format.Schema, Strict: true},
+		}
+	}
+
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal media payload: %w", err)
@@ -89,8 +158,7 @@ func (sdk *OpenAISDK) generateWithMedia(prompt string, media *Media) (string, er
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+sdk.ApiKey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := sdk.httpClient().Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to send request: %w", err)
 	}
@@ -133,3 +201,10 @@ func (sdk *OpenAISDK) generateWithMedia(prompt string, media *Media) (string, er
 
 	return "", fmt.Errorf("no output text found in response")
 }
+
+func (sdk *OpenAISDK) httpClient() *http.Client {
+	if sdk.HTTPClient != nil {
+		return sdk.HTTPClient
+	}
+	return http.DefaultClient
+}
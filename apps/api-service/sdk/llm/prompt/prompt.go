@@ -143,8 +143,7 @@ Follow this schema:
     ]
   }
 
-The URL of the resume image is:
-"""%s"""
+The resume image is attached to this message.
 Output only JSON:
 
 ---
@@ -152,8 +151,10 @@ Output only JSON:
 Things to consider: Instead of returning empty string on results, put null.
 `
 
-func ObjectStorageFileToContent(content string) string {
-	return fmt.Sprintf(resumeImageToJSONPrompt, content)
+// ResumeImageToJSON asks for the attached resume image. It carries no upload URL, so the prompt is the same on
+// every run and recorded fixtures replay.
+func ResumeImageToJSON() string {
+	return resumeImageToJSONPrompt
 }
 
 const menuToJSONPrompt = `
@@ -3,6 +3,9 @@ package llm
 type Media struct {
 	URL      string
 	MimeType string
+
+	// Digest is the hex sha256 of the content, when the caller has it. Fixtures key on it instead of the URL.
+	Digest string
 }
 
 type Provider interface {
@@ -0,0 +1,207 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	stubImage = []byte("\x89PNG\r\n\x1a\nmenu-image")
+	stubPDF   = []byte("%PDF-1.7 resume")
+)
+
+// recordingTransport serves the media URLs and both providers' APIs, keeping every API request body it saw.
+type recordingTransport struct {
+	mu     sync.Mutex
+	bodies map[string][]byte
+}
+
+func (transport *recordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	key := request.URL.Host + request.URL.Path
+
+	var body []byte
+	if request.Body != nil {
+		body, _ = io.ReadAll(request.Body)
+	}
+	transport.mu.Lock()
+	transport.bodies[key] = body
+	transport.mu.Unlock()
+
+	switch key {
+	case "media.test/menu.png":
+		return stubHTTPResponse(request, http.StatusOK, "image/png", stubImage), nil
+	case "media.test/resume.pdf":
+		return stubHTTPResponse(request, http.StatusOK, "application/pdf", stubPDF), nil
+	case "api.openai.com/v1/responses":
+		return stubHTTPResponse(request, http.StatusOK, "application/json",
+			[]byte(`{"output":[{"type":"message","content":[{"type":"output_text","text":"extracted"}]}]}`)), nil
+	case "generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash:generateContent":
+		return stubHTTPResponse(request, http.StatusOK, "application/json",
+			[]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"extracted"}]}}]}`)), nil
+	}
+
+	return stubHTTPResponse(request, http.StatusNotFound, "text/plain", []byte("not found")), nil
+}
+
+func stubHTTPResponse(request *http.Request, status int, contentType string, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    request,
+	}
+}
+
+func (transport *recordingTransport) body(key string) []byte {
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	return transport.bodies[key]
+}
+
+// providerInput is what a provider request carried: the prompt and the attachment, as bytes or as a URL.
+type providerInput struct {
+	Prompt    string
+	MediaURL  string
+	MediaData []byte
+	MimeType  string
+}
+
+func openAIInput(t *testing.T, body []byte) providerInput {
+	t.Helper()
+
+	var payload struct {
+		Input []struct {
+			Content []struct {
+				Type     string `json:"type"`
+				Text     string `json:"text"`
+				ImageURL string `json:"image_url"`
+				FileURL  string `json:"file_url"`
+			} `json:"content"`
+		} `json:"input"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || len(payload.Input) != 1 {
+		t.Fatalf("OpenAI request body %s: %v", body, err)
+	}
+
+	var input providerInput
+	for _, item := range payload.Input[0].Content {
+		switch item.Type {
+		case "input_text":
+			input.Prompt = item.Text
+		case "input_image":
+			input.MediaURL = item.ImageURL
+		case "input_file":
+			input.MediaURL, input.MimeType = item.FileURL, "application/pdf"
+		}
+	}
+	return input
+}
+
+func geminiInput(t *testing.T, body []byte) providerInput {
+	t.Helper()
+
+	var payload struct {
+		Contents []struct {
+			Parts []struct {
+				Text       string `json:"text"`
+				InlineData *struct {
+					MimeType string `json:"mimeType"`
+					Data     string `json:"data"`
+				} `json:"inlineData"`
+			} `json:"parts"`
+		} `json:"contents"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || len(payload.Contents) != 1 {
+		t.Fatalf("Gemini request body %s: %v", body, err)
+	}
+
+	var input providerInput
+	for _, part := range payload.Contents[0].Parts {
+		if part.InlineData != nil {
+			data, err := base64.StdEncoding.DecodeString(part.InlineData.Data)
+			if err != nil {
+				t.Fatalf("Gemini inline data: %v", err)
+			}
+			input.MediaData, input.MimeType = data, part.InlineData.MimeType
+			continue
+		}
+		input.Prompt = part.Text
+	}
+	return input
+}
+
+func TestProvidersReceiveTheSameInputs(t *testing.T) {
+	tests := []struct {
+		name     string
+		media    *Media
+		content  []byte
+		mimeType string
+	}{
+		{name: "image", media: &Media{URL: "https://media.test/menu.png"}, content: stubImage, mimeType: "image/png"},
+		{name: "pdf", media: &Media{URL: "https://media.test/resume.pdf", MimeType: "application/pdf"}, content: stubPDF, mimeType: "application/pdf"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transport := &recordingTransport{bodies: map[string][]byte{}}
+			client := &http.Client{Transport: transport}
+			prompt := "Extract the content of the attached " + test.name + "."
+
+			openAI := &OpenAISDK{ApiKey: "openai-key", Model: "gpt-4o", HTTPClient: client}
+			if text, err := openAI.Generate(prompt, test.media); err != nil || text != "extracted" {
+				t.Fatalf("OpenAI Generate() = %q, %v", text, err)
+			}
+			gemini := &GeminiSDK{ApiKey: "gemini-key", HTTPClient: client}
+			if text, err := gemini.Generate(prompt, test.media); err != nil || text != "extracted" {
+				t.Fatalf("Gemini Generate() = %q, %v", text, err)
+			}
+
+			sent := openAIInput(t, transport.body("api.openai.com/v1/responses"))
+			if sent.Prompt != prompt || sent.MediaURL != test.media.URL {
+				t.Errorf("OpenAI received %+v, want the prompt and %s", sent, test.media.URL)
+			}
+
+			received := geminiInput(t, transport.body("generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash:generateContent"))
+			if received.Prompt != prompt {
+				t.Errorf("Gemini prompt = %q, want %q", received.Prompt, prompt)
+			}
+			if !bytes.Equal(received.MediaData, test.content) || received.MimeType != test.mimeType {
+				t.Errorf("Gemini media = %q (%s), want the fetched %s", received.MediaData, received.MimeType, test.mimeType)
+			}
+		})
+	}
+}
+
+func TestGeminiTextOnlyPromptHasNoMedia(t *testing.T) {
+	transport := &recordingTransport{bodies: map[string][]byte{}}
+	gemini := &GeminiSDK{ApiKey: "gemini-key", HTTPClient: &http.Client{Transport: transport}}
+
+	if _, err := gemini.Generate("Summarize this.", nil); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	received := geminiInput(t, transport.body("generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash:generateContent"))
+	if received.Prompt != "Summarize this." || received.MediaData != nil {
+		t.Errorf("Gemini received %+v, want only the prompt", received)
+	}
+}
+
+func TestGeminiMediaFetchError(t *testing.T) {
+	transport := &recordingTransport{bodies: map[string][]byte{}}
+	gemini := &GeminiSDK{ApiKey: "gemini-key", HTTPClient: &http.Client{Transport: transport}}
+
+	_, err := gemini.Generate("Extract.", &Media{URL: "https://media.test/missing.png"})
+	if err == nil || !strings.Contains(err.Error(), "failed to fetch media (404)") {
+		t.Fatalf("Generate() error = %v, want a media fetch error", err)
+	}
+	if transport.body("generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash:generateContent") != nil {
+		t.Error("Gemini was called although the media could not be fetched")
+	}
+}
@@ -0,0 +1,178 @@
+package llm
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema document. It is kept to the subset both OpenAI strict mode and Gemini's
+// responseJsonSchema accept: every property is required, optional values are nullable instead, and objects
+// are closed with additionalProperties: false. Maps can't be closed objects, so they are asked for as arrays of
+// key/value entries and turned back into objects by mapsFromEntries.
+type Schema map[string]any
+
+// SchemaFor derives the schema of the JSON encoding of T, following its json tags.
+func SchemaFor[T any]() Schema {
+	return schemaForType(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func schemaForType(t reflect.Type) Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema Schema
+	switch {
+	case t == timeType:
+		schema = Schema{"type": "string", "description": "RFC 3339 timestamp"}
+	case t.Kind() == reflect.String:
+		schema = Schema{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		schema = Schema{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = Schema{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema = Schema{"type": "number"}
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() == reflect.Uint8:
+		schema = Schema{"type": "string"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		schema = Schema{"type": "array", "items": schemaForType(t.Elem())}
+	case t.Kind() == reflect.Map:
+		schema = Schema{"type": "array", "items": Schema{
+			"type": "object",
+			"properties": Schema{
+				"key":   Schema{"type": "string"},
+				"value": schemaForType(t.Elem()),
+			},
+			"required":             []string{"key", "value"},
+			"additionalProperties": false,
+		}}
+	case t.Kind() == reflect.Struct:
+		schema = schemaForStruct(t)
+	default:
+		return Schema{}
+	}
+
+	if nullable {
+		schema["type"] = []any{schema["type"], "null"}
+	}
+
+	return schema
+}
+
+func schemaForStruct(t reflect.Type) Schema {
+	properties := Schema{}
+	required := make([]string, 0, t.NumField())
+	collectFields(t, properties, &required)
+
+	return Schema{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func collectFields(t reflect.Type, properties Schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				collectFields(embedded, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = schemaForType(field.Type)
+		*required = append(*required, name)
+	}
+}
+
+// mapsFromEntries rewrites the key/value entry arrays schemaForType asks for in place of maps back into the
+// objects T decodes from.
+func mapsFromEntries(t reflect.Type, value any) any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch typed := value.(type) {
+	case []any:
+		switch {
+		case t.Kind() == reflect.Map:
+			entries := make(map[string]any, len(typed))
+			for _, item := range typed {
+				entry, ok := item.(map[string]any)
+				if !ok {
+					continue
+				}
+				if key, ok := entry["key"].(string); ok {
+					entries[key] = mapsFromEntries(t.Elem(), entry["value"])
+				}
+			}
+			return entries
+		case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+			for i := range typed {
+				typed[i] = mapsFromEntries(t.Elem(), typed[i])
+			}
+		}
+	case map[string]any:
+		if t.Kind() == reflect.Struct && t != timeType {
+			mapFieldsFromEntries(t, typed)
+		}
+	}
+
+	return value
+}
+
+func mapFieldsFromEntries(t reflect.Type, value map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				mapFieldsFromEntries(embedded, value)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if item, ok := value[name]; ok {
+			value[name] = mapsFromEntries(field.Type, item)
+		}
+	}
+}
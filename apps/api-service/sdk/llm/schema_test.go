@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type schemaBase struct {
+	ID uint64 `json:"id"`
+}
+
+type schemaSample struct {
+	schemaBase
+	Name      string            `json:"name"`
+	Price     *float64          `json:"price"`
+	Tags      []string          `json:"tags"`
+	Prices    map[string]int    `json:"prices"`
+	Nested    []schemaBase      `json:"nested"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	Ignored   string            `json:"-"`
+	internal  string
+}
+
+func TestSchemaFor(t *testing.T) {
+	schema := SchemaFor[schemaSample]()
+	properties := schema["properties"].(Schema)
+
+	tests := []struct {
+		property string
+		want     string
+	}{
+		{property: "id", want: `{"type":"integer"}`},
+		{property: "name", want: `{"type":"string"}`},
+		{property: "price", want: `{"type":["number","null"]}`},
+		{property: "tags", want: `{"items":{"type":"string"},"type":"array"}`},
+		{property: "created_at", want: `{"description":"RFC 3339 timestamp","type":"string"}`},
+		{
+			property: "prices",
+			want:     `{"items":{"additionalProperties":false,"properties":{"key":{"type":"string"},"value":{"type":"integer"}},"required":["key","value"],"type":"object"},"type":"array"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.property, func(t *testing.T) {
+			encoded, err := json.Marshal(properties[test.property])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(encoded) != test.want {
+				t.Fatalf("schema of %s = %s, want %s", test.property, encoded, test.want)
+			}
+		})
+	}
+
+	want := []string{"id", "name", "price", "tags", "prices", "nested", "labels", "created_at"}
+	if required := schema["required"].([]string); !reflect.DeepEqual(required, want) {
+		t.Fatalf("required = %v, want %v", required, want)
+	}
+	if _, ok := properties["Ignored"]; ok {
+		t.Fatal("a json:\"-\" field is in the schema")
+	}
+	if _, ok := properties["internal"]; ok {
+		t.Fatal("an unexported field is in the schema")
+	}
+}
+
+// TestSchemaForStaysStrict walks the schema for the rules OpenAI strict mode enforces: every object is closed
+// and requires all of its properties.
+func TestSchemaForStaysStrict(t *testing.T) {
+	var walk func(path string, schema Schema)
+	walk = func(path string, schema Schema) {
+		if schemaTypes(schema)["object"] {
+			if closed, ok := schema["additionalProperties"].(bool); !ok || closed {
+				t.Errorf("%s: additionalProperties = %v, want false", path, schema["additionalProperties"])
+			}
+			properties, _ := asSchema(schema["properties"])
+			if len(requiredKeys(schema)) != len(properties) {
+				t.Errorf("%s: requires %v of %d properties", path, requiredKeys(schema), len(properties))
+			}
+			for name, property := range properties {
+				propertySchema, _ := asSchema(property)
+				walk(path+"."+name, propertySchema)
+			}
+		}
+		if items, ok := asSchema(schema["items"]); ok {
+			walk(path+"[]", items)
+		}
+	}
+
+	walk("$", SchemaFor[schemaSample]())
+}
+
+func TestMapsFromEntries(t *testing.T) {
+	var value any
+	if err := json.Unmarshal([]byte(`{
+		"id": 1,
+		"prices": [{"key": "small", "value": 3}, {"key": "large", "value": 5}],
+		"nested": [{"id": 2}],
+		"labels": []
+	}`), &value); err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := json.Marshal(mapsFromEntries(reflect.TypeOf(schemaSample{}), value))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded schemaSample
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("decoding %s: %v", encoded, err)
+	}
+	if decoded.ID != 1 || !reflect.DeepEqual(decoded.Prices, map[string]int{"small": 3, "large": 5}) || len(decoded.Labels) != 0 {
+		t.Fatalf("decoded = %+v", decoded)
+	}
+}
@@ -0,0 +1,425 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MaxStructuredRetries is how many times GenerateStructured re-prompts after an invalid response.
+var MaxStructuredRetries = 2
+
+// JSONFormat is the response format handed to providers that can constrain their output natively.
+type JSONFormat struct {
+	Name   string
+	Schema Schema
+}
+
+// StructuredProvider is implemented by providers with a native JSON mode. Providers without one fall back to
+// Generate with the schema spelled out in the prompt.
+type StructuredProvider interface {
+	GenerateJSON(prompt string, media *Media, format JSONFormat) (string, error)
+}
+
+type StructuredOptions struct {
+	// MaxRetries overrides MaxStructuredRetries when greater than zero.
+	MaxRetries int
+}
+
+type ValidationError struct {
+	Problems []string
+}
+
+func (err *ValidationError) Error() string {
+	return "structured output does not match schema: " + strings.Join(err.Problems, "; ")
+}
+
+// GenerateStructured asks the provider for JSON matching the schema of T. Each response is repaired, validated
+// and, when it still doesn't fit, sent back with the problems listed so the model can correct itself.
+func GenerateStructured[T any](provider Provider, prompt string, media *Media, options StructuredOptions) (*T, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("no LLM provider initialized")
+	}
+
+	maxRetries := options.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = MaxStructuredRetries
+	}
+
+	targetType := reflect.TypeOf((*T)(nil)).Elem()
+	format := JSONFormat{
+		Name:   schemaName(targetType),
+		Schema: SchemaFor[T](),
+	}
+
+	currentPrompt := prompt
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		raw, err := generateJSON(provider, currentPrompt, media, format)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		value, problems := decodeStructured(raw, format.Schema)
+		if len(problems) == 0 {
+			encoded, err := json.Marshal(mapsFromEntries(targetType, value))
+			if err != nil {
+				return nil, err
+			}
+
+			var target T
+			if err := json.Unmarshal(encoded, &target); err != nil {
+				return nil, fmt.Errorf("failed to parse JSON: %w", err)
+			}
+			return &target, nil
+		}
+
+		lastErr = &ValidationError{Problems: problems}
+		currentPrompt = correctionPrompt(prompt, raw, problems)
+	}
+
+	return nil, lastErr
+}
+
+func generateJSON(provider Provider, prompt string, media *Media, format JSONFormat) (string, error) {
+	if structured, ok := provider.(StructuredProvider); ok {
+		return structured.GenerateJSON(prompt, media, format)
+	}
+
+	schema, err := json.Marshal(format.Schema)
+	if err != nil {
+		return "", err
+	}
+
+	return provider.Generate(fmt.Sprintf("%s\n\nRespond with a single JSON value that matches this JSON Schema:\n%s", prompt, schema), media)
+}
+
+// decodeStructured repairs and parses a raw response, then validates it. Problems are reported as "path: issue".
+func decodeStructured(raw string, schema Schema) (any, []string) {
+	cleaned := RepairJSON(raw)
+	if cleaned == "" || cleaned == "null" {
+		return nil, []string{"$: response was empty"}
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(cleaned), &value); err != nil {
+		return nil, []string{fmt.Sprintf("$: invalid JSON (%v)", err)}
+	}
+
+	value = coerce(schema, value)
+	return value, validate(schema, value, "$")
+}
+
+func correctionPrompt(prompt string, raw string, problems []string) string {
+	if len(raw) > 4000 {
+		raw = raw[:4000] + "..."
+	}
+
+	return fmt.Sprintf(
+		"%s\n\nYour previous response did not match the required JSON schema.\nProblems:\n- %s\n\nPrevious response:\n%s\n\nReturn the corrected JSON only.",
+		prompt, strings.Join(problems, "\n- "), raw,
+	)
+}
+
+func schemaName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return "response"
+	}
+	return t.Name()
+}
+
+// RepairJSON fixes the mistakes models make most often: markdown fences, prose around the JSON, trailing commas
+// and output cut off before the closing brackets.
+func RepairJSON(raw string) string {
+	clean := strings.TrimSpace(raw)
+
+	if start := strings.Index(clean, "```"); start >= 0 {
+		body := clean[start+3:]
+		if newline := strings.Index(body, "\n"); newline >= 0 && !strings.ContainsAny(body[:newline], "{[") {
+			body = body[newline+1:]
+		}
+		if end := strings.LastIndex(body, "```"); end >= 0 {
+			body = body[:end]
+		}
+		clean = strings.TrimSpace(body)
+	}
+
+	start := strings.IndexAny(clean, "{[")
+	if start < 0 {
+		return clean
+	}
+	clean = clean[start:]
+
+	if end := strings.LastIndexAny(clean, "}]"); end >= 0 && json.Valid([]byte(clean[:end+1])) {
+		return clean[:end+1]
+	}
+
+	var builder strings.Builder
+	var stack []byte
+	inString := false
+	escaped := false
+
+	for i := 0; i < len(clean); i++ {
+		char := clean[i]
+
+		if inString {
+			builder.WriteByte(char)
+			switch {
+			case escaped:
+				escaped = false
+			case char == '\\':
+				escaped = true
+			case char == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch char {
+		case '"':
+			inString = true
+		case '{':
+			stack = append(stack, '}')
+		case '[':
+			stack = append(stack, ']')
+		case '}', ']':
+			trimDangling(&builder)
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			builder.WriteByte(char)
+			if len(stack) == 0 {
+				return builder.String()
+			}
+			continue
+		}
+		builder.WriteByte(char)
+	}
+
+	if inString {
+		builder.WriteByte('"')
+	}
+	trimDangling(&builder)
+	for i := len(stack) - 1; i >= 0; i-- {
+		builder.WriteByte(stack[i])
+	}
+
+	return builder.String()
+}
+
+// trimDangling drops a trailing comma, or a key that never got its value, before a container is closed.
+func trimDangling(builder *strings.Builder) {
+	current := strings.TrimRight(builder.String(), " \t\r\n")
+
+	switch {
+	case strings.HasSuffix(current, ","):
+		current = current[:len(current)-1]
+	case strings.HasSuffix(current, ":"):
+		current = strings.TrimRight(current[:len(current)-1], " \t\r\n")
+		if quote := strings.LastIndex(current[:len(current)-1], "\""); quote >= 0 {
+			current = strings.TrimRight(current[:quote], " \t\r\n,")
+		}
+	default:
+		return
+	}
+
+	builder.Reset()
+	builder.WriteString(current)
+}
+
+// coerce nudges near-misses into shape: scalars where a string is expected, missing or null arrays, missing
+// nullable fields and keys the schema doesn't know about.
+func coerce(schema Schema, value any) any {
+	types := schemaTypes(schema)
+
+	if value == nil {
+		if types["array"] && !types["null"] {
+			return []any{}
+		}
+		return nil
+	}
+
+	switch typed := value.(type) {
+	case map[string]any:
+		if types["array"] && !types["object"] {
+			return coerce(schema, []any{typed})
+		}
+
+		properties, _ := asSchema(schema["properties"])
+		for key, property := range properties {
+			propertySchema, _ := asSchema(property)
+			if _, ok := typed[key]; !ok {
+				propertyTypes := schemaTypes(propertySchema)
+				switch {
+				case propertyTypes["null"]:
+					typed[key] = nil
+				case propertyTypes["array"]:
+					typed[key] = []any{}
+				}
+				continue
+			}
+			typed[key] = coerce(propertySchema, typed[key])
+		}
+
+		if closed, ok := schema["additionalProperties"].(bool); ok && !closed {
+			for key := range typed {
+				if _, known := properties[key]; !known {
+					delete(typed, key)
+				}
+			}
+		}
+		return typed
+	case []any:
+		items, _ := asSchema(schema["items"])
+		for i := range typed {
+			typed[i] = coerce(items, typed[i])
+		}
+		return typed
+	case float64:
+		if types["string"] && !types["number"] && !types["integer"] {
+			return strconv.FormatFloat(typed, 'f', -1, 64)
+		}
+	case bool:
+		if types["string"] && !types["boolean"] {
+			return strconv.FormatBool(typed)
+		}
+	case string:
+		if types["null"] && (typed == "null" || typed == "N/A") {
+			return nil
+		}
+	}
+
+	return value
+}
+
+func validate(schema Schema, value any, path string) []string {
+	types := schemaTypes(schema)
+	if len(types) > 0 && !types[jsonType(value)] && !(types["number"] && jsonType(value) == "integer") {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(sortedTypes(types), " or "), jsonType(value))}
+	}
+
+	var problems []string
+	if allowed, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, option := range allowed {
+			if reflect.DeepEqual(option, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: value is not one of the allowed options", path))
+		}
+	}
+
+	switch typed := value.(type) {
+	case map[string]any:
+		properties, _ := asSchema(schema["properties"])
+		for _, key := range requiredKeys(schema) {
+			if _, ok := typed[key]; !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s: is required", path, key))
+			}
+		}
+		for key, item := range typed {
+			property, known := properties[key]
+			if !known {
+				if closed, ok := schema["additionalProperties"].(bool); ok && !closed {
+					problems = append(problems, fmt.Sprintf("%s.%s: is not allowed", path, key))
+				}
+				continue
+			}
+			propertySchema, _ := asSchema(property)
+			problems = append(problems, validate(propertySchema, item, path+"."+key)...)
+		}
+	case []any:
+		items, _ := asSchema(schema["items"])
+		for i, item := range typed {
+			problems = append(problems, validate(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+
+	return problems
+}
+
+func schemaTypes(schema Schema) map[string]bool {
+	types := map[string]bool{}
+	switch typed := schema["type"].(type) {
+	case string:
+		types[typed] = true
+	case []any:
+		for _, item := range typed {
+			if name, ok := item.(string); ok {
+				types[name] = true
+			}
+		}
+	case []string:
+		for _, name := range typed {
+			types[name] = true
+		}
+	}
+	return types
+}
+
+func sortedTypes(types map[string]bool) []string {
+	order := []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+	names := make([]string, 0, len(types))
+	for _, name := range order {
+		if types[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func requiredKeys(schema Schema) []string {
+	switch typed := schema["required"].(type) {
+	case []string:
+		return typed
+	case []any:
+		keys := make([]string, 0, len(typed))
+		for _, item := range typed {
+			if key, ok := item.(string); ok {
+				keys = append(keys, key)
+			}
+		}
+		return keys
+	}
+	return nil
+}
+
+func asSchema(value any) (Schema, bool) {
+	switch typed := value.(type) {
+	case Schema:
+		return typed, true
+	case map[string]any:
+		return Schema(typed), true
+	}
+	return Schema{}, false
+}
+
+func jsonType(value any) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if typed == float64(int64(typed)) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "unknown"
+}
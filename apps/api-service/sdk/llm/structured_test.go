@@ -0,0 +1,226 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// scriptedProvider answers each call with the next response, remembering the prompts it was given.
+type scriptedProvider struct {
+	responses []string
+	prompts   []string
+}
+
+func (provider *scriptedProvider) Generate(prompt string, media *Media) (string, error) {
+	provider.prompts = append(provider.prompts, prompt)
+	if len(provider.responses) == 0 {
+		return "", errors.New("no response scripted")
+	}
+	response := provider.responses[0]
+	provider.responses = provider.responses[1:]
+	return response, nil
+}
+
+// nativeProvider has a JSON mode, so it should be handed the schema instead of a prompt describing it.
+type nativeProvider struct {
+	scriptedProvider
+	formats []JSONFormat
+}
+
+func (provider *nativeProvider) GenerateJSON(prompt string, media *Media, format JSONFormat) (string, error) {
+	provider.formats = append(provider.formats, format)
+	return provider.Generate(prompt, media)
+}
+
+type structuredItem struct {
+	Name  string   `json:"name"`
+	Price *float64 `json:"price"`
+}
+
+type structuredMenu struct {
+	Title    string            `json:"title"`
+	Items    []structuredItem  `json:"items"`
+	Currency map[string]string `json:"currency"`
+}
+
+func TestGenerateStructured(t *testing.T) {
+	price := 4.5
+
+	tests := []struct {
+		name      string
+		responses []string
+		want      structuredMenu
+		wantCalls int
+	}{
+		{
+			name:      "valid on the first try",
+			responses: []string{`{"title":"Lunch","items":[{"name":"Soup","price":4.5}],"currency":[]}`},
+			want:      structuredMenu{Title: "Lunch", Items: []structuredItem{{Name: "Soup", Price: &price}}, Currency: map[string]string{}},
+			wantCalls: 1,
+		},
+		{
+			name:      "fenced and missing nullable fields",
+			responses: []string{"Here you go:\n```json\n{\"title\":\"Lunch\",\"items\":[{\"name\":\"Soup\"}],\"currency\":[{\"key\":\"Soup\",\"value\":\"EUR\"}]}\n```"},
+			want:      structuredMenu{Title: "Lunch", Items: []structuredItem{{Name: "Soup"}}, Currency: map[string]string{"Soup": "EUR"}},
+			wantCalls: 1,
+		},
+		{
+			name:      "corrected after a retry",
+			responses: []string{`{"title":["Lunch"],"items":[],"currency":[]}`, `{"title":"Lunch","items":[],"currency":[]}`},
+			want:      structuredMenu{Title: "Lunch", Items: []structuredItem{}, Currency: map[string]string{}},
+			wantCalls: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider := &scriptedProvider{responses: test.responses}
+
+			got, err := GenerateStructured[structuredMenu](provider, "Extract the menu.", nil, StructuredOptions{})
+			if err != nil {
+				t.Fatalf("GenerateStructured() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, test.want) {
+				t.Fatalf("GenerateStructured() = %+v, want %+v", *got, test.want)
+			}
+			if len(provider.prompts) != test.wantCalls {
+				t.Fatalf("provider called %d times, want %d", len(provider.prompts), test.wantCalls)
+			}
+			if !strings.Contains(provider.prompts[0], "JSON Schema") {
+				t.Fatalf("prompt %q does not spell out the schema", provider.prompts[0])
+			}
+		})
+	}
+}
+
+func TestGenerateStructuredRetriesWithTheProblems(t *testing.T) {
+	invalid := `{"title":["Lunch"],"items":[],"currency":[]}`
+	provider := &scriptedProvider{responses: []string{invalid, invalid, invalid, invalid}}
+
+	_, err := GenerateStructured[structuredMenu](provider, "Extract the menu.", nil, StructuredOptions{MaxRetries: 2})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("GenerateStructured() error = %v, want a ValidationError", err)
+	}
+	if len(provider.prompts) != 3 {
+		t.Fatalf("provider called %d times, want the first try and 2 retries", len(provider.prompts))
+	}
+	if retry := provider.prompts[1]; !strings.Contains(retry, "$.title: expected string, got array") || !strings.Contains(retry, invalid) {
+		t.Fatalf("retry prompt %q does not list the problem and the previous response", retry)
+	}
+}
+
+func TestGenerateStructuredUsesTheNativeJSONMode(t *testing.T) {
+	provider := &nativeProvider{scriptedProvider: scriptedProvider{responses: []string{`{"title":"Lunch","items":[],"currency":[]}`}}}
+
+	if _, err := GenerateStructured[structuredMenu](provider, "Extract the menu.", nil, StructuredOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.formats) != 1 || provider.formats[0].Name != "structuredMenu" {
+		t.Fatalf("formats = %+v, want the structuredMenu schema", provider.formats)
+	}
+	if provider.prompts[0] != "Extract the menu." {
+		t.Fatalf("prompt = %q, want it unchanged", provider.prompts[0])
+	}
+}
+
+func TestGenerateStructuredWithoutProvider(t *testing.T) {
+	if _, err := GenerateStructured[structuredMenu](nil, "Extract the menu.", nil, StructuredOptions{}); err == nil {
+		t.Fatal("GenerateStructured() without a provider succeeded")
+	}
+}
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "clean", raw: `{"a":1}`, want: `{"a":1}`},
+		{name: "json fence", raw: "```json\n{\"a\":1}\n```", want: `{"a":1}`},
+		{name: "bare fence", raw: "```\n[1,2]\n```", want: `[1,2]`},
+		{name: "prose around", raw: `Sure! {"a":1} Hope this helps.`, want: `{"a":1}`},
+		{name: "trailing comma", raw: `{"a":[1,2,],}`, want: `{"a":[1,2]}`},
+		{name: "cut off in a string", raw: `{"a":"unfinish`, want: `{"a":"unfinish"}`},
+		{name: "cut off after a key", raw: `{"a":1,"b":`, want: `{"a":1}`},
+		{name: "cut off in nested arrays", raw: `{"items":[{"name":"Soup"},{"name":"Br`, want: `{"items":[{"name":"Soup"},{"name":"Br"}]}`},
+		{name: "brackets inside strings", raw: `{"a":"x}]"`, want: `{"a":"x}]"}`},
+		{name: "no JSON", raw: "I can't read this file.", want: "I can't read this file."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := RepairJSON(test.raw); got != test.want {
+				t.Fatalf("RepairJSON(%q) = %q, want %q", test.raw, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	schema := SchemaFor[structuredMenu]()
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "missing arrays and nullable fields", value: `{"title":"Lunch","items":[{"name":"Soup"}]}`, want: `{"currency":[],"items":[{"name":"Soup","price":null}],"title":"Lunch"}`},
+		{name: "null array", value: `{"title":"Lunch","items":null,"currency":null}`, want: `{"currency":[],"items":[],"title":"Lunch"}`},
+		{name: "scalars as strings", value: `{"title":2024,"items":[{"name":true,"price":null}],"currency":[]}`, want: `{"currency":[],"items":[{"name":"true","price":null}],"title":"2024"}`},
+		{name: "placeholder nulls", value: `{"title":"Lunch","items":[{"name":"Soup","price":"N/A"}],"currency":[]}`, want: `{"currency":[],"items":[{"name":"Soup","price":null}],"title":"Lunch"}`},
+		{name: "single object for an array", value: `{"title":"Lunch","items":{"name":"Soup","price":1},"currency":[]}`, want: `{"currency":[],"items":[{"name":"Soup","price":1}],"title":"Lunch"}`},
+		{name: "unknown keys", value: `{"title":"Lunch","items":[],"currency":[],"notes":"x"}`, want: `{"currency":[],"items":[],"title":"Lunch"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var value any
+			if err := json.Unmarshal([]byte(test.value), &value); err != nil {
+				t.Fatal(err)
+			}
+			encoded, err := json.Marshal(coerce(schema, value))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(encoded) != test.want {
+				t.Fatalf("coerce(%s) = %s, want %s", test.value, encoded, test.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema := SchemaFor[structuredMenu]()
+	enum := Schema{"type": "string", "enum": []any{"small", "large"}}
+
+	tests := []struct {
+		name   string
+		schema Schema
+		value  string
+		want   []string
+	}{
+		{name: "valid", schema: schema, value: `{"title":"Lunch","items":[{"name":"Soup","price":1.5}],"currency":[]}`},
+		{name: "integer for a number", schema: schema, value: `{"title":"Lunch","items":[{"name":"Soup","price":2}],"currency":[]}`},
+		{name: "missing required", schema: schema, value: `{"items":[],"currency":[]}`, want: []string{"$.title: is required"}},
+		{name: "wrong type", schema: schema, value: `{"title":"Lunch","items":[{"name":"Soup","price":"cheap"}],"currency":[]}`, want: []string{"$.items[0].price: expected number or null, got string"}},
+		{name: "unknown key", schema: schema, value: `{"title":"Lunch","items":[],"currency":[],"notes":"x"}`, want: []string{"$.notes: is not allowed"}},
+		{name: "map entry without a value", schema: schema, value: `{"title":"Lunch","items":[],"currency":[{"key":"Soup"}]}`, want: []string{"$.currency[0].value: is required"}},
+		{name: "enum", schema: enum, value: `"medium"`, want: []string{"$: value is not one of the allowed options"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var value any
+			if err := json.Unmarshal([]byte(test.value), &value); err != nil {
+				t.Fatal(err)
+			}
+			if got := validate(test.schema, value, "$"); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("validate(%s) = %v, want %v", test.value, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package mailer
+
+import "fmt"
+
+var defaultProvider Provider
+
+func Default(provider Provider) Provider {
+	defaultProvider = provider
+
+	return defaultProvider
+}
+
+func Send(message Message) error {
+	if defaultProvider == nil {
+		return fmt.Errorf("no mailer provider initialized")
+	}
+
+	return defaultProvider.Send(message)
+}
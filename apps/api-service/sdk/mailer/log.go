@@ -0,0 +1,11 @@
+package mailer
+
+import "log"
+
+// LogMailer writes messages to the application log instead of sending them, which is enough for local runs.
+type LogMailer struct{}
+
+func (mailer LogMailer) Send(message Message) error {
+	log.Printf("[MAIL] To: %s | Subject: %s\n%s", message.To, message.Subject, message.Text)
+	return nil
+}
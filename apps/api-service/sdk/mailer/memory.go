@@ -0,0 +1,43 @@
+package mailer
+
+import "sync"
+
+// InMemoryMailer keeps every message it is handed. It is meant for tests and throwaway local runs.
+type InMemoryMailer struct {
+	mu       sync.RWMutex
+	messages []Message
+}
+
+func NewInMemoryMailer() *InMemoryMailer {
+	return &InMemoryMailer{}
+}
+
+func (mailer *InMemoryMailer) Send(message Message) error {
+	mailer.mu.Lock()
+	mailer.messages = append(mailer.messages, message)
+	mailer.mu.Unlock()
+
+	return nil
+}
+
+// Sent returns every message delivered so far, oldest first.
+func (mailer *InMemoryMailer) Sent() []Message {
+	mailer.mu.RLock()
+	defer mailer.mu.RUnlock()
+
+	return append([]Message(nil), mailer.messages...)
+}
+
+// Last returns the most recent message sent to an address.
+func (mailer *InMemoryMailer) Last(to string) (Message, bool) {
+	mailer.mu.RLock()
+	defer mailer.mu.RUnlock()
+
+	for i := len(mailer.messages) - 1; i >= 0; i-- {
+		if mailer.messages[i].To == to {
+			return mailer.messages[i], true
+		}
+	}
+
+	return Message{}, false
+}
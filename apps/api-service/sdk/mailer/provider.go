@@ -0,0 +1,12 @@
+package mailer
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Provider interface {
+	Send(message Message) error
+}
@@ -0,0 +1,151 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPMailer delivers through any SMTP relay. Port 465 uses implicit TLS; other ports upgrade with STARTTLS when
+// the server offers it.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (mailer *SMTPMailer) Send(message Message) error {
+	if mailer.Host == "" || mailer.From == "" {
+		return fmt.Errorf("missing required SMTP configuration (Host or From)")
+	}
+
+	port := mailer.Port
+	if port == "" {
+		port = "587"
+	}
+
+	// From may carry a display name; the envelope only takes the bare address.
+	sender, err := mail.ParseAddress(mailer.From)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP From address: %w", err)
+	}
+
+	body, err := mailer.build(sender, message)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if mailer.Username != "" {
+		auth = smtp.PlainAuth("", mailer.Username, mailer.Password, mailer.Host)
+	}
+
+	address := net.JoinHostPort(mailer.Host, port)
+	if port == "465" {
+		err = mailer.sendTLS(address, auth, sender.Address, message.To, body)
+	} else {
+		err = smtp.SendMail(address, auth, sender.Address, []string{message.To}, body)
+	}
+	if err != nil {
+		return fmt.Errorf("SMTP Send error: %w", err)
+	}
+
+	return nil
+}
+
+// sendTLS is smtp.SendMail for servers that expect TLS from the first byte.
+func (mailer *SMTPMailer) sendTLS(address string, auth smtp.Auth, from string, to string, body []byte) error {
+	connection, err := tls.Dial("tcp", address, &tls.Config{ServerName: mailer.Host})
+	if err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(connection, mailer.Host)
+	if err != nil {
+		connection.Close()
+		return err
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(body); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// build renders a multipart/alternative message so clients without HTML support still get the text part.
+func (mailer *SMTPMailer) build(sender *mail.Address, message Message) ([]byte, error) {
+	boundary := make([]byte, 12)
+	if _, err := rand.Read(boundary); err != nil {
+		return nil, err
+	}
+	separator := hex.EncodeToString(boundary)
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "From: %s\r\n", sender.String())
+	fmt.Fprintf(&buffer, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buffer, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buffer, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buffer, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", separator)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain", message.Text},
+		{"text/html", message.HTML},
+	}
+	for _, part := range parts {
+		if strings.TrimSpace(part.content) == "" {
+			continue
+		}
+
+		fmt.Fprintf(&buffer, "--%s\r\n", separator)
+		fmt.Fprintf(&buffer, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		buffer.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+		writer := quotedprintable.NewWriter(&buffer)
+		if _, err := writer.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		buffer.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buffer, "--%s--\r\n", separator)
+
+	return buffer.Bytes(), nil
+}
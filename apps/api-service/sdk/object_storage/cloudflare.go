@@ -2,6 +2,7 @@ package objectStorage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
@@ -11,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 type CloudflareR2SDK struct {
@@ -92,6 +94,27 @@ func (cloudflare *CloudflareR2SDK) Delete(path string) (string, error) {
 	return path, nil
 }
 
+func (cloudflare *CloudflareR2SDK) Download(path string) (io.ReadCloser, error) {
+	cloudflare.init()
+	if cloudflare.err != nil {
+		return nil, cloudflare.err
+	}
+
+	output, err := cloudflare.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(cloudflare.BucketName),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		var missing *types.NoSuchKey
+		if errors.As(err, &missing) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("R2 Download error: %w", err)
+	}
+
+	return output.Body, nil
+}
+
 func (cloudflare *CloudflareR2SDK) GetURL(path string) (string, error) {
 	// Use the private resolver
 	return cloudflare.resolveURL(path), nil
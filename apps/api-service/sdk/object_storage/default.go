@@ -29,6 +29,14 @@ func Delete(path string) (string, error) {
 	return defaultProvider.Delete(path)
 }
 
+func Download(path string) (io.ReadCloser, error) {
+	if defaultProvider == nil {
+		return nil, fmt.Errorf("no Object Storage provider initialized")
+	}
+
+	return defaultProvider.Download(path)
+}
+
 func GetURL(path string) (string, error) {
 	if defaultProvider == nil {
 		return "", fmt.Errorf("no Object Storage provider initialized")
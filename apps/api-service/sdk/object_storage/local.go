@@ -0,0 +1,155 @@
+package objectStorage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidObjectPath = errors.New("invalid object path")
+	ErrInvalidSignature  = errors.New("invalid or expired signature")
+)
+
+// LocalFSProvider keeps objects on disk under RootDir. Files are served back through the API's /files/* route,
+// so BaseURL should point at that route, e.g. http://localhost:5000/files.
+type LocalFSProvider struct {
+	RootDir    string
+	BaseURL    string
+	SigningKey string
+
+	// RequireSignedURLs rejects plain GetURL links, so only signed, unexpired links can read files.
+	RequireSignedURLs bool
+}
+
+func (local *LocalFSProvider) Upload(path string, content io.Reader, contentType string) (string, error) {
+	fullPath, err := local.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return "", fmt.Errorf("local storage mkdir error: %w", err)
+	}
+
+	file, err := os.Create(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("local storage upload error: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, content); err != nil {
+		return "", fmt.Errorf("local storage upload error: %w", err)
+	}
+
+	return local.GetURL(path)
+}
+
+func (local *LocalFSProvider) Delete(path string) (string, error) {
+	fullPath, err := local.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("local storage delete error: %w", err)
+	}
+
+	return path, nil
+}
+
+func (local *LocalFSProvider) Download(path string) (io.ReadCloser, error) {
+	fullPath, err := local.resolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("local storage download error: %w", err)
+	}
+
+	return file, nil
+}
+
+func (local *LocalFSProvider) GetURL(path string) (string, error) {
+	return fmt.Sprintf("%s/%s", strings.TrimRight(local.BaseURL, "/"), strings.TrimLeft(path, "/")), nil
+}
+
+func (local *LocalFSProvider) GetSignedURL(path string, expiry time.Duration) (string, error) {
+	base, err := local.GetURL(path)
+	if err != nil {
+		return "", err
+	}
+
+	expires := time.Now().Add(expiry).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", local.sign(path, expires))
+
+	return base + "?" + query.Encode(), nil
+}
+
+// Open returns the on-disk location of an object after checking the signature when one is required or supplied.
+func (local *LocalFSProvider) Open(path string, expires string, signature string) (string, error) {
+	path = strings.TrimLeft(path, "/")
+
+	if signature != "" || local.RequireSignedURLs {
+		if err := local.verify(path, expires, signature); err != nil {
+			return "", err
+		}
+	}
+
+	return local.resolvePath(path)
+}
+
+func (local *LocalFSProvider) verify(path string, expires string, signature string) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(local.sign(path, expiresAt)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (local *LocalFSProvider) sign(path string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(local.SigningKey))
+	mac.Write([]byte(fmt.Sprintf("%s:%d", strings.TrimLeft(path, "/"), expires)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// resolvePath maps an object key onto RootDir and refuses keys that would escape it.
+func (local *LocalFSProvider) resolvePath(path string) (string, error) {
+	cleaned := filepath.Clean("/" + strings.TrimLeft(path, "/"))
+	if cleaned == "/" {
+		return "", ErrInvalidObjectPath
+	}
+
+	root, err := filepath.Abs(local.RootDir)
+	if err != nil {
+		return "", err
+	}
+
+	fullPath := filepath.Join(root, filepath.FromSlash(cleaned))
+	if !strings.HasPrefix(fullPath, root+string(filepath.Separator)) {
+		return "", ErrInvalidObjectPath
+	}
+
+	return fullPath, nil
+}
@@ -0,0 +1,104 @@
+package objectStorage
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newLocalProvider(t *testing.T) *LocalFSProvider {
+	t.Helper()
+	return &LocalFSProvider{RootDir: t.TempDir(), BaseURL: "http://localhost:5000/files/", SigningKey: "secret"}
+}
+
+func TestLocalFSProviderRoundTrip(t *testing.T) {
+	local := newLocalProvider(t)
+
+	url, err := local.Upload("projects/1/logo.png", strings.NewReader("image"), "image/png")
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if url != "http://localhost:5000/files/projects/1/logo.png" {
+		t.Errorf("Upload() url = %q", url)
+	}
+
+	reader, err := local.Download("projects/1/logo.png")
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "image" {
+		t.Errorf("Download() content = %q, want %q", content, "image")
+	}
+
+	if _, err := local.Delete("projects/1/logo.png"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := local.Delete("projects/1/logo.png"); err != nil {
+		t.Errorf("Delete() of a missing object error = %v, want nil", err)
+	}
+	if _, err := local.Download("projects/1/logo.png"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Download() after delete error = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestLocalFSProviderStaysInsideRootDir(t *testing.T) {
+	local := newLocalProvider(t)
+
+	// Leading slashes and dot segments are folded into the root rather than escaping it.
+	if _, err := local.Upload("/../../outside.txt", strings.NewReader("x"), "text/plain"); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if _, err := os.Stat(local.RootDir + "/outside.txt"); err != nil {
+		t.Errorf("object was not written inside RootDir: %v", err)
+	}
+
+	for _, path := range []string{"", "/", ".."} {
+		if _, err := local.Upload(path, strings.NewReader("x"), "text/plain"); !errors.Is(err, ErrInvalidObjectPath) {
+			t.Errorf("Upload(%q) error = %v, want ErrInvalidObjectPath", path, err)
+		}
+	}
+}
+
+func TestLocalFSProviderSignedURLs(t *testing.T) {
+	local := newLocalProvider(t)
+	if _, err := local.Upload("projects/1/resume.pdf", strings.NewReader("pdf"), "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := local.GetSignedURL("projects/1/resume.pdf", time.Minute)
+	if err != nil {
+		t.Fatalf("GetSignedURL() error = %v", err)
+	}
+	parsed, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires, signature := parsed.Query().Get("expires"), parsed.Query().Get("signature")
+
+	if _, err := local.Open("/projects/1/resume.pdf", expires, signature); err != nil {
+		t.Errorf("Open() with a valid signature error = %v", err)
+	}
+	if _, err := local.Open("projects/1/other.pdf", expires, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Open() of another path error = %v, want ErrInvalidSignature", err)
+	}
+
+	expired, _ := local.GetSignedURL("projects/1/resume.pdf", -time.Minute)
+	parsed, _ = url.Parse(expired)
+	if _, err := local.Open("projects/1/resume.pdf", parsed.Query().Get("expires"), parsed.Query().Get("signature")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Open() with an expired signature error = %v, want ErrInvalidSignature", err)
+	}
+
+	if _, err := local.Open("projects/1/resume.pdf", "", ""); err != nil {
+		t.Errorf("Open() unsigned error = %v, want nil while signatures are optional", err)
+	}
+	local.RequireSignedURLs = true
+	if _, err := local.Open("projects/1/resume.pdf", "", ""); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Open() unsigned error = %v, want ErrInvalidSignature when signatures are required", err)
+	}
+}
@@ -0,0 +1,101 @@
+package objectStorage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+type StoredObject struct {
+	Content     []byte
+	ContentType string
+}
+
+// InMemoryProvider keeps objects in a map. It is meant for tests and throwaway local runs.
+type InMemoryProvider struct {
+	BaseURL string
+
+	mu      sync.RWMutex
+	objects map[string]StoredObject
+}
+
+func NewInMemoryProvider(baseURL string) *InMemoryProvider {
+	return &InMemoryProvider{
+		BaseURL: baseURL,
+		objects: map[string]StoredObject{},
+	}
+}
+
+func (memory *InMemoryProvider) Upload(path string, content io.Reader, contentType string) (string, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", fmt.Errorf("in-memory upload error: %w", err)
+	}
+
+	memory.mu.Lock()
+	if memory.objects == nil {
+		memory.objects = map[string]StoredObject{}
+	}
+	memory.objects[path] = StoredObject{Content: data, ContentType: contentType}
+	memory.mu.Unlock()
+
+	return memory.GetURL(path)
+}
+
+func (memory *InMemoryProvider) Delete(path string) (string, error) {
+	memory.mu.Lock()
+	delete(memory.objects, path)
+	memory.mu.Unlock()
+
+	return path, nil
+}
+
+func (memory *InMemoryProvider) Download(path string) (io.ReadCloser, error) {
+	object, ok := memory.Get(path)
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+
+	return io.NopCloser(bytes.NewReader(object.Content)), nil
+}
+
+func (memory *InMemoryProvider) GetURL(path string) (string, error) {
+	if memory.BaseURL == "" {
+		return path, nil
+	}
+
+	return fmt.Sprintf("%s/%s", strings.TrimRight(memory.BaseURL, "/"), path), nil
+}
+
+func (memory *InMemoryProvider) GetSignedURL(path string, expiry time.Duration) (string, error) {
+	base, err := memory.GetURL(path)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s?expires=%d", base, time.Now().Add(expiry).Unix()), nil
+}
+
+// Get returns a stored object so callers can assert on what was uploaded.
+func (memory *InMemoryProvider) Get(path string) (StoredObject, bool) {
+	memory.mu.RLock()
+	defer memory.mu.RUnlock()
+
+	object, ok := memory.objects[path]
+	return object, ok
+}
+
+func (memory *InMemoryProvider) Paths() []string {
+	memory.mu.RLock()
+	defer memory.mu.RUnlock()
+
+	paths := make([]string, 0, len(memory.objects))
+	for path := range memory.objects {
+		paths = append(paths, path)
+	}
+
+	return paths
+}
@@ -0,0 +1,44 @@
+package objectStorage
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestInMemoryProvider(t *testing.T) {
+	memory := NewInMemoryProvider("https://cdn.test/")
+
+	url, err := memory.Upload("projects/1/logo.png", strings.NewReader("image"), "image/png")
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if url != "https://cdn.test/projects/1/logo.png" {
+		t.Errorf("Upload() url = %q", url)
+	}
+
+	object, ok := memory.Get("projects/1/logo.png")
+	if !ok || string(object.Content) != "image" || object.ContentType != "image/png" {
+		t.Errorf("Get() = %+v, %v", object, ok)
+	}
+
+	reader, err := memory.Download("projects/1/logo.png")
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	content, _ := io.ReadAll(reader)
+	if string(content) != "image" {
+		t.Errorf("Download() content = %q", content)
+	}
+
+	if _, err := memory.Delete("projects/1/logo.png"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if len(memory.Paths()) != 0 {
+		t.Errorf("Paths() = %v after delete, want none", memory.Paths())
+	}
+	if _, err := memory.Download("projects/1/logo.png"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Download() after delete error = %v, want ErrObjectNotFound", err)
+	}
+}
@@ -1,15 +1,21 @@
 package objectStorage
 
 import (
+	"errors"
 	"io"
 	"time"
 )
 
+var ErrObjectNotFound = errors.New("object not found")
+
 type Provider interface {
 	Upload(path string, content io.Reader, contentType string) (string, error)
 
 	Delete(path string) (string, error)
 
+	// Download opens a stored object for reading. ErrObjectNotFound is returned when nothing is stored at path.
+	Download(path string) (io.ReadCloser, error)
+
 	GetURL(path string) (string, error)
 
 	GetSignedURL(path string, expiry time.Duration) (string, error)
@@ -0,0 +1,161 @@
+package objectStorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3SDK talks to any S3-compatible endpoint: AWS itself, MinIO, or another self-hosted store.
+type S3SDK struct {
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	BucketName      string
+
+	// UsePathStyle is required by MinIO and most self-hosted stores.
+	UsePathStyle bool
+
+	BaseURL string
+
+	once          sync.Once
+	client        *s3.Client
+	presignClient *s3.PresignClient
+	err           error
+}
+
+func (store *S3SDK) init() {
+	store.once.Do(func() {
+		if store.AccessKeyID == "" || store.SecretAccessKey == "" || store.BucketName == "" {
+			store.err = fmt.Errorf("missing required S3 configuration (Keys or BucketName)")
+			return
+		}
+
+		region := store.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+
+		cfg, err := config.LoadDefaultConfig(context.Background(),
+			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(store.AccessKeyID, store.SecretAccessKey, "")),
+			config.WithRegion(region),
+		)
+		if err != nil {
+			store.err = fmt.Errorf("failed to load S3 config: %w", err)
+			return
+		}
+
+		store.client = s3.NewFromConfig(cfg, func(o *s3.Options) {
+			if store.Endpoint != "" {
+				o.BaseEndpoint = aws.String(store.Endpoint)
+			}
+			o.UsePathStyle = store.UsePathStyle
+		})
+
+		store.presignClient = s3.NewPresignClient(store.client)
+	})
+}
+
+func (store *S3SDK) Upload(path string, content io.Reader, contentType string) (string, error) {
+	store.init()
+	if store.err != nil {
+		return "", store.err
+	}
+
+	_, err := store.client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String(store.BucketName),
+		Key:         aws.String(path),
+		Body:        content,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("S3 Upload error: %w", err)
+	}
+
+	return store.resolveURL(path), nil
+}
+
+func (store *S3SDK) Delete(path string) (string, error) {
+	store.init()
+	if store.err != nil {
+		return "", store.err
+	}
+
+	_, err := store.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(store.BucketName),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return "", fmt.Errorf("S3 Delete error: %w", err)
+	}
+
+	return path, nil
+}
+
+func (store *S3SDK) Download(path string) (io.ReadCloser, error) {
+	store.init()
+	if store.err != nil {
+		return nil, store.err
+	}
+
+	output, err := store.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(store.BucketName),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		var missing *types.NoSuchKey
+		if errors.As(err, &missing) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("S3 Download error: %w", err)
+	}
+
+	return output.Body, nil
+}
+
+func (store *S3SDK) GetURL(path string) (string, error) {
+	return store.resolveURL(path), nil
+}
+
+func (store *S3SDK) GetSignedURL(path string, expiry time.Duration) (string, error) {
+	store.init()
+	if store.err != nil {
+		return "", store.err
+	}
+
+	req, err := store.presignClient.PresignGetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(store.BucketName),
+		Key:    aws.String(path),
+	}, func(o *s3.PresignOptions) {
+		o.Expires = expiry
+	})
+	if err != nil {
+		return "", fmt.Errorf("S3 GetSignedURL error: %w", err)
+	}
+
+	return req.URL, nil
+}
+
+// resolveURL prefers the public BaseURL, then falls back to a path-style URL on the endpoint.
+func (store *S3SDK) resolveURL(path string) string {
+	if store.BaseURL != "" {
+		return fmt.Sprintf("%s/%s", strings.TrimRight(store.BaseURL, "/"), path)
+	}
+
+	if store.Endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", strings.TrimRight(store.Endpoint, "/"), store.BucketName, path)
+	}
+
+	return path
+}
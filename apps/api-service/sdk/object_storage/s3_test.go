@@ -0,0 +1,98 @@
+package objectStorage
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 answers the path-style object calls the provider makes, the way MinIO does.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (fake *fakeS3) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	key := strings.TrimPrefix(request.URL.Path, "/")
+	switch request.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(request.Body)
+		fake.objects[key] = body
+		writer.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		body, ok := fake.objects[key]
+		if !ok {
+			writer.Header().Set("Content-Type", "application/xml")
+			writer.WriteHeader(http.StatusNotFound)
+			io.WriteString(writer, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>missing</Message></Error>`)
+			return
+		}
+		writer.Write(body)
+	case http.MethodDelete:
+		delete(fake.objects, key)
+		writer.WriteHeader(http.StatusNoContent)
+	default:
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestS3SDKAgainstS3CompatibleEndpoint(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store := &S3SDK{
+		Endpoint:        server.URL,
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio-secret",
+		BucketName:      "kislap",
+		UsePathStyle:    true,
+	}
+
+	url, err := store.Upload("projects/1/logo.png", strings.NewReader("image"), "image/png")
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if url != server.URL+"/kislap/projects/1/logo.png" {
+		t.Errorf("Upload() url = %q, want a path-style endpoint URL", url)
+	}
+	if string(fake.objects["kislap/projects/1/logo.png"]) != "image" {
+		t.Errorf("stored objects = %v", fake.objects)
+	}
+
+	reader, err := store.Download("projects/1/logo.png")
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "image" {
+		t.Errorf("Download() content = %q", content)
+	}
+
+	if _, err := store.Delete("projects/1/logo.png"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Download("projects/1/logo.png"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Download() after delete error = %v, want ErrObjectNotFound", err)
+	}
+
+	store.BaseURL = "https://cdn.test/"
+	if url, _ := store.GetURL("projects/1/logo.png"); url != "https://cdn.test/projects/1/logo.png" {
+		t.Errorf("GetURL() = %q, want the public base URL", url)
+	}
+}
+
+func TestS3SDKRequiresCredentials(t *testing.T) {
+	store := &S3SDK{Endpoint: "http://localhost:9000", BucketName: "kislap"}
+	if _, err := store.Upload("a.txt", strings.NewReader("x"), "text/plain"); err == nil {
+		t.Fatal("Upload() without credentials error = nil")
+	}
+}
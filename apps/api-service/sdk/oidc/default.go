@@ -0,0 +1,17 @@
+package oidc
+
+var defaultRegistry *Registry
+
+func Default(registry *Registry) *Registry {
+	defaultRegistry = registry
+
+	return defaultRegistry
+}
+
+func Get(name string) (*Provider, bool) {
+	return defaultRegistry.Get(name)
+}
+
+func List() []ProviderInfo {
+	return defaultRegistry.List()
+}
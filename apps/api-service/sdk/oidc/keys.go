@@ -0,0 +1,174 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keyRefreshInterval limits how often an unknown kid can trigger a JWKS fetch, so forged tokens can't be used to
+// hammer the issuer.
+const keyRefreshInterval = time.Minute
+
+var ErrUnknownSigningKey = errors.New("ID token is signed with a key the issuer does not publish")
+
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+type keyCache struct {
+	uri string
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// lookup returns the key for a kid, refetching the JWKS when the issuer has rotated to a key we haven't seen.
+// Tokens without a kid are accepted only while the issuer publishes a single key.
+func (cache *keyCache) lookup(ctx context.Context, client *http.Client, keyID string, algorithm string) (crypto.PublicKey, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	key, ok := cache.find(keyID)
+	if !ok && time.Since(cache.fetchedAt) > keyRefreshInterval {
+		if err := cache.refresh(ctx, client); err != nil {
+			return nil, err
+		}
+		key, ok = cache.find(keyID)
+	}
+	if !ok {
+		return nil, ErrUnknownSigningKey
+	}
+
+	if !keyMatchesAlgorithm(key, algorithm) {
+		return nil, fmt.Errorf("ID token algorithm %s does not match its key", algorithm)
+	}
+
+	return key, nil
+}
+
+func (cache *keyCache) find(keyID string) (crypto.PublicKey, bool) {
+	if keyID == "" {
+		if len(cache.keys) != 1 {
+			return nil, false
+		}
+		for _, key := range cache.keys {
+			return key, true
+		}
+	}
+
+	key, ok := cache.keys[keyID]
+	return key, ok
+}
+
+func (cache *keyCache) refresh(ctx context.Context, client *http.Client) error {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, client, cache.uri, &set); err != nil {
+		return fmt.Errorf("failed to fetch OIDC signing keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip key types we don't support rather than failing every login.
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+
+	cache.keys = keys
+	cache.fetchedAt = time.Now()
+	return nil
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %q", jwk.Curve)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if jwk.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve %q", jwk.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+	}
+}
+
+func keyMatchesAlgorithm(key crypto.PublicKey, algorithm string) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(algorithm, "RS") || strings.HasPrefix(algorithm, "PS")
+	case *ecdsa.PublicKey:
+		return strings.HasPrefix(algorithm, "ES")
+	case ed25519.PublicKey:
+		return algorithm == "EdDSA"
+	default:
+		return false
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(decoded), nil
+}
@@ -0,0 +1,255 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const mockKeyID = "mock"
+
+// MockUser is the person the mock issuer signs in as.
+type MockUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type mockGrant struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	user        MockUser
+	expiresAt   time.Time
+}
+
+// MockServer is a minimal OpenID Connect issuer for tests and local runs. /authorize skips the login screen and
+// redirects straight back with a code for User; /token checks the PKCE verifier before issuing an ID token.
+type MockServer struct {
+	*httptest.Server
+
+	ClientID string
+
+	mu     sync.Mutex
+	user   MockUser
+	key    *rsa.PrivateKey
+	grants map[string]mockGrant
+}
+
+func NewMockServer(clientID string) (*MockServer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	mock := &MockServer{
+		ClientID: clientID,
+		key:      key,
+		grants:   map[string]mockGrant{},
+		user: MockUser{
+			Subject:       "mock-user",
+			Email:         "mock.user@kislap.test",
+			EmailVerified: true,
+			Name:          "Mock User",
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", mock.discovery)
+	mux.HandleFunc("/authorize", mock.authorize)
+	mux.HandleFunc("/token", mock.token)
+	mux.HandleFunc("/jwks", mock.jwks)
+	mock.Server = httptest.NewServer(mux)
+
+	return mock, nil
+}
+
+// SetUser changes who the next sign-in is for.
+func (mock *MockServer) SetUser(user MockUser) {
+	mock.mu.Lock()
+	mock.user = user
+	mock.mu.Unlock()
+}
+
+// Config returns a provider configuration pointing at the mock issuer.
+func (mock *MockServer) Config(name string, redirectURL string) Config {
+	return Config{
+		Name:        name,
+		DisplayName: "Mock",
+		Issuer:      mock.URL,
+		ClientID:    mock.ClientID,
+		RedirectURL: redirectURL,
+	}
+}
+
+// SignIn follows an authorization URL the way a browser would and returns the code and state the mock redirects
+// back with.
+func (mock *MockServer) SignIn(authURL string) (string, string, error) {
+	// A copy, so the server's shared client keeps following redirects.
+	client := *mock.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	response, err := client.Get(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusFound {
+		return "", "", fmt.Errorf("authorize returned %s", response.Status)
+	}
+
+	location, err := response.Location()
+	if err != nil {
+		return "", "", err
+	}
+
+	return location.Query().Get("code"), location.Query().Get("state"), nil
+}
+
+func (mock *MockServer) discovery(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, Metadata{
+		Issuer:                mock.URL,
+		AuthorizationEndpoint: mock.URL + "/authorize",
+		TokenEndpoint:         mock.URL + "/token",
+		JWKSURI:               mock.URL + "/jwks",
+	})
+}
+
+func (mock *MockServer) authorize(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	if query.Get("client_id") != mock.ClientID || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(writer, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		http.Error(writer, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomMockValue()
+	mock.mu.Lock()
+	mock.grants[code] = mockGrant{
+		clientID:    mock.ClientID,
+		redirectURI: redirect.String(),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		user:        mock.user,
+		expiresAt:   time.Now().Add(time.Minute),
+	}
+	mock.mu.Unlock()
+
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+
+	http.Redirect(writer, request, redirect.String(), http.StatusFound)
+}
+
+func (mock *MockServer) token(writer http.ResponseWriter, request *http.Request) {
+	if err := request.ParseForm(); err != nil || request.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(writer, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	code := request.PostForm.Get("code")
+	mock.mu.Lock()
+	grant, ok := mock.grants[code]
+	delete(mock.grants, code)
+	mock.mu.Unlock()
+
+	if !ok || time.Now().After(grant.expiresAt) || request.PostForm.Get("redirect_uri") != grant.redirectURI {
+		writeJSON(writer, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	clientID := request.PostForm.Get("client_id")
+	if username, _, hasBasic := request.BasicAuth(); hasBasic {
+		clientID, _ = url.QueryUnescape(username)
+	}
+	if clientID != grant.clientID {
+		writeJSON(writer, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	digest := sha256.Sum256([]byte(request.PostForm.Get("code_verifier")))
+	challenge := base64.RawURLEncoding.EncodeToString(digest[:])
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(grant.challenge)) != 1 {
+		writeJSON(writer, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            mock.URL,
+		"sub":            grant.user.Subject,
+		"aud":            grant.clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          grant.nonce,
+		"email":          grant.user.Email,
+		"email_verified": grant.user.EmailVerified,
+		"name":           grant.user.Name,
+	})
+	idToken.Header["kid"] = mockKeyID
+
+	signed, err := idToken.SignedString(mock.key)
+	if err != nil {
+		writeJSON(writer, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, map[string]any{
+		"access_token": randomMockValue(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func (mock *MockServer) jwks(writer http.ResponseWriter, request *http.Request) {
+	publicKey := mock.key.PublicKey
+	writeJSON(writer, http.StatusOK, map[string]any{
+		"keys": []jsonWebKey{{
+			KeyType:   "RSA",
+			KeyID:     mockKeyID,
+			Use:       "sig",
+			Algorithm: "RS256",
+			N:         base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		}},
+	})
+}
+
+func writeJSON(writer http.ResponseWriter, status int, body any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(body)
+}
+
+func randomMockValue() string {
+	buffer := make([]byte, 16)
+	rand.Read(buffer)
+	return hex.EncodeToString(buffer)
+}
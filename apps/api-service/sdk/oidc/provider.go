@@ -0,0 +1,252 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrMissingIDToken = errors.New("the provider did not return an ID token")
+	ErrNonceMismatch  = errors.New("ID token nonce does not match the sign-in request")
+	ErrInvalidClaims  = errors.New("ID token is missing required claims")
+)
+
+// idTokenLeeway absorbs clock drift between us and the issuer.
+const idTokenLeeway = time.Minute
+
+type Config struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Metadata is the part of the discovery document this package uses.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+	Picture       string
+}
+
+// Provider is one OpenID Connect issuer. Discovery and the signing keys are fetched on first use and cached, so
+// an issuer that is down at startup doesn't keep the API from booting.
+type Provider struct {
+	Config     Config
+	HTTPClient *http.Client
+
+	mu       sync.Mutex
+	metadata *Metadata
+	keys     *keyCache
+}
+
+func NewProvider(config Config) *Provider {
+	return &Provider{Config: config}
+}
+
+func (provider *Provider) httpClient() *http.Client {
+	if provider.HTTPClient != nil {
+		return provider.HTTPClient
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// Discover loads the issuer's openid-configuration. A failed attempt is not cached.
+func (provider *Provider) Discover(ctx context.Context) (*Metadata, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.metadata != nil {
+		return provider.metadata, nil
+	}
+
+	issuer := strings.TrimRight(provider.Config.Issuer, "/")
+	var metadata Metadata
+	if err := provider.getJSON(ctx, issuer+"/.well-known/openid-configuration", &metadata); err != nil {
+		return nil, fmt.Errorf("OIDC discovery for %s failed: %w", provider.Config.Name, err)
+	}
+
+	// The spec requires the document to name the issuer it was fetched from.
+	if strings.TrimRight(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC discovery for %s returned issuer %q", provider.Config.Name, metadata.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery for %s is missing required endpoints", provider.Config.Name)
+	}
+
+	provider.metadata = &metadata
+	provider.keys = &keyCache{uri: metadata.JWKSURI}
+	return provider.metadata, nil
+}
+
+func (provider *Provider) oauthConfig(metadata *Metadata) *oauth2.Config {
+	scopes := provider.Config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &oauth2.Config{
+		ClientID:     provider.Config.ClientID,
+		ClientSecret: provider.Config.ClientSecret,
+		RedirectURL:  provider.Config.RedirectURL,
+		Scopes:       scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  metadata.AuthorizationEndpoint,
+			TokenURL: metadata.TokenEndpoint,
+		},
+	}
+}
+
+// AuthCodeURL builds the URL to send the browser to. The verifier stays on our side; only its S256 challenge is
+// sent along.
+func (provider *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	metadata, err := provider.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return provider.oauthConfig(metadata).AuthCodeURL(state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	), nil
+}
+
+// Exchange trades the authorization code for tokens and returns the verified ID token claims.
+func (provider *Provider) Exchange(ctx context.Context, code string, verifier string, nonce string) (*Claims, error) {
+	metadata, err := provider.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, provider.httpClient())
+	token, err := provider.oauthConfig(metadata).Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, ErrMissingIDToken
+	}
+
+	return provider.VerifyIDToken(ctx, rawIDToken, nonce)
+}
+
+// VerifyIDToken checks the signature against the issuer's published keys, then issuer, audience, expiry and
+// nonce.
+func (provider *Provider) VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (*Claims, error) {
+	metadata, err := provider.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (any, error) {
+		keyID, _ := token.Header["kid"].(string)
+		return provider.keys.lookup(ctx, provider.httpClient(), keyID, token.Method.Alg())
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "EdDSA"}),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(provider.Config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(idTokenLeeway),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	// With several audiences, the token must say it was issued to us.
+	if audience, _ := claims.GetAudience(); len(audience) > 1 {
+		if authorizedParty, _ := claims["azp"].(string); authorizedParty != provider.Config.ClientID {
+			return nil, ErrInvalidClaims
+		}
+	}
+
+	tokenNonce, _ := claims["nonce"].(string)
+	if subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, ErrNonceMismatch
+	}
+
+	result := &Claims{
+		Subject:       stringClaim(claims, "sub"),
+		Email:         strings.TrimSpace(stringClaim(claims, "email")),
+		EmailVerified: boolClaim(claims, "email_verified"),
+		Name:          stringClaim(claims, "name"),
+		GivenName:     stringClaim(claims, "given_name"),
+		FamilyName:    stringClaim(claims, "family_name"),
+		Picture:       stringClaim(claims, "picture"),
+	}
+	if result.Subject == "" {
+		return nil, ErrInvalidClaims
+	}
+
+	return result, nil
+}
+
+func (provider *Provider) getJSON(ctx context.Context, url string, target any) error {
+	return getJSON(ctx, provider.httpClient(), url, target)
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, target any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("GET %s: %s - %s", url, response.Status, string(body))
+	}
+
+	return json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(target)
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// boolClaim also accepts "true", which some issuers send for email_verified.
+func boolClaim(claims jwt.MapClaims, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	default:
+		return false
+	}
+}
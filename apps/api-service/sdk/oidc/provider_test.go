@@ -0,0 +1,213 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newMockProvider(t *testing.T) (*MockServer, *Provider) {
+	t.Helper()
+
+	mock, err := NewMockServer("kislap-web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mock.Close)
+
+	provider := NewProvider(mock.Config("mock", "https://kislap.test/auth/oidc/mock/callback"))
+	provider.HTTPClient = mock.Client()
+	return mock, provider
+}
+
+// signIDToken signs claims the way the mock issuer does, with the given key standing in for its own.
+func signIDToken(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = mockKeyID
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestDiscover(t *testing.T) {
+	mock, provider := newMockProvider(t)
+
+	metadata, err := provider.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if metadata.Issuer != mock.URL || metadata.TokenEndpoint != mock.URL+"/token" || metadata.JWKSURI != mock.URL+"/jwks" {
+		t.Fatalf("Discover() = %+v", metadata)
+	}
+
+	// The document is cached, so a provider that goes down later doesn't break sign-in URLs.
+	mock.Close()
+	if _, err := provider.Discover(context.Background()); err != nil {
+		t.Fatalf("cached Discover() error = %v", err)
+	}
+}
+
+func TestDiscoverRejectsAnotherIssuer(t *testing.T) {
+	mock, _ := newMockProvider(t)
+
+	config := mock.Config("mock", "https://kislap.test/auth/oidc/mock/callback")
+	config.Issuer = mock.URL + "/tenant"
+	provider := NewProvider(config)
+	provider.HTTPClient = mock.Client()
+
+	if _, err := provider.Discover(context.Background()); err == nil {
+		t.Fatal("Discover() accepted a document fetched from the wrong issuer path")
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	mock, provider := newMockProvider(t)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		base := jwt.MapClaims{
+			"iss":            mock.URL,
+			"sub":            "user-1",
+			"aud":            mock.ClientID,
+			"iat":            now.Unix(),
+			"exp":            now.Add(5 * time.Minute).Unix(),
+			"nonce":          "expected-nonce",
+			"email":          " ada@example.com ",
+			"email_verified": "true",
+		}
+		for name, value := range overrides {
+			if value == nil {
+				delete(base, name)
+				continue
+			}
+			base[name] = value
+		}
+		return base
+	}
+
+	tests := []struct {
+		name      string
+		key       *rsa.PrivateKey
+		overrides jwt.MapClaims
+		wantErr   error
+	}{
+		{name: "valid", key: mock.key},
+		{name: "wrong nonce", key: mock.key, overrides: jwt.MapClaims{"nonce": "other-nonce"}, wantErr: ErrNonceMismatch},
+		{name: "missing nonce", key: mock.key, overrides: jwt.MapClaims{"nonce": nil}, wantErr: ErrNonceMismatch},
+		{name: "another audience", key: mock.key, overrides: jwt.MapClaims{"aud": "someone-else"}, wantErr: jwt.ErrTokenInvalidAudience},
+		{name: "another issuer", key: mock.key, overrides: jwt.MapClaims{"iss": "https://evil.test"}, wantErr: jwt.ErrTokenInvalidIssuer},
+		{name: "expired", key: mock.key, overrides: jwt.MapClaims{"exp": now.Add(-time.Hour).Unix()}, wantErr: jwt.ErrTokenExpired},
+		{name: "no expiry", key: mock.key, overrides: jwt.MapClaims{"exp": nil}, wantErr: jwt.ErrTokenRequiredClaimMissing},
+		{name: "shared audience without azp", key: mock.key, overrides: jwt.MapClaims{"aud": []string{mock.ClientID, "other"}}, wantErr: ErrInvalidClaims},
+		{name: "no subject", key: mock.key, overrides: jwt.MapClaims{"sub": nil}, wantErr: ErrInvalidClaims},
+		{name: "signed by another key", key: otherKey, wantErr: jwt.ErrTokenSignatureInvalid},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := provider.VerifyIDToken(context.Background(), signIDToken(t, test.key, claims(test.overrides)), "expected-nonce")
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("VerifyIDToken() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyIDToken() error = %v", err)
+			}
+			if result.Subject != "user-1" || result.Email != "ada@example.com" || !result.EmailVerified {
+				t.Fatalf("VerifyIDToken() = %+v", result)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsUnsignedTokens(t *testing.T) {
+	mock, provider := newMockProvider(t)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
+		"iss":   mock.URL,
+		"sub":   "user-1",
+		"aud":   mock.ClientID,
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": "expected-nonce",
+	})
+	unsigned, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := provider.VerifyIDToken(context.Background(), unsigned, "expected-nonce"); err == nil {
+		t.Fatal("VerifyIDToken() accepted an unsigned token")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	mock, provider := newMockProvider(t)
+	mock.SetUser(MockUser{Subject: "ada", Email: "ada@example.com", EmailVerified: true, Name: "Ada Lovelace"})
+
+	verifier := "verifier-with-enough-entropy-to-pass-as-pkce-0123456789"
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(authURL, mock.URL+"/authorize?") {
+		t.Fatalf("AuthCodeURL() = %s", authURL)
+	}
+
+	code, state, err := mock.SignIn(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "state-1" {
+		t.Fatalf("redirect state = %q, want state-1", state)
+	}
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		// The mock forgets a code once it is presented, so this needs a sign-in of its own.
+		otherCode, _, err := mock.SignIn(authURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := provider.Exchange(context.Background(), otherCode, verifier+"x", "nonce-1"); err == nil {
+			t.Fatal("Exchange() accepted the wrong PKCE verifier")
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		otherCode, _, err := mock.SignIn(authURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := provider.Exchange(context.Background(), otherCode, verifier, "nonce-2"); !errors.Is(err, ErrNonceMismatch) {
+			t.Fatalf("Exchange() error = %v, want %v", err, ErrNonceMismatch)
+		}
+	})
+
+	claims, err := provider.Exchange(context.Background(), code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if claims.Subject != "ada" || claims.Email != "ada@example.com" || !claims.EmailVerified || claims.Name != "Ada Lovelace" {
+		t.Fatalf("Exchange() = %+v", claims)
+	}
+
+	if _, err := provider.Exchange(context.Background(), code, verifier, "nonce-1"); err == nil {
+		t.Fatal("Exchange() accepted a code twice")
+	}
+}
@@ -0,0 +1,94 @@
+package oidc
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// Registry holds the configured issuers by name. The name doubles as the provider stored on user identities, so
+// it must not change once people have signed in with it.
+type Registry struct {
+	providers map[string]*Provider
+}
+
+// ProviderInfo is what the sign-in page needs to render a button.
+type ProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+func NewRegistry(providers ...*Provider) *Registry {
+	registry := &Registry{providers: map[string]*Provider{}}
+	for _, provider := range providers {
+		registry.providers[provider.Config.Name] = provider
+	}
+	return registry
+}
+
+// LoadRegistry reads OIDC_PROVIDERS, a comma separated list of names, and for each name the OIDC_<NAME>_* settings:
+// ISSUER, CLIENT_ID and REDIRECT_URL are required; CLIENT_SECRET (omit for public clients), SCOPES and
+// DISPLAY_NAME are optional.
+func LoadRegistry(getenv func(string) string) (*Registry, error) {
+	registry := NewRegistry()
+
+	for _, name := range strings.Split(getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !providerNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid OIDC provider name %q", name)
+		}
+		if _, exists := registry.providers[name]; exists {
+			return nil, fmt.Errorf("OIDC provider %q is listed twice", name)
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		config := Config{
+			Name:         name,
+			DisplayName:  getenv(prefix + "DISPLAY_NAME"),
+			Issuer:       getenv(prefix + "ISSUER"),
+			ClientID:     getenv(prefix + "CLIENT_ID"),
+			ClientSecret: getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(strings.ReplaceAll(getenv(prefix+"SCOPES"), ",", " ")),
+		}
+		if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+			return nil, fmt.Errorf("OIDC provider %q needs %sISSUER, %sCLIENT_ID and %sREDIRECT_URL", name, prefix, prefix, prefix)
+		}
+		if config.DisplayName == "" {
+			config.DisplayName = name
+		}
+
+		registry.providers[name] = NewProvider(config)
+	}
+
+	return registry, nil
+}
+
+func (registry *Registry) Get(name string) (*Provider, bool) {
+	if registry == nil {
+		return nil, false
+	}
+
+	provider, ok := registry.providers[name]
+	return provider, ok
+}
+
+func (registry *Registry) List() []ProviderInfo {
+	if registry == nil {
+		return []ProviderInfo{}
+	}
+
+	infos := make([]ProviderInfo, 0, len(registry.providers))
+	for _, provider := range registry.providers {
+		infos = append(infos, ProviderInfo{Name: provider.Config.Name, DisplayName: provider.Config.DisplayName})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+
+	return infos
+}
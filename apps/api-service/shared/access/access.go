@@ -0,0 +1,173 @@
+package access
+
+import (
+	"errors"
+	"flash/models"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// Platform roles from users.role, matching the admin app: admins and super admins can do anything, support staff
+// can look but not change. Everyone else has the default role.
+const (
+	RoleDefault    = "default"
+	RoleAdmin      = "admin"
+	RoleSuperAdmin = "super_admin"
+	RoleSupport    = "support"
+)
+
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+type Permission int
+
+const (
+	PermissionRead Permission = iota + 1
+	PermissionWrite
+	PermissionManage
+)
+
+var (
+	ErrProjectNotFound  = errors.New("project not found")
+	ErrProjectForbidden = errors.New("you do not have access to this project")
+)
+
+var rolePermissions = map[string]Permission{
+	RoleOwner:  PermissionManage,
+	RoleEditor: PermissionWrite,
+	RoleViewer: PermissionRead,
+}
+
+// Actor is the signed-in user a service call is made for, with their platform role from users.role.
+type Actor struct {
+	UserID uint64
+	Role   string
+}
+
+// Authorize is AuthorizeProject for the actor. Services call it themselves rather than trusting that the route ran
+// ProjectAccessMiddleware first.
+func (actor Actor) Authorize(db *gorm.DB, projectID uint64, permission Permission) (*models.Project, error) {
+	project, _, err := AuthorizeProject(db, actor.UserID, actor.Role, projectID, permission)
+	return project, err
+}
+
+// HTTPStatus maps ErrProjectForbidden to 403 and ErrProjectNotFound to 404, and any other error to fallback.
+func HTTPStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, ErrProjectForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrProjectNotFound):
+		return http.StatusNotFound
+	default:
+		return fallback
+	}
+}
+
+// AuthorizeProject loads the project and checks that the user's role on it grants the permission.
+func AuthorizeProject(db *gorm.DB, userID uint64, userRole string, projectID uint64, permission Permission) (*models.Project, string, error) {
+	var project models.Project
+	if err := db.First(&project, projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrProjectNotFound
+		}
+		return nil, "", err
+	}
+
+	role, err := ProjectRole(db, userID, userRole, &project)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !RoleAllows(role, permission) {
+		return nil, "", ErrProjectForbidden
+	}
+
+	return &project, role, nil
+}
+
+// ProjectRole resolves the user's role on a project: the owner and admins are owners, support staff are viewers,
+// everyone else needs a membership in the project's workspace.
+func ProjectRole(db *gorm.DB, userID uint64, userRole string, project *models.Project) (string, error) {
+	if project.UserID == userID || IsAdmin(userRole) {
+		return RoleOwner, nil
+	}
+
+	if project.WorkspaceID == nil {
+		return supportRole(userRole)
+	}
+
+	var member models.WorkspaceMember
+	err := db.Where("workspace_id = ? AND user_id = ?", *project.WorkspaceID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return supportRole(userRole)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return member.Role, nil
+}
+
+// IsAdmin reports whether a platform role may manage every project.
+func IsAdmin(userRole string) bool {
+	return userRole == RoleAdmin || userRole == RoleSuperAdmin
+}
+
+// supportRole is the fallback for users without a role of their own on the project.
+func supportRole(userRole string) (string, error) {
+	if userRole == RoleSupport {
+		return RoleViewer, nil
+	}
+	return "", ErrProjectForbidden
+}
+
+func RoleAllows(role string, permission Permission) bool {
+	return rolePermissions[role] >= permission
+}
+
+// AccessibleProjectIDs is a subquery of every project the user owns or reaches through a workspace.
+func AccessibleProjectIDs(db *gorm.DB, userID uint64) *gorm.DB {
+	return db.Model(&models.Project{}).
+		Select("id").
+		Where("user_id = ? OR workspace_id IN (?)", userID, MemberWorkspaceIDs(db, userID))
+}
+
+func MemberWorkspaceIDs(db *gorm.DB, userID uint64) *gorm.DB {
+	return db.Model(&models.WorkspaceMember{}).Select("workspace_id").Where("user_id = ?", userID)
+}
+
+// ProjectIDOf returns the project_id column of a project-owned row, such as a portfolio or appointment.
+func ProjectIDOf(db *gorm.DB, model any, id uint64) (uint64, error) {
+	var row struct {
+		ProjectID uint64
+	}
+
+	err := db.Model(model).Select("project_id").Where("id = ?", id).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrProjectNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return row.ProjectID, nil
+}
+
+// ProjectIDBySlug returns the ID of the project with the given slug.
+func ProjectIDBySlug(db *gorm.DB, slug string) (uint64, error) {
+	var project models.Project
+
+	err := db.Select("id").Where("slug = ?", slug).First(&project).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrProjectNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return project.ID, nil
+}
@@ -0,0 +1,105 @@
+package access
+
+import (
+	"errors"
+	"flash/models"
+	"flash/shared/testdb"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type fixture struct {
+	db        *gorm.DB
+	personal  models.Project
+	workspace models.Project
+}
+
+// Users: 1 owns both projects, 2 edits and 3 views the workspace, 4 is a stranger.
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+	db := testdb.Open(t, &models.Project{}, &models.Workspace{}, &models.WorkspaceMember{})
+
+	workspace := models.Workspace{ID: 10, OwnerID: 1, Name: "Studio"}
+	fixture := fixture{
+		db:        db,
+		personal:  models.Project{ID: 100, UserID: 1, Name: "Personal", Slug: "personal", Type: "portfolio"},
+		workspace: models.Project{ID: 200, UserID: 1, WorkspaceID: &workspace.ID, Name: "Shared", Slug: "shared", Type: "menu"},
+	}
+	testdb.Create(t, db,
+		&workspace,
+		&models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: 1, Role: RoleOwner},
+		&models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: 2, Role: RoleEditor},
+		&models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: 3, Role: RoleViewer},
+		&fixture.personal,
+		&fixture.workspace,
+	)
+	return fixture
+}
+
+func TestAuthorizeProject(t *testing.T) {
+	fixture := newFixture(t)
+
+	tests := []struct {
+		name       string
+		userID     uint64
+		userRole   string
+		projectID  uint64
+		permission Permission
+		wantRole   string
+		wantErr    error
+	}{
+		{"owner manages own project", 1, "user", 100, PermissionManage, RoleOwner, nil},
+		{"owner manages workspace project", 1, "user", 200, PermissionManage, RoleOwner, nil},
+		{"editor writes", 2, "user", 200, PermissionWrite, RoleEditor, nil},
+		{"editor cannot manage", 2, "user", 200, PermissionManage, "", ErrProjectForbidden},
+		{"viewer reads", 3, "user", 200, PermissionRead, RoleViewer, nil},
+		{"viewer cannot write", 3, "user", 200, PermissionWrite, "", ErrProjectForbidden},
+		{"member has no access outside the workspace", 2, "user", 100, PermissionRead, "", ErrProjectForbidden},
+		{"stranger cannot read", 4, "user", 100, PermissionRead, "", ErrProjectForbidden},
+		{"stranger cannot read workspace project", 4, "user", 200, PermissionRead, "", ErrProjectForbidden},
+		{"admin manages any project", 4, RoleAdmin, 100, PermissionManage, RoleOwner, nil},
+		{"super admin manages any project", 4, RoleSuperAdmin, 200, PermissionManage, RoleOwner, nil},
+		{"support reads any project", 4, RoleSupport, 100, PermissionRead, RoleViewer, nil},
+		{"support cannot write", 4, RoleSupport, 100, PermissionWrite, "", ErrProjectForbidden},
+		{"missing project", 1, "user", 999, PermissionRead, "", ErrProjectNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			project, role, err := AuthorizeProject(fixture.db, test.userID, test.userRole, test.projectID, test.permission)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("error = %v, want %v", err, test.wantErr)
+			}
+			if role != test.wantRole {
+				t.Errorf("role = %q, want %q", role, test.wantRole)
+			}
+			if test.wantErr == nil && project.ID != test.projectID {
+				t.Errorf("project = %d, want %d", project.ID, test.projectID)
+			}
+		})
+	}
+}
+
+func TestAccessibleProjectIDs(t *testing.T) {
+	fixture := newFixture(t)
+
+	tests := []struct {
+		userID uint64
+		want   int
+	}{
+		{1, 2},
+		{2, 1},
+		{4, 0},
+	}
+
+	for _, test := range tests {
+		var ids []uint64
+		if err := fixture.db.Model(&models.Project{}).Where("id IN (?)", AccessibleProjectIDs(fixture.db, test.userID)).Pluck("id", &ids).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(ids) != test.want {
+			t.Errorf("user %d reaches %v, want %d projects", test.userID, ids, test.want)
+		}
+	}
+}
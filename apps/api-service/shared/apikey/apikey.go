@@ -0,0 +1,75 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Prefix marks a bearer credential as an API key rather than a JWT, and makes leaked keys easy to grep for.
+const Prefix = "kslp_"
+
+// displayLength is how much of a key is kept in the clear for listing.
+const displayLength = 13
+
+const (
+	ScopeProjectsRead  = "projects:read"
+	ScopeProjectsWrite = "projects:write"
+	ScopeMenuRead      = "menu:read"
+	ScopeMenuWrite     = "menu:write"
+	ScopeAnalyticsRead = "analytics:read"
+)
+
+// Scopes lists every scope a key can be granted, in the order the settings page shows them.
+var Scopes = []string{
+	ScopeProjectsRead,
+	ScopeProjectsWrite,
+	ScopeMenuRead,
+	ScopeMenuWrite,
+	ScopeAnalyticsRead,
+}
+
+// Generate returns a new key, the part of it that is safe to display, and the hash to store.
+func Generate() (string, string, string, error) {
+	buffer := make([]byte, 24)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", "", "", err
+	}
+
+	key := Prefix + hex.EncodeToString(buffer)
+	return key, key[:displayLength], Hash(key), nil
+}
+
+func Hash(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(digest[:])
+}
+
+func IsKey(credential string) bool {
+	return strings.HasPrefix(credential, Prefix)
+}
+
+func ValidScope(scope string) bool {
+	for _, known := range Scopes {
+		if known == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether the granted scopes cover the required one. A write scope also grants reading the same
+// resource.
+func Allows(granted []string, required string) bool {
+	resource, _, _ := strings.Cut(required, ":")
+	for _, scope := range granted {
+		if scope == required {
+			return true
+		}
+		if strings.HasSuffix(required, ":read") && scope == resource+":write" {
+			return true
+		}
+	}
+	return false
+}
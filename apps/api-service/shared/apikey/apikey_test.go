@@ -0,0 +1,64 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		name     string
+		granted  []string
+		required string
+		want     bool
+	}{
+		{name: "exact scope", granted: []string{ScopeMenuRead}, required: ScopeMenuRead, want: true},
+		{name: "write implies read", granted: []string{ScopeMenuWrite}, required: ScopeMenuRead, want: true},
+		{name: "read does not imply write", granted: []string{ScopeMenuRead}, required: ScopeMenuWrite, want: false},
+		{name: "write on another resource", granted: []string{ScopeProjectsWrite}, required: ScopeMenuRead, want: false},
+		{name: "one of several", granted: []string{ScopeAnalyticsRead, ScopeProjectsWrite}, required: ScopeProjectsRead, want: true},
+		{name: "no scopes", granted: nil, required: ScopeProjectsRead, want: false},
+		{name: "prefix lookalike", granted: []string{"menu:writer"}, required: ScopeMenuRead, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Allows(test.granted, test.required); got != test.want {
+				t.Fatalf("Allows(%v, %s) = %v, want %v", test.granted, test.required, got, test.want)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	key, display, hash, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsKey(key) || !strings.HasPrefix(key, display) || len(display) != displayLength {
+		t.Fatalf("Generate() = %q shown as %q", key, display)
+	}
+	if hash != Hash(key) || strings.Contains(hash, key) {
+		t.Fatalf("hash %q does not match the key", hash)
+	}
+	if other, _, _, _ := Generate(); other == key {
+		t.Fatal("Generate() returned the same key twice")
+	}
+	if IsKey("eyJhbGciOiJIUzI1NiJ9.e30.sig") {
+		t.Fatal("IsKey() accepted a JWT")
+	}
+}
+
+func TestValidScope(t *testing.T) {
+	for _, scope := range Scopes {
+		if !ValidScope(scope) {
+			t.Errorf("ValidScope(%s) = false", scope)
+		}
+	}
+	for _, scope := range []string{"", "projects", "projects:delete", "admin:write"} {
+		if ValidScope(scope) {
+			t.Errorf("ValidScope(%q) = true", scope)
+		}
+	}
+}
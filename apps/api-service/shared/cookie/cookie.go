@@ -38,6 +38,11 @@ func resolveCookieConfig(appEnv string) (string, bool, http.SameSite) {
 }
 
 func SetCookie(context *gin.Context, name string, data string) {
+	SetCookieFor(context, name, data, 7*24*time.Hour)
+}
+
+// SetCookieFor is SetCookie with a custom lifetime, for values that only need to survive a short round trip.
+func SetCookieFor(context *gin.Context, name string, data string, lifeSpan time.Duration) {
 	log.Printf("[Cookie] SetCookie called for: %s", name)
 
 	_ = godotenv.Load()
@@ -77,7 +82,7 @@ func SetCookie(context *gin.Context, name string, data string) {
 		Path:     "/",
 		Domain:   domain,
 		HttpOnly: true,
-		MaxAge:   int((7 * 24 * time.Hour).Seconds()),
+		MaxAge:   int(lifeSpan.Seconds()),
 		Secure:   secure,
 		SameSite: sameSite,
 	}
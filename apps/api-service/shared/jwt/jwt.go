@@ -1,7 +1,9 @@
 package jwt
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"flash/models"
 	"golang.org/x/crypto/bcrypt"
@@ -11,9 +13,35 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte("mysecret")
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
 
 func GenerateToken(user models.User, lifeSpanInSeconds *int) (string, error) {
+	return generate(user, lifeSpanInSeconds, nil)
+}
+
+// GenerateSessionToken issues an access or refresh token bound to a user_sessions row, so revoking the row
+// invalidates both. Every call gets a fresh jti, which keeps rotated refresh tokens distinct.
+func GenerateSessionToken(user models.User, lifeSpanInSeconds *int, tokenType string, sessionID uint64) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
+	return generate(user, lifeSpanInSeconds, jwt.MapClaims{
+		"typ": tokenType,
+		"sid": sessionID,
+		"jti": hex.EncodeToString(jti),
+	})
+}
+
+func generate(user models.User, lifeSpanInSeconds *int, extra jwt.MapClaims) (string, error) {
+	if keySet == nil {
+		return "", errors.New("JWT signing keys are not configured")
+	}
+
 	var dur time.Duration
 
 	if lifeSpanInSeconds == nil {
@@ -26,21 +54,43 @@ func GenerateToken(user models.User, lifeSpanInSeconds *int) (string, error) {
 		"user_id": user.ID,
 		"email":   user.Email,
 		"role":    user.Role,
+		"iat":     time.Now().Unix(),
 		"exp":     time.Now().Add(dur).Unix(),
 	}
+	for name, value := range extra {
+		claims[name] = value
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	key := keySet.Keys[keySet.ActiveID]
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
 
-	return token.SignedString(jwtSecret)
+	return token.SignedString(key.signingKey())
 }
 
+// ValidateToken verifies a token against the key named by its kid. The key's own algorithm is enforced, so a
+// token can't pick a weaker one. Tokens without a kid predate rotation and are checked against JWT_SECRET.
 func ValidateToken(tokenString string) (*jwt.Token, error) {
 	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if keySet == nil {
+			return nil, errors.New("JWT signing keys are not configured")
+		}
+
+		keyID, _ := token.Header["kid"].(string)
+		if keyID == "" {
+			keyID = legacyKeyID
+		}
+
+		key, ok := keySet.Keys[keyID]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		if token.Method.Alg() != key.Algorithm {
 			return nil, jwt.ErrTokenSignatureInvalid
 		}
-		return jwtSecret, nil
-	})
+
+		return key.verificationKey(), nil
+	}, jwt.WithValidMethods([]string{AlgorithmHS256, AlgorithmRS256, AlgorithmEdDSA}))
 }
 
 func HashToken(token string) (string, error) {
@@ -74,3 +124,32 @@ func ExtractUser(token *jwt.Token, db *gorm.DB) (*models.User, error) {
 
 	return &user, nil
 }
+
+// TokenType returns the typ claim; tokens issued before sessions existed have none.
+func TokenType(token *jwt.Token) string {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+
+	tokenType, _ := claims["typ"].(string)
+	return tokenType
+}
+
+// SessionID returns the user_sessions row a token belongs to, or 0 for tokens issued before sessions existed.
+func SessionID(token *jwt.Token) uint64 {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0
+	}
+
+	sessionID, _ := claims["sid"].(float64)
+	return uint64(sessionID)
+}
+
+// HashSessionToken is the lookup hash stored for refresh tokens. Unlike HashToken it is deterministic, so a
+// presented token can be matched against user_sessions directly.
+func HashSessionToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
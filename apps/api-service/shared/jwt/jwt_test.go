@@ -0,0 +1,141 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flash/models"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testUser = models.User{ID: 7, Email: "ada@example.com", Role: "default"}
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{"user_id": testUser.ID, "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, keyID string, key any) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, testClaims())
+	if keyID != "" {
+		token.Header["kid"] = keyID
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestGenerateAndValidate(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+
+	for _, key := range []*SigningKey{
+		{ID: "hs", Algorithm: AlgorithmHS256, Secret: []byte(testSecret)},
+		{ID: "rs", Algorithm: AlgorithmRS256, PrivateKey: rsaKey, PublicKey: &rsaKey.PublicKey},
+		{ID: "ed", Algorithm: AlgorithmEdDSA, PrivateKey: edPrivate, PublicKey: edPrivate.Public()},
+	} {
+		t.Run(key.Algorithm, func(t *testing.T) {
+			useKeySet(t, &KeySet{ActiveID: key.ID, Keys: map[string]*SigningKey{key.ID: key}})
+
+			signed, err := GenerateSessionToken(testUser, nil, TokenTypeRefresh, 42)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := ValidateToken(signed)
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			if token.Header["kid"] != key.ID || token.Method.Alg() != key.Algorithm {
+				t.Fatalf("token header = %v, want kid %s signed with %s", token.Header, key.ID, key.Algorithm)
+			}
+			if TokenType(token) != TokenTypeRefresh || SessionID(token) != 42 {
+				t.Fatalf("typ %q, sid %d", TokenType(token), SessionID(token))
+			}
+		})
+	}
+}
+
+func TestValidateTokenLooksUpTheKid(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	retired := newRSAKey(t)
+
+	useKeySet(t, &KeySet{ActiveID: "current", Keys: map[string]*SigningKey{
+		"current": {ID: "current", Algorithm: AlgorithmRS256, PrivateKey: rsaKey, PublicKey: &rsaKey.PublicKey},
+		"retired": {ID: "retired", Algorithm: AlgorithmRS256, PublicKey: &retired.PublicKey},
+	}})
+
+	// A retired key keeps verifying what it signed.
+	if _, err := ValidateToken(sign(t, jwt.SigningMethodRS256, "retired", retired)); err != nil {
+		t.Fatalf("token from the retired key: %v", err)
+	}
+
+	// The signature is checked against the named key only.
+	if _, err := ValidateToken(sign(t, jwt.SigningMethodRS256, "current", retired)); !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		t.Fatalf("token claiming the wrong kid: error = %v", err)
+	}
+
+	if _, err := ValidateToken(sign(t, jwt.SigningMethodRS256, "unknown", rsaKey)); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("unknown kid: error = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestValidateTokenPinsTheKeysAlgorithm(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	publicDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	useKeySet(t, &KeySet{ActiveID: "rs", Keys: map[string]*SigningKey{
+		"rs": {ID: "rs", Algorithm: AlgorithmRS256, PrivateKey: rsaKey, PublicKey: &rsaKey.PublicKey},
+		"hs": {ID: "hs", Algorithm: AlgorithmHS256, Secret: []byte(testSecret)},
+	}})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		// The classic confusion: HMAC over the published RSA public key, presented under the RSA kid.
+		{name: "HS256 with the RSA public key", token: sign(t, jwt.SigningMethodHS256, "rs", publicPEM)},
+		{name: "RS256 under an HS256 kid", token: sign(t, jwt.SigningMethodRS256, "hs", rsaKey)},
+		{name: "none", token: sign(t, jwt.SigningMethodNone, "rs", jwt.UnsafeAllowNoneSignatureType)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if token, err := ValidateToken(test.token); err == nil {
+				t.Fatalf("ValidateToken() accepted %v", token.Header)
+			}
+		})
+	}
+}
+
+func TestValidateTokenWithoutKid(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	legacy := sign(t, jwt.SigningMethodHS256, "", []byte(testSecret))
+
+	useKeySet(t, &KeySet{ActiveID: "rs", Keys: map[string]*SigningKey{
+		"rs":        {ID: "rs", Algorithm: AlgorithmRS256, PrivateKey: rsaKey, PublicKey: &rsaKey.PublicKey},
+		legacyKeyID: {ID: legacyKeyID, Algorithm: AlgorithmHS256, Secret: []byte(testSecret)},
+	}})
+	if _, err := ValidateToken(legacy); err != nil {
+		t.Fatalf("token from before kids existed: %v", err)
+	}
+	if _, err := ValidateToken(sign(t, jwt.SigningMethodHS256, "", []byte("another-secret-another-secret-00"))); err == nil {
+		t.Fatal("a kid-less token signed with another secret validated")
+	}
+
+	// Once JWT_SECRET is dropped, tokens without a kid stop working.
+	useKeySet(t, &KeySet{ActiveID: "rs", Keys: map[string]*SigningKey{
+		"rs": {ID: "rs", Algorithm: AlgorithmRS256, PrivateKey: rsaKey, PublicKey: &rsaKey.PublicKey},
+	}})
+	if _, err := ValidateToken(legacy); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("kid-less token without JWT_SECRET: error = %v, want %v", err, ErrUnknownKey)
+	}
+}
@@ -0,0 +1,264 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// legacyKeyID is the key used for tokens issued before signing keys carried a kid.
+const legacyKeyID = "legacy"
+
+var ErrUnknownKey = errors.New("token signed with an unknown key")
+
+// SigningKey is one entry of the key set. Keys without a private half (or secret) can only verify, which is how
+// a retired key is kept around until the tokens it signed have expired.
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	Secret     []byte
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+type KeySet struct {
+	ActiveID string
+	Keys     map[string]*SigningKey
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+var keySet *KeySet
+
+// Configure installs the key set every token is signed and verified with.
+func Configure(set *KeySet) error {
+	active, ok := set.Keys[set.ActiveID]
+	if !ok {
+		return fmt.Errorf("active signing key %q is not configured", set.ActiveID)
+	}
+	if active.Secret == nil && active.PrivateKey == nil {
+		return fmt.Errorf("active signing key %q has no private key", set.ActiveID)
+	}
+
+	keySet = set
+	return nil
+}
+
+// LoadKeySet reads signing keys from the environment:
+//
+//	JWT_KEYS       comma-separated kid:algorithm:source entries. The source is the secret for HS256 and a PEM file
+//	               path for RS256/EdDSA (a private key to sign, or a public key to keep verifying a retired one).
+//	JWT_ACTIVE_KID the kid new tokens are signed with; defaults to the first entry.
+//	JWT_SECRET     a plain HS256 secret. It also verifies tokens issued before kids existed.
+func LoadKeySet(getenv func(string) string) (*KeySet, error) {
+	set := &KeySet{Keys: map[string]*SigningKey{}}
+
+	for _, entry := range strings.Split(getenv("JWT_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid JWT_KEYS entry %q, expected kid:algorithm:source", entry)
+		}
+
+		key, err := parseKey(parts[0], parts[1], parts[2])
+		if err != nil {
+			return nil, err
+		}
+		set.Keys[key.ID] = key
+		if set.ActiveID == "" {
+			set.ActiveID = key.ID
+		}
+	}
+
+	if secret := getenv("JWT_SECRET"); secret != "" {
+		set.Keys[legacyKeyID] = &SigningKey{ID: legacyKeyID, Algorithm: AlgorithmHS256, Secret: []byte(secret)}
+		if set.ActiveID == "" {
+			set.ActiveID = legacyKeyID
+		}
+	}
+
+	if activeID := getenv("JWT_ACTIVE_KID"); activeID != "" {
+		set.ActiveID = activeID
+	}
+
+	if len(set.Keys) == 0 {
+		return nil, errors.New("no JWT signing keys configured")
+	}
+
+	return set, nil
+}
+
+// EphemeralKeySet signs with a random HS256 secret, for local runs without configured keys. Tokens do not
+// survive a restart.
+func EphemeralKeySet() (*KeySet, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	return &KeySet{
+		ActiveID: "ephemeral",
+		Keys:     map[string]*SigningKey{"ephemeral": {ID: "ephemeral", Algorithm: AlgorithmHS256, Secret: secret}},
+	}, nil
+}
+
+func parseKey(id string, algorithm string, source string) (*SigningKey, error) {
+	key := &SigningKey{ID: id, Algorithm: algorithm}
+
+	if algorithm == AlgorithmHS256 {
+		if len(source) < 32 {
+			return nil, fmt.Errorf("HS256 key %q must be at least 32 characters", id)
+		}
+		key.Secret = []byte(source)
+		return key, nil
+	}
+
+	if algorithm != AlgorithmRS256 && algorithm != AlgorithmEdDSA {
+		return nil, fmt.Errorf("unsupported algorithm %q for key %q", algorithm, id)
+	}
+
+	content, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %q: %w", id, err)
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("key %q is not PEM encoded", id)
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %q: %w", id, err)
+	}
+
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.PrivateKey = signer
+		key.PublicKey = signer.Public()
+	} else {
+		key.PublicKey = parsed
+	}
+
+	switch key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if algorithm != AlgorithmRS256 {
+			return nil, fmt.Errorf("key %q is an RSA key but configured as %s", id, algorithm)
+		}
+	case ed25519.PublicKey:
+		if algorithm != AlgorithmEdDSA {
+			return nil, fmt.Errorf("key %q is an Ed25519 key but configured as %s", id, algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("key %q has an unsupported key type", id)
+	}
+
+	return key, nil
+}
+
+func (key *SigningKey) method() jwt.SigningMethod {
+	switch key.Algorithm {
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256
+	case AlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+func (key *SigningKey) signingKey() any {
+	if key.Secret != nil {
+		return key.Secret
+	}
+	return key.PrivateKey
+}
+
+func (key *SigningKey) verificationKey() any {
+	if key.Secret != nil {
+		return key.Secret
+	}
+	return key.PublicKey
+}
+
+// JWKS lists the public halves of the asymmetric keys. HS256 secrets are never published.
+func JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if keySet == nil {
+		return set
+	}
+
+	ids := make([]string, 0, len(keySet.Keys))
+	for id := range keySet.Keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		key := keySet.Keys[id]
+		switch publicKey := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: AlgorithmRS256,
+				N:         base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: AlgorithmEdDSA,
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(publicKey),
+			})
+		}
+	}
+
+	return set
+}
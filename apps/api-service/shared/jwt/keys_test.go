@@ -0,0 +1,175 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// useKeySet installs set for the duration of the test.
+func useKeySet(t *testing.T, set *KeySet) {
+	t.Helper()
+
+	previous := keySet
+	if err := Configure(set); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { keySet = previous })
+}
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), strings.ReplaceAll(strings.ToLower(blockType), " ", "_")+".pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestLoadKeySet(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	rsaPrivate := writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	rsaPublicDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	rsaPublic := writePEM(t, "PUBLIC KEY", rsaPublicDER)
+
+	_, edPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	edPrivateDER, _ := x509.MarshalPKCS8PrivateKey(edPrivateKey)
+	edPrivate := writePEM(t, "PRIVATE KEY", edPrivateDER)
+
+	set, err := LoadKeySet(envOf(map[string]string{
+		"JWT_KEYS":   "2026-10:EdDSA:" + edPrivate + ", 2026-04:RS256:" + rsaPrivate + ",2025-10:RS256:" + rsaPublic,
+		"JWT_SECRET": testSecret,
+	}))
+	if err != nil {
+		t.Fatalf("LoadKeySet() error = %v", err)
+	}
+
+	if set.ActiveID != "2026-10" {
+		t.Fatalf("active kid = %q, want the first entry", set.ActiveID)
+	}
+	if len(set.Keys) != 4 {
+		t.Fatalf("keys = %v, want the three entries and the legacy secret", set.Keys)
+	}
+	if key := set.Keys["2026-04"]; key.PrivateKey == nil || key.Algorithm != AlgorithmRS256 {
+		t.Fatalf("2026-04 = %+v, want an RS256 signing key", key)
+	}
+	if key := set.Keys["2025-10"]; key.PrivateKey != nil || key.PublicKey == nil {
+		t.Fatalf("2025-10 = %+v, want a verify-only key", key)
+	}
+	if key := set.Keys[legacyKeyID]; string(key.Secret) != testSecret || key.Algorithm != AlgorithmHS256 {
+		t.Fatalf("legacy = %+v, want the JWT_SECRET", key)
+	}
+
+	overridden, err := LoadKeySet(envOf(map[string]string{
+		"JWT_KEYS":       "2026-10:EdDSA:" + edPrivate + ",2026-04:RS256:" + rsaPrivate,
+		"JWT_ACTIVE_KID": "2026-04",
+	}))
+	if err != nil || overridden.ActiveID != "2026-04" {
+		t.Fatalf("JWT_ACTIVE_KID: active = %v, %v", overridden, err)
+	}
+
+	legacyOnly, err := LoadKeySet(envOf(map[string]string{"JWT_SECRET": testSecret}))
+	if err != nil || legacyOnly.ActiveID != legacyKeyID {
+		t.Fatalf("JWT_SECRET alone: %+v, %v, want the legacy key active", legacyOnly, err)
+	}
+}
+
+func TestLoadKeySetRejects(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	rsaPrivate := writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	notPEM := filepath.Join(t.TempDir(), "key.txt")
+	os.WriteFile(notPEM, []byte("not a key"), 0o600)
+
+	tests := []struct {
+		name    string
+		keys    string
+		wantErr string
+	}{
+		{name: "nothing configured", keys: "", wantErr: "no JWT signing keys configured"},
+		{name: "malformed entry", keys: "2026-10:HS256", wantErr: "expected kid:algorithm:source"},
+		{name: "short secret", keys: "2026-10:HS256:short", wantErr: "at least 32 characters"},
+		{name: "unsupported algorithm", keys: "2026-10:ES256:" + rsaPrivate, wantErr: "unsupported algorithm"},
+		{name: "missing file", keys: "2026-10:RS256:/does/not/exist.pem", wantErr: "failed to read key"},
+		{name: "not PEM", keys: "2026-10:RS256:" + notPEM, wantErr: "is not PEM encoded"},
+		{name: "algorithm and key disagree", keys: "2026-10:EdDSA:" + rsaPrivate, wantErr: "is an RSA key but configured as EdDSA"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := LoadKeySet(envOf(map[string]string{"JWT_KEYS": test.keys}))
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("LoadKeySet() error = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigureRequiresASigningActiveKey(t *testing.T) {
+	rsaKey := newRSAKey(t)
+
+	if err := Configure(&KeySet{ActiveID: "missing", Keys: map[string]*SigningKey{}}); err == nil {
+		t.Fatal("Configure() accepted an active kid that isn't in the set")
+	}
+
+	retired := &KeySet{ActiveID: "retired", Keys: map[string]*SigningKey{
+		"retired": {ID: "retired", Algorithm: AlgorithmRS256, PublicKey: &rsaKey.PublicKey},
+	}}
+	if err := Configure(retired); err == nil {
+		t.Fatal("Configure() accepted a verify-only active key")
+	}
+}
+
+func TestJWKSPublishesOnlyPublicKeys(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+
+	useKeySet(t, &KeySet{ActiveID: "b-ed", Keys: map[string]*SigningKey{
+		"b-ed":      {ID: "b-ed", Algorithm: AlgorithmEdDSA, PrivateKey: edPrivate, PublicKey: edPublic},
+		"a-rsa":     {ID: "a-rsa", Algorithm: AlgorithmRS256, PublicKey: &rsaKey.PublicKey},
+		legacyKeyID: {ID: legacyKeyID, Algorithm: AlgorithmHS256, Secret: []byte(testSecret)},
+	}})
+
+	set := JWKS()
+	if len(set.Keys) != 2 || set.Keys[0].KeyID != "a-rsa" || set.Keys[1].KeyID != "b-ed" {
+		t.Fatalf("JWKS() = %+v, want the RSA and Ed25519 keys sorted by kid", set.Keys)
+	}
+
+	rsaJWK := set.Keys[0]
+	n, _ := base64.RawURLEncoding.DecodeString(rsaJWK.N)
+	e, _ := base64.RawURLEncoding.DecodeString(rsaJWK.E)
+	if rsaJWK.KeyType != "RSA" || rsaJWK.Algorithm != AlgorithmRS256 || rsaJWK.Use != "sig" ||
+		new(big.Int).SetBytes(n).Cmp(rsaKey.N) != 0 || int(new(big.Int).SetBytes(e).Int64()) != rsaKey.E {
+		t.Fatalf("RSA JWK = %+v does not describe the key", rsaJWK)
+	}
+
+	edJWK := set.Keys[1]
+	x, _ := base64.RawURLEncoding.DecodeString(edJWK.X)
+	if edJWK.KeyType != "OKP" || edJWK.Curve != "Ed25519" || edJWK.Algorithm != AlgorithmEdDSA || !edPublic.Equal(ed25519.PublicKey(x)) {
+		t.Fatalf("Ed25519 JWK = %+v does not describe the key", edJWK)
+	}
+}
+
+func envOf(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+type Task func(ctx context.Context) error
+
+type entry struct {
+	name     string
+	interval time.Duration
+	task     Task
+}
+
+// Scheduler runs registered tasks on fixed intervals inside the API process.
+type Scheduler struct {
+	entries []entry
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+func (scheduler *Scheduler) Every(name string, interval time.Duration, task Task) {
+	scheduler.entries = append(scheduler.entries, entry{name: name, interval: interval, task: task})
+}
+
+func (scheduler *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler.cancel = cancel
+
+	for _, item := range scheduler.entries {
+		scheduler.wg.Add(1)
+		go scheduler.run(ctx, item)
+	}
+}
+
+func (scheduler *Scheduler) Stop() {
+	if scheduler.cancel != nil {
+		scheduler.cancel()
+	}
+	scheduler.wg.Wait()
+}
+
+func (scheduler *Scheduler) run(ctx context.Context, item entry) {
+	defer scheduler.wg.Done()
+
+	ticker := time.NewTicker(item.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runTask(ctx, item)
+		}
+	}
+}
+
+func runTask(ctx context.Context, item entry) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[ERROR] Scheduled task %s panicked: %v", item.name, r)
+		}
+	}()
+
+	if err := item.task(ctx); err != nil {
+		log.Printf("[ERROR] Scheduled task %s failed: %v", item.name, err)
+	}
+}
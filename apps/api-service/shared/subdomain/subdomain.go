@@ -0,0 +1,176 @@
+// Package subdomain holds the rules a site subdomain must follow regardless of what is already taken: DNS label
+// syntax, blocked words, and lookalikes of protected names.
+package subdomain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the longest DNS label.
+const MaxLength = 63
+
+// Reason codes. Lookups against the database add their own (reserved, taken, recently_released).
+const (
+	ReasonRequired          = "required"
+	ReasonTooLong           = "too_long"
+	ReasonInvalidCharacters = "invalid_characters"
+	ReasonHyphenEdge        = "hyphen_edge"
+	ReasonReservedPrefix    = "reserved_prefix"
+	ReasonProfanity         = "profanity"
+	ReasonImpersonation     = "impersonation"
+)
+
+// Reason explains why a subdomain can't be used.
+type Reason struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ProtectedNames can never be claimed, on top of the reserved_sub_domains table.
+var ProtectedNames = []string{"kislap"}
+
+// blockedWords are rejected anywhere in the subdomain; they don't turn up inside ordinary words.
+var blockedWords = []string{
+	"fuck", "bitch", "nigger", "nigga", "faggot", "whore", "porn",
+	"putangina", "tangina", "kantot", "pokpok", "tarantado",
+}
+
+// blockedTokens are rejected only as a whole hyphen-separated part, so "class", "cucumber", "pussycat",
+// "scunthorpe" and "shitake" stay usable.
+var blockedTokens = []string{
+	"ass", "cock", "dick", "cum", "sex", "xxx", "tits", "slut", "rape", "nazi", "anal",
+	"shit", "cunt", "pussy",
+	"gago", "puke", "titi", "ulol", "puta", "bayag",
+}
+
+// Validate checks subDomain, already lowercased, against the label syntax and the blocked words.
+func Validate(subDomain string) []Reason {
+	var reasons []Reason
+
+	if subDomain == "" {
+		return []Reason{{Code: ReasonRequired, Message: "subdomain is required"}}
+	}
+	if len(subDomain) > MaxLength {
+		reasons = append(reasons, Reason{Code: ReasonTooLong, Message: fmt.Sprintf("subdomain can be at most %d characters", MaxLength)})
+	}
+	if strings.IndexFunc(subDomain, func(r rune) bool { return !isLabelRune(r) }) >= 0 {
+		reasons = append(reasons, Reason{Code: ReasonInvalidCharacters, Message: "subdomain can only contain lowercase letters a-z, digits and hyphens"})
+	}
+	if strings.HasPrefix(subDomain, "-") || strings.HasSuffix(subDomain, "-") {
+		reasons = append(reasons, Reason{Code: ReasonHyphenEdge, Message: "subdomain can't start or end with a hyphen"})
+	}
+	// Two hyphens in the third and fourth position mark encoded labels such as xn--.
+	if len(subDomain) >= 4 && subDomain[2:4] == "--" {
+		reasons = append(reasons, Reason{Code: ReasonReservedPrefix, Message: "subdomain can't have hyphens in the third and fourth position"})
+	}
+	if containsBlockedWord(subDomain) {
+		reasons = append(reasons, Reason{Code: ReasonProfanity, Message: "subdomain contains a word that isn't allowed"})
+	}
+
+	return reasons
+}
+
+// Impersonates returns the protected name subDomain is a lookalike of, such as "adm1n" or "аdmin" with a Cyrillic
+// а for "admin". Exact matches are not reported; callers treat those as reserved.
+func Impersonates(subDomain string, protected []string) (string, bool) {
+	skeletons := []string{Skeleton(subDomain), Skeleton(digitOneAsI(subDomain))}
+	for _, name := range protected {
+		if len(name) < 3 || name == subDomain {
+			continue
+		}
+		if slices.Contains(skeletons, Skeleton(name)) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Skeleton reduces a name to how it reads: accents, hyphens and lookalike characters are folded so two names
+// that look the same get the same skeleton.
+func Skeleton(name string) string {
+	return confusablePairs.Replace(fold(name))
+}
+
+// fold maps single lookalike characters; blocked words are matched on it since letter pairs would turn words such
+// as "pompom" into something they aren't.
+func fold(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(strings.ToLower(name)) {
+		if unicode.Is(unicode.Mn, r) || r == '-' || r == '_' || r == '.' {
+			continue
+		}
+		if mapped, ok := confusables[r]; ok {
+			r = mapped
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Suggestion turns a slug into a usable label: anything outside a-z, 0-9 and hyphens is dropped and the result
+// is cut to maxLen. It returns "" when nothing usable is left.
+func Suggestion(slug string, maxLen int) string {
+	var b strings.Builder
+	for _, r := range slug {
+		if isLabelRune(r) {
+			b.WriteRune(r)
+		}
+	}
+
+	label := b.String()
+	for strings.Contains(label, "--") {
+		label = strings.ReplaceAll(label, "--", "-")
+	}
+	if maxLen > 0 && len(label) > maxLen {
+		label = label[:maxLen]
+	}
+	return strings.Trim(label, "-")
+}
+
+func containsBlockedWord(subDomain string) bool {
+	for _, variant := range []string{subDomain, digitOneAsI(subDomain)} {
+		folded := fold(variant)
+		for _, word := range blockedWords {
+			if strings.Contains(folded, fold(word)) {
+				return true
+			}
+		}
+
+		for _, token := range strings.Split(variant, "-") {
+			token = fold(token)
+			for _, word := range blockedTokens {
+				if token == fold(word) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// digitOneAsI reads 1 as an i; fold reads it as an l, and it passes for either.
+func digitOneAsI(name string) string {
+	return strings.ReplaceAll(name, "1", "i")
+}
+
+func isLabelRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-'
+}
+
+// confusables folds digits and Cyrillic or Greek letters onto the Latin letter they pass for.
+var confusables = map[rune]rune{
+	'0': 'o', '1': 'l', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b',
+	'|': 'l',
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 't',
+	'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ɡ': 'g', 'ℓ': 'l',
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u',
+	'χ': 'x',
+}
+
+// confusablePairs are letter pairs that read as one letter.
+var confusablePairs = strings.NewReplacer("rn", "m", "vv", "w")
@@ -0,0 +1,134 @@
+package subdomain
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		subDomain string
+		want      []string
+	}{
+		{name: "plain", subDomain: "ada", want: nil},
+		{name: "digits and hyphens", subDomain: "cafe-24", want: nil},
+		{name: "longest label", subDomain: strings.Repeat("a", MaxLength), want: nil},
+		{name: "empty", subDomain: "", want: []string{ReasonRequired}},
+		{name: "too long", subDomain: strings.Repeat("a", MaxLength+1), want: []string{ReasonTooLong}},
+		{name: "uppercase", subDomain: "Ada", want: []string{ReasonInvalidCharacters}},
+		{name: "dot", subDomain: "ada.dev", want: []string{ReasonInvalidCharacters}},
+		{name: "underscore", subDomain: "ada_dev", want: []string{ReasonInvalidCharacters}},
+		{name: "leading hyphen", subDomain: "-ada", want: []string{ReasonHyphenEdge}},
+		{name: "trailing hyphen", subDomain: "ada-", want: []string{ReasonHyphenEdge}},
+		{name: "punycode", subDomain: "xn--ada", want: []string{ReasonReservedPrefix}},
+		{name: "several reasons", subDomain: "-a--da_", want: []string{ReasonInvalidCharacters, ReasonHyphenEdge, ReasonReservedPrefix}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var codes []string
+			for _, reason := range Validate(test.subDomain) {
+				codes = append(codes, reason.Code)
+			}
+			if !slices.Equal(codes, test.want) {
+				t.Fatalf("Validate(%q) = %v, want %v", test.subDomain, codes, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateBlockedWords(t *testing.T) {
+	tests := []struct {
+		subDomain string
+		blocked   bool
+	}{
+		// Blocked words are found inside other words, blocked tokens only on their own.
+		{subDomain: "fuckery", blocked: true},
+		{subDomain: "myp0rnsite", blocked: true},
+		{subDomain: "tangina-mo", blocked: true},
+		{subDomain: "big-ass", blocked: true},
+		{subDomain: "shit", blocked: true},
+		{subDomain: "sh1t-happens", blocked: true},
+		{subDomain: "no-cunt", blocked: true},
+		{subDomain: "pussy", blocked: true},
+		{subDomain: "5ex", blocked: true},
+
+		// Ordinary words with a blocked token inside them.
+		{subDomain: "class", blocked: false},
+		{subDomain: "cucumber", blocked: false},
+		{subDomain: "essex", blocked: false},
+		{subDomain: "pussycat", blocked: false},
+		{subDomain: "scunthorpe", blocked: false},
+		{subDomain: "shitake", blocked: false},
+		{subDomain: "analytics", blocked: false},
+		{subDomain: "grape-farm", blocked: false},
+		{subDomain: "pompom", blocked: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.subDomain, func(t *testing.T) {
+			blocked := slices.ContainsFunc(Validate(test.subDomain), func(reason Reason) bool {
+				return reason.Code == ReasonProfanity
+			})
+			if blocked != test.blocked {
+				t.Fatalf("Validate(%q) blocked = %v, want %v", test.subDomain, blocked, test.blocked)
+			}
+		})
+	}
+}
+
+func TestImpersonates(t *testing.T) {
+	protected := []string{"admin", "mail", "kislap", "go"}
+
+	tests := []struct {
+		name      string
+		subDomain string
+		want      string
+	}{
+		{name: "digit for a letter", subDomain: "adm1n", want: "admin"},
+		{name: "Cyrillic a", subDomain: "аdmin", want: "admin"},
+		{name: "accent", subDomain: "ádmin", want: "admin"},
+		{name: "hyphenated", subDomain: "kis-lap", want: "kislap"},
+		{name: "letter pair", subDomain: "rnail", want: "mail"},
+		{name: "digit one as an i", subDomain: "k1slap", want: "kislap"},
+		{name: "exact name", subDomain: "admin", want: ""},
+		{name: "longer name", subDomain: "kislap-fan", want: ""},
+		{name: "short names are not checked", subDomain: "g0", want: ""},
+		{name: "unrelated", subDomain: "ada", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := Impersonates(test.subDomain, protected)
+			if got != test.want || ok != (test.want != "") {
+				t.Fatalf("Impersonates(%q) = %q, %v, want %q", test.subDomain, got, ok, test.want)
+			}
+		})
+	}
+}
+
+func TestSuggestion(t *testing.T) {
+	tests := []struct {
+		slug   string
+		maxLen int
+		want   string
+	}{
+		{slug: "ada-lovelace", maxLen: 0, want: "ada-lovelace"},
+		{slug: "café_au-lait!", maxLen: 0, want: "cafau-lait"},
+		{slug: "coffee--shop", maxLen: 0, want: "coffee-shop"},
+		{slug: "-cafe-", maxLen: 0, want: "cafe"},
+		{slug: "abcdef", maxLen: 3, want: "abc"},
+		{slug: "ab-cd", maxLen: 3, want: "ab"},
+		{slug: "!!!", maxLen: 0, want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.slug, func(t *testing.T) {
+			if got := Suggestion(test.slug, test.maxLen); got != test.want {
+				t.Fatalf("Suggestion(%q, %d) = %q, want %q", test.slug, test.maxLen, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Package testdb opens an in-memory SQLite database with the models' tables, for tests that need real queries.
+package testdb
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// dialector is SQLite with the MySQL-only column types in model tags, such as enum('a','b'), created as text.
+type dialector struct {
+	sqlite.Dialector
+}
+
+func (d dialector) Migrator(db *gorm.DB) gorm.Migrator {
+	migrator := d.Dialector.Migrator(db).(sqlite.Migrator)
+	migrator.Dialector = d
+	return migrator
+}
+
+func (d dialector) DataTypeOf(field *schema.Field) string {
+	dataType := strings.ToLower(string(field.DataType))
+	if strings.HasPrefix(dataType, "enum") || strings.HasPrefix(dataType, "set(") {
+		return "text"
+	}
+	return d.Dialector.DataTypeOf(field)
+}
+
+var databases atomic.Uint64
+
+// Open returns a database of its own for the test with tables for models.
+func Open(t testing.TB, models ...any) *gorm.DB {
+	t.Helper()
+
+	// A named shared-cache database lives as long as a connection does, so every pooled connection sees it.
+	dsn := fmt.Sprintf("file:testdb%d?mode=memory&cache=shared", databases.Add(1))
+	db, err := gorm.Open(dialector{sqlite.Dialector{DSN: dsn}}, &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+		TranslateError:                           true,
+	})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+
+	return db
+}
+
+// Create inserts each row and fails the test on the first error.
+func Create(t testing.TB, db *gorm.DB, rows ...any) {
+	t.Helper()
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("create %T: %v", row, strings.TrimSpace(err.Error()))
+		}
+	}
+}
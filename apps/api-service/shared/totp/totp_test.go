@@ -0,0 +1,92 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of RFC 6238 appendix B, "12345678901234567890", base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	// The RFC lists 8 digit codes; a 6 digit code is the same value mod 10^6.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, test := range tests {
+		code, err := Code(rfcSecret, time.Unix(test.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != test.want {
+			t.Errorf("Code(T=%d) = %s, want %s", test.unix, code, test.want)
+		}
+
+		step, ok := Validate(rfcSecret, test.want, time.Unix(test.unix, 0))
+		if !ok || step != uint64(test.unix)/30 {
+			t.Errorf("Validate(T=%d) = %d, %v, want step %d", test.unix, step, ok, test.unix/30)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := uint64(now.Unix()) / 30
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		at       time.Time
+		want     bool
+		wantStep uint64
+	}{
+		{name: "current step", secret: rfcSecret, code: "005924", at: now, want: true, wantStep: step},
+		{name: "spaced and lowercase secret", secret: "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code: " 005 924 ", at: now, want: true, wantStep: step},
+		{name: "one step late", secret: rfcSecret, code: "005924", at: now.Add(Period), want: true, wantStep: step},
+		{name: "one step early", secret: rfcSecret, code: "005924", at: now.Add(-Period), want: true, wantStep: step},
+		{name: "two steps late", secret: rfcSecret, code: "005924", at: now.Add(2 * Period)},
+		{name: "wrong code", secret: rfcSecret, code: "005925", at: now},
+		{name: "too short", secret: rfcSecret, code: "05924", at: now},
+		{name: "invalid secret", secret: "not base32!", code: "005924", at: now},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := Validate(test.secret, test.code, test.at)
+			if ok != test.want || got != test.wantStep {
+				t.Fatalf("Validate() = %d, %v, want %d, %v", got, ok, test.wantStep, test.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSecretAndURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 32 {
+		t.Fatalf("secret %q is not 160 bits of base32", secret)
+	}
+
+	uri, err := url.Parse(URI("Kislap", "ada@example.com", secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := uri.Query()
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Kislap:ada@example.com" ||
+		query.Get("secret") != secret || query.Get("issuer") != "Kislap" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Fatalf("URI() = %s", uri)
+	}
+}
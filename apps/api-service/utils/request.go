@@ -2,10 +2,13 @@ package utils
 
 import (
 	"errors"
+	"flash/shared/access"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"strings"
+
+	"github.com/gin-gonic/gin"
 )
 
 func ValidateRequestPDF(file multipart.File, filename string, maxSize int64) error {
@@ -60,3 +63,41 @@ func ValidateRequestImageOrPDF(file multipart.File, filename string, maxSize int
 
 	return nil
 }
+
+// ValidateRequestImage checks an uploaded image and returns its sniffed MIME type, which is safer to store than
+// the Content-Type the client sent.
+func ValidateRequestImage(file multipart.File, maxSize int64) (string, error) {
+	if sizer, ok := file.(interface{ Size() int64 }); ok && sizer.Size() > maxSize {
+		return "", errors.New("file too large")
+	}
+
+	buffer := make([]byte, 512)
+	n, err := file.Read(buffer)
+	if err != nil && err != io.EOF {
+		return "", errors.New("failed to read file for validation")
+	}
+	mimeType := http.DetectContentType(buffer[:n])
+	allowed := mimeType == "image/png" ||
+		mimeType == "image/jpeg" ||
+		mimeType == "image/webp" ||
+		mimeType == "image/gif"
+
+	if !allowed {
+		return "", errors.New("invalid file type, must be PNG, JPG, WEBP, or GIF")
+	}
+
+	if seeker, ok := file.(io.Seeker); ok {
+		_, _ = seeker.Seek(0, io.SeekStart)
+	}
+
+	return mimeType, nil
+}
+
+// RequestActor is the signed-in user that AccessTokenValidatorMiddleware (or the API key middleware) put on the
+// request, for services that check project permissions themselves.
+func RequestActor(context *gin.Context) access.Actor {
+	return access.Actor{
+		UserID: context.GetUint64("user_id"),
+		Role:   context.GetString("user_role"),
+	}
+}
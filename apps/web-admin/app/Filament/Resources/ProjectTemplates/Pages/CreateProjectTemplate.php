@@ -0,0 +1,11 @@
+<?php
+
+namespace App\Filament\Resources\ProjectTemplates\Pages;
+
+use App\Filament\Resources\ProjectTemplates\ProjectTemplateResource;
+use Filament\Resources\Pages\CreateRecord;
+
+class CreateProjectTemplate extends CreateRecord
+{
+    protected static string $resource = ProjectTemplateResource::class;
+}
@@ -0,0 +1,23 @@
+<?php
+
+namespace App\Filament\Resources\ProjectTemplates\Pages;
+
+use App\Filament\Resources\ProjectTemplates\ProjectTemplateResource;
+use Filament\Actions\CreateAction;
+use Filament\Resources\Pages\ListRecords;
+
+class ListProjectTemplates extends ListRecords
+{
+    protected static string $resource = ProjectTemplateResource::class;
+
+    protected function getHeaderActions(): array
+    {
+        if (auth()->user()?->role === 'support') {
+            return [];
+        }
+
+        return [
+            CreateAction::make(),
+        ];
+    }
+}
@@ -0,0 +1,50 @@
+<?php
+
+namespace App\Filament\Resources\ProjectTemplates;
+
+use App\Filament\Resources\BaseResource;
+use App\Filament\Resources\ProjectTemplates\Pages\CreateProjectTemplate;
+use App\Filament\Resources\ProjectTemplates\Pages\EditProjectTemplate;
+use App\Filament\Resources\ProjectTemplates\Pages\ListProjectTemplates;
+use App\Filament\Resources\ProjectTemplates\Schemas\ProjectTemplateForm;
+use App\Filament\Resources\ProjectTemplates\Tables\ProjectTemplatesTable;
+use App\Models\ProjectTemplate;
+use BackedEnum;
+use Filament\Schemas\Schema;
+use Filament\Tables\Table;
+use UnitEnum;
+
+class ProjectTemplateResource extends BaseResource
+{
+    protected static ?string $model = ProjectTemplate::class;
+
+    protected static string|BackedEnum|null $navigationIcon = 'heroicon-o-document-duplicate';
+
+    protected static string | UnitEnum | null $navigationGroup = 'Publishing';
+
+    public static function form(Schema $schema): Schema
+    {
+        return ProjectTemplateForm::configure($schema);
+    }
+
+    public static function table(Table $table): Table
+    {
+        return ProjectTemplatesTable::configure($table);
+    }
+
+    public static function getRelations(): array
+    {
+        return [
+            //
+        ];
+    }
+
+    public static function getPages(): array
+    {
+        return [
+            'index' => ListProjectTemplates::route('/'),
+            'create' => CreateProjectTemplate::route('/create'),
+            'edit' => EditProjectTemplate::route('/{record}/edit'),
+        ];
+    }
+}
@@ -0,0 +1,62 @@
+<?php
+
+namespace App\Filament\Resources\ProjectTemplates\Schemas;
+
+use App\Models\Project;
+use Filament\Forms\Components\Select;
+use Filament\Forms\Components\TextInput;
+use Filament\Forms\Components\Textarea;
+use Filament\Forms\Components\Toggle;
+use Filament\Schemas\Components\Section;
+use Filament\Schemas\Schema;
+
+class ProjectTemplateForm
+{
+    public static function configure(Schema $schema): Schema
+    {
+        return $schema
+            ->components([
+                Section::make('Template')
+                    ->schema([
+                        TextInput::make('name')
+                            ->required()
+                            ->maxLength(255),
+                        TextInput::make('key')
+                            ->helperText('Used in the API, e.g. "coffee-shop-menu". Don\'t change it once the template is live.')
+                            ->required()
+                            ->alphaDash()
+                            ->maxLength(100)
+                            ->unique(ignoreRecord: true),
+                        Select::make('type')
+                            ->options([
+                                'portfolio' => 'Portfolio',
+                                'biz' => 'Biz',
+                                'linktree' => 'Linktree',
+                                'menu' => 'Menu',
+                                'waitlist' => 'Waitlist',
+                            ])
+                            ->required(),
+                        Select::make('source_project_id')
+                            ->label('Source project')
+                            ->helperText('New projects start as a copy of this project\'s content, including its images.')
+                            ->relationship('sourceProject', 'name')
+                            ->getOptionLabelFromRecordUsing(fn (Project $record): string => "{$record->name} ({$record->sub_domain})")
+                            ->searchable()
+                            ->required(),
+                        TextInput::make('preview_image_url')
+                            ->url()
+                            ->maxLength(255)
+                            ->columnSpanFull(),
+                        TextInput::make('placement_order')
+                            ->numeric()
+                            ->default(0),
+                        Toggle::make('is_active')
+                            ->default(true),
+                        Textarea::make('description')
+                            ->rows(3)
+                            ->columnSpanFull(),
+                    ])
+                    ->columns(2),
+            ]);
+    }
+}
@@ -0,0 +1,67 @@
+<?php
+
+namespace App\Filament\Resources\ProjectTemplates\Tables;
+
+use Filament\Actions\BulkActionGroup;
+use Filament\Actions\DeleteBulkAction;
+use Filament\Actions\EditAction;
+use Filament\Tables\Columns\IconColumn;
+use Filament\Tables\Columns\ImageColumn;
+use Filament\Tables\Columns\TextColumn;
+use Filament\Tables\Filters\SelectFilter;
+use Filament\Tables\Filters\TernaryFilter;
+use Filament\Tables\Table;
+
+class ProjectTemplatesTable
+{
+    public static function configure(Table $table): Table
+    {
+        return $table
+            ->defaultSort('placement_order')
+            ->columns([
+                ImageColumn::make('preview_image_url')
+                    ->label('Preview')
+                    ->square(),
+                TextColumn::make('name')
+                    ->searchable()
+                    ->sortable(),
+                TextColumn::make('key')
+                    ->searchable()
+                    ->toggleable(),
+                TextColumn::make('type')
+                    ->badge()
+                    ->sortable(),
+                TextColumn::make('sourceProject.name')
+                    ->label('Source project')
+                    ->toggleable(),
+                TextColumn::make('placement_order')
+                    ->label('Order')
+                    ->sortable()
+                    ->toggleable(),
+                IconColumn::make('is_active')
+                    ->label('Active')
+                    ->boolean(),
+            ])
+            ->filters([
+                SelectFilter::make('type')
+                    ->options([
+                        'portfolio' => 'Portfolio',
+                        'biz' => 'Biz',
+                        'linktree' => 'Linktree',
+                        'menu' => 'Menu',
+                        'waitlist' => 'Waitlist',
+                    ]),
+                TernaryFilter::make('is_active')
+                    ->label('Active'),
+            ])
+            ->recordActions([
+                EditAction::make()
+                    ->visible(fn (): bool => auth()->user()?->role !== 'support'),
+            ])
+            ->toolbarActions([
+                BulkActionGroup::make([
+                    DeleteBulkAction::make(),
+                ])->visible(fn (): bool => auth()->user()?->role !== 'support'),
+            ]);
+    }
+}
@@ -61,12 +61,7 @@ public static function configure(Schema $schema): Schema
                 Section::make('Flags')
                     ->schema([
                         Toggle::make('newsletter'),
-                        Toggle::make('github')
-                            ->label('Signed in with GitHub'),
-                        Toggle::make('google')
-                            ->label('Signed in with Google'),
-                    ])
-                    ->columns(3),
+                    ]),
             ]);
     }
 }
@@ -37,8 +37,6 @@ class User extends Authenticatable implements FilamentUser, FilamentHasName
         'refresh_token',
         'image_url',
         'newsletter',
-        'github',
-        'google',
     ];
 
     /**
@@ -49,6 +47,7 @@ class User extends Authenticatable implements FilamentUser, FilamentHasName
     protected $hidden = [
         'password',
         'remember_token',
+        'two_factor_secret',
     ];
 
     /**
@@ -60,10 +59,11 @@ protected function casts(): array
     {
         return [
             'email_verified_at' => 'datetime',
+            'two_factor_confirmed_at' => 'datetime',
+            'deletion_requested_at' => 'datetime',
+            'deletion_scheduled_at' => 'datetime',
             'password' => 'hashed',
             'newsletter' => 'boolean',
-            'github' => 'boolean',
-            'google' => 'boolean',
             'is_banned' => 'boolean',
             'banned_at' => 'datetime',
         ];
@@ -0,0 +1,41 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::create('waitlists', function (Blueprint $table) {
+            $table->id();
+            $table->unsignedBigInteger('project_id')->index();
+            $table->unsignedBigInteger('user_id')->index();
+
+            $table->string('headline')->nullable();
+            $table->text('description')->nullable();
+            $table->string('cta_label')->nullable();
+            $table->text('success_message')->nullable();
+
+            $table->string('layout_name')->default('waitlist-default');
+            $table->string('theme_name')->nullable()->default('default');
+            $table->json('theme_object')->nullable();
+            $table->json('fields')->nullable();
+
+            $table->boolean('referrals_enabled')->default(true);
+            $table->unsignedInteger('referral_boost')->default(5);
+
+            $table->softDeletes();
+            $table->timestamps();
+
+            $table->foreign('project_id')->references('id')->on('projects')->cascadeOnDelete();
+            $table->foreign('user_id')->references('id')->on('users')->cascadeOnDelete();
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::dropIfExists('waitlists');
+    }
+};
@@ -0,0 +1,37 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::create('waitlist_signups', function (Blueprint $table) {
+            $table->id();
+            $table->foreignId('waitlist_id')->constrained('waitlists')->cascadeOnDelete();
+            $table->foreignId('project_id')->constrained('projects')->cascadeOnDelete();
+
+            $table->string('email');
+            $table->string('name')->nullable();
+            $table->json('answers')->nullable();
+
+            $table->string('referral_code', 32)->unique();
+            $table->foreignId('referred_by_id')->nullable()->constrained('waitlist_signups')->nullOnDelete();
+            $table->unsignedInteger('referral_count')->default(0);
+            $table->integer('priority')->index();
+
+            $table->string('ip_address', 45)->index();
+            $table->text('user_agent')->nullable();
+            $table->timestamps();
+
+            $table->unique(['waitlist_id', 'email']);
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::dropIfExists('waitlist_signups');
+    }
+};
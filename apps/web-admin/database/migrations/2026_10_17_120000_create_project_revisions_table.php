@@ -0,0 +1,30 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::create('project_revisions', function (Blueprint $table) {
+            $table->id();
+            $table->foreignId('project_id')->constrained('projects')->cascadeOnDelete();
+            $table->foreignId('user_id')->nullable()->constrained('users')->nullOnDelete();
+            $table->unsignedInteger('revision');
+            $table->string('type');
+            $table->string('source')->default('save');
+            $table->unsignedBigInteger('restored_from_id')->nullable();
+            $table->longText('snapshot');
+            $table->timestamp('created_at')->useCurrent();
+
+            $table->unique(['project_id', 'revision']);
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::dropIfExists('project_revisions');
+    }
+};
@@ -0,0 +1,26 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::table('projects', function (Blueprint $table) {
+            $table->foreignId('published_revision_id')->nullable()->after('published')->constrained('project_revisions')->nullOnDelete();
+            $table->timestamp('published_at')->nullable()->after('published_revision_id');
+            $table->timestamp('publish_at')->nullable()->index()->after('published_at');
+            $table->timestamp('unpublish_at')->nullable()->index()->after('publish_at');
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::table('projects', function (Blueprint $table) {
+            $table->dropConstrainedForeignId('published_revision_id');
+            $table->dropColumn(['published_at', 'publish_at', 'unpublish_at']);
+        });
+    }
+};
@@ -0,0 +1,36 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::create('jobs', function (Blueprint $table) {
+            $table->id();
+            $table->foreignId('user_id')->nullable()->constrained('users')->nullOnDelete();
+            $table->string('type', 50)->index();
+            $table->string('reference')->nullable();
+            $table->string('status', 30)->default('pending');
+            $table->longText('payload');
+            $table->json('result')->nullable();
+            $table->text('error')->nullable();
+            $table->unsignedInteger('attempts')->default(0);
+            $table->unsignedInteger('max_attempts')->default(3);
+            $table->timestamp('available_at')->useCurrent();
+            $table->timestamp('reserved_at')->nullable();
+            $table->timestamp('finished_at')->nullable();
+            $table->timestamps();
+
+            $table->index(['status', 'available_at']);
+            $table->index(['type', 'reference', 'status']);
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::dropIfExists('jobs');
+    }
+};
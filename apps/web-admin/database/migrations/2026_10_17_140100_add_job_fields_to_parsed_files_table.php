@@ -0,0 +1,24 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::table('parsed_files', function (Blueprint $table) {
+            $table->foreignId('job_id')->nullable()->after('status')->constrained('jobs')->nullOnDelete();
+            $table->text('error')->nullable()->after('parsed_data');
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::table('parsed_files', function (Blueprint $table) {
+            $table->dropConstrainedForeignId('job_id');
+            $table->dropColumn('error');
+        });
+    }
+};
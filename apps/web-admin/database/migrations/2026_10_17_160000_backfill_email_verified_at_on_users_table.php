@@ -0,0 +1,19 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Support\Facades\DB;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        // The API now enforces verification. Accounts that predate it keep publishing; only new sign-ups have to
+        // confirm their address.
+        DB::table('users')->whereNull('email_verified_at')->update(['email_verified_at' => DB::raw('created_at')]);
+    }
+
+    public function down(): void
+    {
+        // Backfilled timestamps can't be told apart from real verifications, so there is nothing to undo.
+    }
+};
@@ -0,0 +1,29 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::create('user_tokens', function (Blueprint $table) {
+            $table->id();
+            $table->foreignId('user_id')->constrained('users')->cascadeOnDelete();
+            $table->enum('purpose', ['verify_email', 'reset_password', 'change_email']);
+            $table->string('email');
+            $table->char('token_hash', 64)->unique();
+            $table->timestamp('expires_at');
+            $table->timestamp('used_at')->nullable();
+            $table->timestamps();
+
+            $table->index(['user_id', 'purpose']);
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::dropIfExists('user_tokens');
+    }
+};
@@ -0,0 +1,39 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\DB;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        // The flags never stored the provider's user id, so these links are claimed by email on the next login.
+        foreach (['github', 'google'] as $provider) {
+            DB::statement(
+                "INSERT INTO user_identities (user_id, provider, email, created_at, updated_at)
+                 SELECT id, ?, email, NOW(), NOW() FROM users WHERE {$provider} = 1",
+                [$provider],
+            );
+        }
+
+        Schema::table('users', function (Blueprint $table) {
+            $table->dropColumn(['github', 'google']);
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::table('users', function (Blueprint $table) {
+            $table->boolean('github')->default(0)->after('newsletter');
+            $table->boolean('google')->default(0)->after('github');
+        });
+
+        foreach (['github', 'google'] as $provider) {
+            DB::table('users')
+                ->whereIn('id', DB::table('user_identities')->where('provider', $provider)->select('user_id'))
+                ->update([$provider => 1]);
+        }
+    }
+};
@@ -0,0 +1,29 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::create('oidc_states', function (Blueprint $table) {
+            $table->id();
+            $table->string('provider', 50);
+            $table->char('state_hash', 64)->unique();
+            $table->string('nonce', 64);
+            $table->string('code_verifier', 128);
+            $table->enum('intent', ['login', 'link'])->default('login');
+            // Set for link requests: the signed-in user the provider account gets attached to.
+            $table->foreignId('user_id')->nullable()->constrained('users')->cascadeOnDelete();
+            $table->timestamp('expires_at')->index();
+            $table->timestamps();
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::dropIfExists('oidc_states');
+    }
+};
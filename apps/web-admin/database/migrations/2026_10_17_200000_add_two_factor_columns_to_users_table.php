@@ -0,0 +1,25 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::table('users', function (Blueprint $table) {
+            $table->string('two_factor_secret', 64)->nullable()->after('password');
+            $table->timestamp('two_factor_confirmed_at')->nullable()->after('two_factor_secret');
+            // The last TOTP time step accepted, so a code can't be replayed within its window.
+            $table->unsignedBigInteger('two_factor_last_step')->nullable()->after('two_factor_confirmed_at');
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::table('users', function (Blueprint $table) {
+            $table->dropColumn(['two_factor_secret', 'two_factor_confirmed_at', 'two_factor_last_step']);
+        });
+    }
+};
@@ -0,0 +1,26 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::create('user_recovery_codes', function (Blueprint $table) {
+            $table->id();
+            $table->foreignId('user_id')->constrained('users')->cascadeOnDelete();
+            $table->char('code_hash', 64);
+            $table->timestamp('used_at')->nullable();
+            $table->timestamps();
+
+            $table->unique(['user_id', 'code_hash']);
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::dropIfExists('user_recovery_codes');
+    }
+};
@@ -0,0 +1,25 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::table('users', function (Blueprint $table) {
+            $table->timestamp('deletion_requested_at')->nullable()->after('two_factor_last_step');
+            // The account and everything it owns is purged once this passes, unless the user cancels first.
+            $table->timestamp('deletion_scheduled_at')->nullable()->index()->after('deletion_requested_at');
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::table('users', function (Blueprint $table) {
+            $table->dropIndex(['deletion_scheduled_at']);
+            $table->dropColumn(['deletion_requested_at', 'deletion_scheduled_at']);
+        });
+    }
+};
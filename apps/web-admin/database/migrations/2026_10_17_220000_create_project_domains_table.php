@@ -0,0 +1,31 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::create('project_domains', function (Blueprint $table) {
+            $table->id();
+            $table->foreignId('project_id')->constrained('projects')->cascadeOnDelete();
+            $table->string('host', 253);
+            // Value the owner publishes in a TXT record to prove they control the host.
+            $table->string('verification_token', 64);
+            $table->timestamp('verified_at')->nullable();
+            $table->timestamp('last_checked_at')->nullable();
+            $table->string('last_error', 255)->nullable();
+            $table->timestamps();
+
+            $table->unique(['project_id', 'host']);
+            $table->index(['host', 'verified_at']);
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::dropIfExists('project_domains');
+    }
+};
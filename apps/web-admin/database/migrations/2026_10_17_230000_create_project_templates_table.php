@@ -0,0 +1,32 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::create('project_templates', function (Blueprint $table) {
+            $table->id();
+            $table->string('key', 100)->unique();
+            $table->string('name');
+            $table->text('description')->nullable();
+            $table->enum('type', ['portfolio', 'biz', 'links', 'waitlist', 'linktree', 'menu'])->default('portfolio');
+            $table->string('preview_image_url')->nullable();
+            // The project whose content is copied; usually one kept on an internal account for this purpose.
+            $table->foreignId('source_project_id')->nullable()->constrained('projects')->nullOnDelete();
+            $table->integer('placement_order')->default(0);
+            $table->boolean('is_active')->default(true);
+            $table->timestamps();
+
+            $table->index(['is_active', 'type']);
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::dropIfExists('project_templates');
+    }
+};
@@ -0,0 +1,28 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::create('project_sub_domain_histories', function (Blueprint $table) {
+            $table->id();
+            $table->foreignId('project_id')->constrained('projects')->cascadeOnDelete();
+            // Owner at the time of the change; only they can claim the subdomain during the cooldown.
+            $table->foreignId('user_id')->constrained('users')->cascadeOnDelete();
+            $table->string('sub_domain', 255);
+            $table->timestamp('released_at');
+            $table->timestamp('created_at')->nullable();
+
+            $table->index(['sub_domain', 'released_at']);
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::dropIfExists('project_sub_domain_histories');
+    }
+};
@@ -0,0 +1,23 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::table('oidc_states', function (Blueprint $table) {
+            // Hash of the cookie set on the browser that started the sign-in; the callback must present it.
+            $table->char('browser_hash', 64)->default('')->after('state_hash');
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::table('oidc_states', function (Blueprint $table) {
+            $table->dropColumn('browser_hash');
+        });
+    }
+};
@@ -20,8 +20,6 @@ public function run(): void
                 'password' => Hash::make('password'),
                 'role' => 'super_admin',
                 'newsletter' => false,
-                'github' => false,
-                'google' => false,
             ],
         );
     }
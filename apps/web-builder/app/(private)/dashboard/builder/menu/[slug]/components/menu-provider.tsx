@@ -313,6 +313,7 @@ export function MenuProvider({ children }: { children: ReactNode }) {
       .filter((imageURL) => availableGalleryImages.includes(imageURL))
       .slice(0, 2);
 
+    const toastID = toast.loading('Generating display poster...');
     const response = await apiGenerateDisplayPoster({
       menu_id: menuID,
       project_id: project.id,
@@ -337,6 +338,7 @@ export function MenuProvider({ children }: { children: ReactNode }) {
         preferred_images: preferredImages,
       },
     });
+    toast.dismiss(toastID);
 
     if (!response.success || !response.data) {
       toast.error(response.message || 'Failed to generate display poster');
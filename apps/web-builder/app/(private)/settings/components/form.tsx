@@ -23,6 +23,7 @@ import { Avatar, AvatarFallback, AvatarImage } from '@/components/ui/avatar';
 import { Skeleton } from '@/components/ui/skeleton';
 import { Loader2, Github, Globe, Camera, CheckCircle2, CircleOff, LinkIcon } from 'lucide-react';
 import { useAuth } from '@/hooks/api/use-auth';
+import type { UserIdentity } from '@/types/user';
 import { Tooltip, TooltipContent, TooltipProvider, TooltipTrigger } from '@/components/ui/tooltip';
 
 export function SettingsForm() {
@@ -249,7 +250,7 @@ export function SettingsForm() {
                     </div>
                   </div>
 
-                  {user?.github ? (
+                  {user?.identities?.some((identity: UserIdentity) => identity.provider === 'github') ? (
                     <div className="inline-flex items-center gap-1.5 px-2.5 py-1 rounded-full text-[10px] font-semibold bg-emerald-100 text-emerald-700 dark:bg-emerald-500/15 dark:text-emerald-400 border border-emerald-200 dark:border-emerald-500/20">
                       <CheckCircle2 className="w-3 h-3" />
                       LINKED
@@ -276,7 +277,7 @@ export function SettingsForm() {
                     </div>
                   </div>
 
-                  {user?.google ? (
+                  {user?.identities?.some((identity: UserIdentity) => identity.provider === 'google') ? (
                     <div className="inline-flex items-center gap-1.5 px-2.5 py-1 rounded-full text-[10px] font-semibold bg-emerald-100 text-emerald-700 dark:bg-emerald-500/15 dark:text-emerald-400 border border-emerald-200 dark:border-emerald-500/20">
                       <CheckCircle2 className="w-3 h-3" />
                       LINKED
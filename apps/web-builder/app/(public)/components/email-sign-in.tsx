@@ -0,0 +1,199 @@
+'use client';
+
+import type React from 'react';
+import { useState } from 'react';
+import { useRouter } from 'next/navigation';
+import Link from 'next/link';
+import { Loader2 } from 'lucide-react';
+import { Button } from '@/components/ui/button';
+import { Input } from '@/components/ui/input';
+import { Label } from '@/components/ui/label';
+import { useLocalStorage } from '@/hooks/use-local-storage';
+import { AuthLoginData, AuthTwoFactorChallenge, AuthUser, useAuth } from '@/hooks/api/use-auth';
+
+export default function EmailSignIn({ disabled }: { disabled?: boolean }) {
+  const { login, completeTwoFactorLogin, setAuthUser } = useAuth();
+  const router = useRouter();
+  const [_, setAccessToken] = useLocalStorage<string | null>('access_token', null);
+  const [__, setStorageAuthUser] = useLocalStorage<AuthUser | null>('auth_user', null);
+
+  const [email, setEmail] = useState('');
+  const [password, setPassword] = useState('');
+  const [challenge, setChallenge] = useState<AuthTwoFactorChallenge | null>(null);
+  const [code, setCode] = useState('');
+  const [useRecoveryCode, setUseRecoveryCode] = useState(false);
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState('');
+
+  const signIn = async (data: AuthLoginData) => {
+    setAuthUser(data.user);
+    setStorageAuthUser(data.user);
+    setAccessToken(data.access_token);
+
+    const pendingRedirect = window.sessionStorage.getItem('post_auth_redirect');
+    if (pendingRedirect) {
+      window.sessionStorage.removeItem('post_auth_redirect');
+      await router.push(pendingRedirect);
+      return;
+    }
+
+    await router.push('/dashboard');
+  };
+
+  // The API drops a challenge once it expires or has seen too many wrong codes, so the
+  // user has to enter their password again.
+  const restart = (message: string) => {
+    setChallenge(null);
+    setCode('');
+    setUseRecoveryCode(false);
+    setError(message);
+  };
+
+  const handlePassword = async (event: React.FormEvent) => {
+    event.preventDefault();
+    setError('');
+    setLoading(true);
+
+    const { success, data, message } = await login(email, password);
+    setLoading(false);
+
+    if (!success || !data) {
+      setError(message || 'Login failed');
+      return;
+    }
+    if (data.two_factor_required) {
+      setPassword('');
+      setChallenge(data as AuthTwoFactorChallenge);
+      return;
+    }
+
+    await signIn(data as AuthLoginData);
+  };
+
+  const handleCode = async (event: React.FormEvent) => {
+    event.preventDefault();
+    if (!challenge) return;
+    setError('');
+    setLoading(true);
+
+    const { success, data, message } = await completeTwoFactorLogin(
+      challenge.challenge_token,
+      code,
+      useRecoveryCode
+    );
+    setLoading(false);
+
+    if (success && data) {
+      await signIn(data as AuthLoginData);
+      return;
+    }
+    if (message && /log in again/i.test(message)) {
+      restart(message);
+      return;
+    }
+
+    setCode('');
+    setError(message || 'Invalid authentication code');
+  };
+
+  if (challenge) {
+    return (
+      <form className="grid gap-4" onSubmit={handleCode}>
+        <div className="grid gap-2">
+          <Label htmlFor="two-factor-code">
+            {useRecoveryCode ? 'Recovery code' : 'Authentication code'}
+          </Label>
+          <p className="text-sm text-muted-foreground">
+            {useRecoveryCode
+              ? 'Enter one of the recovery codes you saved when you turned on two-factor authentication.'
+              : 'Enter the 6-digit code from your authenticator app.'}
+          </p>
+          <Input
+            id="two-factor-code"
+            value={code}
+            onChange={(event) => setCode(event.target.value)}
+            placeholder={useRecoveryCode ? 'XXXXX-XXXXX' : '123456'}
+            inputMode={useRecoveryCode ? 'text' : 'numeric'}
+            autoComplete="one-time-code"
+            autoFocus
+            className="h-11 bg-background"
+          />
+        </div>
+        {error ? <p className="text-sm text-red-500">{error}</p> : null}
+        <Button
+          className="h-11 w-full font-medium"
+          type="submit"
+          disabled={loading || !code.trim()}
+        >
+          {loading ? <Loader2 className="h-4 w-4 animate-spin" /> : 'Verify'}
+        </Button>
+        <div className="flex items-center justify-between text-sm">
+          <button
+            type="button"
+            className="font-medium text-primary hover:underline"
+            onClick={() => {
+              setUseRecoveryCode((current) => !current);
+              setCode('');
+              setError('');
+            }}
+          >
+            {useRecoveryCode ? 'Use an authentication code' : 'Use a recovery code'}
+          </button>
+          <button
+            type="button"
+            className="text-muted-foreground hover:underline"
+            onClick={() => restart('')}
+          >
+            Back
+          </button>
+        </div>
+      </form>
+    );
+  }
+
+  return (
+    <form className="grid gap-4" onSubmit={handlePassword}>
+      <div className="grid gap-2">
+        <Label htmlFor="email">Email</Label>
+        <Input
+          id="email"
+          value={email}
+          onChange={(event) => setEmail(event.target.value)}
+          placeholder="name@example.com"
+          type="email"
+          autoCapitalize="none"
+          autoComplete="email"
+          autoCorrect="off"
+          className="h-11 bg-background"
+        />
+      </div>
+      <div className="grid gap-2">
+        <div className="flex items-center justify-between">
+          <Label htmlFor="password">Password</Label>
+          <Link
+            href="/forgot-password"
+            className="text-sm font-medium text-primary hover:underline"
+          >
+            Forgot password?
+          </Link>
+        </div>
+        <Input
+          id="password"
+          value={password}
+          onChange={(event) => setPassword(event.target.value)}
+          type="password"
+          autoComplete="current-password"
+          className="h-11 bg-background"
+        />
+      </div>
+      {error ? <p className="text-sm text-red-500">{error}</p> : null}
+      <Button
+        className="h-11 w-full font-medium"
+        type="submit"
+        disabled={disabled || loading || !email.trim() || !password}
+      >
+        {loading ? <Loader2 className="h-4 w-4 animate-spin" /> : 'Sign In'}
+      </Button>
+    </form>
+  );
+}
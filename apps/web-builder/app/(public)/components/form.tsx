@@ -4,32 +4,12 @@ import type React from 'react';
 import { useState } from 'react';
 import { cn } from '@/lib/utils';
 import { Button } from '@/components/ui/button';
-import { Input } from '@/components/ui/input';
 import { Label } from '@/components/ui/label';
 import { Checkbox } from '@/components/ui/checkbox';
 import { useLocalStorage } from '@/hooks/use-local-storage';
 import Link from 'next/link';
 import { Github } from 'lucide-react';
-
-// 1. Restored ComingSoonWrapper
-function ComingSoonWrapper({
-  children,
-  className,
-}: {
-  children: React.ReactNode;
-  className?: string;
-}) {
-  return (
-    <div className={cn('relative overflow-hidden rounded-md', className)}>
-      <div className="opacity-60 pointer-events-none select-none filter blur-[1px]">{children}</div>
-      <div className="absolute inset-0 bg-background/10 backdrop-blur-[1px] flex items-center justify-center z-10 select-none">
-        <span className="bg-muted px-3 py-1 rounded-full text-xs font-bold uppercase tracking-wider border shadow-sm text-muted-foreground">
-          Coming Soon
-        </span>
-      </div>
-    </div>
-  );
-}
+import EmailSignIn from './email-sign-in';
 
 // Helper: Official Google Icon
 const GoogleIcon = ({ className }: { className?: string }) => (
@@ -132,37 +112,7 @@ export default function Form({ className, ...props }: React.ComponentPropsWithou
           </div>
         </div>
 
-        <ComingSoonWrapper>
-          <div className="grid gap-4">
-            <div className="grid gap-2">
-              <Label htmlFor="email">Email</Label>
-              <Input
-                id="email"
-                placeholder="name@example.com"
-                type="email"
-                autoCapitalize="none"
-                autoComplete="email"
-                autoCorrect="off"
-                className="h-11 bg-background"
-              />
-            </div>
-            <div className="grid gap-2">
-              <div className="flex items-center justify-between">
-                <Label htmlFor="password">Password</Label>
-                <Link
-                  href="/forgot-password"
-                  className="text-sm font-medium text-primary hover:underline"
-                >
-                  Forgot password?
-                </Link>
-              </div>
-              <Input id="password" type="password" className="h-11 bg-background" />
-            </div>
-            <Button className="h-11 w-full font-medium" type="submit">
-              Sign In
-            </Button>
-          </div>
-        </ComingSoonWrapper>
+        <EmailSignIn disabled={!termsAccepted} />
 
         {/* 4. Footer & Terms */}
         <div className="space-y-4 pt-2">
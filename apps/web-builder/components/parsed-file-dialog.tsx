@@ -218,9 +218,10 @@ export function ParsedFileDialog({
                             variant="outline"
                             size="sm"
                             className="shadow-none"
+                            disabled={item.status !== 'completed'}
                             onClick={() => handleSelectParsed(item)}
                           >
-                            Use
+                            {item.status === 'failed' ? 'Failed' : item.status === 'completed' ? 'Use' : 'Parsing...'}
                           </Button>
                         </TableCell>
                       </TableRow>
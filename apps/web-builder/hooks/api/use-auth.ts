@@ -23,6 +23,12 @@ export type AuthLoginData = {
   user: AuthUser;
 };
 
+export type AuthTwoFactorChallenge = {
+  two_factor_required: true;
+  challenge_token: string;
+  expires_at: string;
+};
+
 export function useAuth() {
   const { apiPost, apiGet } = useApi();
 
@@ -36,6 +42,13 @@ export function useAuth() {
     return await apiPost('api/auth/login', { email, password });
   };
 
+  const completeTwoFactorLogin = async (challengeToken: string, code: string, recovery = false) => {
+    return await apiPost('api/auth/two-factor/challenge', {
+      challenge_token: challengeToken,
+      ...(recovery ? { recovery_code: code } : { code }),
+    });
+  };
+
   const syncAuthUser = () => {
     if (storageAuthUser) {
       setAuthUser(storageAuthUser);
@@ -64,6 +77,7 @@ export function useAuth() {
 
   return {
     login,
+    completeTwoFactorLogin,
     authUser,
     setAuthUser,
     syncAuthUser,
@@ -1,18 +1,25 @@
 import { useApi } from '@/lib/api';
 import { useFormData } from '../use-form-data';
-import { APIResponseDocumentResume } from '@/types/api-response';
+import { useJob } from './use-job';
+import { APIResponseDocumentResume, APIResponseQueuedJob } from '@/types/api-response';
 
 export function useDocument() {
   const { apiPost } = useApi();
+  const { waitFor } = useJob();
 
   const parse = async (file: File, type: string) => {
-    return await apiPost<APIResponseDocumentResume>(
+    const queued = await apiPost<APIResponseQueuedJob>(
       'api/documents',
       useFormData({
         file,
         type,
       })
     );
+    if (!queued.success || !queued.data) {
+      return { ...queued, data: null };
+    }
+
+    return await waitFor<APIResponseDocumentResume>(queued.data.job.id);
   };
 
   return {
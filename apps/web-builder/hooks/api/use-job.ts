@@ -0,0 +1,40 @@
+import { APIResponse, useApi } from '@/lib/api';
+import { APIResponseJob } from '@/types/api-response';
+
+const POLL_INTERVAL_MS = 2000;
+const POLL_TIMEOUT_MS = 5 * 60 * 1000; // a worker gives a job at most 5 minutes per attempt
+
+export function useJob() {
+  const { apiGet } = useApi();
+
+  const show = async <T>(id: number) => {
+    return await apiGet<APIResponseJob<T>>(`api/jobs/${id}`);
+  };
+
+  // Polls a queued job until a worker finishes it and resolves like the endpoint would have, had it answered
+  // synchronously: the job's result on success, its error message otherwise.
+  const waitFor = async <T>(id: number): Promise<APIResponse<T>> => {
+    const deadline = Date.now() + POLL_TIMEOUT_MS;
+
+    while (Date.now() < deadline) {
+      const response = await show<T>(id);
+      if (!response.success || !response.data) {
+        return { success: false, status: response.status, message: response.message, data: null };
+      }
+
+      const job = response.data;
+      if (job.status === 'completed') {
+        return { success: true, status: 200, message: 'Success', data: job.result };
+      }
+      if (job.status === 'failed') {
+        return { success: false, status: 500, message: job.error || 'Job failed', data: null };
+      }
+
+      await new Promise((resolve) => setTimeout(resolve, POLL_INTERVAL_MS));
+    }
+
+    return { success: false, status: 408, message: 'Still working on it. Try again in a moment.', data: null };
+  };
+
+  return { show, waitFor };
+}
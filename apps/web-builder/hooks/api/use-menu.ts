@@ -1,7 +1,12 @@
 'use client';
 
 import { useApi } from '@/lib/api';
-import { APIResponseMenu, APIResponseMenuDisplayPosterSettings } from '@/types/api-response';
+import { useJob } from './use-job';
+import {
+  APIResponseMenu,
+  APIResponseMenuDisplayPosterSettings,
+  APIResponseQueuedJob,
+} from '@/types/api-response';
 
 interface GenerateDisplayPosterPayload {
   menu_id?: number | null;
@@ -41,13 +46,19 @@ interface GenerateDisplayPosterResponse {
 
 export function useMenu() {
   const { apiPost } = useApi();
+  const { waitFor } = useJob();
 
   const create = async (form: FormData) => {
     return await apiPost<APIResponseMenu>('api/menu', form);
   };
 
   const generateDisplayPoster = async (payload: GenerateDisplayPosterPayload) => {
-    return await apiPost<GenerateDisplayPosterResponse>('api/menu/display-poster', payload);
+    const queued = await apiPost<APIResponseQueuedJob>('api/menu/display-poster', payload);
+    if (!queued.success || !queued.data) {
+      return { ...queued, data: null };
+    }
+
+    return await waitFor<GenerateDisplayPosterResponse>(queued.data.job.id);
   };
 
   return {
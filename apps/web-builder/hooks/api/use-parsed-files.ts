@@ -1,4 +1,5 @@
 import { useApi } from '@/lib/api';
+import { useJob } from './use-job';
 import { APIResponseParsedFile, APIResponseParsedFilesList } from '@/types/api-response';
 
 interface ListParams {
@@ -15,6 +16,7 @@ interface CreateParams {
 
 export function useParsedFiles() {
   const { apiGet, apiPost } = useApi();
+  const { waitFor } = useJob();
 
   const list = async ({ projectType, page = 1, limit = 10 }: ListParams) => {
     return await apiGet<APIResponseParsedFilesList>(
@@ -28,7 +30,21 @@ export function useParsedFiles() {
     form.append('source_type', sourceType);
     files.forEach((file) => form.append('files', file));
 
-    return await apiPost<APIResponseParsedFile>('api/parsed-files', form);
+    const queued = await apiPost<APIResponseParsedFile>('api/parsed-files', form);
+    if (!queued.success || !queued.data?.job_id) {
+      return queued;
+    }
+
+    // The upload is stored as pending; its parse job fills in parsed_data.
+    const parsed = await waitFor<Record<string, any>>(queued.data.job_id);
+    if (!parsed.success) {
+      return { ...parsed, data: null };
+    }
+
+    return {
+      ...parsed,
+      data: { ...queued.data, status: 'completed' as const, parsed_data: parsed.data },
+    };
   };
 
   return { list, create };
@@ -1,9 +1,11 @@
 import { useApi } from '@/lib/api';
 import { ProjectFormValues } from '@/lib/schemas/project';
-import { APIResponseProject } from '@/types/api-response';
+import { useJob } from './use-job';
+import { APIResponseProject, APIResponseQueuedJob } from '@/types/api-response';
 
 export function useProject() {
   const { apiPost, apiGet, apiPut, apiDelete } = useApi();
+  const { waitFor } = useJob();
 
   const create = async (form: ProjectFormValues) => {
     return await apiPost<APIResponseProject>('api/projects', form);
@@ -31,6 +33,15 @@ export function useProject() {
     });
   };
 
+  const saveOGImage = async (id: number) => {
+    const queued = await apiPost<APIResponseQueuedJob>(`api/projects/og-image/${id}`, {});
+    if (!queued.success || !queued.data) {
+      return { ...queued, data: null };
+    }
+
+    return await waitFor<{ project_id: number; og_image_url: string | null }>(queued.data.job.id);
+  };
+
   const remove = async (id: number) => {
     return await apiDelete<APIResponseProject>(`api/projects/${id}`);
   };
@@ -41,6 +52,7 @@ export function useProject() {
     getList,
     getPublicList,
     publish,
+    saveOGImage,
     update,
     remove,
   };
@@ -5,6 +5,26 @@ export interface APIResponsePaginationMeta {
   total: number;
 }
 
+export type APIResponseJobStatus = 'pending' | 'processing' | 'completed' | 'failed';
+
+// Work that outlives a request (parsing, poster and OG image rendering) comes back as a queued job.
+export interface APIResponseJob<T = unknown> {
+  id: number;
+  type: string;
+  status: APIResponseJobStatus;
+  result: T | null;
+  error: string | null;
+  attempts: number;
+  max_attempts: number;
+  finished_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface APIResponseQueuedJob {
+  job: APIResponseJob;
+}
+
 export interface APIResponseDocumentResume {
   name: string;
   location: string;
@@ -54,7 +74,9 @@ export interface APIResponseParsedFile {
   project_type: string;
   source_type: string;
   source_name: string;
-  status: string;
+  status: APIResponseJobStatus;
+  job_id: number | null;
+  error: string | null;
   parsed_data: Record<string, any> | null;
   created_at: string;
   updated_at: string;
@@ -1,3 +1,10 @@
+export interface UserIdentity {
+  id: number;
+  provider: 'github' | 'google';
+  email: string | null;
+  last_login_at: string | null;
+}
+
 export interface User {
   id: number;
   first_name: string;
@@ -8,4 +15,8 @@ export interface User {
   created_at: string;
   updated_at: string;
   deleted_at: string | null;
+  two_factor_confirmed_at?: string | null;
+  deletion_requested_at?: string | null;
+  deletion_scheduled_at?: string | null;
+  identities?: UserIdentity[];
 }
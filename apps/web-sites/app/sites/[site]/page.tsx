@@ -3,21 +3,14 @@ import { headers } from 'next/headers';
 import { Builder } from '@/app/components/builder';
 import { SiteError } from '@/components/site-error';
 import { buildProjectJsonLd, buildProjectMetadata } from '@/lib/site-seo';
+import { rootDomain, SiteAddress, siteAddress, siteApiPath } from '@/lib/site-host';
 
-const getSubdomain = async () => {
+const getSiteAddress = async () => {
   const headersList = await headers();
-  const host = headersList.get('host') || '';
-
-  const hostname = host.split(':')[0];
-  const parts = hostname.split('.');
-
-  if (parts.length >= 2) {
-    return parts[0];
-  }
-  return null;
+  return siteAddress(headersList.get('host') || '');
 };
 
-const getLiveUrl = async (subdomain: string) => {
+const getLiveUrl = async (address: SiteAddress) => {
   const headersList = await headers();
   const forwardedProto = headersList.get('x-forwarded-proto');
   const host = headersList.get('host');
@@ -28,15 +21,14 @@ const getLiveUrl = async (subdomain: string) => {
     return `${protocol}://${host}`;
   }
 
-  const rootDomain = process.env.NEXT_PUBLIC_ROOT_DOMAIN || 'kislap.app';
-  return `https://${subdomain}.${rootDomain}`;
+  return address.host ? `https://${address.host}` : `https://${address.subdomain}.${rootDomain()}`;
 };
 
-async function getProject(subdomain: string) {
+async function getProject(address: SiteAddress) {
   const API_BASE_URL = process.env.NEXT_PUBLIC_API_BASE_URL || 'http://api.kislap.test';
 
   try {
-    const res = await fetch(`${API_BASE_URL}/api/projects/show/sub-domain/${subdomain}?level=full`);
+    const res = await fetch(`${API_BASE_URL}${siteApiPath(address)}`);
 
     if (!res.ok) return null;
     const json = await res.json();
@@ -51,25 +43,25 @@ export async function generateMetadata(
   props: { params: { site: string } },
   parent: ResolvingMetadata
 ): Promise<Metadata> {
-  const subdomain = await getSubdomain();
+  const address = await getSiteAddress();
 
-  if (!subdomain) return { title: 'Not Found' };
+  if (!address) return { title: 'Not Found' };
 
-  const project = await getProject(subdomain);
+  const project = await getProject(address);
   if (!project || !project.published) return { title: 'Not Found' };
 
-  const liveUrl = await getLiveUrl(subdomain);
+  const liveUrl = await getLiveUrl(address);
   return buildProjectMetadata(project, liveUrl);
 }
 
 export default async function Page() {
-  const subdomain = await getSubdomain();
+  const address = await getSiteAddress();
 
-  if (!subdomain) {
+  if (!address) {
     return <SiteError type="invalid-domain" />;
   }
 
-  const project = await getProject(subdomain);
+  const project = await getProject(address);
 
   if (!project) {
     return <SiteError type="not-found" />;
@@ -79,7 +71,7 @@ export default async function Page() {
     return <SiteError type="not-published" />;
   }
 
-  const liveUrl = await getLiveUrl(subdomain);
+  const liveUrl = await getLiveUrl(address);
   const jsonLd = buildProjectJsonLd(project, liveUrl);
 
   return (
@@ -88,7 +80,10 @@ export default async function Page() {
         type="application/ld+json"
         dangerouslySetInnerHTML={{ __html: JSON.stringify(jsonLd) }}
       />
-      <Builder initialProject={project} initialSubdomain={subdomain} />
+      <Builder
+        initialProject={project}
+        initialSubdomain={address.subdomain ?? address.host ?? ''}
+      />
     </>
   );
 }
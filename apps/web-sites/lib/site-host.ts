@@ -0,0 +1,34 @@
+// A site is reached on a subdomain of the root domain or on a custom domain its owner verified.
+export type SiteAddress =
+  | { subdomain: string; host?: never }
+  | { host: string; subdomain?: never };
+
+export function rootDomain() {
+  return (process.env.NEXT_PUBLIC_ROOT_DOMAIN || 'kislap.app').split(':')[0].toLowerCase();
+}
+
+// siteAddress works out which site a request is for from its Host header. The root domain
+// itself, its www and bare hosts such as localhost are not sites.
+export function siteAddress(host: string): SiteAddress | null {
+  const hostname = host.split(':')[0].toLowerCase().replace(/\.$/, '');
+  const root = rootDomain();
+
+  if (!hostname.includes('.') || hostname === root || hostname === `www.${root}`) {
+    return null;
+  }
+
+  if (hostname.endsWith(`.${root}`)) {
+    const subdomain = hostname.slice(0, -(root.length + 1));
+    return subdomain.includes('.') ? null : { subdomain };
+  }
+
+  return { host: hostname };
+}
+
+// siteApiPath is the public endpoint that returns the site's project.
+export function siteApiPath(address: SiteAddress) {
+  if (address.subdomain) {
+    return `/api/projects/show/sub-domain/${encodeURIComponent(address.subdomain)}?level=full`;
+  }
+  return `/api/projects/show/host/${encodeURIComponent(address.host!)}`;
+}
@@ -1,22 +1,16 @@
 import { NextRequest, NextResponse } from 'next/server';
+import { siteAddress } from '@/lib/site-host';
 
 export default function middleware(req: NextRequest) {
   const url = req.nextUrl;
   const response = NextResponse.next();
 
-  let hostname = req.headers.get('host') || '';
-  hostname = hostname.split(':')[0];
-  const rootDomain = process.env.NEXT_PUBLIC_ROOT_DOMAIN?.split(':')[0] || 'kislap.app';
-
-  const isSubdomain =
-    hostname.includes('.') &&
-    !hostname.includes('www') &&
-    !hostname.endsWith(rootDomain) &&
-    hostname !== 'localhost';
-
-  const currentHost = hostname.replace(`.${rootDomain}`, '');
-  if (hostname.includes('.') && !hostname.includes('www') && hostname !== rootDomain) {
-    url.pathname = `/sites/${currentHost}${url.pathname}`;
+  // Subdomains of the root domain and verified custom domains both render a site; the page
+  // resolves which one from the Host header.
+  const address = siteAddress(req.headers.get('host') || '');
+  if (address) {
+    const site = address.subdomain ?? address.host;
+    url.pathname = `/sites/${site}${url.pathname}`;
 
     return NextResponse.rewrite(url);
   }